@@ -0,0 +1,223 @@
+// Package apns sends notifications straight to Apple Push Notification service,
+// using token-based authentication with an APNs signing key.
+package apns
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/pkg/errors"
+	pushnotifications "github.com/pusher/push-notifications-go"
+)
+
+const (
+	productionBaseURL     = "https://api.push.apple.com"
+	sandboxBaseURL        = "https://api.sandbox.push.apple.com"
+	defaultRequestTimeout = 30 * time.Second
+	// responses are read up to this size, so a misbehaving proxy can't exhaust memory
+	maxResponseSize = 1 << 20
+	// APNs rejects tokens older than an hour, and refreshing more often than every 20 minutes
+	tokenRefreshInterval = 50 * time.Minute
+)
+
+// Credentials and settings for an APNs backend
+type Config struct {
+	// Id of the APNs signing key
+	KeyId string
+	// Id of the Apple developer team the key belongs to
+	TeamId string
+	// Contents of the `.p8` signing key file
+	PrivateKey []byte
+	// Bundle id of the app
+	Topic string
+	// Sends to the development environment instead of production
+	Sandbox bool
+}
+
+type Option func(*backend)
+
+// Sends requests to `url` instead of the APNs endpoints
+func WithCustomBaseURL(url string) Option {
+	return func(b *backend) {
+		b.baseURL = url
+	}
+}
+
+func WithRequestTimeout(timeout time.Duration) Option {
+	return func(b *backend) {
+		b.httpClient.Timeout = timeout
+	}
+}
+
+type backend struct {
+	config     Config
+	baseURL    string
+	httpClient *http.Client
+	signingKey interface{}
+
+	tokenMutex    sync.Mutex
+	token         string
+	tokenIssuedAt time.Time
+}
+
+// Creates a `pushnotifications.Backend` sending the `apns` section of publish requests to APNs.
+// Returns a non-nil error if the config is incomplete or the private key can't be parsed.
+func New(config Config, options ...Option) (pushnotifications.Backend, error) {
+	if config.KeyId == "" {
+		return nil, errors.New("Key Id cannot be an empty string")
+	}
+	if config.TeamId == "" {
+		return nil, errors.New("Team Id cannot be an empty string")
+	}
+	if config.Topic == "" {
+		return nil, errors.New("Topic cannot be an empty string")
+	}
+
+	signingKey, err := jwt.ParseECPrivateKeyFromPEM(config.PrivateKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to parse the APNs private key")
+	}
+
+	b := &backend{
+		config:     config,
+		baseURL:    productionBaseURL,
+		httpClient: &http.Client{Timeout: defaultRequestTimeout},
+		signingKey: signingKey,
+	}
+	if config.Sandbox {
+		b.baseURL = sandboxBaseURL
+	}
+
+	for _, option := range options {
+		option(b)
+	}
+
+	return b, nil
+}
+
+func (b *backend) Name() string {
+	return "apns"
+}
+
+func (b *backend) Send(deviceTokens []string, request map[string]interface{}) (*pushnotifications.SendResult, error) {
+	if len(deviceTokens) == 0 {
+		return nil, errors.New("Must supply at least one device token")
+	}
+
+	payload, ok := request["apns"].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("The publish request has no `apns` section")
+	}
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to marshal the APNs payload")
+	}
+
+	authToken, err := b.authToken()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &pushnotifications.SendResult{Failed: map[string]error{}}
+	for _, deviceToken := range deviceTokens {
+		err := b.sendToDevice(deviceToken, authToken, pushType(payload), payloadBytes)
+		if err != nil {
+			result.Failed[deviceToken] = err
+		} else {
+			result.Sent = append(result.Sent, deviceToken)
+		}
+	}
+
+	return result, nil
+}
+
+type errorResponse struct {
+	Reason string `json:"reason"`
+}
+
+func (b *backend) sendToDevice(deviceToken, authToken, pushType string, payloadBytes []byte) error {
+	URL := fmt.Sprintf("%s/3/device/%s", b.baseURL, deviceToken)
+	httpReq, err := http.NewRequest(http.MethodPost, URL, bytes.NewReader(payloadBytes))
+	if err != nil {
+		return errors.Wrap(err, "Failed to prepare the APNs request")
+	}
+
+	httpReq.Header.Set("Authorization", "bearer "+authToken)
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("apns-topic", b.config.Topic)
+	httpReq.Header.Set("apns-push-type", pushType)
+
+	httpResp, err := b.httpClient.Do(httpReq)
+	if err != nil {
+		return errors.Wrap(err, "Failed to send the APNs notification due to a network error")
+	}
+
+	defer httpResp.Body.Close()
+	responseBytes, err := io.ReadAll(io.LimitReader(httpResp.Body, maxResponseSize))
+	if err != nil {
+		return errors.Wrap(err, "Failed to read the APNs response due to a network error")
+	}
+
+	if httpResp.StatusCode >= 200 && httpResp.StatusCode < 300 {
+		return nil
+	}
+
+	errResponse := &errorResponse{}
+	if err := json.Unmarshal(responseBytes, errResponse); err != nil || errResponse.Reason == "" {
+		return errors.Wrap(pushnotifications.NewAPIError(httpResp.StatusCode, responseBytes), "Failed to send the APNs notification")
+	}
+
+	apiError := &pushnotifications.APIError{
+		StatusCode:  httpResp.StatusCode,
+		Code:        errResponse.Reason,
+		Description: "APNs rejected the notification",
+		Body:        responseBytes,
+	}
+	return errors.Wrap(apiError, "Failed to send the APNs notification")
+}
+
+// Returns the provider token used to authenticate with APNs, signing a new one when it's due
+func (b *backend) authToken() (string, error) {
+	b.tokenMutex.Lock()
+	defer b.tokenMutex.Unlock()
+
+	if b.token != "" && time.Since(b.tokenIssuedAt) < tokenRefreshInterval {
+		return b.token, nil
+	}
+
+	issuedAt := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"iss": b.config.TeamId,
+		"iat": issuedAt.Unix(),
+	})
+	token.Header["kid"] = b.config.KeyId
+
+	tokenString, err := token.SignedString(b.signingKey)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to sign the APNs provider token")
+	}
+
+	b.token = tokenString
+	b.tokenIssuedAt = issuedAt
+	return tokenString, nil
+}
+
+// Notifications without an alert, sound or badge must be sent as "background" pushes
+func pushType(payload map[string]interface{}) string {
+	aps, _ := payload["aps"].(map[string]interface{})
+	for _, key := range []string{"alert", "sound", "badge"} {
+		if _, ok := aps[key]; ok {
+			return "alert"
+		}
+	}
+	if _, ok := aps["content-available"]; ok {
+		return "background"
+	}
+	return "alert"
+}
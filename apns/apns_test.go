@@ -0,0 +1,131 @@
+package apns
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/pkg/errors"
+	pushnotifications "github.com/pusher/push-notifications-go"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func generateKey() (*ecdsa.PrivateKey, []byte) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	der, _ := x509.MarshalECPrivateKey(key)
+	return key, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+}
+
+func TestAPNs(t *testing.T) {
+	Convey("An APNs backend", t, func() {
+		key, keyPEM := generateKey()
+		config := Config{
+			KeyId:      "KEY123",
+			TeamId:     "TEAM123",
+			PrivateKey: keyPEM,
+			Topic:      "com.example.app",
+		}
+
+		Convey("should not be created with an incomplete config", func() {
+			config.Topic = ""
+			b, err := New(config)
+			So(b, ShouldBeNil)
+			So(err.Error(), ShouldContainSubstring, "Topic cannot be an empty string")
+		})
+
+		Convey("should not be created with an invalid private key", func() {
+			config.PrivateKey = []byte("not a key")
+			b, err := New(config)
+			So(b, ShouldBeNil)
+			So(err.Error(), ShouldContainSubstring, "Failed to parse the APNs private key")
+		})
+
+		Convey("given a server, it", func() {
+			var requests []*http.Request
+			var bodies []string
+			testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				requests = append(requests, r)
+				bodies = append(bodies, string(body))
+				if strings.HasSuffix(r.URL.Path, "/bad-token") {
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write([]byte(`{"reason":"BadDeviceToken"}`))
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer testServer.Close()
+
+			b, err := New(config, WithCustomBaseURL(testServer.URL))
+			So(err, ShouldBeNil)
+			So(b.Name(), ShouldEqual, "apns")
+
+			request := map[string]interface{}{
+				"apns": map[string]interface{}{
+					"aps": map[string]interface{}{"alert": "Hello"},
+				},
+				"fcm": map[string]interface{}{"notification": map[string]interface{}{"title": "Hello"}},
+			}
+
+			Convey("should send the apns section to every device", func() {
+				result, err := b.Send([]string{"token-1", "token-2"}, request)
+				So(err, ShouldBeNil)
+				So(result.Sent, ShouldResemble, []string{"token-1", "token-2"})
+				So(result.Failed, ShouldBeEmpty)
+
+				So(requests[0].URL.Path, ShouldEqual, "/3/device/token-1")
+				So(requests[1].URL.Path, ShouldEqual, "/3/device/token-2")
+				So(bodies[0], ShouldEqual, `{"aps":{"alert":"Hello"}}`)
+				So(requests[0].Header.Get("apns-topic"), ShouldEqual, "com.example.app")
+				So(requests[0].Header.Get("apns-push-type"), ShouldEqual, "alert")
+			})
+
+			Convey("should authenticate with a signed provider token", func() {
+				b.Send([]string{"token-1"}, request)
+
+				authorization := requests[0].Header.Get("Authorization")
+				So(authorization, ShouldStartWith, "bearer ")
+				token, err := jwt.Parse(strings.TrimPrefix(authorization, "bearer "), func(token *jwt.Token) (interface{}, error) {
+					return &key.PublicKey, nil
+				})
+				So(err, ShouldBeNil)
+				So(token.Header["kid"], ShouldEqual, "KEY123")
+				So(token.Claims.(jwt.MapClaims)["iss"], ShouldEqual, "TEAM123")
+			})
+
+			Convey("should report devices that were rejected", func() {
+				result, err := b.Send([]string{"token-1", "bad-token"}, request)
+				So(err, ShouldBeNil)
+				So(result.Sent, ShouldResemble, []string{"token-1"})
+
+				apiError, ok := errors.Cause(result.Failed["bad-token"]).(*pushnotifications.APIError)
+				So(ok, ShouldBeTrue)
+				So(apiError.StatusCode, ShouldEqual, http.StatusBadRequest)
+				So(apiError.Code, ShouldEqual, "BadDeviceToken")
+			})
+
+			Convey("should send background pushes for silent notifications", func() {
+				request["apns"] = map[string]interface{}{
+					"aps": map[string]interface{}{"content-available": 1},
+				}
+				b.Send([]string{"token-1"}, request)
+				So(requests[0].Header.Get("apns-push-type"), ShouldEqual, "background")
+			})
+
+			Convey("should fail if the request has no apns section", func() {
+				result, err := b.Send([]string{"token-1"}, map[string]interface{}{})
+				So(result, ShouldBeNil)
+				So(err.Error(), ShouldContainSubstring, "no `apns` section")
+				So(requests, ShouldBeEmpty)
+			})
+		})
+	})
+}
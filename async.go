@@ -0,0 +1,294 @@
+package pushnotifications
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// How urgently a queued publish must be sent, when the queue is longer than the workers can keep up with
+type Priority int
+
+const (
+	// Bulk traffic, such as campaigns
+	MarketingPriority Priority = iota
+	NormalPriority
+	// Notifications users are waiting for, such as login codes and alerts
+	TransactionalPriority
+
+	numPriorities = int(TransactionalPriority) + 1
+)
+
+const (
+	defaultAsyncWorkers   = 4
+	defaultAsyncQueueSize = 1000
+)
+
+var (
+	// Returned by `Enqueue` and `TryEnqueue` once the publisher is closed
+	ErrPublisherClosed = errors.New("The async publisher is closed")
+	// Returned by `TryEnqueue` when the queue is full
+	ErrQueueFull = errors.New("The async publisher queue is full")
+)
+
+// A publish waiting in the queue of an `AsyncPublisher`
+type QueuedPublish struct {
+	// Interests or users to publish to
+	Target   Target
+	Request  map[string]interface{}
+	Priority Priority
+	Options  []CallOption
+	// When set, the publish is dropped with an `*ExpiredError` if it is still queued at that time,
+	// e.g. a flash sale or a login code held up by an outage
+	ExpiresAt time.Time
+	// Called from a worker once the publish is done, if not nil
+	Done func(publishId string, err error)
+}
+
+// Passed to `QueuedPublish.Done` when a publish expired before it could be sent
+type ExpiredError struct {
+	ExpiresAt time.Time
+	DroppedAt time.Time
+}
+
+func (e *ExpiredError) Error() string {
+	return fmt.Sprintf("The publish expired at %s and was dropped %s later",
+		e.ExpiresAt.Format(time.RFC3339), e.DroppedAt.Sub(e.ExpiresAt))
+}
+
+// Settings of an `AsyncPublisher`
+type AsyncConfig struct {
+	// Number of publishes sent at once. Defaults to 4.
+	Workers int
+	// Number of publishes the queue holds before `Enqueue` blocks and `TryEnqueue` fails.
+	// Transactional publishes are accepted beyond it. Defaults to 1000.
+	QueueSize int
+	// Merges queued publishes of the same priority to users with identical requests and no
+	// call options into publishes to up to `Limits.MaxUsers` users, to make fewer calls to the Beams API
+	Coalesce bool
+}
+
+type queuedPublish struct {
+	QueuedPublish
+	// The encoded request, for publishes that can be coalesced
+	coalesceKey string
+}
+
+// Publishes in the background from a queue, highest priority first and in order within a priority
+type AsyncPublisher struct {
+	pn        PushNotifications
+	queueSize int
+	coalesce  bool
+	limits    Limits
+
+	mutex   sync.Mutex
+	changed *sync.Cond
+	queues  [numPriorities][]queuedPublish
+	depth   int
+	closed  bool
+	workers sync.WaitGroup
+}
+
+// Returns an `AsyncPublisher` publishing with `pn`, its workers started
+func NewAsyncPublisher(pn PushNotifications, config AsyncConfig) *AsyncPublisher {
+	if config.Workers <= 0 {
+		config.Workers = defaultAsyncWorkers
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = defaultAsyncQueueSize
+	}
+
+	p := &AsyncPublisher{pn: pn, queueSize: config.QueueSize, coalesce: config.Coalesce, limits: limitsOf(pn)}
+	p.changed = sync.NewCond(&p.mutex)
+	for i := 0; i < config.Workers; i++ {
+		p.workers.Add(1)
+		go p.work()
+	}
+	return p
+}
+
+// Adds the publish to the queue, waiting for room if the queue is full, unless it is transactional.
+// Returns `ErrPublisherClosed` if the publisher is closed.
+func (p *AsyncPublisher) Enqueue(publish QueuedPublish) error {
+	return p.enqueue(publish, true)
+}
+
+// Like `Enqueue`, but returns `ErrQueueFull` rather than waiting when the queue is full,
+// so that producers can shed load. Transactional publishes are always accepted.
+func (p *AsyncPublisher) TryEnqueue(publish QueuedPublish) error {
+	return p.enqueue(publish, false)
+}
+
+func (p *AsyncPublisher) enqueue(publish QueuedPublish, wait bool) error {
+	if publish.Priority < MarketingPriority || int(publish.Priority) >= numPriorities {
+		return newValidationError("Unknown priority %d", publish.Priority)
+	}
+
+	queued := queuedPublish{QueuedPublish: publish}
+	if p.coalesce && publish.Target.Kind == UsersTarget && len(publish.Options) == 0 {
+		// keys of maps are sorted when encoded, so identical requests encode identically
+		if request, err := json.Marshal(publish.Request); err == nil {
+			queued.coalesceKey = string(request)
+		}
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for p.full(publish.Priority) && !p.closed {
+		if !wait {
+			return ErrQueueFull
+		}
+		p.changed.Wait()
+	}
+	if p.closed {
+		return ErrPublisherClosed
+	}
+	p.queues[publish.Priority] = append(p.queues[publish.Priority], queued)
+	p.depth++
+	p.changed.Broadcast()
+	return nil
+}
+
+func (p *AsyncPublisher) full(priority Priority) bool {
+	return priority != TransactionalPriority && p.depth >= p.queueSize
+}
+
+// Returns the number of publishes waiting in the queue
+func (p *AsyncPublisher) Depth() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.depth
+}
+
+// Returns the number of publishes of the priority waiting in the queue
+func (p *AsyncPublisher) DepthOf(priority Priority) int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if priority < MarketingPriority || int(priority) >= numPriorities {
+		return 0
+	}
+	return len(p.queues[priority])
+}
+
+// Stops accepting publishes and waits for those in the queue to be sent
+func (p *AsyncPublisher) Close() {
+	p.mutex.Lock()
+	p.closed = true
+	p.changed.Broadcast()
+	p.mutex.Unlock()
+
+	p.workers.Wait()
+}
+
+func (p *AsyncPublisher) work() {
+	defer p.workers.Done()
+	for {
+		publishes, ok := p.next()
+		if !ok {
+			return
+		}
+		p.send(publishes)
+	}
+}
+
+// Takes the oldest publish of the highest priority, and those of the same priority it can be
+// coalesced with, waiting for one if the queue is empty.
+// Returns false once the publisher is closed and the queue drained.
+func (p *AsyncPublisher) next() ([]QueuedPublish, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for p.depth == 0 && !p.closed {
+		p.changed.Wait()
+	}
+	for priority := numPriorities - 1; priority >= 0; priority-- {
+		queue := p.queues[priority]
+		if len(queue) == 0 {
+			continue
+		}
+
+		first := queue[0]
+		publishes := []QueuedPublish{first.QueuedPublish}
+		users := len(first.Target.Ids)
+		remaining := queue[:0]
+		for _, queued := range queue[1:] {
+			if first.coalesceKey != "" && queued.coalesceKey == first.coalesceKey &&
+				users+len(queued.Target.Ids) <= p.limits.MaxUsers {
+				publishes = append(publishes, queued.QueuedPublish)
+				users += len(queued.Target.Ids)
+			} else {
+				remaining = append(remaining, queued)
+			}
+		}
+		for i := len(remaining); i < len(queue); i++ {
+			queue[i] = queuedPublish{}
+		}
+
+		p.queues[priority] = remaining
+		p.depth -= len(publishes)
+		p.changed.Broadcast()
+		return publishes, true
+	}
+	return nil, false
+}
+
+// Sends the publishes as one, the publishes being either a single one or coalesced ones
+func (p *AsyncPublisher) send(publishes []QueuedPublish) {
+	publishes = dropExpired(publishes, time.Now())
+	if len(publishes) == 0 {
+		return
+	}
+
+	publish := publishes[0]
+	if len(publishes) > 1 {
+		publish.Target = Users(mergeUsers(publishes)...)
+	}
+
+	var publishId string
+	var err error
+	switch publish.Target.Kind {
+	case InterestsTarget:
+		publishId, err = p.pn.PublishToInterests(publish.Target.Ids, publish.Request, publish.Options...)
+	case UsersTarget:
+		publishId, err = p.pn.PublishToUsers(publish.Target.Ids, publish.Request, publish.Options...)
+	default:
+		err = newValidationError("The async publisher can only publish to interests and users")
+	}
+
+	for _, publish := range publishes {
+		if publish.Done != nil {
+			publish.Done(publishId, err)
+		}
+	}
+}
+
+// Calls `Done` with an `*ExpiredError` for the expired publishes, returning the others
+func dropExpired(publishes []QueuedPublish, now time.Time) []QueuedPublish {
+	live := publishes[:0]
+	for _, publish := range publishes {
+		if publish.ExpiresAt.IsZero() || now.Before(publish.ExpiresAt) {
+			live = append(live, publish)
+		} else if publish.Done != nil {
+			publish.Done("", &ExpiredError{ExpiresAt: publish.ExpiresAt, DroppedAt: now})
+		}
+	}
+	return live
+}
+
+func mergeUsers(publishes []QueuedPublish) []string {
+	seen := map[string]bool{}
+	users := []string{}
+	for _, publish := range publishes {
+		for _, userId := range publish.Target.Ids {
+			if !seen[userId] {
+				seen[userId] = true
+				users = append(users, userId)
+			}
+		}
+	}
+	return users
+}
@@ -0,0 +1,137 @@
+package pushnotifications
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAsyncPublisher(t *testing.T) {
+	Convey("An async publisher", t, func() {
+		mutex := sync.Mutex{}
+		published := []string{}
+		blocked := make(chan struct{})
+		release := make(chan struct{})
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			request := struct {
+				Interests []string `json:"interests"`
+				Users     []string `json:"users"`
+			}{}
+			json.Unmarshal(body, &request)
+			if len(request.Interests) > 0 && request.Interests[0] == "blocker" {
+				close(blocked)
+				<-release
+			}
+
+			mutex.Lock()
+			published = append(published, append(request.Interests, request.Users...)...)
+			mutex.Unlock()
+			w.Write([]byte(`{"publishId":"pub-123"}`))
+		}))
+		defer testServer.Close()
+
+		pn, _ := New(testInstanceId, testSecretKey, WithCustomBaseURL(testServer.URL))
+		p := NewAsyncPublisher(pn, AsyncConfig{Workers: 1})
+		request := map[string]interface{}{"fcm": map[string]interface{}{}}
+
+		Convey("should publish in the background and report the outcome", func() {
+			done := make(chan string, 1)
+			err := p.Enqueue(QueuedPublish{
+				Target:  Users("user-1"),
+				Request: request,
+				Done:    func(publishId string, err error) { done <- publishId },
+			})
+			So(err, ShouldBeNil)
+			So(<-done, ShouldEqual, "pub-123")
+			p.Close()
+		})
+
+		Convey("should publish higher priorities first", func() {
+			// keeps the only worker busy while the queue fills up
+			p.Enqueue(QueuedPublish{Target: Interests("blocker"), Request: request})
+			<-blocked
+			p.Enqueue(QueuedPublish{Target: Interests("campaign"), Request: request, Priority: MarketingPriority})
+			p.Enqueue(QueuedPublish{Target: Interests("news"), Request: request, Priority: NormalPriority})
+			p.Enqueue(QueuedPublish{Target: Users("otp"), Request: request, Priority: TransactionalPriority})
+			close(release)
+			p.Close()
+
+			So(published, ShouldResemble, []string{"blocker", "otp", "news", "campaign"})
+		})
+
+		Convey("should shed load when the queue is full", func() {
+			p.Close()
+			p := NewAsyncPublisher(pn, AsyncConfig{Workers: 1, QueueSize: 1})
+			p.Enqueue(QueuedPublish{Target: Interests("blocker"), Request: request})
+			<-blocked
+
+			So(p.TryEnqueue(QueuedPublish{Target: Interests("campaign"), Request: request}), ShouldBeNil)
+			So(p.TryEnqueue(QueuedPublish{Target: Interests("campaign"), Request: request}), ShouldEqual, ErrQueueFull)
+			So(p.TryEnqueue(QueuedPublish{Target: Users("otp"), Request: request, Priority: TransactionalPriority}), ShouldBeNil)
+			So(p.Depth(), ShouldEqual, 2)
+			So(p.DepthOf(MarketingPriority), ShouldEqual, 1)
+			So(p.DepthOf(TransactionalPriority), ShouldEqual, 1)
+
+			close(release)
+			p.Close()
+			So(p.Depth(), ShouldEqual, 0)
+			So(published, ShouldResemble, []string{"blocker", "otp", "campaign"})
+		})
+
+		Convey("should coalesce publishes of identical requests to users", func() {
+			p.Close()
+			p := NewAsyncPublisher(pn, AsyncConfig{Workers: 1, Coalesce: true})
+			p.Enqueue(QueuedPublish{Target: Interests("blocker"), Request: request})
+			<-blocked
+
+			publishIds := make(chan string, 4)
+			done := func(publishId string, err error) { publishIds <- publishId }
+			p.Enqueue(QueuedPublish{Target: Users("user-1"), Request: request, Done: done})
+			p.Enqueue(QueuedPublish{Target: Users("user-2", "user-1"), Request: request, Done: done})
+			p.Enqueue(QueuedPublish{Target: Users("user-3"), Request: map[string]interface{}{"web": map[string]interface{}{}}, Done: done})
+			p.Enqueue(QueuedPublish{Target: Users("user-4"), Request: map[string]interface{}{"fcm": map[string]interface{}{}}, Done: done})
+			close(release)
+			p.Close()
+
+			So(published, ShouldResemble, []string{"blocker", "user-1", "user-2", "user-4", "user-3"})
+			So(len(publishIds), ShouldEqual, 4)
+		})
+
+		Convey("should drop publishes that expired in the queue", func() {
+			p.Enqueue(QueuedPublish{Target: Interests("blocker"), Request: request})
+			<-blocked
+
+			errs := make(chan error, 2)
+			done := func(publishId string, err error) { errs <- err }
+			p.Enqueue(QueuedPublish{Target: Users("flash-sale"), Request: request, ExpiresAt: time.Now().Add(10 * time.Millisecond), Done: done})
+			p.Enqueue(QueuedPublish{Target: Users("news"), Request: request, ExpiresAt: time.Now().Add(time.Hour), Done: done})
+			time.Sleep(20 * time.Millisecond)
+			close(release)
+			p.Close()
+
+			expired, ok := (<-errs).(*ExpiredError)
+			So(ok, ShouldBeTrue)
+			So(expired.Error(), ShouldStartWith, "The publish expired at")
+			So(<-errs, ShouldBeNil)
+			So(published, ShouldResemble, []string{"blocker", "news"})
+		})
+
+		Convey("should refuse publishes once closed", func() {
+			p.Close()
+			So(p.Enqueue(QueuedPublish{Target: Users("user-1"), Request: request}), ShouldEqual, ErrPublisherClosed)
+		})
+
+		Convey("should refuse unknown priorities", func() {
+			err := p.Enqueue(QueuedPublish{Target: Users("user-1"), Request: request, Priority: 7})
+			So(Classify(err), ShouldEqual, Validation)
+			p.Close()
+		})
+	})
+}
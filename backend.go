@@ -0,0 +1,22 @@
+package pushnotifications
+
+// Delivers publish requests straight to devices, without going through the Beams service.
+// Backends take the same request as `PublishToUsers`, each using the section for its platform
+// (e.g. `apns`), so a notification can be sent through Beams or directly with no changes.
+type Backend interface {
+	// Short name of the backend, e.g. "apns"
+	Name() string
+
+	// Sends the notification to every device token given.
+	// Returns a non-nil `error` only if nothing could be sent; failures of individual
+	// devices are reported in the result.
+	Send(deviceTokens []string, request map[string]interface{}) (result *SendResult, err error)
+}
+
+// Outcome of sending a notification through a `Backend`
+type SendResult struct {
+	// Device tokens the notification was accepted for
+	Sent []string
+	// Errors for the device tokens the notification could not be sent to
+	Failed map[string]error
+}
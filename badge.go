@@ -0,0 +1,84 @@
+package pushnotifications
+
+import "sync"
+
+// Keeps count of the unread notifications of each user, for apps that track them server-side.
+// Implementations must be safe for concurrent use.
+type BadgeStore interface {
+	// Adds `delta` to the user's count, and returns the new count
+	Add(userId string, delta int) (int, error)
+	// Sets the user's count back to zero
+	Reset(userId string) error
+}
+
+// Returns a `BadgeStore` keeping counts in memory, for tests and single-process services
+func NewMemoryBadgeStore() BadgeStore {
+	return &memoryBadgeStore{counts: map[string]int{}}
+}
+
+type memoryBadgeStore struct {
+	mutex  sync.Mutex
+	counts map[string]int
+}
+
+func (s *memoryBadgeStore) Add(userId string, delta int) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	count := s.counts[userId] + delta
+	if count < 0 {
+		count = 0
+	}
+	s.counts[userId] = count
+	return count, nil
+}
+
+func (s *memoryBadgeStore) Reset(userId string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.counts, userId)
+	return nil
+}
+
+// Sets the number shown on the app icon. APNs leaves the badge unchanged when it is
+// not set, and removes it when it is set to 0.
+func (n *Notification) Badge(count int) *Notification {
+	if count < 0 {
+		n.fail(newValidationError("Badge count cannot be negative, got %d", count))
+		return n
+	}
+	n.badge = &count
+	return n
+}
+
+// Removes the badge from the app icon
+func (n *Notification) ClearBadge() *Notification {
+	return n.Badge(0)
+}
+
+// Adds one to the user's unread count in `store`, and shows the new count on the app icon.
+// The count is added to when the notification is built, before it is published, so callers must
+// take it back with `store.Add(userId, -1)` if the publish fails. It is left as it is if the
+// notification already failed to build.
+func (n *Notification) IncrementBadge(store BadgeStore, userId string) *Notification {
+	if n.err != nil {
+		return n
+	}
+	count, err := store.Add(userId, 1)
+	if err != nil {
+		n.fail(err)
+		return n
+	}
+	return n.Badge(count)
+}
+
+// Returns a publish request that only removes the badge from the app icon, without alerting the user.
+// Publish it to a user once they have read their notifications, after resetting their count.
+func ClearBadgeRequest() map[string]interface{} {
+	return map[string]interface{}{
+		"apns": map[string]interface{}{
+			"aps": map[string]interface{}{"badge": 0},
+		},
+	}
+}
@@ -0,0 +1,58 @@
+package pushnotifications
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBadges(t *testing.T) {
+	Convey("Managing the APNs badge", t, func() {
+		aps := func(n *Notification) map[string]interface{} {
+			request, err := n.Request()
+			So(err, ShouldBeNil)
+			return request["apns"].(map[string]interface{})["aps"].(map[string]interface{})
+		}
+
+		Convey("should leave the badge unchanged unless it is set", func() {
+			So(aps(NewNotification("Hi", "Hello")), ShouldNotContainKey, "badge")
+			So(aps(NewNotification("Hi", "Hello").Badge(3))["badge"], ShouldEqual, 3)
+		})
+
+		Convey("should clear the badge by setting it to 0", func() {
+			So(aps(NewNotification("Hi", "Hello").ClearBadge())["badge"], ShouldEqual, 0)
+			So(ClearBadgeRequest(), ShouldResemble, map[string]interface{}{
+				"apns": map[string]interface{}{"aps": map[string]interface{}{"badge": 0}},
+			})
+			So(ValidateRequest(ClearBadgeRequest()), ShouldBeNil)
+		})
+
+		Convey("should reject negative badges", func() {
+			_, err := NewNotification("Hi", "Hello").Badge(-1).Request()
+			So(Classify(err), ShouldEqual, Validation)
+		})
+
+		Convey("should increment the count kept in a store", func() {
+			store := NewMemoryBadgeStore()
+			So(aps(NewNotification("Hi", "Hello").IncrementBadge(store, "user-1"))["badge"], ShouldEqual, 1)
+			So(aps(NewNotification("Hi", "Hello").IncrementBadge(store, "user-1"))["badge"], ShouldEqual, 2)
+			So(aps(NewNotification("Hi", "Hello").IncrementBadge(store, "user-2"))["badge"], ShouldEqual, 1)
+
+			So(store.Reset("user-1"), ShouldBeNil)
+			So(aps(NewNotification("Hi", "Hello").IncrementBadge(store, "user-1"))["badge"], ShouldEqual, 1)
+
+			count, err := store.Add("user-2", -5)
+			So(err, ShouldBeNil)
+			So(count, ShouldEqual, 0)
+		})
+
+		Convey("should not count notifications that failed to build", func() {
+			store := NewMemoryBadgeStore()
+			_, err := NewNotification("Hi", "Hello").Badge(-1).IncrementBadge(store, "user-1").Request()
+			So(Classify(err), ShouldEqual, Validation)
+
+			count, _ := store.Add("user-1", 0)
+			So(count, ShouldEqual, 0)
+		})
+	})
+}
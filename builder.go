@@ -0,0 +1,174 @@
+package pushnotifications
+
+import "net/url"
+
+// Keys of the `apns` data read by a Notification Service Extension to download the attachment
+const (
+	AttachmentURLDataKey  = "attachment-url"
+	AttachmentTypeDataKey = "attachment-type"
+)
+
+// Key of the `apns` and `fcm` data holding the URL set with `Notification.DeepLink`
+const DeepLinkDataKey = "deep_link"
+
+const maxDeepLinkLength = 2000
+
+// Schemes that would run code or read local files if opened from a notification
+var forbiddenDeepLinkSchemes = map[string]bool{
+	"javascript": true,
+	"data":       true,
+	"file":       true,
+	"vbscript":   true,
+}
+
+// Builds a publish request for every platform from a single description of a notification.
+// Methods can be chained, and the first problem found is returned by `Request`.
+type Notification struct {
+	title      string
+	body       string
+	data       map[string]interface{}
+	attachment *attachment
+	category   *Category
+	badge      *int
+	deepLink   string
+	err        error
+}
+
+type attachment struct {
+	url  string
+	kind string
+}
+
+// Starts building a notification with the given title and body
+func NewNotification(title, body string) *Notification {
+	return &Notification{title: title, body: body}
+}
+
+// Sets the custom data sent along with the notification on every platform
+func (n *Notification) Data(data map[string]interface{}) *Notification {
+	n.data = data
+	return n
+}
+
+// Shows the image at `imageURL` in the notification. FCM and web notifications display
+// it directly, while APNs notifications are marked `mutable-content` so that the app's
+// Notification Service Extension can download it from the `attachment-url` data.
+func (n *Notification) Image(imageURL string) *Notification {
+	return n.attach(imageURL, "image")
+}
+
+// Attaches the video at `videoURL` to APNs notifications, through the same
+// `mutable-content` flow as `Image`. Other platforms can't show videos and ignore it.
+func (n *Notification) Video(videoURL string) *Notification {
+	return n.attach(videoURL, "video")
+}
+
+// Shows the buttons of `category` on the notification. APNs notifications carry the category id,
+// and the app must have registered the category's actions; web notifications list the actions.
+func (n *Notification) Category(category Category) *Notification {
+	if err := category.validate(); err != nil {
+		n.fail(err)
+		return n
+	}
+	n.category = &category
+	return n
+}
+
+// Opens `link` when the notification is tapped. The link is set as the `deep_link` of web
+// notifications, and in the `deep_link` data of APNs and FCM notifications for the app to open.
+// Links with a custom scheme, such as `myapp://orders/1`, are only sent to the mobile apps
+// because browsers can't open them.
+func (n *Notification) DeepLink(link string) *Notification {
+	if len(link) > maxDeepLinkLength {
+		n.fail(newValidationError("Deep link is %d characters long, up to %d are allowed", len(link), maxDeepLinkLength))
+		return n
+	}
+	parsed, err := url.Parse(link)
+	if err != nil || parsed.Scheme == "" {
+		n.fail(newValidationError("Deep link `%s` must be an absolute URL", link))
+		return n
+	}
+	if forbiddenDeepLinkSchemes[parsed.Scheme] {
+		n.fail(newValidationError("Deep link scheme `%s` is not allowed", parsed.Scheme))
+		return n
+	}
+	n.deepLink = link
+	return n
+}
+
+func isWebURL(link string) bool {
+	parsed, err := url.Parse(link)
+	return err == nil && (parsed.Scheme == "https" || parsed.Scheme == "http")
+}
+
+func (n *Notification) attach(attachmentURL, kind string) *Notification {
+	parsed, err := url.Parse(attachmentURL)
+	if err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+		n.fail(newValidationError("Attachment URL `%s` must be an absolute https URL", attachmentURL))
+		return n
+	}
+	n.attachment = &attachment{url: attachmentURL, kind: kind}
+	return n
+}
+
+func (n *Notification) fail(err error) {
+	if n.err == nil {
+		n.err = err
+	}
+}
+
+// Returns the publish request with the `apns`, `fcm` and `web` sections of the notification,
+// or the first problem found while building it
+func (n *Notification) Request() (map[string]interface{}, error) {
+	if n.err != nil {
+		return nil, n.err
+	}
+
+	aps := map[string]interface{}{
+		"alert": map[string]interface{}{"title": n.title, "body": n.body},
+	}
+	apnsData := copyRequest(n.data)
+	fcmNotification := map[string]interface{}{"title": n.title, "body": n.body}
+	webNotification := map[string]interface{}{"title": n.title, "body": n.body}
+
+	if n.attachment != nil {
+		aps["mutable-content"] = 1
+		apnsData[AttachmentURLDataKey] = n.attachment.url
+		apnsData[AttachmentTypeDataKey] = n.attachment.kind
+		if n.attachment.kind == "image" {
+			fcmNotification["image"] = n.attachment.url
+			webNotification["image"] = n.attachment.url
+		}
+	}
+
+	if n.badge != nil {
+		aps["badge"] = *n.badge
+	}
+	fcmData := copyRequest(n.data)
+	if n.deepLink != "" {
+		apnsData[DeepLinkDataKey] = n.deepLink
+		fcmData[DeepLinkDataKey] = n.deepLink
+		if isWebURL(n.deepLink) {
+			webNotification["deep_link"] = n.deepLink
+		}
+	}
+	if n.category != nil {
+		aps["category"] = n.category.Id
+		webNotification["actions"] = n.category.webActions()
+	}
+
+	apns := map[string]interface{}{"aps": aps}
+	fcm := map[string]interface{}{"notification": fcmNotification}
+	web := map[string]interface{}{"notification": webNotification}
+	if len(apnsData) > 0 {
+		apns["data"] = apnsData
+	}
+	if len(fcmData) > 0 {
+		fcm["data"] = fcmData
+	}
+	if n.data != nil {
+		web["data"] = copyRequest(n.data)
+	}
+
+	return map[string]interface{}{"apns": apns, "fcm": fcm, "web": web}, nil
+}
@@ -0,0 +1,139 @@
+package pushnotifications
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNotificationBuilder(t *testing.T) {
+	Convey("Building a notification", t, func() {
+		Convey("should fill in every platform", func() {
+			request, err := NewNotification("Hi", "Hello").Data(map[string]interface{}{"id": "1"}).Request()
+			So(err, ShouldBeNil)
+			So(request, ShouldResemble, map[string]interface{}{
+				"apns": map[string]interface{}{
+					"aps":  map[string]interface{}{"alert": map[string]interface{}{"title": "Hi", "body": "Hello"}},
+					"data": map[string]interface{}{"id": "1"},
+				},
+				"fcm": map[string]interface{}{
+					"notification": map[string]interface{}{"title": "Hi", "body": "Hello"},
+					"data":         map[string]interface{}{"id": "1"},
+				},
+				"web": map[string]interface{}{
+					"notification": map[string]interface{}{"title": "Hi", "body": "Hello"},
+					"data":         map[string]interface{}{"id": "1"},
+				},
+			})
+			So(ValidateRequest(request), ShouldBeNil)
+		})
+
+		Convey("should attach images to every platform", func() {
+			request, err := NewNotification("Hi", "Hello").Image("https://example.com/cat.jpg").Request()
+			So(err, ShouldBeNil)
+
+			apns := request["apns"].(map[string]interface{})
+			So(apns["aps"].(map[string]interface{})["mutable-content"], ShouldEqual, 1)
+			So(apns["data"], ShouldResemble, map[string]interface{}{
+				"attachment-url":  "https://example.com/cat.jpg",
+				"attachment-type": "image",
+			})
+			So(request["fcm"].(map[string]interface{})["notification"].(map[string]interface{})["image"],
+				ShouldEqual, "https://example.com/cat.jpg")
+			So(request["web"].(map[string]interface{})["notification"].(map[string]interface{})["image"],
+				ShouldEqual, "https://example.com/cat.jpg")
+		})
+
+		Convey("should only attach videos to APNs", func() {
+			request, err := NewNotification("Hi", "Hello").Video("https://example.com/cat.mp4").Request()
+			So(err, ShouldBeNil)
+
+			apns := request["apns"].(map[string]interface{})
+			So(apns["data"].(map[string]interface{})["attachment-type"], ShouldEqual, "video")
+			So(request["fcm"].(map[string]interface{})["notification"], ShouldNotContainKey, "image")
+		})
+
+		Convey("should reject attachment URLs that devices can't download", func() {
+			for _, attachmentURL := range []string{"http://example.com/cat.jpg", "/cat.jpg", "https://"} {
+				_, err := NewNotification("Hi", "Hello").Image(attachmentURL).Request()
+				So(err, ShouldNotBeNil)
+				So(Classify(err), ShouldEqual, Validation)
+			}
+		})
+	})
+}
+
+func TestNotificationCategories(t *testing.T) {
+	Convey("Building a notification with a category", t, func() {
+		reply := Category{
+			Id: "MESSAGE",
+			Actions: []Action{
+				{Id: "REPLY", Title: "Reply", Icon: "https://example.com/reply.png"},
+				{Id: "MUTE", Title: "Mute"},
+			},
+		}
+
+		Convey("should set the APNs category and the web actions", func() {
+			request, err := NewNotification("Hi", "Hello").Category(reply).Request()
+			So(err, ShouldBeNil)
+
+			So(request["apns"].(map[string]interface{})["aps"].(map[string]interface{})["category"], ShouldEqual, "MESSAGE")
+			So(request["web"].(map[string]interface{})["notification"].(map[string]interface{})["actions"], ShouldResemble, []interface{}{
+				map[string]interface{}{"action": "REPLY", "title": "Reply", "icon": "https://example.com/reply.png"},
+				map[string]interface{}{"action": "MUTE", "title": "Mute"},
+			})
+		})
+
+		Convey("should reject incomplete categories", func() {
+			_, err := NewNotification("Hi", "Hello").Category(Category{}).Request()
+			So(err, ShouldNotBeNil)
+
+			_, err = NewNotification("Hi", "Hello").Category(Category{Id: "MESSAGE", Actions: []Action{{Id: "REPLY"}}}).Request()
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("should reject actions with the same id", func() {
+			reply.Actions = append(reply.Actions, Action{Id: "MUTE", Title: "Silence"})
+			_, err := NewNotification("Hi", "Hello").Category(reply).Request()
+			So(err.Error(), ShouldEqual, "Category `MESSAGE` has more than one action with id `MUTE`")
+		})
+	})
+}
+
+func TestNotificationDeepLinks(t *testing.T) {
+	Convey("Building a notification with a deep link", t, func() {
+		Convey("should place web links on every platform", func() {
+			request, err := NewNotification("Hi", "Hello").DeepLink("https://example.com/orders/1").Request()
+			So(err, ShouldBeNil)
+
+			So(request["apns"].(map[string]interface{})["data"], ShouldResemble,
+				map[string]interface{}{"deep_link": "https://example.com/orders/1"})
+			So(request["fcm"].(map[string]interface{})["data"], ShouldResemble,
+				map[string]interface{}{"deep_link": "https://example.com/orders/1"})
+			So(request["web"].(map[string]interface{})["notification"].(map[string]interface{})["deep_link"],
+				ShouldEqual, "https://example.com/orders/1")
+			So(ValidateRequest(request), ShouldBeNil)
+		})
+
+		Convey("should only send custom schemes to the mobile apps", func() {
+			request, err := NewNotification("Hi", "Hello").
+				Data(map[string]interface{}{"id": "1"}).
+				DeepLink("myapp://orders/1").
+				Request()
+			So(err, ShouldBeNil)
+
+			So(request["fcm"].(map[string]interface{})["data"], ShouldResemble,
+				map[string]interface{}{"id": "1", "deep_link": "myapp://orders/1"})
+			So(request["web"].(map[string]interface{})["notification"], ShouldNotContainKey, "deep_link")
+			So(request["web"].(map[string]interface{})["data"], ShouldResemble, map[string]interface{}{"id": "1"})
+		})
+
+		Convey("should reject unsafe or malformed links", func() {
+			for _, link := range []string{"javascript:alert(1)", "DATA:text/html,hi", "/orders/1", "https://example.com/" + strings.Repeat("a", 2000)} {
+				_, err := NewNotification("Hi", "Hello").DeepLink(link).Request()
+				So(Classify(err), ShouldEqual, Validation)
+			}
+		})
+	})
+}
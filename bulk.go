@@ -0,0 +1,421 @@
+package pushnotifications
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// The format of a list of user ids read by `PublishToUsersFromReader`
+type InputFormat int
+
+const (
+	// Comma separated values, with the user id in the first column.
+	// A header row whose first column is "user_id" is skipped.
+	CSV InputFormat = iota
+	// One JSON value per line: either a string, or an object with a "user_id" field
+	NDJSON
+)
+
+const defaultBulkConcurrency = 4
+
+// A user id that was skipped because it is not valid
+type RejectedUserId struct {
+	// Line of the input (starting at 1) the user id was read from
+	Line   int
+	UserId string
+	Err    error
+}
+
+// The outcome of publishing to one chunk of users in a bulk operation
+type ChunkResult struct {
+	// Position of the chunk in the input, starting at 0
+	Index     int
+	Users     []string
+	PublishId string
+	Err       error
+}
+
+// Encodes the result as a line of the NDJSON written by `WithResultsWriter`, e.g.
+// `{"index":0,"users":["user-1"],"publish_id":"pub-123"}` or `{"index":1,"users":["user-2"],"error":"..."}`
+func (r ChunkResult) MarshalJSON() ([]byte, error) {
+	line := struct {
+		Index     int      `json:"index"`
+		Users     []string `json:"users"`
+		PublishId string   `json:"publish_id,omitempty"`
+		Error     string   `json:"error,omitempty"`
+	}{Index: r.Index, Users: r.Users, PublishId: r.PublishId}
+	if r.Err != nil {
+		line.Error = r.Err.Error()
+	}
+	return json.Marshal(line)
+}
+
+// The outcome of a bulk publish
+type BulkResult struct {
+	// Results of every chunk published, in input order.
+	// When the operation is cancelled (see `WithContext`), only the chunks started before it stopped.
+	Chunks []ChunkResult
+	// User ids skipped because they are not valid
+	Rejected []RejectedUserId
+	// Number of chunks skipped because the checkpoint of an earlier publish says they are done
+	Skipped int
+}
+
+// Returns the chunks that were published
+func (r *BulkResult) Sent() []ChunkResult {
+	sent := []ChunkResult{}
+	for _, chunk := range r.Chunks {
+		if chunk.Err == nil {
+			sent = append(sent, chunk)
+		}
+	}
+	return sent
+}
+
+// Returns the chunks that failed to publish
+func (r *BulkResult) Failed() []ChunkResult {
+	failed := []ChunkResult{}
+	for _, chunk := range r.Chunks {
+		if chunk.Err != nil {
+			failed = append(failed, chunk)
+		}
+	}
+	return failed
+}
+
+// Sets how many chunks bulk operations publish at once. Defaults to 4.
+func WithConcurrency(concurrency int) CallOption {
+	return func(callOpts *callOptions) {
+		callOpts.concurrency = concurrency
+	}
+}
+
+// Persists how many chunks of a bulk publish are done, so that an interrupted publish
+// of the same input resumes after them instead of notifying the same users again
+type BulkCheckpoint interface {
+	// Returns the number of chunks, from the start of the input, that are done
+	Load() (chunksDone int, err error)
+	Save(chunksDone int) error
+}
+
+// Makes bulk operations skip the chunks the checkpoint says are done, and save their progress to it.
+// Chunks that fail to publish count as done: they are reported in the result, to be retried separately.
+func WithCheckpoint(checkpoint BulkCheckpoint) CallOption {
+	return func(callOpts *callOptions) {
+		callOpts.checkpoint = checkpoint
+	}
+}
+
+// Writes the result of every chunk of a bulk operation to `w` as a line of JSON (see `ChunkResult.MarshalJSON`)
+// as soon as the chunk is done, so that long-running operations can be monitored and processed as they go.
+// Lines are written in the order chunks finish, which may differ from their order in the input.
+func WithResultsWriter(w io.Writer) CallOption {
+	return func(callOpts *callOptions) {
+		callOpts.resultsWriter = w
+	}
+}
+
+// Calls `progress` from a single goroutine every time a chunk of a bulk operation is done, with the number
+// of chunks done so far, the total number of chunks and the error of the last chunk, e.g. to render a progress bar.
+// When user ids are read from a stream, `total` counts the chunks read so far, and is final once the input has been read.
+func WithProgress(progress func(done, total int, lastErr error)) CallOption {
+	return func(callOpts *callOptions) {
+		callOpts.progress = progress
+	}
+}
+
+// Reports the chunks of a bulk operation as they are done, to the results writer and progress callback of the call
+type chunkReporter struct {
+	progress func(done, total int, lastErr error)
+	encoder  *json.Encoder
+	done     int
+	writeErr error
+}
+
+func newChunkReporter(callOpts callOptions) *chunkReporter {
+	r := &chunkReporter{progress: callOpts.progress}
+	if callOpts.resultsWriter != nil {
+		r.encoder = json.NewEncoder(callOpts.resultsWriter)
+	}
+	return r
+}
+
+func (r *chunkReporter) report(chunk ChunkResult, total int) {
+	r.done++
+	if r.encoder != nil && r.writeErr == nil {
+		r.writeErr = r.encoder.Encode(chunk)
+	}
+	if r.progress != nil {
+		r.progress(r.done, total, chunk.Err)
+	}
+}
+
+// Returns the first error writing the chunk results, if any
+func (r *chunkReporter) err() error {
+	return errors.Wrap(r.writeErr, "Failed to write the chunk results")
+}
+
+// Returns a `BulkCheckpoint` kept in the file at `path`
+func FileCheckpoint(path string) BulkCheckpoint {
+	return fileCheckpoint(path)
+}
+
+type fileCheckpoint string
+
+func (path fileCheckpoint) Load() (int, error) {
+	data, err := os.ReadFile(string(path))
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errors.Wrap(err, "Failed to read the checkpoint")
+	}
+
+	chunksDone, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	return chunksDone, errors.Wrap(err, "Failed to read the checkpoint")
+}
+
+// Writes to a temporary file first, so that a crash never leaves half a checkpoint
+func (path fileCheckpoint) Save(chunksDone int) error {
+	tmp := string(path) + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.Itoa(chunksDone)+"\n"), 0644); err != nil {
+		return errors.Wrap(err, "Failed to write the checkpoint")
+	}
+	return errors.Wrap(os.Rename(tmp, string(path)), "Failed to write the checkpoint")
+}
+
+func (pn *pushNotifications) PublishToUsersFromReader(
+	r io.Reader,
+	format InputFormat,
+	request map[string]interface{},
+	options ...CallOption,
+) (*BulkResult, error) {
+	callOpts := newCallOptions(options)
+	ctx := callOpts.context()
+	concurrency := callOpts.concurrency
+	if concurrency <= 0 {
+		concurrency = defaultBulkConcurrency
+	}
+
+	chunksDone := 0
+	if callOpts.checkpoint != nil {
+		var err error
+		if chunksDone, err = callOpts.checkpoint.Load(); err != nil {
+			return nil, err
+		}
+	}
+
+	chunks := make(chan ChunkResult)
+	results := make(chan ChunkResult)
+	workers := sync.WaitGroup{}
+	for i := 0; i < concurrency; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for chunk := range chunks {
+				chunk.PublishId, chunk.Err = pn.PublishToUsers(chunk.Users, request, options...)
+				results <- chunk
+			}
+		}()
+	}
+
+	result := &BulkResult{}
+	reporter := newChunkReporter(callOpts)
+	var chunksRead int64
+	var saveErr error
+	collected := make(chan struct{})
+	go func() {
+		// chunks finish out of order, so only the leading run of finished chunks is saved
+		finished := map[int]bool{}
+		for chunk := range results {
+			result.Chunks = append(result.Chunks, chunk)
+			reporter.report(chunk, int(atomic.LoadInt64(&chunksRead)))
+			if chunk.Err != nil && ctx.Err() != nil {
+				// cancelled chunks are not done, so that a resumed publish sends them
+				continue
+			}
+			finished[chunk.Index] = true
+			if callOpts.checkpoint == nil || !finished[chunksDone] {
+				continue
+			}
+			for finished[chunksDone] {
+				delete(finished, chunksDone)
+				chunksDone++
+			}
+			if err := callOpts.checkpoint.Save(chunksDone); err != nil && saveErr == nil {
+				saveErr = err
+			}
+		}
+		close(collected)
+	}()
+
+	readErr := readUserIds(contextReader{ctx: ctx, r: r}, format, pn.limits.MaxUsers, pn.userIdLengthInRunes, func(users []string, index int) {
+		if index < chunksDone {
+			result.Skipped++
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		atomic.AddInt64(&chunksRead, 1)
+		select {
+		case chunks <- ChunkResult{Index: index, Users: users}:
+		case <-ctx.Done():
+			atomic.AddInt64(&chunksRead, -1)
+		}
+	}, func(rejected RejectedUserId) {
+		result.Rejected = append(result.Rejected, rejected)
+	})
+
+	close(chunks)
+	workers.Wait()
+	close(results)
+	<-collected
+
+	sort.Slice(result.Chunks, func(i, j int) bool {
+		return result.Chunks[i].Index < result.Chunks[j].Index
+	})
+
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+	if readErr != nil {
+		return result, errors.Wrap(readErr, "Failed to read user ids")
+	}
+	if saveErr != nil {
+		return result, saveErr
+	}
+	if err := reporter.err(); err != nil {
+		return result, err
+	}
+	if failed := result.Failed(); len(failed) > 0 {
+		return result, errors.Wrapf(failed[0].Err, "%d of %d chunks failed to publish", len(failed), len(result.Chunks))
+	}
+	return result, nil
+}
+
+// Stops reading once the context is done
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// Reads user ids from `r`, calling `chunk` for every `chunkSize` valid ids
+// and `reject` for every invalid one
+func readUserIds(r io.Reader, format InputFormat, chunkSize int, lengthInRunes bool, chunk func(users []string, index int), reject func(RejectedUserId)) error {
+	users := make([]string, 0, chunkSize)
+	index := 0
+	add := func(line int, userId string) {
+		if err := validateUserId(userId, lengthInRunes); err != nil {
+			reject(RejectedUserId{Line: line, UserId: userId, Err: err})
+			return
+		}
+
+		users = append(users, userId)
+		if len(users) == chunkSize {
+			chunk(users, index)
+			users = make([]string, 0, chunkSize)
+			index++
+		}
+	}
+
+	var err error
+	switch format {
+	case CSV:
+		err = readCSV(r, add)
+	case NDJSON:
+		err = readNDJSON(r, add)
+	default:
+		err = newValidationError("Unknown input format %d", format)
+	}
+
+	if len(users) > 0 {
+		chunk(users, index)
+	}
+	return err
+}
+
+func readCSV(r io.Reader, add func(line int, userId string)) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if line == 1 && record[0] == "user_id" {
+			continue
+		}
+		add(line, record[0])
+	}
+}
+
+func readNDJSON(r io.Reader, add func(line int, userId string)) error {
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		value := bytes.TrimSpace(scanner.Bytes())
+		if len(value) == 0 {
+			continue
+		}
+
+		var userId string
+		if value[0] == '{' {
+			object := struct {
+				UserId string `json:"user_id"`
+			}{}
+			if err := json.Unmarshal(value, &object); err != nil {
+				return errors.Wrapf(err, "Line %d is not valid JSON", line)
+			}
+			userId = object.UserId
+		} else if err := json.Unmarshal(value, &userId); err != nil {
+			return errors.Wrapf(err, "Line %d is not a valid JSON string", line)
+		}
+		add(line, userId)
+	}
+	return scanner.Err()
+}
+
+func validateUserId(userId string, lengthInRunes bool) error {
+	if userId == "" {
+		return newValidationError("Empty user ids are not valid")
+	}
+	if err := checkUserIdLength(userId, lengthInRunes); err != nil {
+		return err
+	}
+	if !utf8.ValidString(userId) {
+		return newValidationError("User Id must be encoded using utf8")
+	}
+	return nil
+}
+
+// Returns a shallow copy of the request, with room for its targets
+func copyRequest(request map[string]interface{}) map[string]interface{} {
+	requestCopy := make(map[string]interface{}, len(request)+1)
+	for key, value := range request {
+		requestCopy[key] = value
+	}
+	return requestCopy
+}
@@ -0,0 +1,185 @@
+package pushnotifications
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPublishToUsersFromReader(t *testing.T) {
+	Convey("Publishing to users read from a list", t, func() {
+		mutex := sync.Mutex{}
+		published := map[string]int{}
+		failUser := ""
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			request := struct {
+				Users []string `json:"users"`
+			}{}
+			json.Unmarshal(body, &request)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			for _, user := range request.Users {
+				if user == failUser {
+					w.WriteHeader(http.StatusInternalServerError)
+					w.Write([]byte(`{"error":"Internal Error","description":"oops"}`))
+					return
+				}
+			}
+			for _, user := range request.Users {
+				published[user]++
+			}
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(fmt.Sprintf(`{"publishId":"pub-%s"}`, request.Users[0])))
+		}))
+		defer testServer.Close()
+
+		pn, _ := New(testInstanceId, testSecretKey, WithCustomBaseURL(testServer.URL))
+		request := map[string]interface{}{"fcm": map[string]interface{}{}}
+
+		csvInput := "user_id,name\n"
+		for i := 0; i < 2500; i++ {
+			csvInput += fmt.Sprintf("user-%d,User %d\n", i, i)
+		}
+
+		Convey("should publish CSV input in chunks of 1000 users", func() {
+			result, err := pn.PublishToUsersFromReader(strings.NewReader(csvInput), CSV, request, WithConcurrency(2))
+			So(err, ShouldBeNil)
+			So(len(result.Chunks), ShouldEqual, 3)
+			So(len(result.Chunks[0].Users), ShouldEqual, 1000)
+			So(len(result.Chunks[2].Users), ShouldEqual, 500)
+			So(result.Chunks[1].PublishId, ShouldEqual, "pub-user-1000")
+			So(len(published), ShouldEqual, 2500)
+			So(published["user-2499"], ShouldEqual, 1)
+			So(request, ShouldNotContainKey, "users")
+		})
+
+		Convey("should publish NDJSON strings and objects", func() {
+			input := `"user-1"` + "\n\n" + `{"user_id":"user-2"}` + "\n"
+			result, err := pn.PublishToUsersFromReader(strings.NewReader(input), NDJSON, request)
+			So(err, ShouldBeNil)
+			So(result.Chunks[0].Users, ShouldResemble, []string{"user-1", "user-2"})
+		})
+
+		Convey("should skip and report invalid user ids", func() {
+			input := "user-1\n\"\"\n" + strings.Repeat("a", maxUserIdLength+1) + "\nuser-2\n"
+			result, err := pn.PublishToUsersFromReader(strings.NewReader(input), CSV, request)
+			So(err, ShouldBeNil)
+			So(result.Chunks[0].Users, ShouldResemble, []string{"user-1", "user-2"})
+			So(len(result.Rejected), ShouldEqual, 2)
+			So(result.Rejected[0].Line, ShouldEqual, 2)
+			So(result.Rejected[1].Line, ShouldEqual, 3)
+			So(Classify(result.Rejected[1].Err), ShouldEqual, Validation)
+		})
+
+		Convey("should report progress after every chunk", func() {
+			failUser = "user-0"
+			calls := [][2]int{}
+			var firstErr error
+			pn.PublishToUsersFromReader(strings.NewReader(csvInput), CSV, request, WithConcurrency(1),
+				WithProgress(func(done, total int, lastErr error) {
+					if done == 1 {
+						firstErr = lastErr
+					}
+					calls = append(calls, [2]int{done, total})
+				}))
+			So(len(calls), ShouldEqual, 3)
+			So(calls[2], ShouldResemble, [2]int{3, 3})
+			So(firstErr, ShouldNotBeNil)
+		})
+
+		Convey("should stop publishing chunks once cancelled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			result, err := pn.PublishToUsersFromReader(strings.NewReader(csvInput), CSV, request,
+				WithConcurrency(1),
+				WithContext(ctx),
+				WithProgress(func(done, total int, lastErr error) {
+					cancel()
+				}))
+			So(err, ShouldEqual, context.Canceled)
+			So(len(result.Sent()), ShouldBeLessThan, 3)
+			So(result.Sent()[0].Users[0], ShouldEqual, "user-0")
+			So(published, ShouldNotContainKey, "user-2000")
+		})
+
+		Convey("should stream the result of every chunk as NDJSON", func() {
+			failUser = "user-1500"
+			results := &bytes.Buffer{}
+			_, err := pn.PublishToUsersFromReader(strings.NewReader(csvInput), CSV, request, WithResultsWriter(results))
+			So(err, ShouldNotBeNil)
+
+			lines := map[int]map[string]interface{}{}
+			scanner := bufio.NewScanner(results)
+			scanner.Buffer(nil, 1<<20)
+			for scanner.Scan() {
+				line := map[string]interface{}{}
+				So(json.Unmarshal(scanner.Bytes(), &line), ShouldBeNil)
+				lines[int(line["index"].(float64))] = line
+			}
+			So(len(lines), ShouldEqual, 3)
+			So(lines[0]["publish_id"], ShouldEqual, "pub-user-0")
+			So(len(lines[2]["users"].([]interface{})), ShouldEqual, 500)
+			So(lines[1]["error"], ShouldContainSubstring, "oops")
+			So(lines[1], ShouldNotContainKey, "publish_id")
+		})
+
+		Convey("should report chunks that failed to publish", func() {
+			failUser = "user-1500"
+			result, err := pn.PublishToUsersFromReader(strings.NewReader(csvInput), CSV, request)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "1 of 3 chunks failed to publish")
+			So(len(result.Failed()), ShouldEqual, 1)
+			So(result.Failed()[0].Index, ShouldEqual, 1)
+			So(published, ShouldNotContainKey, "user-1000")
+			So(published, ShouldContainKey, "user-2000")
+		})
+
+		Convey("with a checkpoint", func() {
+			dir, _ := os.MkdirTemp("", "bulk")
+			defer os.RemoveAll(dir)
+			checkpoint := FileCheckpoint(filepath.Join(dir, "checkpoint"))
+
+			Convey("should save the chunks done", func() {
+				result, err := pn.PublishToUsersFromReader(strings.NewReader(csvInput), CSV, request, WithCheckpoint(checkpoint))
+				So(err, ShouldBeNil)
+				So(result.Skipped, ShouldEqual, 0)
+
+				chunksDone, err := checkpoint.Load()
+				So(err, ShouldBeNil)
+				So(chunksDone, ShouldEqual, 3)
+			})
+
+			Convey("should resume after the chunks done by an interrupted publish", func() {
+				So(checkpoint.Save(2), ShouldBeNil)
+
+				result, err := pn.PublishToUsersFromReader(strings.NewReader(csvInput), CSV, request, WithCheckpoint(checkpoint))
+				So(err, ShouldBeNil)
+				So(result.Skipped, ShouldEqual, 2)
+				So(len(result.Chunks), ShouldEqual, 1)
+				So(result.Chunks[0].Index, ShouldEqual, 2)
+				So(len(published), ShouldEqual, 500)
+				So(published, ShouldContainKey, "user-2000")
+			})
+		})
+
+		Convey("should return an error for malformed input", func() {
+			input := `"user-1"` + "\n" + `not json` + "\n"
+			result, err := pn.PublishToUsersFromReader(strings.NewReader(input), NDJSON, request)
+			So(err.Error(), ShouldContainSubstring, "Line 2 is not a valid JSON string")
+			So(result.Chunks[0].Users, ShouldResemble, []string{"user-1"})
+		})
+	})
+}
@@ -0,0 +1,58 @@
+package campaign
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// The user ids a campaign is sent to. Audiences must yield the same ids in the same
+// order every time they are read, for interrupted campaigns to resume where they stopped.
+type Audience interface {
+	// Returns the next user id, or `io.EOF` once every id has been read
+	Next() (userId string, err error)
+}
+
+// Returns an `Audience` of the given user ids
+func Users(userIds ...string) Audience {
+	return &sliceAudience{userIds: userIds}
+}
+
+type sliceAudience struct {
+	userIds []string
+}
+
+func (a *sliceAudience) Next() (string, error) {
+	if len(a.userIds) == 0 {
+		return "", io.EOF
+	}
+	userId := a.userIds[0]
+	a.userIds = a.userIds[1:]
+	return userId, nil
+}
+
+// Returns the number of user ids left
+func (a *sliceAudience) Len() int {
+	return len(a.userIds)
+}
+
+// Returns an `Audience` reading one user id per line from `r`, skipping blank lines
+func Lines(r io.Reader) Audience {
+	return &lineAudience{scanner: bufio.NewScanner(r)}
+}
+
+type lineAudience struct {
+	scanner *bufio.Scanner
+}
+
+func (a *lineAudience) Next() (string, error) {
+	for a.scanner.Scan() {
+		if userId := strings.TrimSpace(a.scanner.Text()); userId != "" {
+			return userId, nil
+		}
+	}
+	if err := a.scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", io.EOF
+}
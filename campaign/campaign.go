@@ -0,0 +1,233 @@
+// Package campaign sends a notification to a large audience of users, in paced batches,
+// saving its progress so that an interrupted campaign can be resumed.
+package campaign
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+	pushnotifications "github.com/pusher/push-notifications-go"
+)
+
+// Key of the campaign id in the metadata of the campaign's publishes (see `pushnotifications.WithMetadata`)
+const CampaignMetadataKey = "campaign"
+
+// Describes what to send, to whom, and how fast
+type Campaign struct {
+	// Identifies the campaign in the checkpoint store
+	Id       string
+	Audience Audience
+	// The publish request, published with `PublishPayloadToUsers`
+	Payload interface{}
+	// Number of users per publish, up to (and by default) the most users a publish of the client
+	// can target, `Limits().MaxUsers` of clients that report their limits
+	BatchSize int
+	// Time to wait between batches, to spread the load of the campaign
+	Interval time.Duration
+	// Spreads the batches evenly over this window instead, e.g. 100k users over 30 minutes,
+	// so that users don't all open the app at once. Needs the size of the audience.
+	Window time.Duration
+	// Number of users in the audience, for `Window`. Defaults to the audience's `Len()`, if any.
+	Size int
+	// Where progress is saved. Campaigns without a store can't be resumed.
+	Checkpoints CheckpointStore
+	// Where the result of every batch is written as a line of JSON once the batch is done, if not nil
+	// (see `pushnotifications.ChunkResult.MarshalJSON`)
+	Results io.Writer
+	// Called after every batch with the number of batches published by this run, the number of batches
+	// it should publish (0 if the size of the audience isn't known) and the error of the last batch, if not nil
+	Progress func(done, total int, lastErr error)
+}
+
+// Outcome of one publish of a campaign
+type BatchResult struct {
+	Index     int
+	Users     []string
+	PublishId string
+	Err       error
+}
+
+// Outcome of a run of a campaign
+type Result struct {
+	// Batches published by this run
+	Batches []BatchResult
+	// Number of user ids skipped because an earlier run already handled them
+	Skipped int
+}
+
+// A client reporting its limits, like the one returned by `pushnotifications.New` does
+type limitedClient interface {
+	Limits() pushnotifications.Limits
+}
+
+// Returns the most users a publish of `pn` can target, from its limits if it reports them
+// (see `pushnotifications.WithLimits`)
+func maxUsers(pn interface{}) int {
+	if limited, ok := pn.(limitedClient); ok {
+		return limited.Limits().MaxUsers
+	}
+	return pushnotifications.DefaultLimits().MaxUsers
+}
+
+// Returns the batches that failed to publish
+func (r *Result) Failed() []BatchResult {
+	failed := []BatchResult{}
+	for _, batch := range r.Batches {
+		if batch.Err != nil {
+			failed = append(failed, batch)
+		}
+	}
+	return failed
+}
+
+// Publishes the campaign's payload to its audience in batches, waiting `Interval` between them.
+// A campaign with a checkpoint from an earlier run resumes after the last batch of that run.
+// Failed batches don't stop the campaign; they are reported in the result, the checkpoint and the error.
+// Returns `ctx.Err()` if the context is cancelled, aborting the batch in flight, after saving the progress made.
+// The result then lists exactly the batches published before the cancellation.
+func Run(ctx context.Context, pn pushnotifications.UserPublisher, c Campaign) (*Result, error) {
+	batchSize := c.BatchSize
+	if maxUsers := maxUsers(pn); batchSize <= 0 || batchSize > maxUsers {
+		batchSize = maxUsers
+	}
+
+	checkpoint, err := c.loadCheckpoint()
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{}
+	if checkpoint.Done {
+		return result, nil
+	}
+
+	size := c.Size
+	if sized, ok := c.Audience.(interface{ Len() int }); ok && size == 0 {
+		size = sized.Len()
+	}
+	total := 0
+	if size > 0 {
+		total = (size - checkpoint.Offset + batchSize - 1) / batchSize
+	}
+
+	interval := c.Interval
+	if c.Window > 0 {
+		if size <= 0 {
+			return nil, errors.New("Campaigns spread over a window need the size of their audience")
+		}
+		interval = windowInterval(c.Window, size-checkpoint.Offset, batchSize)
+	}
+	for ; result.Skipped < checkpoint.Offset; result.Skipped++ {
+		if _, err := c.Audience.Next(); err != nil {
+			return result, errors.Wrap(err, "Failed to skip the users of the previous run")
+		}
+	}
+
+	for {
+		users, readErr := readBatch(c.Audience, batchSize)
+		if readErr != nil && readErr != io.EOF {
+			return result, errors.Wrap(readErr, "Failed to read the audience")
+		}
+
+		if len(users) > 0 {
+			if len(result.Batches) > 0 && interval > 0 {
+				if err := wait(ctx, interval); err != nil {
+					return result, err
+				}
+			} else if err := ctx.Err(); err != nil {
+				return result, err
+			}
+
+			batch := BatchResult{Index: checkpoint.Batches, Users: users}
+			batch.PublishId, batch.Err = pn.PublishPayloadToUsers(users, c.Payload,
+				pushnotifications.WithMetadata(map[string]string{CampaignMetadataKey: c.Id}),
+				pushnotifications.WithContext(ctx))
+			if batch.Err != nil && ctx.Err() != nil {
+				// the batch was cancelled, so a resumed run publishes it again
+				return result, ctx.Err()
+			}
+			result.Batches = append(result.Batches, batch)
+
+			checkpoint.Offset += len(users)
+			checkpoint.Batches++
+			if batch.Err != nil {
+				checkpoint.Failed = append(checkpoint.Failed, FailedBatch{Users: users, Error: batch.Err.Error()})
+			}
+		}
+
+		checkpoint.Done = readErr == io.EOF
+		if err := c.saveCheckpoint(checkpoint); err != nil {
+			return result, err
+		}
+		if len(users) > 0 && c.Results != nil {
+			batch := result.Batches[len(result.Batches)-1]
+			if err := json.NewEncoder(c.Results).Encode(pushnotifications.ChunkResult(batch)); err != nil {
+				return result, errors.Wrap(err, "Failed to write the batch result")
+			}
+		}
+		if len(users) > 0 && c.Progress != nil {
+			c.Progress(len(result.Batches), total, result.Batches[len(result.Batches)-1].Err)
+		}
+		if checkpoint.Done {
+			break
+		}
+	}
+
+	if failed := result.Failed(); len(failed) > 0 {
+		return result, errors.Wrapf(failed[0].Err, "%d of %d batches failed to publish", len(failed), len(result.Batches))
+	}
+	return result, nil
+}
+
+func (c Campaign) loadCheckpoint() (Checkpoint, error) {
+	if c.Checkpoints == nil {
+		return Checkpoint{}, nil
+	}
+	checkpoint, err := c.Checkpoints.Load(c.Id)
+	if err != nil || checkpoint == nil {
+		return Checkpoint{}, err
+	}
+	return *checkpoint, nil
+}
+
+func (c Campaign) saveCheckpoint(checkpoint Checkpoint) error {
+	if c.Checkpoints == nil {
+		return nil
+	}
+	return c.Checkpoints.Save(c.Id, checkpoint)
+}
+
+// Returns the interval between batches that spreads `users` over the window,
+// the first batch being published at its start and the last one at its end
+func windowInterval(window time.Duration, users, batchSize int) time.Duration {
+	batches := (users + batchSize - 1) / batchSize
+	if batches <= 1 {
+		return 0
+	}
+	return window / time.Duration(batches-1)
+}
+
+func readBatch(audience Audience, size int) ([]string, error) {
+	users := make([]string, 0, size)
+	for len(users) < size {
+		userId, err := audience.Next()
+		if err != nil {
+			return users, err
+		}
+		users = append(users, userId)
+	}
+	return users, nil
+}
+
+func wait(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
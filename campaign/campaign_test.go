@@ -0,0 +1,240 @@
+package campaign
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pushnotifications "github.com/pusher/push-notifications-go"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const (
+	testInstanceId = "a11aec92-146a-4708-9a62-8c61f46a82ad"
+	testSecretKey  = "EIJ2EESAH8DUUMAI8EE"
+)
+
+func TestCampaign(t *testing.T) {
+	Convey("A campaign", t, func() {
+		mutex := sync.Mutex{}
+		published := [][]string{}
+		failBatch := -1
+		hangBatch := -1
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			request := struct {
+				Users []string `json:"users"`
+			}{}
+			json.Unmarshal(body, &request)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			if len(published) == hangBatch {
+				<-r.Context().Done()
+				return
+			}
+			if len(published) == failBatch {
+				failBatch = -1
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":"Invalid request","description":"bad batch"}`))
+				return
+			}
+			published = append(published, request.Users)
+			fmt.Fprintf(w, `{"publishId":"pub-%d"}`, len(published))
+		}))
+		defer testServer.Close()
+
+		pn, _ := pushnotifications.New(testInstanceId, testSecretKey, pushnotifications.WithCustomBaseURL(testServer.URL))
+		payload := map[string]interface{}{
+			"apns": map[string]interface{}{"aps": map[string]interface{}{"alert": "Sale!"}},
+		}
+		checkpoints := NewMemoryCheckpointStore()
+		users := func(n int) []string {
+			ids := []string{}
+			for i := 0; i < n; i++ {
+				ids = append(ids, fmt.Sprintf("user-%d", i))
+			}
+			return ids
+		}
+
+		Convey("should publish its audience in batches", func() {
+			result, err := Run(context.Background(), pn, Campaign{
+				Id:          "sale",
+				Audience:    Users(users(5)...),
+				Payload:     payload,
+				BatchSize:   2,
+				Checkpoints: checkpoints,
+			})
+			So(err, ShouldBeNil)
+			So(len(result.Batches), ShouldEqual, 3)
+			So(result.Batches[2].PublishId, ShouldEqual, "pub-3")
+			So(published, ShouldResemble, [][]string{{"user-0", "user-1"}, {"user-2", "user-3"}, {"user-4"}})
+
+			checkpoint, _ := checkpoints.Load("sale")
+			So(checkpoint, ShouldResemble, &Checkpoint{Offset: 5, Batches: 3, Done: true})
+
+			Convey("and do nothing when run again", func() {
+				result, err := Run(context.Background(), pn, Campaign{
+					Id: "sale", Audience: Users(users(5)...), Payload: payload, Checkpoints: checkpoints,
+				})
+				So(err, ShouldBeNil)
+				So(len(result.Batches), ShouldEqual, 0)
+				So(len(published), ShouldEqual, 3)
+			})
+		})
+
+		Convey("should report its progress after every batch", func() {
+			progress := [][2]int{}
+			Run(context.Background(), pn, Campaign{
+				Id:        "sale",
+				Audience:  Users(users(5)...),
+				Payload:   payload,
+				BatchSize: 2,
+				Progress: func(done, total int, lastErr error) {
+					So(lastErr, ShouldBeNil)
+					progress = append(progress, [2]int{done, total})
+				},
+			})
+			So(progress, ShouldResemble, [][2]int{{1, 3}, {2, 3}, {3, 3}})
+		})
+
+		Convey("should resume after the last batch of an interrupted run", func() {
+			checkpoints.Save("sale", Checkpoint{Offset: 2, Batches: 1})
+
+			result, err := Run(context.Background(), pn, Campaign{
+				Id:          "sale",
+				Audience:    Lines(strings.NewReader("user-0\nuser-1\n\nuser-2\nuser-3\n")),
+				Payload:     payload,
+				BatchSize:   2,
+				Checkpoints: checkpoints,
+			})
+			So(err, ShouldBeNil)
+			So(result.Skipped, ShouldEqual, 2)
+			So(result.Batches[0].Index, ShouldEqual, 1)
+			So(published, ShouldResemble, [][]string{{"user-2", "user-3"}})
+		})
+
+		Convey("should carry on after a failed batch and record it", func() {
+			failBatch = 1
+			results := &strings.Builder{}
+
+			result, err := Run(context.Background(), pn, Campaign{
+				Id:          "sale",
+				Audience:    Users(users(5)...),
+				Payload:     payload,
+				BatchSize:   2,
+				Checkpoints: checkpoints,
+				Results:     results,
+			})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "1 of 3 batches failed to publish")
+			So(len(result.Failed()), ShouldEqual, 1)
+			So(published, ShouldResemble, [][]string{{"user-0", "user-1"}, {"user-4"}})
+
+			checkpoint, _ := checkpoints.Load("sale")
+			So(checkpoint.Done, ShouldBeTrue)
+			So(checkpoint.Failed[0].Users, ShouldResemble, []string{"user-2", "user-3"})
+
+			So(results.String(), ShouldEqual, `{"index":0,"users":["user-0","user-1"],"publish_id":"pub-1"}
+{"index":1,"users":["user-2","user-3"],"error":"Failed to publish notification: Invalid request: bad batch"}
+{"index":2,"users":["user-4"],"publish_id":"pub-2"}
+`)
+		})
+
+		Convey("should spread its batches over a window", func() {
+			start := time.Now()
+			_, err := Run(context.Background(), pn, Campaign{
+				Audience:  Users(users(5)...),
+				Payload:   payload,
+				BatchSize: 2,
+				Window:    100 * time.Millisecond,
+			})
+			So(err, ShouldBeNil)
+			So(len(published), ShouldEqual, 3)
+			So(time.Since(start), ShouldBeGreaterThanOrEqualTo, 100*time.Millisecond)
+		})
+
+		Convey("should not be spread over a window without the size of its audience", func() {
+			_, err := Run(context.Background(), pn, Campaign{
+				Audience: Lines(strings.NewReader("user-0\n")),
+				Payload:  payload,
+				Window:   time.Minute,
+			})
+			So(err.Error(), ShouldContainSubstring, "need the size of their audience")
+			So(len(published), ShouldEqual, 0)
+		})
+
+		Convey("should stop when the context is cancelled, keeping its progress", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(50*time.Millisecond, cancel)
+
+			_, err := Run(ctx, pn, Campaign{
+				Id:          "sale",
+				Audience:    Users(users(5)...),
+				Payload:     payload,
+				BatchSize:   2,
+				Interval:    time.Second,
+				Checkpoints: checkpoints,
+			})
+			So(err, ShouldEqual, context.Canceled)
+			So(len(published), ShouldEqual, 1)
+
+			checkpoint, _ := checkpoints.Load("sale")
+			So(checkpoint, ShouldResemble, &Checkpoint{Offset: 2, Batches: 1})
+		})
+
+		Convey("should abort the batch in flight when the context is cancelled", func() {
+			hangBatch = 1
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(50*time.Millisecond, cancel)
+
+			result, err := Run(ctx, pn, Campaign{
+				Id:          "sale",
+				Audience:    Users(users(5)...),
+				Payload:     payload,
+				BatchSize:   2,
+				Checkpoints: checkpoints,
+			})
+			So(err, ShouldEqual, context.Canceled)
+			So(len(result.Batches), ShouldEqual, 1)
+			So(result.Batches[0].Users, ShouldResemble, []string{"user-0", "user-1"})
+
+			checkpoint, _ := checkpoints.Load("sale")
+			So(checkpoint, ShouldResemble, &Checkpoint{Offset: 2, Batches: 1})
+		})
+	})
+
+	Convey("The interval between the batches of a window", t, func() {
+		So(windowInterval(30*time.Minute, 100000, 1000), ShouldEqual, 30*time.Minute/99)
+		So(windowInterval(30*time.Minute, 1000, 1000), ShouldEqual, 0)
+		So(windowInterval(30*time.Minute, 1001, 1000), ShouldEqual, 30*time.Minute)
+	})
+
+	Convey("A file checkpoint store", t, func() {
+		dir, _ := os.MkdirTemp("", "campaign")
+		store := NewFileCheckpointStore(dir)
+
+		Convey("should return nil for campaigns never saved", func() {
+			checkpoint, err := store.Load("sale")
+			So(err, ShouldBeNil)
+			So(checkpoint, ShouldBeNil)
+		})
+
+		Convey("should load what it saved", func() {
+			saved := Checkpoint{Offset: 10, Batches: 1, Failed: []FailedBatch{{Users: []string{"a"}, Error: "oops"}}}
+			So(store.Save("sale", saved), ShouldBeNil)
+
+			checkpoint, err := store.Load("sale")
+			So(err, ShouldBeNil)
+			So(*checkpoint, ShouldResemble, saved)
+		})
+	})
+}
@@ -0,0 +1,109 @@
+package campaign
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Progress of a campaign, saved after every batch so that an interrupted campaign
+// resumes after the last batch it published instead of notifying the same users again
+type Checkpoint struct {
+	// Number of user ids read from the audience so far
+	Offset int `json:"offset"`
+	// Number of batches published or failed so far
+	Batches int `json:"batches"`
+	// Batches that failed to publish, to retry separately
+	Failed []FailedBatch `json:"failed,omitempty"`
+	// Whether the whole audience was read
+	Done bool `json:"done"`
+}
+
+// A batch of users a campaign failed to notify
+type FailedBatch struct {
+	Users []string `json:"users"`
+	Error string   `json:"error"`
+}
+
+// Keeps the checkpoints of campaigns between runs
+type CheckpointStore interface {
+	// Returns the checkpoint of the campaign, or nil if it was never saved
+	Load(campaignId string) (*Checkpoint, error)
+	Save(campaignId string, checkpoint Checkpoint) error
+}
+
+// Returns a `CheckpointStore` keeping checkpoints in memory, for tests and campaigns
+// retried within the same process
+func NewMemoryCheckpointStore() CheckpointStore {
+	return &memoryCheckpointStore{checkpoints: map[string]Checkpoint{}}
+}
+
+type memoryCheckpointStore struct {
+	mutex       sync.Mutex
+	checkpoints map[string]Checkpoint
+}
+
+func (s *memoryCheckpointStore) Load(campaignId string) (*Checkpoint, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	checkpoint, ok := s.checkpoints[campaignId]
+	if !ok {
+		return nil, nil
+	}
+	return &checkpoint, nil
+}
+
+func (s *memoryCheckpointStore) Save(campaignId string, checkpoint Checkpoint) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.checkpoints[campaignId] = checkpoint
+	return nil
+}
+
+// Returns a `CheckpointStore` keeping each checkpoint in a JSON file named after the campaign in `dir`
+func NewFileCheckpointStore(dir string) CheckpointStore {
+	return fileCheckpointStore{dir: dir}
+}
+
+type fileCheckpointStore struct {
+	dir string
+}
+
+func (s fileCheckpointStore) path(campaignId string) string {
+	return filepath.Join(s.dir, filepath.Base(campaignId)+".checkpoint.json")
+}
+
+func (s fileCheckpointStore) Load(campaignId string) (*Checkpoint, error) {
+	data, err := os.ReadFile(s.path(campaignId))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read the campaign checkpoint")
+	}
+
+	checkpoint := &Checkpoint{}
+	if err := json.Unmarshal(data, checkpoint); err != nil {
+		return nil, errors.Wrap(err, "Failed to read the campaign checkpoint due to invalid JSON")
+	}
+	return checkpoint, nil
+}
+
+// Writes the checkpoint to a temporary file first, so that a crash never leaves half a checkpoint
+func (s fileCheckpointStore) Save(campaignId string, checkpoint Checkpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return errors.Wrap(err, "Failed to marshal the campaign checkpoint")
+	}
+
+	path := s.path(campaignId)
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return errors.Wrap(err, "Failed to write the campaign checkpoint")
+	}
+	return errors.Wrap(os.Rename(path+".tmp", path), "Failed to write the campaign checkpoint")
+}
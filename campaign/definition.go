@@ -0,0 +1,331 @@
+package campaign
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	pushnotifications "github.com/pusher/push-notifications-go"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// A campaign described in a YAML file, so that it can be reviewed and kept under version control:
+//
+//	id: spring-sale
+//	audience:
+//	  file: spring-sale-users.txt   # one user id per line, relative to the definition file
+//	template:
+//	  apns:
+//	    aps:
+//	      alert: "{{.discount}} off everything!"
+//	variables:
+//	  discount: 20%
+//	schedule:
+//	  start: 2024-03-01T09:00:00Z
+//	pacing:
+//	  batch_size: 1000
+//	  window: 30m
+//
+// The audience is either a `file` or a list of `users`. The template is the publish request,
+// whose strings are rendered with the variables (see `pushnotifications.NewTemplate`).
+// Pacing takes either an `interval` between batches or a `window` to spread them over.
+type Definition struct {
+	Id        string                 `yaml:"id"`
+	Audience  AudienceDefinition     `yaml:"audience"`
+	Template  map[string]interface{} `yaml:"template"`
+	Variables map[string]interface{} `yaml:"variables"`
+	Schedule  ScheduleDefinition     `yaml:"schedule"`
+	Pacing    PacingDefinition       `yaml:"pacing"`
+
+	// Directory the audience file is relative to
+	dir string
+}
+
+type AudienceDefinition struct {
+	File  string   `yaml:"file"`
+	Users []string `yaml:"users"`
+}
+
+type ScheduleDefinition struct {
+	// When the campaign starts. The zero value starts it right away.
+	Start time.Time `yaml:"start"`
+}
+
+type PacingDefinition struct {
+	BatchSize int           `yaml:"batch_size"`
+	Interval  time.Duration `yaml:"interval"`
+	Window    time.Duration `yaml:"window"`
+}
+
+// Reads and validates a campaign definition from `r`. An audience file is relative to the working directory.
+func LoadDefinition(r io.Reader) (*Definition, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read the campaign definition")
+	}
+
+	d := &Definition{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.SetStrict(true)
+	if err := decoder.Decode(d); err != nil {
+		return nil, errors.Wrap(err, "Failed to parse the campaign definition")
+	}
+	template, err := stringKeys(d.Template)
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid template")
+	}
+	d.Template, _ = template.(map[string]interface{})
+	variables, err := stringKeys(d.Variables)
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid variables")
+	}
+	d.Variables, _ = variables.(map[string]interface{})
+
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Reads and validates the campaign definition in the file at `path`.
+// An audience file is relative to the directory of the definition.
+func LoadDefinitionFile(path string) (*Definition, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to open the campaign definition")
+	}
+	defer file.Close()
+
+	d, err := LoadDefinition(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid campaign definition %s", path)
+	}
+	d.dir = filepath.Dir(path)
+	return d, nil
+}
+
+// YAML maps are decoded with interface{} keys, which can't be encoded to JSON
+func stringKeys(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, nested := range v {
+			keyString, ok := key.(string)
+			if !ok {
+				return nil, errors.Errorf("Key %v is not a string", key)
+			}
+			var err error
+			if converted[keyString], err = stringKeys(nested); err != nil {
+				return nil, err
+			}
+		}
+		return converted, nil
+	case map[string]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, nested := range v {
+			var err error
+			if converted[key], err = stringKeys(nested); err != nil {
+				return nil, err
+			}
+		}
+		return converted, nil
+	case []interface{}:
+		converted := make([]interface{}, len(v))
+		for i, nested := range v {
+			var err error
+			if converted[i], err = stringKeys(nested); err != nil {
+				return nil, err
+			}
+		}
+		return converted, nil
+	default:
+		return v, nil
+	}
+}
+
+// Returns a non-nil error listing every problem of the definition
+func (d *Definition) Validate() error {
+	problems := []string{}
+	if d.Id == "" {
+		problems = append(problems, "id is required")
+	}
+	if (d.Audience.File == "") == (len(d.Audience.Users) == 0) {
+		problems = append(problems, "audience needs either a file or a list of users")
+	}
+	if len(d.Template) == 0 {
+		problems = append(problems, "template is required")
+	} else if _, err := d.request(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if d.Pacing.BatchSize < 0 {
+		problems = append(problems, "pacing.batch_size cannot be negative")
+	}
+	if d.Pacing.Interval < 0 || d.Pacing.Window < 0 {
+		problems = append(problems, "pacing durations cannot be negative")
+	}
+	if d.Pacing.Interval > 0 && d.Pacing.Window > 0 {
+		problems = append(problems, "pacing takes either an interval or a window, not both")
+	}
+
+	if len(problems) > 0 {
+		return errors.Errorf("Invalid campaign definition: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Returns the publish request rendered from the template
+func (d *Definition) request() (map[string]interface{}, error) {
+	template, err := pushnotifications.NewTemplate(d.Template)
+	if err != nil {
+		return nil, err
+	}
+	request, err := template.Render(d.Variables)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to render the template")
+	}
+	return request, pushnotifications.ValidateRequest(request)
+}
+
+// What applying a definition will do, for reviewing it before it is sent
+type Plan struct {
+	Id string
+	// The publish request, rendered from the template
+	Request map[string]interface{}
+	Users   int
+	Batches int
+	Start   time.Time
+	// Time between batches, and from the first batch to the last
+	Interval time.Duration
+	Duration time.Duration
+}
+
+func (p *Plan) String() string {
+	start := "now"
+	if !p.Start.IsZero() {
+		start = p.Start.Format(time.RFC3339)
+	}
+	return fmt.Sprintf("Campaign %s: %d users in %d batches, starting %s, %s apart, over %s",
+		p.Id, p.Users, p.Batches, start, p.Interval, p.Duration)
+}
+
+// Returns what applying the definition with a client of the limits will do, without publishing anything.
+// Reads the audience to count it.
+func (d *Definition) Plan(limits pushnotifications.Limits) (*Plan, error) {
+	request, err := d.request()
+	if err != nil {
+		return nil, err
+	}
+	batchSize, err := d.batchSize(limits.MaxUsers)
+	if err != nil {
+		return nil, err
+	}
+	users, err := d.countUsers()
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{
+		Id:       d.Id,
+		Request:  request,
+		Users:    users,
+		Batches:  (users + batchSize - 1) / batchSize,
+		Start:    d.Schedule.Start,
+		Interval: d.Pacing.Interval,
+	}
+	if d.Pacing.Window > 0 {
+		plan.Interval = windowInterval(d.Pacing.Window, users, batchSize)
+	}
+	if plan.Batches > 1 {
+		plan.Duration = plan.Interval * time.Duration(plan.Batches-1)
+	}
+	return plan, nil
+}
+
+// Waits for the start of the schedule, then runs the campaign with `Run`, saving its progress to `checkpoints`
+func (d *Definition) Apply(ctx context.Context, pn pushnotifications.UserPublisher, checkpoints CheckpointStore) (*Result, error) {
+	request, err := d.request()
+	if err != nil {
+		return nil, err
+	}
+	batchSize, err := d.batchSize(maxUsers(pn))
+	if err != nil {
+		return nil, err
+	}
+	size, err := d.countUsers()
+	if err != nil {
+		return nil, err
+	}
+	audience, closeAudience, err := d.audience()
+	if err != nil {
+		return nil, err
+	}
+	defer closeAudience()
+
+	if delay := time.Until(d.Schedule.Start); delay > 0 {
+		if err := wait(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+	return Run(ctx, pn, Campaign{
+		Id:          d.Id,
+		Audience:    audience,
+		Payload:     request,
+		BatchSize:   batchSize,
+		Interval:    d.Pacing.Interval,
+		Window:      d.Pacing.Window,
+		Size:        size,
+		Checkpoints: checkpoints,
+	})
+}
+
+// Returns the batch size of the pacing, `maxUsers` by default
+func (d *Definition) batchSize(maxUsers int) (int, error) {
+	if d.Pacing.BatchSize > maxUsers {
+		return 0, errors.Errorf("pacing.batch_size must be at most %d, the most users a publish can target", maxUsers)
+	}
+	if d.Pacing.BatchSize <= 0 {
+		return maxUsers, nil
+	}
+	return d.Pacing.BatchSize, nil
+}
+
+func (d *Definition) audience() (Audience, func() error, error) {
+	if d.Audience.File == "" {
+		return Users(d.Audience.Users...), func() error { return nil }, nil
+	}
+	path := d.Audience.File
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(d.dir, path)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Failed to open the audience")
+	}
+	return Lines(file), file.Close, nil
+}
+
+func (d *Definition) countUsers() (int, error) {
+	audience, closeAudience, err := d.audience()
+	if err != nil {
+		return 0, err
+	}
+	defer closeAudience()
+
+	users := 0
+	for {
+		_, err := audience.Next()
+		if err == io.EOF {
+			return users, nil
+		}
+		if err != nil {
+			return 0, errors.Wrap(err, "Failed to read the audience")
+		}
+		users++
+	}
+}
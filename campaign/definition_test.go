@@ -0,0 +1,108 @@
+package campaign
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	pushnotifications "github.com/pusher/push-notifications-go"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDefinition(t *testing.T) {
+	Convey("A campaign definition", t, func() {
+		Convey("should load from a YAML file", func() {
+			d, err := LoadDefinitionFile("testdata/spring-sale.yaml")
+			So(err, ShouldBeNil)
+			So(d.Id, ShouldEqual, "spring-sale")
+			So(d.Pacing.Window, ShouldEqual, 10*time.Millisecond)
+
+			Convey("and plan what it will do", func() {
+				plan, err := d.Plan(pushnotifications.DefaultLimits())
+				So(err, ShouldBeNil)
+				So(plan.Users, ShouldEqual, 3)
+				So(plan.Batches, ShouldEqual, 2)
+				So(plan.Interval, ShouldEqual, 10*time.Millisecond)
+				So(plan.String(), ShouldEqual, "Campaign spring-sale: 3 users in 2 batches, starting now, 10ms apart, over 10ms")
+
+				fcm := plan.Request["fcm"].(map[string]interface{})
+				So(fcm["notification"].(map[string]interface{})["body"], ShouldEqual, "20% off everything!")
+			})
+
+			Convey("and apply it", func() {
+				published := [][]string{}
+				testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					body, _ := io.ReadAll(r.Body)
+					request := struct {
+						Users []string `json:"users"`
+					}{}
+					json.Unmarshal(body, &request)
+					published = append(published, request.Users)
+					w.Write([]byte(`{"publishId":"pub-1"}`))
+				}))
+				defer testServer.Close()
+				pn, _ := pushnotifications.New(testInstanceId, testSecretKey, pushnotifications.WithCustomBaseURL(testServer.URL))
+
+				checkpoints := NewMemoryCheckpointStore()
+				result, err := d.Apply(context.Background(), pn, checkpoints)
+				So(err, ShouldBeNil)
+				So(len(result.Batches), ShouldEqual, 2)
+				So(published, ShouldResemble, [][]string{{"user-0", "user-1"}, {"user-2"}})
+
+				checkpoint, _ := checkpoints.Load("spring-sale")
+				So(checkpoint.Done, ShouldBeTrue)
+			})
+		})
+
+		Convey("should list every problem", func() {
+			_, err := LoadDefinition(strings.NewReader(`
+audience:
+  users: [user-1]
+  file: users.txt
+template:
+  fcm:
+    notification:
+      title: "{{.missing}}"
+pacing:
+  interval: 1s
+  window: 1m
+`))
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "id is required")
+			So(err.Error(), ShouldContainSubstring, "audience needs either a file or a list of users")
+			So(err.Error(), ShouldContainSubstring, "Failed to render the template")
+			So(err.Error(), ShouldContainSubstring, "either an interval or a window")
+		})
+
+		Convey("should cap the batch size at the limits of the client", func() {
+			d, err := LoadDefinition(strings.NewReader("id: x\naudience:\n  users: [a, b, c]\ntemplate: {fcm: {}}\npacing:\n  batch_size: 2\n"))
+			So(err, ShouldBeNil)
+
+			plan, err := d.Plan(pushnotifications.Limits{MaxUsers: 2})
+			So(err, ShouldBeNil)
+			So(plan.Batches, ShouldEqual, 2)
+
+			_, err = d.Plan(pushnotifications.Limits{MaxUsers: 1})
+			So(err.Error(), ShouldEqual, "pacing.batch_size must be at most 1, the most users a publish can target")
+
+			pn, _ := pushnotifications.New(testInstanceId, testSecretKey, pushnotifications.WithLimits(pushnotifications.Limits{MaxUsers: 1}))
+			_, err = d.Apply(context.Background(), pn, NewMemoryCheckpointStore())
+			So(err, ShouldNotBeNil)
+
+			d.Pacing.BatchSize = 0
+			plan, _ = d.Plan(pushnotifications.Limits{MaxUsers: 1})
+			So(plan.Batches, ShouldEqual, 3)
+		})
+
+		Convey("should reject unknown fields", func() {
+			_, err := LoadDefinition(strings.NewReader("id: x\naudience:\n  users: [a]\ntemplate: {fcm: {}}\npace: fast\n"))
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "pace")
+		})
+	})
+}
@@ -0,0 +1,47 @@
+package pushnotifications
+
+// A button shown on notifications of a `Category`
+type Action struct {
+	// Identifies the action to the app when the button is tapped
+	Id    string
+	Title string
+	// URL of an icon shown on the button by browsers that support it
+	Icon string
+}
+
+// A kind of notification and the buttons shown on it. Define categories once, register
+// the same ids in the iOS app, and reference them from notifications with `Notification.Category`.
+type Category struct {
+	Id      string
+	Actions []Action
+}
+
+func (c Category) validate() error {
+	if c.Id == "" {
+		return newValidationError("Category id cannot be an empty string")
+	}
+	seen := map[string]bool{}
+	for _, action := range c.Actions {
+		if action.Id == "" || action.Title == "" {
+			return newValidationError("Actions of category `%s` must have an id and a title", c.Id)
+		}
+		if seen[action.Id] {
+			return newValidationError("Category `%s` has more than one action with id `%s`", c.Id, action.Id)
+		}
+		seen[action.Id] = true
+	}
+	return nil
+}
+
+// Returns the `actions` of a web notification
+func (c Category) webActions() []interface{} {
+	actions := make([]interface{}, len(c.Actions))
+	for i, action := range c.Actions {
+		webAction := map[string]interface{}{"action": action.Id, "title": action.Title}
+		if action.Icon != "" {
+			webAction["icon"] = action.Icon
+		}
+		actions[i] = webAction
+	}
+	return actions
+}
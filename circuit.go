@@ -0,0 +1,79 @@
+package pushnotifications
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Returned, as the cause, by publishes refused by an open circuit breaker (see `WithCircuitBreaker`)
+var ErrCircuitOpen = errors.New("The circuit breaker is open after repeated failures")
+
+// Classes of failure that count towards opening the circuit: those that say the instance is
+// unreachable or unusable, rather than that the request was wrong
+var circuitBreakingClasses = map[ErrorClass]bool{
+	Unauthorized: true,
+	RateLimited:  true,
+	ServerError:  true,
+	Network:      true,
+}
+
+// Makes publishes fail fast with `ErrCircuitOpen` for `cooldown` after `failures` publishes in a row
+// failed because the instance is unreachable, rate limited or refusing the credentials.
+// After the cooldown, one publish is let through: the circuit closes again if it succeeds.
+// Each client built with the option has its own breaker, so a `MultiTenant` isolates each tenant.
+func WithCircuitBreaker(failures int, cooldown time.Duration) Option {
+	return func(pn *pushNotifications) {
+		breaker := &circuitBreaker{threshold: failures, cooldown: cooldown}
+		WithPublishStage(EncodeStage, PublishStageFunc(func(job *PublishJob) error {
+			return breaker.allow(job, time.Now())
+		}))(pn)
+		WithPublishRecorder(breaker)(pn)
+	}
+}
+
+type circuitBreaker struct {
+	mutex     sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+	// The publish let through after the cooldown, if it is not done yet
+	probe *PublishJob
+}
+
+func (b *circuitBreaker) allow(job *PublishJob, now time.Time) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.failures < b.threshold {
+		return nil
+	}
+	if now.Before(b.openUntil) || b.probe != nil {
+		return errors.WithStack(ErrCircuitOpen)
+	}
+	b.probe = job
+	return nil
+}
+
+func (b *circuitBreaker) Record(job *PublishJob) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if job == b.probe {
+		b.probe = nil
+	}
+	if job.Attempts == 0 {
+		// refused before reaching the service, by the breaker or otherwise
+		return
+	}
+	if job.Err == nil || !circuitBreakingClasses[Classify(job.Err)] {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
@@ -0,0 +1,86 @@
+package pushnotifications
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	Convey("A multi-tenant client with circuit breakers", t, func() {
+		failing := map[string]bool{"/publish_api/v1/instances/instance-acme/publishes": true}
+		requests := 0
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if failing[r.URL.Path] {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				w.Write([]byte(`{"error":"Service Unavailable","description":"down"}`))
+				return
+			}
+			w.Write([]byte(`{"publishId":"pub-123"}`))
+		}))
+		defer testServer.Close()
+
+		m := NewMultiTenant(func(tenantId string) (Credentials, error) {
+			return Credentials{InstanceId: "instance-" + tenantId, SecretKey: testSecretKey}, nil
+		},
+			WithCustomBaseURL(testServer.URL),
+			WithRetryPolicy(RetryPolicy{MaxAttempts: 1}),
+			WithCircuitBreaker(2, 50*time.Millisecond))
+		request := map[string]interface{}{"fcm": map[string]interface{}{}}
+
+		m.PublishToInterests("acme", []string{"hello"}, request)
+		m.PublishToInterests("acme", []string{"hello"}, request)
+		So(requests, ShouldEqual, 2)
+
+		Convey("should fail fast once a tenant failed repeatedly", func() {
+			_, err := m.PublishToInterests("acme", []string{"hello"}, request)
+			So(errors.Cause(err), ShouldEqual, ErrCircuitOpen)
+			So(requests, ShouldEqual, 2)
+		})
+
+		Convey("should not affect other tenants", func() {
+			_, err := m.PublishToInterests("globex", []string{"hello"}, request)
+			So(err, ShouldBeNil)
+		})
+
+		Convey("should close again once a publish succeeds after the cooldown", func() {
+			time.Sleep(60 * time.Millisecond)
+			failing = map[string]bool{}
+
+			_, err := m.PublishToInterests("acme", []string{"hello"}, request)
+			So(err, ShouldBeNil)
+			_, err = m.PublishToInterests("acme", []string{"hello"}, request)
+			So(err, ShouldBeNil)
+		})
+	})
+}
+
+func TestTokenBucket(t *testing.T) {
+	Convey("A token bucket", t, func() {
+		bucket := &tokenBucket{rate: 10, burst: 2, tokens: 2}
+		now := time.Now()
+
+		Convey("should allow bursts without waiting", func() {
+			So(bucket.reserve(now), ShouldEqual, 0)
+			So(bucket.reserve(now), ShouldEqual, 0)
+		})
+
+		Convey("should make callers beyond the burst wait for the rate", func() {
+			bucket.reserve(now)
+			bucket.reserve(now)
+			So(bucket.reserve(now), ShouldEqual, 100*time.Millisecond)
+			So(bucket.reserve(now), ShouldEqual, 200*time.Millisecond)
+		})
+
+		Convey("should refill over time", func() {
+			bucket.reserve(now)
+			bucket.reserve(now)
+			So(bucket.reserve(now.Add(time.Second)), ShouldEqual, 0)
+		})
+	})
+}
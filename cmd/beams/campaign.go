@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/pkg/errors"
+	pushnotifications "github.com/pusher/push-notifications-go"
+	"github.com/pusher/push-notifications-go/campaign"
+)
+
+func runCampaign(args []string) error {
+	flags := newFlagSet("campaign")
+	file := flags.String("file", "", "campaign definition to read (required)")
+	apply := flags.Bool("apply", false, "send the campaign instead of only printing its plan")
+	checkpoints := flags.String("checkpoints", ".beams-campaigns", "directory the progress of campaigns is saved in")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		return errors.New("-file is required")
+	}
+
+	definition, err := campaign.LoadDefinitionFile(*file)
+	if err != nil {
+		return err
+	}
+	plan, err := definition.Plan(pushnotifications.DefaultLimits())
+	if err != nil {
+		return err
+	}
+	request, err := json.MarshalIndent(plan.Request, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s\n%s\n", plan, request)
+	if !*apply {
+		fmt.Println("\nRun again with -apply to send it.")
+		return nil
+	}
+
+	pn, err := newClient()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(*checkpoints, 0755); err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	result, err := definition.Apply(ctx, pn, campaign.NewFileCheckpointStore(*checkpoints))
+	if result != nil {
+		fmt.Printf("Published %d batches, %d failed\n", len(result.Batches), len(result.Failed()))
+	}
+	return err
+}
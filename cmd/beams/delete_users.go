@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	pushnotifications "github.com/pusher/push-notifications-go"
+)
+
+func deleteUsers(args []string) error {
+	flags := newFlagSet("delete-users")
+	file := flags.String("file", "", "file of user ids to delete, one per line, or - for stdin (required)")
+	concurrency := flags.Int("concurrency", 4, "number of users deleted at once")
+	rate := flags.Float64("rate", 10, "maximum number of users deleted per second, 0 for no limit")
+	reportPath := flags.String("report", "", "CSV file to write the outcome for every user to (default stdout)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		return errors.New("-file is required")
+	}
+	if *concurrency <= 0 {
+		return errors.New("-concurrency must be at least 1")
+	}
+	if err := checkRate(*rate); err != nil {
+		return err
+	}
+
+	var input io.Reader = os.Stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		input = f
+	}
+	var output io.Writer = os.Stdout
+	if *reportPath != "" {
+		f, err := os.Create(*reportPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		output = f
+	}
+	pn, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	deleted, failed, err := deleteUserIds(pn, input, output, *concurrency, *rate)
+	fmt.Fprintf(os.Stderr, "Deleted %d users, %d failed\n", deleted, failed)
+	return err
+}
+
+// Deletes the users whose ids are read from `input`, one per line, writing the outcome for every user
+// to `output` as CSV. Makes up to `concurrency` deletions at once, and at most `rate` per second (0 for no limit).
+func deleteUserIds(
+	pn pushnotifications.UserDeleter,
+	input io.Reader,
+	output io.Writer,
+	concurrency int,
+	rate float64,
+) (deleted, failed int, err error) {
+	if err := checkRate(rate); err != nil {
+		return 0, 0, err
+	}
+
+	// a shared ticker spaces out the deletions of every worker, as `WithRateLimit` only limits publishes
+	var ticks <-chan time.Time
+	if rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / rate))
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	type outcome struct {
+		userId string
+		err    error
+	}
+	userIds := make(chan string)
+	outcomes := make(chan outcome)
+	workers := sync.WaitGroup{}
+	for i := 0; i < concurrency; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for userId := range userIds {
+				if ticks != nil {
+					<-ticks
+				}
+				outcomes <- outcome{userId: userId, err: pn.DeleteUser(userId)}
+			}
+		}()
+	}
+
+	report := csv.NewWriter(output)
+	report.Write([]string{"user_id", "status", "error"})
+	reported := make(chan struct{})
+	go func() {
+		for o := range outcomes {
+			if o.err != nil {
+				failed++
+				report.Write([]string{o.userId, "failed", o.err.Error()})
+			} else {
+				deleted++
+				report.Write([]string{o.userId, "deleted", ""})
+			}
+			report.Flush()
+		}
+		close(reported)
+	}()
+
+	scanner := bufio.NewScanner(input)
+	for scanner.Scan() {
+		if userId := strings.TrimSpace(scanner.Text()); userId != "" {
+			userIds <- userId
+		}
+	}
+	close(userIds)
+	workers.Wait()
+	close(outcomes)
+	<-reported
+
+	if err := scanner.Err(); err != nil {
+		return deleted, failed, errors.Wrap(err, "Failed to read the user ids")
+	}
+	if err := report.Error(); err != nil {
+		return deleted, failed, errors.Wrap(err, "Failed to write the report")
+	}
+	if failed > 0 {
+		return deleted, failed, errors.Errorf("%d of %d users could not be deleted", failed, deleted+failed)
+	}
+	return deleted, failed, nil
+}
+
+// Rejects negative rates, and rates too high for a ticker to space out the deletions
+func checkRate(rate float64) error {
+	if rate < 0 || rate > float64(time.Second) {
+		return errors.Errorf("-rate must be between 0 and %d", time.Second)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// Deletes every user but those it is set up to fail
+type fakeDeleter struct {
+	mutex   sync.Mutex
+	failing map[string]bool
+	deleted []string
+}
+
+func (d *fakeDeleter) DeleteUser(userId string) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.failing[userId] {
+		return errors.New("user not found")
+	}
+	d.deleted = append(d.deleted, userId)
+	return nil
+}
+
+func TestDeleteUserIds(t *testing.T) {
+	Convey("Deleting users", t, func() {
+		deleter := &fakeDeleter{failing: map[string]bool{"user-2": true}}
+		report := &bytes.Buffer{}
+
+		Convey("should report the outcome for every user", func() {
+			deleted, failed, err := deleteUserIds(deleter, strings.NewReader("user-1\n\n user-2 \nuser-3\n"), report, 1, 0)
+			So(deleted, ShouldEqual, 2)
+			So(failed, ShouldEqual, 1)
+			So(err.Error(), ShouldEqual, "1 of 3 users could not be deleted")
+			So(deleter.deleted, ShouldResemble, []string{"user-1", "user-3"})
+			So(report.String(), ShouldEqual, "user_id,status,error\nuser-1,deleted,\nuser-2,failed,user not found\nuser-3,deleted,\n")
+		})
+
+		Convey("should delete users concurrently, at the rate", func() {
+			deleter.failing = nil
+			deleted, failed, err := deleteUserIds(deleter, strings.NewReader("user-1\nuser-2\nuser-3\nuser-4\n"), report, 2, 1000)
+			So(err, ShouldBeNil)
+			So(deleted, ShouldEqual, 4)
+			So(failed, ShouldEqual, 0)
+			So(strings.Count(report.String(), ",deleted,"), ShouldEqual, 4)
+		})
+
+		Convey("should reject negative rates and rates too high to tick", func() {
+			for _, rate := range []float64{-1, 2e9} {
+				_, _, err := deleteUserIds(deleter, strings.NewReader("user-1\n"), report, 1, rate)
+				So(err.Error(), ShouldEqual, "-rate must be between 0 and 1000000000")
+			}
+			So(deleter.deleted, ShouldBeEmpty)
+			So(report.String(), ShouldBeEmpty)
+		})
+	})
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	pushnotifications "github.com/pusher/push-notifications-go"
+)
+
+func doctor(args []string) error {
+	flags := newFlagSet("doctor")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	findings := pushnotifications.ValidateConfig(os.Getenv("BEAMS_INSTANCE_ID"), os.Getenv("BEAMS_SECRET_KEY"))
+	problems := 0
+	for _, finding := range findings {
+		fmt.Println(finding)
+		if finding.Severity == pushnotifications.SeverityError {
+			problems++
+		}
+	}
+	if problems > 0 {
+		return errors.Errorf("%d problems found", problems)
+	}
+	return nil
+}
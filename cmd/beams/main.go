@@ -0,0 +1,72 @@
+// Command beams runs operations against a Beams instance from the terminal.
+//
+// The instance is read from the BEAMS_INSTANCE_ID and BEAMS_SECRET_KEY environment variables.
+//
+//	beams <command> [flags]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/pkg/errors"
+	pushnotifications "github.com/pusher/push-notifications-go"
+)
+
+type command struct {
+	summary string
+	run     func(args []string) error
+}
+
+var commands = map[string]command{
+	"campaign":     {"plan or send a campaign described in a YAML file", runCampaign},
+	"delete-users": {"delete the users listed in a file, e.g. for erasure requests", deleteUsers},
+	"doctor":       {"check the credentials and connection to the Beams service", doctor},
+	"publish":      {"publish a request to interests or users, asking to confirm large audiences", publish},
+	"replay":       {"publish again the publishes of a publish log matching a filter", replay},
+	"scaffold":     {"print an example publish request for every platform, as JSON or Go code", scaffold},
+	"webhooks":     {"receive webhooks on a local port and print their events", tailWebhooks},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "beams: unknown command %q\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	if err := cmd.run(os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, "beams:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(os.Stderr, "Usage: beams <command> [flags]\n\nCommands:")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", name, commands[name].summary)
+	}
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	return flag.NewFlagSet("beams "+name, flag.ContinueOnError)
+}
+
+// Returns a client for the instance of the environment
+func newClient(options ...pushnotifications.Option) (pushnotifications.PushNotifications, error) {
+	pn, err := pushnotifications.New(os.Getenv("BEAMS_INSTANCE_ID"), os.Getenv("BEAMS_SECRET_KEY"), options...)
+	return pn, errors.Wrap(err, "Set BEAMS_INSTANCE_ID and BEAMS_SECRET_KEY")
+}
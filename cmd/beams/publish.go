@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	pushnotifications "github.com/pusher/push-notifications-go"
+)
+
+func publish(args []string) error {
+	flags := newFlagSet("publish")
+	requestPath := flags.String("request", "", "JSON file of the publish request, or - for stdin (required)")
+	interests := flags.String("interests", "", "comma separated interests to publish to")
+	users := flags.String("users", "", "comma separated user ids to publish to")
+	usersFile := flags.String("users-file", "", "file of user ids to publish to, one per line, or - for stdin")
+	confirmAbove := flags.Int("confirm-above", 10, "ask for confirmation when publishing to more interests or users than this")
+	yes := flags.Bool("yes", false, "publish without asking for confirmation")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *requestPath == "" {
+		return errors.New("-request is required")
+	}
+	if *requestPath == "-" && *usersFile == "-" {
+		return errors.New("-request and -users-file cannot both be read from stdin")
+	}
+
+	request, err := readRequest(*requestPath)
+	if err != nil {
+		return err
+	}
+	targets, kind, err := readTargets(*interests, *users, *usersFile)
+	if err != nil {
+		return err
+	}
+
+	if len(targets) > *confirmAbove && !*yes {
+		readStdin := *requestPath == "-" || *usersFile == "-"
+		if err := confirmPublish(os.Stdin, os.Stderr, targets, kind, request, readStdin); err != nil {
+			return err
+		}
+	}
+
+	pn, err := newClient()
+	if err != nil {
+		return err
+	}
+	if kind == "interests" {
+		publishId, err := pn.PublishToInterests(targets, request)
+		if err != nil {
+			return err
+		}
+		fmt.Println(publishId)
+		return nil
+	}
+
+	maxUsers := pushnotifications.DefaultLimits().MaxUsers
+	if limited, ok := pn.(limitedClient); ok {
+		maxUsers = limited.Limits().MaxUsers
+	}
+	return publishToUsers(pn, targets, maxUsers, request, os.Stdout)
+}
+
+// A client reporting its limits, like the one returned by `pushnotifications.New` does
+type limitedClient interface {
+	Limits() pushnotifications.Limits
+}
+
+// Publishes to the users in chunks of up to `maxUsers`, writing the publish id of every chunk to `out`.
+// The user ids are passed as they are, as reading them again as CSV would change ids with commas or quotes.
+func publishToUsers(
+	pn pushnotifications.UserPublisher,
+	users []string,
+	maxUsers int,
+	request map[string]interface{},
+	out io.Writer,
+) error {
+	for start := 0; start < len(users); start += maxUsers {
+		end := start + maxUsers
+		if end > len(users) {
+			end = len(users)
+		}
+		publishId, err := pn.PublishToUsers(users[start:end], request)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to publish to users %d to %d", start+1, end)
+		}
+		fmt.Fprintln(out, publishId)
+	}
+	return nil
+}
+
+func readRequest(path string) (map[string]interface{}, error) {
+	var input io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		input = file
+	}
+
+	request := map[string]interface{}{}
+	if err := json.NewDecoder(input).Decode(&request); err != nil {
+		return nil, errors.Wrap(err, "The request must be a JSON object")
+	}
+	return request, pushnotifications.ValidateRequest(request)
+}
+
+// Returns the interests or users to publish to, and which of the two they are
+func readTargets(interests, users, usersFile string) ([]string, string, error) {
+	given := 0
+	for _, flag := range []string{interests, users, usersFile} {
+		if flag != "" {
+			given++
+		}
+	}
+	if given != 1 {
+		return nil, "", errors.New("Exactly one of -interests, -users and -users-file is required")
+	}
+
+	switch {
+	case interests != "":
+		return splitList(interests), "interests", nil
+	case users != "":
+		return splitList(users), "users", nil
+	}
+
+	var input io.Reader = os.Stdin
+	if usersFile != "-" {
+		file, err := os.Open(usersFile)
+		if err != nil {
+			return nil, "", err
+		}
+		defer file.Close()
+		input = file
+	}
+	targets := []string{}
+	scanner := bufio.NewScanner(input)
+	for scanner.Scan() {
+		if userId := strings.TrimSpace(scanner.Text()); userId != "" {
+			targets = append(targets, userId)
+		}
+	}
+	return targets, "users", errors.Wrap(scanner.Err(), "Failed to read the user ids")
+}
+
+func splitList(list string) []string {
+	values := []string{}
+	for _, value := range strings.Split(list, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// Shows a summary of the publish and asks to confirm it on the terminal. Publishes whose request or
+// user ids are read from stdin can't be confirmed there, and need -yes.
+func confirmPublish(in io.Reader, out io.Writer, targets []string, kind string, request map[string]interface{}, readStdin bool) error {
+	if readStdin {
+		return errors.Errorf("Publishing to %d %s needs confirmation: pass -yes, or the request and user ids as files", len(targets), kind)
+	}
+	shown := targets
+	if len(shown) > 5 {
+		shown = shown[:5]
+	}
+	fmt.Fprintf(out, "About to publish to %d %s: %s", len(targets), kind, strings.Join(shown, ", "))
+	if len(shown) < len(targets) {
+		fmt.Fprintf(out, " and %d more", len(targets)-len(shown))
+	}
+	fmt.Fprintln(out)
+	for _, preview := range pushnotifications.Preview(request) {
+		fmt.Fprintf(out, "  %-5s %q %q\n", preview.Platform, preview.Title, preview.Body)
+	}
+
+	fmt.Fprintf(out, "Type the number of %s (%d) to confirm: ", kind, len(targets))
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if strings.TrimSpace(answer) != fmt.Sprint(len(targets)) {
+		return pushnotifications.ErrNotConfirmed
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	pushnotifications "github.com/pusher/push-notifications-go"
+	"github.com/pusher/push-notifications-go/pushnotificationstest"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPublish(t *testing.T) {
+	Convey("Publishing to users", t, func() {
+		fake := pushnotificationstest.NewFake()
+		out := &bytes.Buffer{}
+		request := map[string]interface{}{"fcm": map[string]interface{}{"notification": map[string]interface{}{"title": "Hi"}}}
+
+		Convey("should publish in chunks of the most users a publish can target", func() {
+			err := publishToUsers(fake, []string{"user-1", "user-2", "user-3"}, 2, request, out)
+			So(err, ShouldBeNil)
+			So(out.String(), ShouldEqual, "pub-1\npub-2\n")
+
+			publishes := fake.Publishes()
+			So(len(publishes), ShouldEqual, 2)
+			So(publishes[0].Users, ShouldResemble, []string{"user-1", "user-2"})
+			So(publishes[1].Users, ShouldResemble, []string{"user-3"})
+		})
+
+		Convey("should publish to the user ids as they are", func() {
+			users := []string{"user_id", "user,2", `user"3`}
+			So(publishToUsers(fake, users, 1000, request, out), ShouldBeNil)
+			So(fake.Publishes()[0].Users, ShouldResemble, users)
+		})
+
+		Convey("should stop at the first chunk that fails", func() {
+			fake.FailPublish(1, 422)
+			err := publishToUsers(fake, []string{"user-1", "user-2", "user-3"}, 1, request, out)
+			So(pushnotifications.Classify(err), ShouldEqual, pushnotifications.Validation)
+			So(err.Error(), ShouldStartWith, "Failed to publish to users 2 to 2")
+			So(out.String(), ShouldEqual, "pub-1\n")
+			So(len(fake.Publishes()), ShouldEqual, 2)
+		})
+	})
+
+	Convey("Confirming a publish", t, func() {
+		out := &bytes.Buffer{}
+		users := []string{"user-1", "user-2", "user-3", "user-4", "user-5", "user-6"}
+		request := map[string]interface{}{"fcm": map[string]interface{}{"notification": map[string]interface{}{"title": "Hi"}}}
+
+		Convey("should go ahead when the number of users is typed", func() {
+			err := confirmPublish(strings.NewReader("6\n"), out, users, "users", request, false)
+			So(err, ShouldBeNil)
+			So(out.String(), ShouldStartWith, "About to publish to 6 users: user-1, user-2, user-3, user-4, user-5 and 1 more\n")
+			So(out.String(), ShouldContainSubstring, `"Hi"`)
+			So(out.String(), ShouldEndWith, "Type the number of users (6) to confirm: ")
+		})
+
+		Convey("should stop on any other answer", func() {
+			for _, answer := range []string{"5\n", "yes\n", ""} {
+				err := confirmPublish(strings.NewReader(answer), out, users, "users", request, false)
+				So(err, ShouldEqual, pushnotifications.ErrNotConfirmed)
+			}
+		})
+
+		Convey("should need -yes when reading from stdin", func() {
+			err := confirmPublish(strings.NewReader("6\n"), out, users, "users", request, true)
+			So(err.Error(), ShouldEqual, "Publishing to 6 users needs confirmation: pass -yes, or the request and user ids as files")
+			So(out.String(), ShouldBeEmpty)
+		})
+	})
+}
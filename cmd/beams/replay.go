@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	pushnotifications "github.com/pusher/push-notifications-go"
+)
+
+func replay(args []string) error {
+	flags := newFlagSet("replay")
+	logPath := flags.String("log", "", "publish log to read (required)")
+	from := flags.String("from", "", "only publishes made at or after this RFC 3339 time")
+	to := flags.String("to", "", "only publishes made before this RFC 3339 time")
+	failedOnly := flags.Bool("failed", false, "only publishes that failed")
+	operation := flags.String("operation", "", "only publishes of this operation (publish_to_interests or publish_to_users)")
+	recordPath := flags.String("record", "", "publish log to append the replayed publishes to")
+	keyFlag := flags.String("key", os.Getenv("BEAMS_LOG_KEY"),
+		"key of encrypted publish logs, as <key id>:<base64 secret> (default $BEAMS_LOG_KEY)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *logPath == "" {
+		return errors.New("-log is required")
+	}
+	key, err := parseKey(*keyFlag)
+	if err != nil {
+		return err
+	}
+
+	filter := pushnotifications.ReplayFilter{FailedOnly: *failedOnly, Operation: *operation}
+	if filter.From, err = parseTime(*from); err != nil {
+		return err
+	}
+	if filter.To, err = parseTime(*to); err != nil {
+		return err
+	}
+
+	log, err := os.Open(*logPath)
+	if err != nil {
+		return err
+	}
+	defer log.Close()
+
+	options := []pushnotifications.Option{}
+	if *recordPath != "" {
+		record, err := os.OpenFile(*recordPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return err
+		}
+		defer record.Close()
+		recordLog := pushnotifications.NewPublishLog(record)
+		if key != nil {
+			if recordLog, err = pushnotifications.NewEncryptedPublishLog(record, *key); err != nil {
+				return err
+			}
+		}
+		options = append(options, pushnotifications.WithPublishRecorder(recordLog))
+	}
+	pn, err := newClient(options...)
+	if err != nil {
+		return err
+	}
+
+	logOptions := []pushnotifications.PublishLogOption{}
+	if key != nil {
+		logOptions = append(logOptions, pushnotifications.WithLogKeys(*key))
+	}
+	results, err := pushnotifications.Replay(pn, log, filter, logOptions...)
+	failed := 0
+	for _, result := range results {
+		original := result.Original.Time.Format(time.RFC3339)
+		if result.Err != nil {
+			failed++
+			fmt.Printf("%s %s: %v\n", original, result.Original.Operation, result.Err)
+		} else {
+			fmt.Printf("%s %s: replayed as %s\n", original, result.Original.Operation, result.PublishId)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return errors.Errorf("%d of %d replays failed", failed, len(results))
+	}
+	return nil
+}
+
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	return t, errors.Wrapf(err, "Invalid time %q", value)
+}
+
+// Parses a key given as <key id>:<base64 secret>, returning nil if none is given
+func parseKey(value string) (*pushnotifications.EncryptionKey, error) {
+	if value == "" {
+		return nil, nil
+	}
+	id, encoded := value, ""
+	if i := strings.Index(value, ":"); i >= 0 {
+		id, encoded = value[:i], value[i+1:]
+	}
+	secret, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || id == "" || encoded == "" {
+		return nil, errors.New("Invalid key, expected <key id>:<base64 secret>")
+	}
+	return &pushnotifications.EncryptionKey{Id: id, Secret: secret}, nil
+}
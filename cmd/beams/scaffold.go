@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	pushnotifications "github.com/pusher/push-notifications-go"
+)
+
+var platforms = []string{"apns", "fcm", "web"}
+
+func scaffold(args []string) error {
+	flags := newFlagSet("scaffold")
+	format := flags.String("format", "json", "output format: json for a publish request, go for code using the builder")
+	only := flags.String("platforms", strings.Join(platforms, ","), "comma separated platforms to include")
+	title := flags.String("title", "Hello", "title of the notification")
+	body := flags.String("body", "Hello, world!", "body of the notification")
+	image := flags.String("image", "", "https URL of an image to show")
+	link := flags.String("link", "", "URL to open when the notification is tapped")
+	badge := flags.Int("badge", -1, "badge count to set on the app icon")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	included := map[string]bool{}
+	for _, platform := range strings.Split(*only, ",") {
+		platform = strings.TrimSpace(platform)
+		if !contains(platforms, platform) {
+			return errors.Errorf("Unknown platform %q, expected some of %s", platform, strings.Join(platforms, ", "))
+		}
+		included[platform] = true
+	}
+
+	notification := pushnotifications.NewNotification(*title, *body)
+	if *image != "" {
+		notification.Image(*image)
+	}
+	if *link != "" {
+		notification.DeepLink(*link)
+	}
+	if *badge >= 0 {
+		notification.Badge(*badge)
+	}
+	request, err := notification.Request()
+	if err != nil {
+		return err
+	}
+	for _, platform := range platforms {
+		if !included[platform] {
+			delete(request, platform)
+		}
+	}
+	if err := pushnotifications.ValidateRequest(request); err != nil {
+		return err
+	}
+
+	switch *format {
+	case "json":
+		encoded, err := json.MarshalIndent(request, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(encoded))
+		return nil
+	case "go":
+		return writeScaffoldCode(os.Stdout, *title, *body, *image, *link, *badge, included)
+	default:
+		return errors.Errorf("Unknown format %q, expected json or go", *format)
+	}
+}
+
+func writeScaffoldCode(w io.Writer, title, body, image, link string, badge int, included map[string]bool) error {
+	code := &strings.Builder{}
+	fmt.Fprintf(code, "request, err := pushnotifications.NewNotification(%q, %q)", title, body)
+	if image != "" {
+		fmt.Fprintf(code, ".\n\tImage(%q)", image)
+	}
+	if link != "" {
+		fmt.Fprintf(code, ".\n\tDeepLink(%q)", link)
+	}
+	if badge >= 0 {
+		fmt.Fprintf(code, ".\n\tBadge(%d)", badge)
+	}
+	code.WriteString(".\n\tRequest()\nif err != nil {\n\treturn err\n}\n")
+	for _, platform := range platforms {
+		if !included[platform] {
+			fmt.Fprintf(code, "delete(request, %q)\n", platform)
+		}
+	}
+	code.WriteString("\npublishId, err := beamsClient.PublishToInterests([]string{\"hello\"}, request)\n")
+
+	_, err := io.WriteString(w, code.String())
+	return err
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	pushnotifications "github.com/pusher/push-notifications-go"
+)
+
+var webhookEventNames = map[string]string{
+	pushnotifications.PublishToUserAttemptEvent:            "attempted",
+	pushnotifications.UserNotificationAcknowledgementEvent: "delivered",
+	pushnotifications.UserNotificationOpenEvent:            "opened",
+}
+
+func tailWebhooks(args []string) error {
+	flags := newFlagSet("webhooks")
+	port := flags.Int("port", 8080, "port to listen on")
+	path := flags.String("path", "/webhooks", "path to receive webhooks on")
+	secret := flags.String("secret", os.Getenv("BEAMS_WEBHOOK_SECRET"), "webhook secret (default $BEAMS_WEBHOOK_SECRET)")
+	public := flags.Bool("public", false, "listen on every interface instead of localhost, e.g. behind a tunnel")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *secret == "" {
+		return errors.New("-secret or BEAMS_WEBHOOK_SECRET is required")
+	}
+
+	host := "localhost"
+	if *public {
+		host = ""
+	}
+	address := net.JoinHostPort(host, strconv.Itoa(*port))
+
+	mux := http.NewServeMux()
+	mux.Handle(*path, pushnotifications.WebhookHandler(*secret, printWebhook))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(os.Stderr, "%s ignored %s %s: webhooks are received on %s\n",
+			time.Now().Format("15:04:05"), r.Method, r.URL.Path, *path)
+		http.NotFound(w, r)
+	})
+
+	fmt.Fprintf(os.Stderr, "Listening for webhooks on http://localhost:%d%s\n", *port, *path)
+	if *public {
+		fmt.Fprintf(os.Stderr, "Point your tunnel at port %d and set the webhook URL to <tunnel URL>%s\n", *port, *path)
+	}
+	server := &http.Server{Addr: address, Handler: logRejected(mux), ReadHeaderTimeout: 10 * time.Second}
+	return server.ListenAndServe()
+}
+
+func printWebhook(event pushnotifications.WebhookEvent) {
+	name, ok := webhookEventNames[event.Type]
+	if !ok {
+		name = event.Type
+	}
+	fmt.Printf("%s %-9s user=%s device=%s publish=%s event=%s\n",
+		event.CreatedAt.Local().Format("15:04:05"), name, event.UserId, event.DeviceId, event.PublishId, event.EventId)
+}
+
+// Reports requests the webhook handler rejected, e.g. because of a wrong secret
+func logRejected(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+		if recorder.status >= 400 && recorder.status != http.StatusNotFound {
+			fmt.Fprintf(os.Stderr, "%s rejected %s %s: %d %s\n", time.Now().Format("15:04:05"),
+				r.Method, r.URL.Path, recorder.status, http.StatusText(recorder.status))
+		}
+	})
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
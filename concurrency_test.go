@@ -0,0 +1,80 @@
+package pushnotifications
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// Run with `go test -race` to check that a shared client has no data races
+func TestConcurrentUse(t *testing.T) {
+	Convey("A Push Notifications Instance shared between goroutines", t, func() {
+		var requests int64
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// every other request fails, so that publishes are retried
+			if atomic.AddInt64(&requests, 1)%2 == 0 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Header().Set("X-RateLimit-Limit", "1000")
+			w.Header().Set("X-RateLimit-Remaining", "900")
+			w.Write([]byte(`{"publishId":"pub-123"}`))
+		}))
+		defer testServer.Close()
+
+		var hooked int64
+		policy := DefaultRetryPolicy()
+		policy.MaxAttempts = 10
+		policy.Backoff = func(retry int) time.Duration { return time.Millisecond }
+		history := NewHistory(10)
+		pn, err := New(testInstanceId, testSecretKey,
+			WithCustomBaseURL(testServer.URL),
+			WithRetryPolicy(policy),
+			WithRateLimit(10000, 100),
+			WithHeader("X-Team", "notifications"),
+			WithHeaderFunc(func(r *http.Request) { r.Header.Add("X-Team", "growth") }),
+			WithPublishHook(func(PublishEvent) { atomic.AddInt64(&hooked, 1) }),
+			WithPublishRecorder(history),
+			WithPublishRecorder(NewExperiments()),
+		)
+		So(err, ShouldBeNil)
+
+		Convey("should publish, delete users and report from every goroutine", func() {
+			request := map[string]interface{}{"fcm": map[string]interface{}{"notification": map[string]interface{}{"title": "Hi"}}}
+			var wg sync.WaitGroup
+			errs := make(chan error, 100)
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					user := fmt.Sprintf("user-%d", i)
+					_, err := pn.PublishToUsers([]string{user}, request, WithExperiment("concurrency"))
+					errs <- err
+					_, err = pn.PublishToInterests([]string{"hello"}, request)
+					errs <- err
+					_, err = pn.GenerateToken(user)
+					errs <- err
+					errs <- pn.DeleteUser(user)
+					pn.Stats()
+					pn.Quota()
+					history.Records()
+				}(i)
+			}
+			wg.Wait()
+			close(errs)
+
+			for err := range errs {
+				So(err, ShouldBeNil)
+			}
+			So(pn.Stats().PublishesSent, ShouldEqual, 40)
+			So(atomic.LoadInt64(&hooked), ShouldEqual, 40)
+			So(pn.Quota().Remaining, ShouldEqual, 900)
+		})
+	})
+}
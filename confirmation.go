@@ -0,0 +1,23 @@
+package pushnotifications
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// Returned by confirmation functions (see `WithConfirmation`) to refuse a publish
+var ErrNotConfirmed = errors.New("The publish was not confirmed")
+
+// Makes publishes to `threshold` or more interests or users wait for `confirm` to approve them,
+// so that a test notification can't be sent to everyone by mistake. `confirm` receives the targets
+// and the encoded publish request; returning a non-nil error stops the publish and is returned to the caller.
+// Note that a single interest can reach every device of the instance.
+func WithConfirmation(threshold int, confirm func(targets []string, payload json.RawMessage) error) Option {
+	return WithPublishStage(EncodeStage, PublishStageFunc(func(job *PublishJob) error {
+		if len(job.Targets) < threshold {
+			return nil
+		}
+		return errors.Wrap(confirm(job.Targets, job.Body), "Publish refused")
+	}))
+}
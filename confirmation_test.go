@@ -0,0 +1,57 @@
+package pushnotifications
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestConfirmation(t *testing.T) {
+	Convey("A Push Notifications Instance asking for confirmation", t, func() {
+		published := 0
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			published++
+			w.Write([]byte(`{"publishId":"pub-123"}`))
+		}))
+		defer testServer.Close()
+
+		confirmed := false
+		var confirmedTargets []string
+		var confirmedPayload json.RawMessage
+		pn, _ := New(testInstanceId, testSecretKey,
+			WithCustomBaseURL(testServer.URL),
+			WithConfirmation(2, func(targets []string, payload json.RawMessage) error {
+				confirmedTargets, confirmedPayload = targets, payload
+				if !confirmed {
+					return ErrNotConfirmed
+				}
+				return nil
+			}))
+		request := map[string]interface{}{"fcm": map[string]interface{}{}}
+
+		Convey("should publish to fewer targets than the threshold without confirmation", func() {
+			_, err := pn.PublishToUsers([]string{"user-1"}, request)
+			So(err, ShouldBeNil)
+			So(confirmedTargets, ShouldBeNil)
+			So(published, ShouldEqual, 1)
+		})
+
+		Convey("should not publish to more targets unless confirmed", func() {
+			_, err := pn.PublishToUsers([]string{"user-1", "user-2"}, request)
+			So(errors.Cause(err), ShouldEqual, ErrNotConfirmed)
+			So(err.Error(), ShouldStartWith, "Publish refused")
+			So(confirmedTargets, ShouldResemble, []string{"user-1", "user-2"})
+			So(string(confirmedPayload), ShouldEqual, `{"fcm":{},"users":["user-1","user-2"]}`)
+			So(published, ShouldEqual, 0)
+
+			confirmed = true
+			_, err = pn.PublishToUsers([]string{"user-1", "user-2"}, request)
+			So(err, ShouldBeNil)
+			So(published, ShouldEqual, 1)
+		})
+	})
+}
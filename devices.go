@@ -0,0 +1,99 @@
+package pushnotifications
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// A device registered to receive notifications through a direct `Backend`
+type Device struct {
+	// Name of the backend that delivers to the device, e.g. "apns"
+	Platform string
+	// Token identifying the device to the platform
+	Token string
+	// User the device belongs to, empty for anonymous devices
+	UserId string
+	// When the device was last registered
+	RegisteredAt time.Time
+}
+
+// Keeps track of the device tokens of each user, for apps sending through direct backends.
+// Implementations must be safe for concurrent use.
+type DeviceStore interface {
+	// Adds the device, or updates it if its platform and token are already registered
+	Register(device Device) error
+	// Removes the device with the given platform and token. Removing an unknown device is not an error.
+	Unregister(platform, token string) error
+	// Returns the devices of the user, ordered by platform and token
+	DevicesForUser(userId string) ([]Device, error)
+}
+
+// Returns a `DeviceStore` keeping devices in memory, for tests and single-process services
+func NewMemoryDeviceStore() DeviceStore {
+	return &memoryDeviceStore{devices: map[deviceKey]Device{}}
+}
+
+type deviceKey struct {
+	platform string
+	token    string
+}
+
+type memoryDeviceStore struct {
+	mutex   sync.RWMutex
+	devices map[deviceKey]Device
+}
+
+func (s *memoryDeviceStore) Register(device Device) error {
+	if err := validateDevice(device); err != nil {
+		return err
+	}
+	if device.RegisteredAt.IsZero() {
+		device.RegisteredAt = time.Now()
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.devices[deviceKey{device.Platform, device.Token}] = device
+	return nil
+}
+
+func (s *memoryDeviceStore) Unregister(platform, token string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.devices, deviceKey{platform, token})
+	return nil
+}
+
+func (s *memoryDeviceStore) DevicesForUser(userId string) ([]Device, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	devices := []Device{}
+	for _, device := range s.devices {
+		if device.UserId == userId {
+			devices = append(devices, device)
+		}
+	}
+	sortDevices(devices)
+	return devices, nil
+}
+
+func validateDevice(device Device) error {
+	if device.Platform == "" {
+		return newValidationError("Device platform cannot be an empty string")
+	}
+	if device.Token == "" {
+		return newValidationError("Device token cannot be an empty string")
+	}
+	return nil
+}
+
+func sortDevices(devices []Device) {
+	sort.Slice(devices, func(i, j int) bool {
+		if devices[i].Platform != devices[j].Platform {
+			return devices[i].Platform < devices[j].Platform
+		}
+		return devices[i].Token < devices[j].Token
+	})
+}
@@ -0,0 +1,182 @@
+package pushnotifications
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// A database/sql driver understanding just the statements of the SQL device store,
+// keeping rows in memory and recording the statements it ran
+type fakeDeviceDriver struct {
+	mutex      sync.Mutex
+	rows       map[string][]driver.Value
+	statements []string
+}
+
+func (d *fakeDeviceDriver) Open(name string) (driver.Conn, error) { return d, nil }
+func (d *fakeDeviceDriver) Prepare(query string) (driver.Stmt, error) {
+	return &fakeDeviceStmt{driver: d, query: query}, nil
+}
+func (d *fakeDeviceDriver) Close() error              { return nil }
+func (d *fakeDeviceDriver) Begin() (driver.Tx, error) { return d, nil }
+func (d *fakeDeviceDriver) Commit() error             { return nil }
+func (d *fakeDeviceDriver) Rollback() error           { return nil }
+
+type fakeDeviceStmt struct {
+	driver *fakeDeviceDriver
+	query  string
+}
+
+func (s *fakeDeviceStmt) Close() error  { return nil }
+func (s *fakeDeviceStmt) NumInput() int { return -1 }
+
+func (s *fakeDeviceStmt) Exec(args []driver.Value) (driver.Result, error) {
+	d := s.driver
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.statements = append(d.statements, s.query)
+
+	key := args[0].(string) + "/" + args[1].(string)
+	if strings.HasPrefix(s.query, "DELETE") {
+		delete(d.rows, key)
+	} else {
+		d.rows[key] = args
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeDeviceStmt) Query(args []driver.Value) (driver.Rows, error) {
+	d := s.driver
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.statements = append(d.statements, s.query)
+
+	keys := []string{}
+	for key, row := range d.rows {
+		if row[2] == args[0] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	rows := &fakeDeviceRows{}
+	for _, key := range keys {
+		rows.rows = append(rows.rows, d.rows[key])
+	}
+	return rows, nil
+}
+
+type fakeDeviceRows struct {
+	rows [][]driver.Value
+}
+
+func (r *fakeDeviceRows) Columns() []string {
+	return []string{"platform", "token", "user_id", "registered_at"}
+}
+func (r *fakeDeviceRows) Close() error { return nil }
+
+func (r *fakeDeviceRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+var fakeDevices = &fakeDeviceDriver{}
+
+func init() {
+	sql.Register("fakedevices", fakeDevices)
+}
+
+func TestDeviceStores(t *testing.T) {
+	registeredAt := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	stores := map[string]func() DeviceStore{
+		"memory": NewMemoryDeviceStore,
+		"SQL": func() DeviceStore {
+			fakeDevices.rows = map[string][]driver.Value{}
+			fakeDevices.statements = nil
+			db, _ := sql.Open("fakedevices", "")
+			return NewSQLDeviceStore(db, SQLDeviceStoreConfig{})
+		},
+	}
+
+	for name, newStore := range stores {
+		Convey("A "+name+" device store", t, func() {
+			store := newStore()
+
+			Convey("should look up the devices of a user", func() {
+				So(store.Register(Device{Platform: "fcm", Token: "t-2", UserId: "user-1", RegisteredAt: registeredAt}), ShouldBeNil)
+				So(store.Register(Device{Platform: "apns", Token: "t-1", UserId: "user-1", RegisteredAt: registeredAt}), ShouldBeNil)
+				So(store.Register(Device{Platform: "apns", Token: "t-3", UserId: "user-2", RegisteredAt: registeredAt}), ShouldBeNil)
+
+				devices, err := store.DevicesForUser("user-1")
+				So(err, ShouldBeNil)
+				So(devices, ShouldResemble, []Device{
+					{Platform: "apns", Token: "t-1", UserId: "user-1", RegisteredAt: registeredAt},
+					{Platform: "fcm", Token: "t-2", UserId: "user-1", RegisteredAt: registeredAt},
+				})
+			})
+
+			Convey("should move re-registered devices to their new user", func() {
+				So(store.Register(Device{Platform: "apns", Token: "t-1", UserId: "user-1", RegisteredAt: registeredAt}), ShouldBeNil)
+				So(store.Register(Device{Platform: "apns", Token: "t-1", UserId: "user-2", RegisteredAt: registeredAt}), ShouldBeNil)
+
+				devices, _ := store.DevicesForUser("user-1")
+				So(devices, ShouldBeEmpty)
+				devices, _ = store.DevicesForUser("user-2")
+				So(len(devices), ShouldEqual, 1)
+			})
+
+			Convey("should forget unregistered devices", func() {
+				So(store.Register(Device{Platform: "apns", Token: "t-1", UserId: "user-1"}), ShouldBeNil)
+				So(store.Unregister("apns", "t-1"), ShouldBeNil)
+				So(store.Unregister("apns", "unknown"), ShouldBeNil)
+
+				devices, _ := store.DevicesForUser("user-1")
+				So(devices, ShouldBeEmpty)
+			})
+
+			Convey("should reject incomplete devices", func() {
+				So(Classify(store.Register(Device{Platform: "apns"})), ShouldEqual, Validation)
+				So(Classify(store.Register(Device{Token: "t-1"})), ShouldEqual, Validation)
+			})
+		})
+	}
+
+	Convey("A SQL device store", t, func() {
+		fakeDevices.statements = nil
+		db, _ := sql.Open("fakedevices", "")
+		store := NewSQLDeviceStore(db, SQLDeviceStoreConfig{Table: "devices", Placeholder: PostgresPlaceholder})
+
+		Convey("should use the configured table and placeholders", func() {
+			store.Register(Device{Platform: "apns", Token: "t-1", UserId: "user-1"})
+			store.DevicesForUser("user-1")
+
+			So(fakeDevices.statements, ShouldResemble, []string{
+				"DELETE FROM devices WHERE platform = $1 AND token = $2",
+				"INSERT INTO devices (platform, token, user_id, registered_at) VALUES ($1, $2, $3, $4)",
+				"SELECT platform, token, user_id, registered_at FROM devices WHERE user_id = $1 ORDER BY platform, token",
+			})
+		})
+
+		Convey("should have a schema for the configured table, one statement at a time", func() {
+			schema := DeviceTableSchema("devices")
+			So(len(schema), ShouldEqual, 2)
+			So(schema[0], ShouldStartWith, "CREATE TABLE devices (")
+			So(schema[0], ShouldNotContainSubstring, ";")
+			So(schema[1], ShouldEqual, "CREATE INDEX devices_user_id ON devices (user_id)")
+
+			So(DeviceTableSchema("")[1], ShouldEqual, "CREATE INDEX beams_devices_user_id ON beams_devices (user_id)")
+		})
+	})
+}
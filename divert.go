@@ -0,0 +1,34 @@
+package pushnotifications
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Diverts every publish to the test users: the notification is published to them instead
+// of its real audience, e.g. in a staging environment hooked to production data.
+// The real audience is passed to `onDiverted` or, if it is nil, logged to the `WithLogger` logger.
+// The test users are validated like the users of any publish, failing publishes if they are not valid.
+func WithTestUsers(testUserIds []string, onDiverted func(audience Target)) Option {
+	return func(pn *pushNotifications) {
+		WithPublishStage(ValidateStage, PublishStageFunc(func(job *PublishJob) error {
+			audience := Users(job.Targets...)
+			if job.Operation == publishToInterestsOperation {
+				audience = Interests(job.Targets...)
+			}
+
+			switch {
+			case onDiverted != nil:
+				onDiverted(audience)
+			case pn.logger != nil:
+				pn.logger.Printf("Diverting the publish to %s %s to the test users",
+					targetsKey(job.Operation), strings.Join(job.Targets, ", "))
+			}
+
+			job.Operation = publishToUsersOperation
+			job.Targets = testUserIds
+			return errors.Wrap(validateUsers(testUserIds, pn.limits.MaxUsers, pn.userIdLengthInRunes), "Invalid test users")
+		}))(pn)
+	}
+}
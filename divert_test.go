@@ -0,0 +1,67 @@
+package pushnotifications
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTestUsers(t *testing.T) {
+	Convey("A Push Notifications Instance diverting publishes to test users", t, func() {
+		var path string
+		var body map[string]interface{}
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+			bodyBytes, _ := io.ReadAll(r.Body)
+			body = map[string]interface{}{}
+			json.Unmarshal(bodyBytes, &body)
+			w.Write([]byte(`{"publishId":"pub-123"}`))
+		}))
+		defer testServer.Close()
+
+		request := map[string]interface{}{"fcm": map[string]interface{}{}}
+
+		Convey("should publish to the test users instead of the interests", func() {
+			diverted := []Target{}
+			pn, _ := New(testInstanceId, testSecretKey,
+				WithCustomBaseURL(testServer.URL),
+				WithTestUsers([]string{"tester-1"}, func(audience Target) { diverted = append(diverted, audience) }))
+
+			publishId, err := pn.PublishToInterests([]string{"everyone"}, request)
+			So(err, ShouldBeNil)
+			So(publishId, ShouldEqual, "pub-123")
+			So(path, ShouldEndWith, "/publishes/users")
+			So(body["users"], ShouldResemble, []interface{}{"tester-1"})
+			So(body, ShouldNotContainKey, "interests")
+			So(diverted, ShouldResemble, []Target{Interests("everyone")})
+		})
+
+		Convey("should log the real audience without a callback", func() {
+			logger := &recordingLogger{}
+			pn, _ := New(testInstanceId, testSecretKey,
+				WithCustomBaseURL(testServer.URL),
+				WithLogger(logger),
+				WithTestUsers([]string{"tester-1"}, nil))
+
+			_, err := pn.PublishToUsers([]string{"user-1", "user-2"}, request)
+			So(err, ShouldBeNil)
+			So(body["users"], ShouldResemble, []interface{}{"tester-1"})
+			So(logger.messages, ShouldResemble, []string{"Diverting the publish to users user-1, user-2 to the test users"})
+		})
+
+		Convey("should fail publishes when the test users are not valid", func() {
+			pn, _ := New(testInstanceId, testSecretKey,
+				WithCustomBaseURL(testServer.URL),
+				WithTestUsers([]string{"tester-1", ""}, func(Target) {}))
+
+			_, err := pn.PublishToInterests([]string{"everyone"}, request)
+			So(Classify(err), ShouldEqual, Validation)
+			So(err.Error(), ShouldEqual, "Invalid test users: Empty user ids are not valid")
+			So(path, ShouldBeEmpty)
+		})
+	})
+}
@@ -0,0 +1,124 @@
+package pushnotifications
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type cachedAddrs struct {
+	addrs   []string
+	expires time.Time
+}
+
+// Resolves hostnames for the client's transport, using pre-resolved addresses
+// and a cache of earlier lookups before falling back to DNS
+type cachingResolver struct {
+	lookupHost func(ctx context.Context, host string) ([]string, error)
+	ttl        time.Duration
+	static     map[string][]string
+
+	mutex sync.Mutex
+	cache map[string]cachedAddrs
+}
+
+func newCachingResolver() *cachingResolver {
+	return &cachingResolver{
+		lookupHost: net.DefaultResolver.LookupHost,
+		static:     map[string][]string{},
+		cache:      map[string]cachedAddrs{},
+	}
+}
+
+func (r *cachingResolver) lookup(ctx context.Context, host string) ([]string, error) {
+	if addrs, ok := r.static[host]; ok {
+		return addrs, nil
+	}
+	if net.ParseIP(host) != nil || r.ttl <= 0 {
+		return r.lookupHost(ctx, host)
+	}
+
+	r.mutex.Lock()
+	cached, ok := r.cache[host]
+	r.mutex.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.addrs, nil
+	}
+
+	addrs, err := r.lookupHost(ctx, host)
+	if err != nil {
+		// a stale answer is better than none when DNS is flaky
+		if ok {
+			return cached.addrs, nil
+		}
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	r.cache[host] = cachedAddrs{addrs: addrs, expires: time.Now().Add(r.ttl)}
+	r.mutex.Unlock()
+
+	return addrs, nil
+}
+
+func (r *cachingResolver) dialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+
+		addrs, err := r.lookup(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+
+		var lastErr error = &net.DNSError{Err: "no addresses found", Name: host}
+		for _, ip := range addrs {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+		return nil, lastErr
+	}
+}
+
+// Returns a transport with the same settings as `http.DefaultTransport` that resolves hostnames with `r`
+func (r *cachingResolver) transport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           r.dialContext(dialer),
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
+
+func (pn *pushNotifications) dnsResolver() *cachingResolver {
+	if pn.resolver == nil {
+		pn.resolver = newCachingResolver()
+	}
+	return pn.resolver
+}
+
+func (pn *pushNotifications) installResolver() error {
+	if pn.resolver == nil {
+		return nil
+	}
+	if pn.httpClient.Transport != nil {
+		return newValidationError("DNS options cannot be combined with a custom transport")
+	}
+
+	pn.httpClient.Transport = pn.resolver.transport()
+	return nil
+}
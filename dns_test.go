@@ -0,0 +1,90 @@
+package pushnotifications
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDNS(t *testing.T) {
+	Convey("A Push Notifications Instance with DNS options", t, func() {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"publishId":"pub-123"}`))
+		}))
+		defer testServer.Close()
+		serverURL, _ := url.Parse(testServer.URL)
+		_, port, _ := net.SplitHostPort(serverURL.Host)
+
+		Convey("should connect to pre-resolved addresses", func() {
+			pn, err := New(testInstanceId, testSecretKey,
+				WithCustomBaseURL("http://beams.invalid:"+port),
+				WithResolvedAddresses("beams.invalid", "127.0.0.1"),
+			)
+			So(err, ShouldBeNil)
+
+			pubId, err := pn.PublishToInterests([]string{"hello"}, map[string]interface{}{})
+			So(err, ShouldBeNil)
+			So(pubId, ShouldEqual, "pub-123")
+		})
+
+		Convey("should not be combined with a custom transport", func() {
+			pn, err := New(testInstanceId, testSecretKey,
+				WithTransport(http.DefaultTransport),
+				WithDNSCache(time.Minute),
+			)
+			So(pn, ShouldBeNil)
+			So(err.Error(), ShouldContainSubstring, "DNS options cannot be combined with a custom transport")
+		})
+	})
+
+	Convey("A caching resolver", t, func() {
+		lookups := 0
+		var lookupErr error
+		resolver := newCachingResolver()
+		resolver.ttl = time.Minute
+		resolver.lookupHost = func(ctx context.Context, host string) ([]string, error) {
+			lookups++
+			if lookupErr != nil {
+				return nil, lookupErr
+			}
+			return []string{"127.0.0.1"}, nil
+		}
+
+		Convey("should only look up a host once within the ttl", func() {
+			resolver.lookup(context.Background(), "beams.invalid")
+			addrs, err := resolver.lookup(context.Background(), "beams.invalid")
+			So(err, ShouldBeNil)
+			So(addrs, ShouldResemble, []string{"127.0.0.1"})
+			So(lookups, ShouldEqual, 1)
+		})
+
+		Convey("should look up a host again after the ttl expired", func() {
+			resolver.lookup(context.Background(), "beams.invalid")
+			resolver.cache["beams.invalid"] = cachedAddrs{addrs: []string{"10.0.0.1"}}
+			addrs, _ := resolver.lookup(context.Background(), "beams.invalid")
+			So(addrs, ShouldResemble, []string{"127.0.0.1"})
+			So(lookups, ShouldEqual, 2)
+		})
+
+		Convey("should fall back to stale addresses when a lookup fails", func() {
+			resolver.cache["beams.invalid"] = cachedAddrs{addrs: []string{"10.0.0.1"}}
+			lookupErr = &net.DNSError{Err: "timeout", Name: "beams.invalid"}
+			addrs, err := resolver.lookup(context.Background(), "beams.invalid")
+			So(err, ShouldBeNil)
+			So(addrs, ShouldResemble, []string{"10.0.0.1"})
+		})
+
+		Convey("should return lookup errors when nothing is cached", func() {
+			lookupErr = &net.DNSError{Err: "timeout", Name: "beams.invalid"}
+			_, err := resolver.lookup(context.Background(), "beams.invalid")
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,164 @@
+package pushnotifications
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// How serious a `Finding` of `ValidateConfig` is
+type Severity int
+
+const (
+	// The check passed
+	SeverityOK Severity = iota
+	// Something that works but should be looked at before going live
+	SeverityWarning
+	// Something that will make calls fail
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "WARN"
+	case SeverityError:
+		return "ERROR"
+	default:
+		return "OK"
+	}
+}
+
+// The outcome of one check made by `ValidateConfig`
+type Finding struct {
+	// Name of the check, e.g. "credentials"
+	Check    string
+	Severity Severity
+	// What was found and, for problems, what to do about it
+	Message string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%-5s %s: %s", f.Severity, f.Check, f.Message)
+}
+
+const (
+	maxClockSkew      = 30 * time.Second
+	certificateMargin = 30 * 24 * time.Hour
+)
+
+var instanceIdRegex = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// Checks the credentials and connection to the Beams service that `New` would be given, reporting
+// the format of the credentials, whether the service can be reached and accepts them, the TLS setup
+// and the skew of the local clock, which must be right for the tokens of `GenerateToken` to be accepted.
+// The service is reached with an empty publish, which it rejects without notifying anyone.
+func ValidateConfig(instanceId, secretKey string, options ...Option) []Finding {
+	findings := checkCredentials(instanceId, secretKey)
+	for _, finding := range findings {
+		if finding.Severity == SeverityError {
+			return findings
+		}
+	}
+
+	pn, err := newClient(instanceId, secretKey, options)
+	if err != nil {
+		return append(findings, Finding{"options", SeverityError, err.Error()})
+	}
+	return append(findings, pn.checkService()...)
+}
+
+func checkCredentials(instanceId, secretKey string) []Finding {
+	findings := []Finding{}
+	switch {
+	case instanceId == "":
+		findings = append(findings, Finding{"instance id", SeverityError, "The instance id is empty"})
+	case strings.TrimSpace(instanceId) != instanceId:
+		findings = append(findings, Finding{"instance id", SeverityError, "The instance id has surrounding whitespace, check how it is loaded"})
+	case !instanceIdRegex.MatchString(instanceId):
+		findings = append(findings, Finding{"instance id", SeverityWarning,
+			"The instance id is not a lowercase UUID, copy it from the dashboard"})
+	default:
+		findings = append(findings, Finding{"instance id", SeverityOK, "Well formed"})
+	}
+
+	switch {
+	case secretKey == "":
+		findings = append(findings, Finding{"secret key", SeverityError, "The secret key is empty"})
+	case strings.TrimSpace(secretKey) != secretKey:
+		findings = append(findings, Finding{"secret key", SeverityError, "The secret key has surrounding whitespace, check how it is loaded"})
+	default:
+		findings = append(findings, Finding{"secret key", SeverityOK, "Set"})
+	}
+	return findings
+}
+
+func (pn *pushNotifications) checkService() []Finding {
+	endpoint, err := url.Parse(pn.baseEndpoint)
+	if err != nil {
+		return []Finding{{"endpoint", SeverityError, fmt.Sprintf("Invalid base URL %s: %s", pn.baseEndpoint, err)}}
+	}
+
+	httpReq, err := http.NewRequest(http.MethodPost, pn.interestsPublishURL(), bytes.NewReader([]byte(`{"interests":[]}`)))
+	if err != nil {
+		return []Finding{{"endpoint", SeverityError, err.Error()}}
+	}
+	pn.setHeaders(httpReq)
+	sent := time.Now()
+	httpResp, err := pn.httpClient.Do(httpReq)
+	if err != nil {
+		return []Finding{{"endpoint", SeverityError,
+			fmt.Sprintf("%s can't be reached, check DNS, proxies and firewalls: %s", endpoint.Host, err)}}
+	}
+	httpResp.Body.Close()
+	received := time.Now()
+
+	findings := []Finding{{"endpoint", SeverityOK, fmt.Sprintf("%s answered in %s", endpoint.Host, received.Sub(sent).Round(time.Millisecond))}}
+	findings = append(findings, checkTLS(endpoint, httpResp.TLS, received))
+
+	switch Classify(&APIError{StatusCode: httpResp.StatusCode}) {
+	case Unauthorized:
+		findings = append(findings, Finding{"authentication", SeverityError,
+			fmt.Sprintf("The service refused the secret key (%s), check it belongs to the instance", httpResp.Status)})
+	case ServerError:
+		findings = append(findings, Finding{"authentication", SeverityWarning,
+			fmt.Sprintf("The service failed (%s), so the secret key could not be checked", httpResp.Status)})
+	default:
+		findings = append(findings, Finding{"authentication", SeverityOK, "The service accepted the secret key"})
+	}
+
+	if date, err := http.ParseTime(httpResp.Header.Get("Date")); err == nil {
+		// the service's clock was read somewhere between sending and receiving, to the second
+		skew := date.Sub(sent.Add(received.Sub(sent) / 2))
+		if skew < 0 {
+			skew = -skew
+		}
+		if skew > maxClockSkew {
+			findings = append(findings, Finding{"clock", SeverityWarning,
+				fmt.Sprintf("The local clock is %s away from the service's, sync it with NTP or tokens may be rejected", skew.Round(time.Second))})
+		} else {
+			findings = append(findings, Finding{"clock", SeverityOK, "In sync with the service"})
+		}
+	}
+	return findings
+}
+
+func checkTLS(endpoint *url.URL, state *tls.ConnectionState, now time.Time) Finding {
+	if endpoint.Scheme != "https" || state == nil {
+		return Finding{"tls", SeverityWarning, fmt.Sprintf("%s is not reached over TLS, requests can be read in transit", endpoint.Host)}
+	}
+	if state.Version < tls.VersionTLS12 {
+		return Finding{"tls", SeverityWarning, "The connection uses a TLS version older than 1.2"}
+	}
+	if len(state.PeerCertificates) > 0 {
+		if expiry := state.PeerCertificates[0].NotAfter; expiry.Sub(now) < certificateMargin {
+			return Finding{"tls", SeverityWarning, fmt.Sprintf("The certificate of %s expires on %s", endpoint.Host, expiry.Format("2006-01-02"))}
+		}
+	}
+	return Finding{"tls", SeverityOK, "Verified connection"}
+}
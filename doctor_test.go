@@ -0,0 +1,78 @@
+package pushnotifications
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestValidateConfig(t *testing.T) {
+	Convey("Validating a config", t, func() {
+		instanceId := "a11aec92-146a-4708-9a62-8c61f46a82ad"
+		status := http.StatusUnprocessableEntity
+		date := ""
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if date != "" {
+				w.Header().Set("Date", date)
+			}
+			w.WriteHeader(status)
+			w.Write([]byte(`{"error":"Unprocessable Entity","description":"No interests"}`))
+		})
+		findingsOf := func(findings []Finding) map[string]Finding {
+			byCheck := map[string]Finding{}
+			for _, finding := range findings {
+				byCheck[finding.Check] = finding
+			}
+			return byCheck
+		}
+
+		Convey("should pass a working config", func() {
+			testServer := httptest.NewTLSServer(handler)
+			defer testServer.Close()
+
+			findings := findingsOf(ValidateConfig(instanceId, testSecretKey,
+				WithCustomBaseURL(testServer.URL), WithTransport(testServer.Client().Transport)))
+			for _, check := range []string{"instance id", "secret key", "endpoint", "tls", "authentication", "clock"} {
+				So(findings[check].Severity, ShouldEqual, SeverityOK)
+			}
+		})
+
+		Convey("with a plain HTTP endpoint", func() {
+			testServer := httptest.NewServer(handler)
+			defer testServer.Close()
+
+			Convey("should warn about the lack of TLS", func() {
+				findings := findingsOf(ValidateConfig(instanceId, testSecretKey, WithCustomBaseURL(testServer.URL)))
+				So(findings["tls"].Severity, ShouldEqual, SeverityWarning)
+			})
+
+			Convey("should report a refused secret key", func() {
+				status = http.StatusUnauthorized
+				findings := findingsOf(ValidateConfig(instanceId, testSecretKey, WithCustomBaseURL(testServer.URL)))
+				So(findings["authentication"].Severity, ShouldEqual, SeverityError)
+			})
+
+			Convey("should report a skewed clock", func() {
+				date = time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)
+				findings := findingsOf(ValidateConfig(instanceId, testSecretKey, WithCustomBaseURL(testServer.URL)))
+				So(findings["clock"].Severity, ShouldEqual, SeverityWarning)
+				So(findings["clock"].Message, ShouldContainSubstring, "1h0m")
+			})
+		})
+
+		Convey("should report malformed credentials without calling the service", func() {
+			findings := ValidateConfig(" "+instanceId, "", WithCustomBaseURL("http://127.0.0.1:1"))
+			So(len(findings), ShouldEqual, 2)
+			So(findings[0].Severity, ShouldEqual, SeverityError)
+			So(findings[1].String(), ShouldEqual, "ERROR secret key: The secret key is empty")
+		})
+
+		Convey("should report an unreachable service", func() {
+			findings := findingsOf(ValidateConfig(instanceId, testSecretKey, WithCustomBaseURL("http://127.0.0.1:1")))
+			So(findings["endpoint"].Severity, ShouldEqual, SeverityError)
+		})
+	})
+}
@@ -0,0 +1,107 @@
+// Package dogstatsd sends the client's metrics to a Datadog agent, or any
+// other server speaking the DogStatsD protocol.
+package dogstatsd
+
+import (
+	"bytes"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	pushnotifications "github.com/pusher/push-notifications-go"
+)
+
+const defaultNamespace = "beams."
+
+type Option func(*Client)
+
+// Prefixes every metric name with `namespace`, instead of "beams."
+func WithNamespace(namespace string) Option {
+	return func(c *Client) {
+		c.namespace = namespace
+	}
+}
+
+// Adds `tags` (such as "env:production") to every metric
+func WithTags(tags ...string) Option {
+	return func(c *Client) {
+		c.tags = append(c.tags, tags...)
+	}
+}
+
+// Sends metrics to a DogStatsD server over UDP.
+// Metrics are sent as they are recorded; failures to send them are ignored.
+type Client struct {
+	conn      net.Conn
+	namespace string
+	tags      []string
+}
+
+var _ pushnotifications.Metrics = (*Client)(nil)
+
+// Creates a `Client` sending metrics to the DogStatsD server at `addr`, such as "127.0.0.1:8125".
+// Returns a non-nil error if the address can't be resolved.
+func New(addr string, options ...Option) (*Client, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to connect to the DogStatsD server")
+	}
+
+	c := &Client{
+		conn:      conn,
+		namespace: defaultNamespace,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c, nil
+}
+
+func (c *Client) Counter(name string, value float64, tags map[string]string) {
+	c.send(name, value, "c", tags)
+}
+
+func (c *Client) Histogram(name string, value float64, tags map[string]string) {
+	c.send(name, value, "h", tags)
+}
+
+func (c *Client) Gauge(name string, value float64, tags map[string]string) {
+	c.send(name, value, "g", tags)
+}
+
+// Closes the connection to the DogStatsD server
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+func (c *Client) send(name string, value float64, metricType string, tags map[string]string) {
+	var line bytes.Buffer
+	line.WriteString(c.namespace)
+	line.WriteString(name)
+	line.WriteString(":")
+	line.WriteString(strconv.FormatFloat(value, 'f', -1, 64))
+	line.WriteString("|")
+	line.WriteString(metricType)
+
+	allTags := append([]string{}, c.tags...)
+	for _, key := range sortedKeys(tags) {
+		allTags = append(allTags, key+":"+tags[key])
+	}
+	if len(allTags) > 0 {
+		line.WriteString("|#")
+		line.WriteString(strings.Join(allTags, ","))
+	}
+
+	c.conn.Write(line.Bytes())
+}
+
+func sortedKeys(tags map[string]string) []string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,75 @@
+package dogstatsd
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	pushnotifications "github.com/pusher/push-notifications-go"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDogStatsD(t *testing.T) {
+	Convey("A DogStatsD client", t, func() {
+		server, err := net.ListenPacket("udp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		defer server.Close()
+
+		receive := func() string {
+			buffer := make([]byte, 1024)
+			server.SetReadDeadline(time.Now().Add(time.Second))
+			n, _, err := server.ReadFrom(buffer)
+			So(err, ShouldBeNil)
+			return string(buffer[:n])
+		}
+
+		client, err := New(server.LocalAddr().String(), WithTags("env:test"))
+		So(err, ShouldBeNil)
+		defer client.Close()
+
+		Convey("should send gauges", func() {
+			client.Gauge("quota.remaining", 42, nil)
+			So(receive(), ShouldEqual, "beams.quota.remaining:42|g|#env:test")
+		})
+
+		Convey("should send histograms with sorted tags", func() {
+			client.Histogram("request.duration", 0.0015, map[string]string{
+				"operation": "publish_to_users",
+				"instance":  "i-123",
+			})
+			So(receive(), ShouldEqual,
+				"beams.request.duration:0.0015|h|#env:test,instance:i-123,operation:publish_to_users")
+		})
+
+		Convey("should use a custom namespace", func() {
+			client, err := New(server.LocalAddr().String(), WithNamespace("push."))
+			So(err, ShouldBeNil)
+			defer client.Close()
+
+			client.Counter("request.count", 2, nil)
+			So(receive(), ShouldEqual, "push.request.count:2|c")
+		})
+
+		Convey("should receive the outcome of publishes", func() {
+			testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(`{"publishId":"pub-123"}`))
+			}))
+			defer testServer.Close()
+
+			pn, err := pushnotifications.New("i-123", "secret",
+				pushnotifications.WithCustomBaseURL(testServer.URL),
+				pushnotifications.WithMetrics(client),
+			)
+			So(err, ShouldBeNil)
+			_, err = pn.PublishToInterests([]string{"hello"}, map[string]interface{}{})
+			So(err, ShouldBeNil)
+
+			So(receive(), ShouldStartWith, "beams.request.duration:")
+			So(receive(), ShouldEqual,
+				"beams.request.count:1|c|#env:test,instance:i-123,operation:publish_to_interests,outcome:success")
+		})
+	})
+}
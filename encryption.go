@@ -0,0 +1,140 @@
+package pushnotifications
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+const dataEncryptionAlgorithm = "A256GCM"
+
+// A 256-bit AES key shared with the mobile and web apps, used to encrypt the custom `data`
+// of notifications. The `Id` is sent along with the encrypted data so that apps holding
+// several keys can pick the right one while keys are being rotated.
+type EncryptionKey struct {
+	Id     string
+	Secret []byte
+}
+
+func (k EncryptionKey) aead() (cipher.AEAD, error) {
+	if k.Id == "" {
+		return nil, newValidationError("Encryption key id cannot be an empty string")
+	}
+	if len(k.Secret) != 32 {
+		return nil, newValidationError("Encryption key `%s` must be 32 bytes long, got %d", k.Id, len(k.Secret))
+	}
+	block, err := aes.NewCipher(k.Secret)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create the encryption cipher")
+	}
+	return cipher.NewGCM(block)
+}
+
+// Encrypts `data` with AES-256-GCM, returning an envelope to use as the `data` of a notification:
+//
+//	{"kid": "<key id>", "alg": "A256GCM", "nonce": "<base64>", "ciphertext": "<base64>"}
+//
+// The ciphertext decrypts to the JSON encoding of `data`, authenticated with the key id.
+func EncryptData(key EncryptionKey, data interface{}) (map[string]interface{}, error) {
+	aead, err := key.aead()
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := json.Marshal(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to marshal the notification data")
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errors.Wrap(err, "Failed to generate a nonce")
+	}
+	ciphertext := aead.Seal(nil, nonce, plaintext, []byte(key.Id))
+
+	return map[string]interface{}{
+		"kid":        key.Id,
+		"alg":        dataEncryptionAlgorithm,
+		"nonce":      base64.StdEncoding.EncodeToString(nonce),
+		"ciphertext": base64.StdEncoding.EncodeToString(ciphertext),
+	}, nil
+}
+
+// Returns a copy of `request` with the `data` of its `apns`, `fcm` and `web` sections
+// replaced by envelopes encrypted with `key`. `request` is left unchanged.
+func EncryptRequestData(key EncryptionKey, request map[string]interface{}) (map[string]interface{}, error) {
+	encrypted := copyRequest(request)
+	for _, platform := range []string{"apns", "fcm", "web"} {
+		section, ok := request[platform].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		data, ok := section["data"]
+		if !ok {
+			continue
+		}
+
+		envelope, err := EncryptData(key, data)
+		if err != nil {
+			return nil, err
+		}
+		sectionCopy := copyRequest(section)
+		sectionCopy["data"] = envelope
+		encrypted[platform] = sectionCopy
+	}
+	return encrypted, nil
+}
+
+// Decrypts an envelope made by `EncryptData` with whichever of `keys` has its key id,
+// unmarshaling the data into `v`. Returns a non-nil error if no key matches or the
+// envelope was tampered with.
+func DecryptData(keys []EncryptionKey, envelope map[string]interface{}, v interface{}) error {
+	kid, _ := envelope["kid"].(string)
+	alg, _ := envelope["alg"].(string)
+	if alg != dataEncryptionAlgorithm {
+		return newValidationError("Unsupported encryption algorithm `%s`", alg)
+	}
+
+	for _, key := range keys {
+		if key.Id != kid {
+			continue
+		}
+		aead, err := key.aead()
+		if err != nil {
+			return err
+		}
+
+		nonce, err := decodeEnvelopeField(envelope, "nonce")
+		if err != nil {
+			return err
+		}
+		ciphertext, err := decodeEnvelopeField(envelope, "ciphertext")
+		if err != nil {
+			return err
+		}
+		if len(nonce) != aead.NonceSize() {
+			return newValidationError("Invalid nonce length %d", len(nonce))
+		}
+
+		plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(kid))
+		if err != nil {
+			return errors.Wrap(err, "Failed to decrypt the notification data")
+		}
+		return errors.Wrap(json.Unmarshal(plaintext, v), "Failed to unmarshal the notification data")
+	}
+
+	return newValidationError("No encryption key with id `%s`", kid)
+}
+
+func decodeEnvelopeField(envelope map[string]interface{}, name string) ([]byte, error) {
+	encoded, _ := envelope[name].(string)
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, newValidationError("Invalid base64 in the `%s` of the encrypted data", name)
+	}
+	return decoded, nil
+}
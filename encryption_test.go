@@ -0,0 +1,70 @@
+package pushnotifications
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDataEncryption(t *testing.T) {
+	Convey("Notification data encryption", t, func() {
+		oldKey := EncryptionKey{Id: "2020-01", Secret: bytes.Repeat([]byte{1}, 32)}
+		newKey := EncryptionKey{Id: "2020-02", Secret: bytes.Repeat([]byte{2}, 32)}
+		data := map[string]interface{}{"account": "savings", "balance": "1024.00"}
+
+		Convey("should round-trip data with the key named in the envelope", func() {
+			envelope, err := EncryptData(newKey, data)
+			So(err, ShouldBeNil)
+			So(envelope["kid"], ShouldEqual, "2020-02")
+			So(envelope["alg"], ShouldEqual, "A256GCM")
+
+			decrypted := map[string]interface{}{}
+			err = DecryptData([]EncryptionKey{oldKey, newKey}, envelope, &decrypted)
+			So(err, ShouldBeNil)
+			So(decrypted, ShouldResemble, data)
+		})
+
+		Convey("should reject envelopes for unknown keys", func() {
+			envelope, err := EncryptData(newKey, data)
+			So(err, ShouldBeNil)
+
+			err = DecryptData([]EncryptionKey{oldKey}, envelope, &map[string]interface{}{})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "No encryption key with id `2020-02`")
+		})
+
+		Convey("should reject envelopes whose key id was changed", func() {
+			envelope, err := EncryptData(newKey, data)
+			So(err, ShouldBeNil)
+
+			envelope["kid"] = oldKey.Id
+			err = DecryptData([]EncryptionKey{EncryptionKey{Id: oldKey.Id, Secret: newKey.Secret}}, envelope, &map[string]interface{}{})
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("should reject keys of the wrong size", func() {
+			_, err := EncryptData(EncryptionKey{Id: "short", Secret: []byte("secret")}, data)
+			So(err, ShouldNotBeNil)
+			So(Classify(err), ShouldEqual, Validation)
+		})
+
+		Convey("should encrypt the data of every platform without mutating the request", func() {
+			request := map[string]interface{}{
+				"apns": map[string]interface{}{"aps": map[string]interface{}{"alert": "Hi"}, "data": data},
+				"fcm":  map[string]interface{}{"data": data},
+				"web":  map[string]interface{}{"notification": map[string]interface{}{"title": "Hi"}},
+			}
+
+			encrypted, err := EncryptRequestData(newKey, request)
+			So(err, ShouldBeNil)
+			So(request["fcm"].(map[string]interface{})["data"], ShouldResemble, data)
+
+			apns := encrypted["apns"].(map[string]interface{})
+			So(apns["aps"], ShouldResemble, map[string]interface{}{"alert": "Hi"})
+			So(apns["data"].(map[string]interface{})["kid"], ShouldEqual, "2020-02")
+			So(encrypted["fcm"].(map[string]interface{})["data"].(map[string]interface{})["kid"], ShouldEqual, "2020-02")
+			So(encrypted["web"], ShouldResemble, request["web"])
+		})
+	})
+}
@@ -0,0 +1,42 @@
+package pushnotifications
+
+import "os"
+
+// Name of the process environment variable that must name a production environment for it to be used
+const EnvironmentVariable = "BEAMS_ENVIRONMENT"
+
+// The Beams instance of an environment, such as a sandbox or production
+type Environment struct {
+	InstanceId string
+	SecretKey  string
+	// Production environments are only used on hosts where `BEAMS_ENVIRONMENT` names them,
+	// so that a dev machine can't publish to real users by mistake
+	Production bool
+}
+
+// Named environments, e.g. "sandbox", "staging" and "production"
+type Environments map[string]Environment
+
+// Returns a client for the named environment.
+// Returns a non-nil `error` if there is no such environment, or if it is a production
+// environment and the `BEAMS_ENVIRONMENT` variable doesn't name it.
+func (e Environments) UseEnvironment(name string, options ...Option) (PushNotifications, error) {
+	environment, ok := e[name]
+	if !ok {
+		return nil, newValidationError("Unknown environment `%s`", name)
+	}
+	if environment.Production && os.Getenv(EnvironmentVariable) != name {
+		return nil, newValidationError(
+			"The production environment `%s` can only be used where %s=%s", name, EnvironmentVariable, name)
+	}
+	return New(environment.InstanceId, environment.SecretKey, options...)
+}
+
+// Returns a client for the environment named by the `BEAMS_ENVIRONMENT` variable
+func (e Environments) FromEnv(options ...Option) (PushNotifications, error) {
+	name := os.Getenv(EnvironmentVariable)
+	if name == "" {
+		return nil, newValidationError("%s is not set", EnvironmentVariable)
+	}
+	return e.UseEnvironment(name, options...)
+}
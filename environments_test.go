@@ -0,0 +1,50 @@
+package pushnotifications
+
+import (
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestEnvironments(t *testing.T) {
+	Convey("Environments", t, func() {
+		environments := Environments{
+			"sandbox":    {InstanceId: "sandbox-instance", SecretKey: testSecretKey},
+			"production": {InstanceId: testInstanceId, SecretKey: testSecretKey, Production: true},
+		}
+		defer os.Unsetenv(EnvironmentVariable)
+
+		Convey("should create a client for a sandbox environment", func() {
+			pn, err := environments.UseEnvironment("sandbox")
+			So(err, ShouldBeNil)
+			So(pn.(*pushNotifications).InstanceId, ShouldEqual, "sandbox-instance")
+		})
+
+		Convey("should not create a client for an unknown environment", func() {
+			_, err := environments.UseEnvironment("staging")
+			So(err.Error(), ShouldContainSubstring, "Unknown environment `staging`")
+		})
+
+		Convey("should only create a client for production where the environment variable names it", func() {
+			_, err := environments.UseEnvironment("production")
+			So(err.Error(), ShouldContainSubstring, "can only be used where BEAMS_ENVIRONMENT=production")
+			So(Classify(err), ShouldEqual, Validation)
+
+			os.Setenv(EnvironmentVariable, "production")
+			pn, err := environments.UseEnvironment("production")
+			So(err, ShouldBeNil)
+			So(pn.(*pushNotifications).InstanceId, ShouldEqual, testInstanceId)
+		})
+
+		Convey("should create a client for the environment named by the environment variable", func() {
+			_, err := environments.FromEnv()
+			So(err.Error(), ShouldContainSubstring, "BEAMS_ENVIRONMENT is not set")
+
+			os.Setenv(EnvironmentVariable, "sandbox")
+			pn, err := environments.FromEnv()
+			So(err, ShouldBeNil)
+			So(pn.(*pushNotifications).InstanceId, ShouldEqual, "sandbox-instance")
+		})
+	})
+}
@@ -0,0 +1,172 @@
+package pushnotifications
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// A stable category of failure, used to write retry and alerting policies
+type ErrorClass int
+
+const (
+	// The error could not be attributed to any other class
+	Unknown ErrorClass = iota
+	// The request was rejected before or by the service because it was not valid
+	Validation
+	// The credentials were missing, wrong or not allowed to perform the operation
+	Unauthorized
+	// The service refused the request because the rate limit was exceeded
+	RateLimited
+	// The service failed to process a valid request
+	ServerError
+	// The request did not complete because of a connection problem or timeout
+	Network
+	// The request body was larger than the service accepts
+	PayloadTooLarge
+)
+
+func (c ErrorClass) String() string {
+	switch c {
+	case Validation:
+		return "Validation"
+	case Unauthorized:
+		return "Unauthorized"
+	case RateLimited:
+		return "RateLimited"
+	case ServerError:
+		return "ServerError"
+	case Network:
+		return "Network"
+	case PayloadTooLarge:
+		return "PayloadTooLarge"
+	default:
+		return "Unknown"
+	}
+}
+
+// An error response returned by the Beams service
+type APIError struct {
+	StatusCode  int
+	Code        string
+	Description string
+	// The response body as received, which may not come from the service, e.g. the HTML page of a proxy
+	Body []byte
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Description)
+}
+
+const maxBodySnippetLength = 200
+
+// Returns the error for a failed response with the status and body. Bodies that are not a
+// Beams error response, such as the HTML page or empty body of a proxy, are described by
+// the status and the start of the body.
+func NewAPIError(statusCode int, body []byte) *APIError {
+	apiError := &APIError{StatusCode: statusCode, Body: body}
+	response := &errorResponse{}
+	if err := json.Unmarshal(body, response); err == nil && response.Error != "" {
+		apiError.Code = response.Error
+		apiError.Description = response.Description
+		return apiError
+	}
+
+	apiError.Code = http.StatusText(statusCode)
+	if apiError.Code == "" {
+		apiError.Code = fmt.Sprintf("Status %d", statusCode)
+	}
+	apiError.Description = bodySnippet(body)
+	return apiError
+}
+
+// Returns the start of the body on one line, for error messages
+func bodySnippet(body []byte) string {
+	snippet := strings.Join(strings.Fields(string(body)), " ")
+	if snippet == "" {
+		return "The response body is empty"
+	}
+	if len(snippet) > maxBodySnippetLength {
+		cut := maxBodySnippetLength
+		for cut > 0 && !utf8.RuneStart(snippet[cut]) {
+			cut--
+		}
+		snippet = snippet[:cut] + "..."
+	}
+	return fmt.Sprintf("Unexpected response body %q", snippet)
+}
+
+type validationError struct {
+	message string
+}
+
+func (e *validationError) Error() string {
+	return e.message
+}
+
+func newValidationError(format string, args ...interface{}) error {
+	return errors.WithStack(&validationError{message: fmt.Sprintf(format, args...)})
+}
+
+// Returns the category of an error returned by the client.
+// Returns `Unknown` for nil errors and errors that don't come from this package.
+func Classify(err error) ErrorClass {
+	if err == nil {
+		return Unknown
+	}
+
+	switch cause := errors.Cause(err).(type) {
+	case *validationError:
+		return Validation
+	case *APIError:
+		return classifyStatusCode(cause.StatusCode)
+	case *PayloadTooLargeError:
+		return PayloadTooLarge
+	case net.Error:
+		return Network
+	default:
+		return Unknown
+	}
+}
+
+// Codes direct backends report for device tokens that will never work again
+var invalidDeviceTokenCodes = map[string]bool{
+	"BadDeviceToken":         true, // APNs
+	"DeviceTokenNotForTopic": true, // APNs
+	"Unregistered":           true, // APNs and Web Push
+	"UNREGISTERED":           true, // FCM
+}
+
+// Reports whether a direct `Backend` failed to send to a device because its token
+// is invalid or expired, in which case the device should be forgotten
+func IsInvalidDeviceToken(err error) bool {
+	apiError, ok := errors.Cause(err).(*APIError)
+	return ok && (apiError.StatusCode == http.StatusGone || invalidDeviceTokenCodes[apiError.Code])
+}
+
+// Reports whether the status is in the 2xx class, all of which mean the request succeeded
+func isSuccessStatus(statusCode int) bool {
+	return statusCode >= 200 && statusCode < 300
+}
+
+func classifyStatusCode(statusCode int) ErrorClass {
+	switch {
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return Unauthorized
+	case statusCode == http.StatusTooManyRequests:
+		return RateLimited
+	case statusCode == http.StatusRequestEntityTooLarge:
+		return PayloadTooLarge
+	case statusCode >= 500 && statusCode < 600:
+		return ServerError
+	case statusCode >= 400 && statusCode < 500:
+		return Validation
+	default:
+		return Unknown
+	}
+}
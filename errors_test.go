@@ -0,0 +1,119 @@
+package pushnotifications
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClassify(t *testing.T) {
+	Convey("Classifying errors", t, func() {
+		Convey("should return Unknown for nil and foreign errors", func() {
+			So(Classify(nil), ShouldEqual, Unknown)
+			So(Classify(errors.New("boom")), ShouldEqual, Unknown)
+		})
+
+		Convey("should classify client-side validation errors", func() {
+			_, err := New("", testSecretKey)
+			So(Classify(err), ShouldEqual, Validation)
+
+			pn, _ := New(testInstanceId, testSecretKey)
+			_, err = pn.PublishToInterests([]string{}, map[string]interface{}{})
+			So(Classify(err), ShouldEqual, Validation)
+
+			err = pn.DeleteUser("")
+			So(Classify(err), ShouldEqual, Validation)
+		})
+
+		Convey("should classify API errors by status code", func() {
+			statusCode := http.StatusOK
+			testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(statusCode)
+				w.Write([]byte(`{"error":"Some Error","description":"why"}`))
+			}))
+			defer testServer.Close()
+
+			pn, _ := New(testInstanceId, testSecretKey, WithCustomBaseURL(testServer.URL))
+
+			expectations := map[int]ErrorClass{
+				http.StatusBadRequest:            Validation,
+				http.StatusUnauthorized:          Unauthorized,
+				http.StatusForbidden:             Unauthorized,
+				http.StatusTooManyRequests:       RateLimited,
+				http.StatusRequestEntityTooLarge: PayloadTooLarge,
+				http.StatusInternalServerError:   ServerError,
+				http.StatusServiceUnavailable:    ServerError,
+			}
+			for code, class := range expectations {
+				statusCode = code
+				_, err := pn.PublishToUsers([]string{"user-1"}, map[string]interface{}{})
+				So(Classify(err), ShouldEqual, class)
+
+				err = pn.DeleteUser("user-1")
+				So(Classify(err), ShouldEqual, class)
+			}
+
+			statusCode = http.StatusTooManyRequests
+			_, err := pn.PublishToInterests([]string{"hello"}, map[string]interface{}{})
+			apiError, ok := errors.Cause(err).(*APIError)
+			So(ok, ShouldBeTrue)
+			So(apiError.StatusCode, ShouldEqual, http.StatusTooManyRequests)
+			So(apiError.Code, ShouldEqual, "Some Error")
+			So(apiError.Description, ShouldEqual, "why")
+		})
+
+		Convey("should classify timeouts as network errors", func() {
+			testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(200 * time.Millisecond)
+			}))
+			defer testServer.Close()
+
+			pn, _ := New(testInstanceId, testSecretKey,
+				WithCustomBaseURL(testServer.URL),
+				WithRequestTimeout(time.Nanosecond),
+			)
+			_, err := pn.PublishToInterests([]string{"hello"}, map[string]interface{}{})
+			So(Classify(err), ShouldEqual, Network)
+		})
+
+		Convey("should name every class", func() {
+			So(RateLimited.String(), ShouldEqual, "RateLimited")
+			So(ErrorClass(100).String(), ShouldEqual, "Unknown")
+		})
+	})
+}
+
+func TestAPIError(t *testing.T) {
+	Convey("API errors built from a response", t, func() {
+		Convey("should read Beams error responses", func() {
+			apiError := NewAPIError(http.StatusBadRequest, []byte(`{"error":"Unprocessable Entity","description":"Invalid interest"}`))
+			So(apiError.Error(), ShouldEqual, "Unprocessable Entity: Invalid interest")
+			So(string(apiError.Body), ShouldEqual, `{"error":"Unprocessable Entity","description":"Invalid interest"}`)
+		})
+
+		Convey("should describe HTML bodies with the status and their start", func() {
+			body := []byte("<html>\n  <head><title>502 Bad Gateway</title></head>\n  <body>" + strings.Repeat("nginx ", 100) + "</body>\n</html>")
+			apiError := NewAPIError(http.StatusBadGateway, body)
+			So(apiError.Code, ShouldEqual, "Bad Gateway")
+			So(apiError.Description, ShouldStartWith, `Unexpected response body "<html> <head><title>502 Bad Gateway</title></head> <body>nginx nginx`)
+			So(apiError.Description, ShouldEndWith, `..."`)
+			So(apiError.Body, ShouldResemble, body)
+			So(Classify(apiError), ShouldEqual, ServerError)
+		})
+
+		Convey("should describe empty bodies", func() {
+			apiError := NewAPIError(http.StatusServiceUnavailable, nil)
+			So(apiError.Error(), ShouldEqual, "Service Unavailable: The response body is empty")
+		})
+
+		Convey("should describe JSON bodies that are not Beams errors", func() {
+			apiError := NewAPIError(599, []byte(`{"message":"upstream timed out"}`))
+			So(apiError.Error(), ShouldEqual, `Status 599: Unexpected response body "{\"message\":\"upstream timed out\"}"`)
+		})
+	})
+}
@@ -0,0 +1,75 @@
+package pushnotifications
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Describes the outcome of a single publish, once every attempt has been made
+type PublishEvent struct {
+	InstanceId string
+	// "publish_to_interests" or "publish_to_users"
+	Operation string
+	// Empty unless the publish succeeded
+	PublishId string
+	// Number of interests or users the notification was published to
+	TargetCount int
+	// Interests the notification was published to, nil when it was published to users
+	Interests []string
+	// Time taken by every attempt, including backoff between them
+	Latency time.Duration
+	// Number of requests sent to the Beams service
+	Attempts int
+	// "success", or the `ErrorClass` of the failure
+	Outcome string
+	// Why the publish failed, nil on success
+	Err error
+	// Caller metadata set with `WithMetadata`
+	Metadata map[string]string
+	// Conditions that didn't stop the publish
+	Warnings []Warning
+}
+
+// Formats the event as a single logfmt line with a fixed set of keys, such as
+// `event=beams_publish instance_id=... operation=publish_to_users publish_id=pub-123 target_count=2
+// latency_ms=41.2 attempts=1 outcome=success error=""`
+func (e PublishEvent) String() string {
+	errorMessage := ""
+	if e.Err != nil {
+		errorMessage = e.Err.Error()
+	}
+
+	fields := []string{
+		"event=beams_publish",
+		"instance_id=" + e.InstanceId,
+		"operation=" + e.Operation,
+		"publish_id=" + e.PublishId,
+		"target_count=" + strconv.Itoa(e.TargetCount),
+		"latency_ms=" + strconv.FormatFloat(float64(e.Latency)/float64(time.Millisecond), 'f', 1, 64),
+		"attempts=" + strconv.Itoa(e.Attempts),
+		"outcome=" + e.Outcome,
+		"error=" + strconv.Quote(errorMessage),
+	}
+	return strings.Join(fields, " ")
+}
+
+// Returns a publish hook writing every event to `logger`, for use with `WithPublishHook`
+func LogPublishEvents(logger Logger) func(PublishEvent) {
+	return func(event PublishEvent) {
+		logger.Printf("%s", event)
+	}
+}
+
+func (pn *pushNotifications) emitPublishEvent(event PublishEvent) {
+	for _, hook := range pn.publishHooks {
+		hook(event)
+	}
+}
+
+func outcomeOf(err error) string {
+	if err != nil {
+		return Classify(err).String()
+	}
+	return "success"
+}
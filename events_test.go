@@ -0,0 +1,85 @@
+package pushnotifications
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPublishEvents(t *testing.T) {
+	Convey("A Push Notifications Instance with a publish hook", t, func() {
+		failures := 0
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if failures > 0 {
+				failures--
+				w.WriteHeader(http.StatusServiceUnavailable)
+				w.Write([]byte(`{"error":"Unavailable","description":"Try again"}`))
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"publishId":"pub-123"}`))
+		}))
+		defer testServer.Close()
+
+		events := []PublishEvent{}
+		pn, err := New(testInstanceId, testSecretKey,
+			WithCustomBaseURL(testServer.URL),
+			WithRetryPolicy(RetryPolicy{MaxAttempts: 2, Retryable: map[ErrorClass]bool{ServerError: true}}),
+			WithPublishHook(func(event PublishEvent) {
+				events = append(events, event)
+			}),
+		)
+		So(err, ShouldBeNil)
+
+		Convey("should describe successful publishes", func() {
+			failures = 1
+			_, err := pn.PublishToUsers([]string{"user-1", "user-2"}, map[string]interface{}{})
+			So(err, ShouldBeNil)
+
+			So(len(events), ShouldEqual, 1)
+			So(events[0].InstanceId, ShouldEqual, testInstanceId)
+			So(events[0].Operation, ShouldEqual, "publish_to_users")
+			So(events[0].PublishId, ShouldEqual, "pub-123")
+			So(events[0].TargetCount, ShouldEqual, 2)
+			So(events[0].Attempts, ShouldEqual, 2)
+			So(events[0].Outcome, ShouldEqual, "success")
+			So(events[0].Err, ShouldBeNil)
+		})
+
+		Convey("should describe failed publishes", func() {
+			failures = 2
+			_, err := pn.PublishToInterests([]string{"hello"}, map[string]interface{}{})
+			So(err, ShouldNotBeNil)
+
+			So(len(events), ShouldEqual, 1)
+			So(events[0].PublishId, ShouldEqual, "")
+			So(events[0].Outcome, ShouldEqual, "ServerError")
+			So(events[0].Err, ShouldEqual, err)
+		})
+	})
+
+	Convey("A publish event", t, func() {
+		event := PublishEvent{
+			InstanceId:  "i-123",
+			Operation:   "publish_to_interests",
+			TargetCount: 1,
+			Latency:     1500 * time.Microsecond,
+			Attempts:    3,
+			Outcome:     "Network",
+			Err:         errors.New("connection refused"),
+		}
+
+		Convey("should be logged as a logfmt line", func() {
+			logger := &recordingLogger{}
+			LogPublishEvents(logger)(event)
+			So(logger.messages, ShouldResemble, []string{
+				`event=beams_publish instance_id=i-123 operation=publish_to_interests publish_id= target_count=1 ` +
+					`latency_ms=1.5 attempts=3 outcome=Network error="connection refused"`,
+			})
+		})
+	})
+}
@@ -0,0 +1,116 @@
+package pushnotifications
+
+import (
+	"sort"
+	"sync"
+)
+
+// Key of the experiment set with `WithExperiment` in the publish metadata
+const ExperimentMetadataKey = "experiment"
+
+// Marks the publish as part of an experiment, whose results are counted by `Experiments`.
+// It is kept in the publish metadata under `ExperimentMetadataKey`.
+func WithExperiment(experimentId string) CallOption {
+	return func(callOpts *callOptions) {
+		callOpts.experimentId = experimentId
+	}
+}
+
+// How one variant of an experiment did
+type VariantResult struct {
+	Variant string
+	// Users the variant was published to
+	Users int
+	// Users who opened the notification
+	Opened int
+	// Opened / Users, 0 when the variant wasn't published to anyone
+	OpenRate float64
+}
+
+// Counts the users each variant of an experiment was published to and how many of them opened
+// the notification, for A/B tests run with `PublishVariants` and `WithExperiment` without a
+// separate analytics pipeline. Add it with `WithPublishRecorder`, and pass `HandleWebhook` to
+// `WebhookHandler` to count the opens. Publishes without a variant count as the "" variant.
+type Experiments struct {
+	mutex     sync.Mutex
+	variants  map[string]map[string]*VariantResult
+	published map[string]experimentVariant
+	opened    map[string]bool
+}
+
+type experimentVariant struct {
+	experimentId string
+	variant      string
+}
+
+func NewExperiments() *Experiments {
+	return &Experiments{
+		variants:  map[string]map[string]*VariantResult{},
+		published: map[string]experimentVariant{},
+		opened:    map[string]bool{},
+	}
+}
+
+func (e *Experiments) Record(job *PublishJob) {
+	experimentId, ok := job.Metadata[ExperimentMetadataKey]
+	if !ok || job.Err != nil || job.Operation != publishToUsersOperation {
+		return
+	}
+	variant := job.Metadata[VariantMetadataKey]
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.result(experimentId, variant).Users += len(job.Targets)
+	e.published[job.PublishId] = experimentVariant{experimentId, variant}
+}
+
+// Counts the user of an open event as having opened the notification of their variant.
+// Other events, and opens of publishes that aren't part of an experiment, are ignored.
+func (e *Experiments) HandleWebhook(event WebhookEvent) {
+	if event.Type != UserNotificationOpenEvent {
+		return
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	published, ok := e.published[event.PublishId]
+	// users with several devices can open the notification more than once
+	key := event.PublishId + "/" + event.UserId
+	if !ok || e.opened[key] {
+		return
+	}
+	e.opened[key] = true
+	e.result(published.experimentId, published.variant).Opened++
+}
+
+// Returns the results of the variants of the experiment so far, sorted by variant
+func (e *Experiments) Results(experimentId string) []VariantResult {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	results := make([]VariantResult, 0, len(e.variants[experimentId]))
+	for _, result := range e.variants[experimentId] {
+		if result.Users > 0 {
+			result.OpenRate = float64(result.Opened) / float64(result.Users)
+		}
+		results = append(results, *result)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Variant < results[j].Variant })
+	return results
+}
+
+func (e *Experiments) result(experimentId, variant string) *VariantResult {
+	variants, ok := e.variants[experimentId]
+	if !ok {
+		variants = map[string]*VariantResult{}
+		e.variants[experimentId] = variants
+	}
+	result, ok := variants[variant]
+	if !ok {
+		result = &VariantResult{Variant: variant}
+		variants[variant] = result
+	}
+	return result
+}
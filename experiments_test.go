@@ -0,0 +1,76 @@
+package pushnotifications
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestExperiments(t *testing.T) {
+	Convey("Experiments", t, func() {
+		publishes := 0
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			publishes++
+			w.Write([]byte(`{"publishId":"pub-` + strconv.Itoa(publishes) + `"}`))
+		}))
+		defer testServer.Close()
+		experiments := NewExperiments()
+		pn, _ := New(testInstanceId, testSecretKey, WithCustomBaseURL(testServer.URL), WithPublishRecorder(experiments))
+
+		variants := map[string]map[string]interface{}{
+			"a": {"web": map[string]interface{}{"notification": map[string]interface{}{"title": "Sale"}}},
+			"b": {"web": map[string]interface{}{"notification": map[string]interface{}{"title": "20% off"}}},
+		}
+		selector := VariantSelectorFunc(func(notificationType, userId string) (string, error) {
+			if userId == "user-1" || userId == "user-2" {
+				return "a", nil
+			}
+			return "b", nil
+		})
+		_, err := PublishVariants(pn, selector, []string{"user-1", "user-2", "user-3", "user-4"}, variants, "a",
+			WithExperiment("sale-title"))
+		So(err, ShouldBeNil)
+
+		open := func(publishId, userId string) {
+			experiments.HandleWebhook(WebhookEvent{Type: UserNotificationOpenEvent, PublishId: publishId, UserId: userId})
+		}
+
+		Convey("should count the users of each variant", func() {
+			So(experiments.Results("sale-title"), ShouldResemble, []VariantResult{
+				{Variant: "a", Users: 2},
+				{Variant: "b", Users: 2},
+			})
+		})
+
+		Convey("should compute the open rate of each variant", func() {
+			open("pub-1", "user-1")
+			open("pub-2", "user-3")
+			open("pub-2", "user-4")
+			So(experiments.Results("sale-title"), ShouldResemble, []VariantResult{
+				{Variant: "a", Users: 2, Opened: 1, OpenRate: 0.5},
+				{Variant: "b", Users: 2, Opened: 2, OpenRate: 1},
+			})
+		})
+
+		Convey("should count a user opening on several devices once", func() {
+			open("pub-1", "user-1")
+			open("pub-1", "user-1")
+			So(experiments.Results("sale-title")[0].Opened, ShouldEqual, 1)
+		})
+
+		Convey("should ignore events of other publishes", func() {
+			_, err := pn.PublishToUsers([]string{"user-5"}, variants["a"])
+			So(err, ShouldBeNil)
+			open("pub-3", "user-5")
+			experiments.HandleWebhook(WebhookEvent{Type: UserNotificationAcknowledgementEvent, PublishId: "pub-1", UserId: "user-1"})
+			So(experiments.Results("sale-title")[0].Opened, ShouldEqual, 0)
+		})
+
+		Convey("should have no results for unknown experiments", func() {
+			So(experiments.Results("unknown"), ShouldBeEmpty)
+		})
+	})
+}
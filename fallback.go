@@ -0,0 +1,78 @@
+package pushnotifications
+
+import (
+	"github.com/pkg/errors"
+)
+
+// A notifier in a fallback chain
+type FallbackStep struct {
+	// Name reported in `Delivery.FailedBackends` when this step fails
+	Name string
+	// Notifier to deliver the notification with
+	Notifier Notifier
+	// Number of times to try this step before falling back to the next one. Defaults to 1.
+	Attempts int
+	// Converts the original target to one the notifier understands, e.g. user ids to the
+	// device tokens of a direct backend. When nil, the original target is used.
+	Target func(target Target) (Target, error)
+}
+
+type fallbackChain struct {
+	steps []FallbackStep
+}
+
+// Returns a `Notifier` trying each step in order until one delivers the notification,
+// e.g. Beams first and direct FCM and APNs backends when Beams keeps failing.
+// Validation errors are returned straight away, as they would fail on every step.
+func NewFallbackChain(steps ...FallbackStep) Notifier {
+	return &fallbackChain{steps: steps}
+}
+
+func (c *fallbackChain) Notify(target Target, request map[string]interface{}) (*Delivery, error) {
+	if len(c.steps) == 0 {
+		return nil, newValidationError("The fallback chain has no steps")
+	}
+
+	failed := map[string]error{}
+	var lastErr error
+	for _, step := range c.steps {
+		delivery, err := step.notify(target, request)
+		if err == nil {
+			delivery.FailedBackends = failed
+			return delivery, nil
+		}
+		if Classify(err) == Validation {
+			return nil, err
+		}
+
+		failed[step.Name] = err
+		lastErr = err
+	}
+
+	return nil, errors.Wrap(lastErr, "Failed to deliver the notification with every backend")
+}
+
+func (s FallbackStep) notify(target Target, request map[string]interface{}) (*Delivery, error) {
+	if s.Target != nil {
+		var err error
+		target, err = s.Target(target)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to resolve the target for %s", s.Name)
+		}
+	}
+
+	attempts := s.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		var delivery *Delivery
+		delivery, err = s.Notifier.Notify(target, request)
+		if err == nil || Classify(err) == Validation {
+			return delivery, err
+		}
+	}
+	return nil, err
+}
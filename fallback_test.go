@@ -0,0 +1,95 @@
+package pushnotifications
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type notifierFunc func(target Target, request map[string]interface{}) (*Delivery, error)
+
+func (f notifierFunc) Notify(target Target, request map[string]interface{}) (*Delivery, error) {
+	return f(target, request)
+}
+
+func TestFallbackChain(t *testing.T) {
+	Convey("A fallback chain", t, func() {
+		beamsCalls := 0
+		var beamsErr error = &APIError{StatusCode: 503, Code: "Unavailable"}
+		beams := notifierFunc(func(target Target, request map[string]interface{}) (*Delivery, error) {
+			beamsCalls++
+			if beamsErr != nil {
+				return nil, errors.Wrap(beamsErr, "Failed to publish notification")
+			}
+			return &Delivery{Backend: "beams", PublishId: "pub-123"}, nil
+		})
+		fcm := &fakeBackend{name: "fcm"}
+		apns := &fakeBackend{name: "apns"}
+
+		chain := NewFallbackChain(
+			FallbackStep{Name: "beams", Notifier: beams, Attempts: 2},
+			FallbackStep{
+				Name:     "fcm",
+				Notifier: BackendNotifier(fcm),
+				Target: func(target Target) (Target, error) {
+					return Devices("fcm", "token-of-"+target.Ids[0]), nil
+				},
+			},
+			FallbackStep{
+				Name:     "apns",
+				Notifier: BackendNotifier(apns),
+				Target: func(target Target) (Target, error) {
+					return Devices("apns", "apns-token-of-"+target.Ids[0]), nil
+				},
+			},
+		)
+
+		Convey("should deliver with the first step when it succeeds", func() {
+			beamsErr = nil
+			delivery, err := chain.Notify(Users("user-1"), map[string]interface{}{})
+			So(err, ShouldBeNil)
+			So(delivery.Backend, ShouldEqual, "beams")
+			So(delivery.FailedBackends, ShouldBeEmpty)
+			So(fcm.sends, ShouldBeEmpty)
+		})
+
+		Convey("should fall back after the step failed repeatedly", func() {
+			delivery, err := chain.Notify(Users("user-1"), map[string]interface{}{})
+			So(err, ShouldBeNil)
+			So(beamsCalls, ShouldEqual, 2)
+			So(delivery.Backend, ShouldEqual, "fcm")
+			So(delivery.FailedBackends["beams"], ShouldNotBeNil)
+			So(fcm.sends, ShouldResemble, [][]string{{"token-of-user-1"}})
+		})
+
+		Convey("should not fall back on validation errors", func() {
+			beamsErr = &APIError{StatusCode: 400, Code: "Bad Request"}
+			_, err := chain.Notify(Users("user-1"), map[string]interface{}{})
+			So(Classify(err), ShouldEqual, Validation)
+			So(beamsCalls, ShouldEqual, 1)
+			So(fcm.sends, ShouldBeEmpty)
+		})
+
+		Convey("should fall back when a backend sent to no device", func() {
+			fcm.failed = map[string]error{"token-of-user-1": &APIError{StatusCode: 404, Code: "UNREGISTERED"}}
+			delivery, err := chain.Notify(Users("user-1"), map[string]interface{}{})
+			So(err, ShouldBeNil)
+			So(delivery.Backend, ShouldEqual, "apns")
+			So(delivery.FailedBackends["fcm"].Error(), ShouldEqual,
+				"fcm sent the notification to none of 1 devices, e.g. token-of-user-1: UNREGISTERED: ")
+
+			notSent, ok := errors.Cause(delivery.FailedBackends["fcm"]).(*NotSentError)
+			So(ok, ShouldBeTrue)
+			So(notSent.Failed, ShouldContainKey, "token-of-user-1")
+		})
+
+		Convey("should fail when every step fails", func() {
+			fcm.err = errors.New("fcm is down")
+			apns.err = errors.New("apns is down")
+			_, err := chain.Notify(Users("user-1"), map[string]interface{}{})
+			So(err.Error(), ShouldContainSubstring, "Failed to deliver the notification with every backend")
+			So(err.Error(), ShouldContainSubstring, "apns is down")
+		})
+	})
+}
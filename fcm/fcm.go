@@ -0,0 +1,271 @@
+// Package fcm sends notifications straight to Firebase Cloud Messaging, using the
+// HTTP v1 API authenticated with a Google service account.
+package fcm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/pkg/errors"
+	pushnotifications "github.com/pusher/push-notifications-go"
+)
+
+const (
+	defaultBaseURL        = "https://fcm.googleapis.com"
+	defaultTokenURL       = "https://oauth2.googleapis.com/token"
+	defaultRequestTimeout = 30 * time.Second
+	// responses are read up to this size, so a misbehaving proxy can't exhaust memory
+	maxResponseSize = 1 << 20
+	messagingScope  = "https://www.googleapis.com/auth/firebase.messaging"
+	accessTokenTTL  = time.Hour
+	// refresh access tokens a little before they expire, to allow for clock skew
+	accessTokenExpiryMargin = time.Minute
+)
+
+// Credentials and settings for an FCM backend
+type Config struct {
+	// Contents of the service account JSON key file downloaded from the Firebase console
+	ServiceAccount []byte
+	// Firebase project to send through. Defaults to the project of the service account.
+	ProjectId string
+}
+
+type serviceAccount struct {
+	ProjectId   string `json:"project_id"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+type Option func(*backend)
+
+// Sends requests to `url` instead of the FCM endpoint
+func WithCustomBaseURL(url string) Option {
+	return func(b *backend) {
+		b.baseURL = url
+	}
+}
+
+func WithRequestTimeout(timeout time.Duration) Option {
+	return func(b *backend) {
+		b.httpClient.Timeout = timeout
+	}
+}
+
+type backend struct {
+	account    serviceAccount
+	projectId  string
+	baseURL    string
+	httpClient *http.Client
+	signingKey interface{}
+
+	tokenMutex        sync.Mutex
+	accessToken       string
+	accessTokenExpiry time.Time
+}
+
+// Creates a `pushnotifications.Backend` sending the `fcm` section of publish requests with FCM HTTP v1.
+// Returns a non-nil error if the service account can't be parsed.
+func New(config Config, options ...Option) (pushnotifications.Backend, error) {
+	account := serviceAccount{}
+	if err := json.Unmarshal(config.ServiceAccount, &account); err != nil {
+		return nil, errors.Wrap(err, "Failed to parse the service account JSON")
+	}
+	if account.ClientEmail == "" {
+		return nil, errors.New("The service account has no `client_email`")
+	}
+	if account.TokenURI == "" {
+		account.TokenURI = defaultTokenURL
+	}
+
+	projectId := config.ProjectId
+	if projectId == "" {
+		projectId = account.ProjectId
+	}
+	if projectId == "" {
+		return nil, errors.New("Project Id cannot be an empty string")
+	}
+
+	signingKey, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(account.PrivateKey))
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to parse the service account private key")
+	}
+
+	b := &backend{
+		account:    account,
+		projectId:  projectId,
+		baseURL:    defaultBaseURL,
+		httpClient: &http.Client{Timeout: defaultRequestTimeout},
+		signingKey: signingKey,
+	}
+
+	for _, option := range options {
+		option(b)
+	}
+
+	return b, nil
+}
+
+func (b *backend) Name() string {
+	return "fcm"
+}
+
+func (b *backend) Send(deviceTokens []string, request map[string]interface{}) (*pushnotifications.SendResult, error) {
+	if len(deviceTokens) == 0 {
+		return nil, errors.New("Must supply at least one device token")
+	}
+
+	section, ok := request["fcm"].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("The publish request has no `fcm` section")
+	}
+
+	accessToken, err := b.token()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &pushnotifications.SendResult{Failed: map[string]error{}}
+	for _, deviceToken := range deviceTokens {
+		messageBytes, err := json.Marshal(map[string]interface{}{
+			"message": newMessage(deviceToken, section),
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to marshal the FCM message")
+		}
+
+		if err := b.sendMessage(accessToken, messageBytes); err != nil {
+			result.Failed[deviceToken] = err
+		} else {
+			result.Sent = append(result.Sent, deviceToken)
+		}
+	}
+
+	return result, nil
+}
+
+type errorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+		Status  string `json:"status"`
+		Details []struct {
+			ErrorCode string `json:"errorCode"`
+		} `json:"details"`
+	} `json:"error"`
+}
+
+func (b *backend) sendMessage(accessToken string, messageBytes []byte) error {
+	URL := fmt.Sprintf("%s/v1/projects/%s/messages:send", b.baseURL, b.projectId)
+	httpReq, err := http.NewRequest(http.MethodPost, URL, bytes.NewReader(messageBytes))
+	if err != nil {
+		return errors.Wrap(err, "Failed to prepare the FCM request")
+	}
+
+	httpReq.Header.Set("Authorization", "Bearer "+accessToken)
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	httpResp, err := b.httpClient.Do(httpReq)
+	if err != nil {
+		return errors.Wrap(err, "Failed to send the FCM message due to a network error")
+	}
+
+	defer httpResp.Body.Close()
+	responseBytes, err := io.ReadAll(io.LimitReader(httpResp.Body, maxResponseSize))
+	if err != nil {
+		return errors.Wrap(err, "Failed to read the FCM response due to a network error")
+	}
+
+	if httpResp.StatusCode >= 200 && httpResp.StatusCode < 300 {
+		return nil
+	}
+
+	errResponse := &errorResponse{}
+	if err := json.Unmarshal(responseBytes, errResponse); err != nil || errResponse.Error.Status == "" {
+		return errors.Wrap(pushnotifications.NewAPIError(httpResp.StatusCode, responseBytes), "Failed to send the FCM message")
+	}
+
+	// the FCM specific error code is more precise than the generic status
+	code := errResponse.Error.Status
+	for _, detail := range errResponse.Error.Details {
+		if detail.ErrorCode != "" {
+			code = detail.ErrorCode
+		}
+	}
+
+	apiError := &pushnotifications.APIError{
+		StatusCode:  httpResp.StatusCode,
+		Code:        code,
+		Description: errResponse.Error.Message,
+		Body:        responseBytes,
+	}
+	return errors.Wrap(apiError, "Failed to send the FCM message")
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// Returns an OAuth access token for the service account, exchanging a newly signed assertion when it's due
+func (b *backend) token() (string, error) {
+	b.tokenMutex.Lock()
+	defer b.tokenMutex.Unlock()
+
+	if b.accessToken != "" && time.Now().Before(b.accessTokenExpiry) {
+		return b.accessToken, nil
+	}
+
+	now := time.Now()
+	assertion := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   b.account.ClientEmail,
+		"scope": messagingScope,
+		"aud":   b.account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(accessTokenTTL).Unix(),
+	})
+	assertionString, err := assertion.SignedString(b.signingKey)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to sign the service account assertion")
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertionString},
+	}
+	httpResp, err := b.httpClient.PostForm(b.account.TokenURI, form)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to get an FCM access token due to a network error")
+	}
+
+	defer httpResp.Body.Close()
+	responseBytes, err := io.ReadAll(io.LimitReader(httpResp.Body, maxResponseSize))
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to read the FCM access token due to a network error")
+	}
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		apiError := &pushnotifications.APIError{
+			StatusCode:  httpResp.StatusCode,
+			Code:        "AccessTokenRejected",
+			Description: strings.TrimSpace(string(responseBytes)),
+			Body:        responseBytes,
+		}
+		return "", errors.Wrap(apiError, "Failed to get an FCM access token")
+	}
+
+	tokenResp := &tokenResponse{}
+	if err := json.Unmarshal(responseBytes, tokenResp); err != nil {
+		return "", errors.Wrap(err, "Failed to read the FCM access token due to invalid JSON")
+	}
+
+	b.accessToken = tokenResp.AccessToken
+	b.accessTokenExpiry = now.Add(time.Duration(tokenResp.ExpiresIn)*time.Second - accessTokenExpiryMargin)
+	return b.accessToken, nil
+}
@@ -0,0 +1,128 @@
+package fcm
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/pkg/errors"
+	pushnotifications "github.com/pusher/push-notifications-go"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFCM(t *testing.T) {
+	Convey("An FCM backend", t, func() {
+		key, _ := rsa.GenerateKey(rand.Reader, 2048)
+		keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+		tokenRequests := 0
+		var assertion string
+		var messages []map[string]interface{}
+		var lastAuthorization string
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/token":
+				tokenRequests++
+				r.ParseForm()
+				assertion = r.PostForm.Get("assertion")
+				w.Write([]byte(`{"access_token":"access-123","expires_in":3600}`))
+			case "/v1/projects/my-project/messages:send":
+				lastAuthorization = r.Header.Get("Authorization")
+				body, _ := io.ReadAll(r.Body)
+				message := map[string]interface{}{}
+				json.Unmarshal(body, &message)
+				messages = append(messages, message["message"].(map[string]interface{}))
+				if message["message"].(map[string]interface{})["token"] == "stale-token" {
+					w.WriteHeader(http.StatusNotFound)
+					w.Write([]byte(`{"error":{"code":404,"message":"Requested entity was not found.","status":"NOT_FOUND","details":[{"errorCode":"UNREGISTERED"}]}}`))
+					return
+				}
+				w.Write([]byte(`{"name":"projects/my-project/messages/1"}`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		defer testServer.Close()
+
+		serviceAccount, _ := json.Marshal(map[string]string{
+			"project_id":   "my-project",
+			"client_email": "beams@my-project.iam.gserviceaccount.com",
+			"private_key":  string(keyPEM),
+			"token_uri":    testServer.URL + "/token",
+		})
+
+		Convey("should not be created with an invalid service account", func() {
+			b, err := New(Config{ServiceAccount: []byte(`{`)})
+			So(b, ShouldBeNil)
+			So(err.Error(), ShouldContainSubstring, "Failed to parse the service account JSON")
+		})
+
+		b, err := New(Config{ServiceAccount: serviceAccount}, WithCustomBaseURL(testServer.URL))
+		So(err, ShouldBeNil)
+		So(b.Name(), ShouldEqual, "fcm")
+
+		request := map[string]interface{}{
+			"fcm": map[string]interface{}{
+				"notification": map[string]interface{}{
+					"title": "Hello",
+					"body":  "Hello, world",
+					"icon":  "ic_hello",
+				},
+				"data":     map[string]interface{}{"orderId": "o-1", "count": 2},
+				"priority": "high",
+			},
+		}
+
+		Convey("should send a v1 message to every device", func() {
+			result, err := b.Send([]string{"token-1", "token-2"}, request)
+			So(err, ShouldBeNil)
+			So(result.Sent, ShouldResemble, []string{"token-1", "token-2"})
+			So(len(messages), ShouldEqual, 2)
+
+			So(messages[1]["token"], ShouldEqual, "token-2")
+			So(messages[0]["notification"], ShouldResemble, map[string]interface{}{"title": "Hello", "body": "Hello, world"})
+			So(messages[0]["data"], ShouldResemble, map[string]interface{}{"orderId": "o-1", "count": "2"})
+			So(messages[0]["android"], ShouldResemble, map[string]interface{}{
+				"priority":     "HIGH",
+				"notification": map[string]interface{}{"icon": "ic_hello"},
+			})
+		})
+
+		Convey("should authenticate with a cached access token for the service account", func() {
+			b.Send([]string{"token-1"}, request)
+			b.Send([]string{"token-1"}, request)
+			So(tokenRequests, ShouldEqual, 1)
+			So(lastAuthorization, ShouldEqual, "Bearer access-123")
+
+			parsed, err := jwt.Parse(assertion, func(token *jwt.Token) (interface{}, error) {
+				return &key.PublicKey, nil
+			})
+			So(err, ShouldBeNil)
+			So(parsed.Claims.(jwt.MapClaims)["iss"], ShouldEqual, "beams@my-project.iam.gserviceaccount.com")
+			So(parsed.Claims.(jwt.MapClaims)["scope"], ShouldEqual, messagingScope)
+		})
+
+		Convey("should report devices that were rejected", func() {
+			result, err := b.Send([]string{"token-1", "stale-token"}, request)
+			So(err, ShouldBeNil)
+			So(result.Sent, ShouldResemble, []string{"token-1"})
+
+			apiError, ok := errors.Cause(result.Failed["stale-token"]).(*pushnotifications.APIError)
+			So(ok, ShouldBeTrue)
+			So(apiError.StatusCode, ShouldEqual, http.StatusNotFound)
+			So(apiError.Code, ShouldEqual, "UNREGISTERED")
+		})
+
+		Convey("should fail if the request has no fcm section", func() {
+			_, err := b.Send([]string{"token-1"}, map[string]interface{}{})
+			So(err.Error(), ShouldContainSubstring, "no `fcm` section")
+		})
+	})
+}
@@ -0,0 +1,73 @@
+package fcm
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Builds an HTTP v1 message from the `fcm` section of a Beams publish request,
+// which uses the legacy FCM format
+func newMessage(deviceToken string, section map[string]interface{}) map[string]interface{} {
+	message := map[string]interface{}{
+		"token": deviceToken,
+	}
+	android := map[string]interface{}{}
+
+	if legacyNotification, ok := section["notification"].(map[string]interface{}); ok {
+		notification := map[string]interface{}{}
+		androidNotification := map[string]interface{}{}
+		for key, value := range legacyNotification {
+			switch key {
+			case "title", "body", "image":
+				notification[key] = value
+			default:
+				androidNotification[key] = value
+			}
+		}
+		if len(notification) > 0 {
+			message["notification"] = notification
+		}
+		if len(androidNotification) > 0 {
+			android["notification"] = androidNotification
+		}
+	}
+
+	if data, ok := section["data"].(map[string]interface{}); ok {
+		message["data"] = stringValues(data)
+	}
+	if priority, ok := section["priority"].(string); ok {
+		android["priority"] = strings.ToUpper(priority)
+	}
+	if ttl, ok := section["time_to_live"].(float64); ok {
+		android["ttl"] = fmt.Sprintf("%ds", int64(ttl))
+	}
+	if ttl, ok := section["time_to_live"].(int); ok {
+		android["ttl"] = fmt.Sprintf("%ds", ttl)
+	}
+	if collapseKey, ok := section["collapse_key"].(string); ok {
+		android["collapse_key"] = collapseKey
+	}
+
+	if len(android) > 0 {
+		message["android"] = android
+	}
+	return message
+}
+
+// FCM v1 only accepts string data values, so anything else is sent as JSON
+func stringValues(data map[string]interface{}) map[string]string {
+	values := make(map[string]string, len(data))
+	for key, value := range data {
+		if s, ok := value.(string); ok {
+			values[key] = s
+			continue
+		}
+		valueBytes, err := json.Marshal(value)
+		if err != nil {
+			continue
+		}
+		values[key] = string(valueBytes)
+	}
+	return values
+}
@@ -0,0 +1,48 @@
+package pushnotifications
+
+// A device a direct backend could not send to because its token is invalid or expired
+type InvalidDevice struct {
+	// Name of the backend, e.g. "apns"
+	Platform string
+	Token    string
+	// The error reported by the backend
+	Err error
+	// Why the device could not be removed from the `DeviceStore`, if it couldn't
+	UnregisterErr error
+}
+
+// Wraps `backend` so that devices whose token it reports as invalid or expired (see
+// `IsInvalidDeviceToken`) are unregistered from `store`, then passed to `onInvalid`.
+// Either of `store` and `onInvalid` may be nil.
+func WithDeviceFeedback(backend Backend, store DeviceStore, onInvalid func(InvalidDevice)) Backend {
+	return &feedbackBackend{Backend: backend, store: store, onInvalid: onInvalid}
+}
+
+type feedbackBackend struct {
+	Backend
+	store     DeviceStore
+	onInvalid func(InvalidDevice)
+}
+
+func (b *feedbackBackend) Send(deviceTokens []string, request map[string]interface{}) (*SendResult, error) {
+	result, err := b.Backend.Send(deviceTokens, request)
+	if err != nil || result == nil {
+		return result, err
+	}
+
+	for _, deviceToken := range deviceTokens {
+		sendErr, failed := result.Failed[deviceToken]
+		if !failed || !IsInvalidDeviceToken(sendErr) {
+			continue
+		}
+
+		invalid := InvalidDevice{Platform: b.Name(), Token: deviceToken, Err: sendErr}
+		if b.store != nil {
+			invalid.UnregisterErr = b.store.Unregister(invalid.Platform, deviceToken)
+		}
+		if b.onInvalid != nil {
+			b.onInvalid(invalid)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,75 @@
+package pushnotifications
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type failingBackend struct {
+	failures map[string]error
+}
+
+func (b *failingBackend) Name() string {
+	return "apns"
+}
+
+func (b *failingBackend) Send(deviceTokens []string, request map[string]interface{}) (*SendResult, error) {
+	result := &SendResult{Failed: map[string]error{}}
+	for _, deviceToken := range deviceTokens {
+		if err, ok := b.failures[deviceToken]; ok {
+			result.Failed[deviceToken] = err
+		} else {
+			result.Sent = append(result.Sent, deviceToken)
+		}
+	}
+	return result, nil
+}
+
+func TestDeviceFeedback(t *testing.T) {
+	Convey("Invalid device token errors", t, func() {
+		So(IsInvalidDeviceToken(errors.Wrap(&APIError{StatusCode: 400, Code: "BadDeviceToken"}, "Failed")), ShouldBeTrue)
+		So(IsInvalidDeviceToken(&APIError{StatusCode: http.StatusGone, Code: "Gone"}), ShouldBeTrue)
+		So(IsInvalidDeviceToken(&APIError{StatusCode: 404, Code: "UNREGISTERED"}), ShouldBeTrue)
+		So(IsInvalidDeviceToken(&APIError{StatusCode: 400, Code: "PayloadEmpty"}), ShouldBeFalse)
+		So(IsInvalidDeviceToken(&APIError{StatusCode: 503, Code: "ServiceUnavailable"}), ShouldBeFalse)
+		So(IsInvalidDeviceToken(errors.New("connection reset")), ShouldBeFalse)
+	})
+
+	Convey("A backend with device feedback", t, func() {
+		store := NewMemoryDeviceStore()
+		for _, token := range []string{"t-ok", "t-expired", "t-busy"} {
+			store.Register(Device{Platform: "apns", Token: token, UserId: "user-1"})
+		}
+
+		invalid := []InvalidDevice{}
+		expired := &APIError{StatusCode: http.StatusGone, Code: "Unregistered"}
+		backend := WithDeviceFeedback(&failingBackend{failures: map[string]error{
+			"t-expired": expired,
+			"t-busy":    &APIError{StatusCode: 429, Code: "TooManyRequests"},
+		}}, store, func(device InvalidDevice) {
+			invalid = append(invalid, device)
+		})
+
+		Convey("should remove invalid devices from the store and report them", func() {
+			result, err := backend.Send([]string{"t-ok", "t-expired", "t-busy"}, map[string]interface{}{})
+			So(err, ShouldBeNil)
+			So(result.Sent, ShouldResemble, []string{"t-ok"})
+			So(backend.Name(), ShouldEqual, "apns")
+
+			So(invalid, ShouldResemble, []InvalidDevice{{Platform: "apns", Token: "t-expired", Err: expired}})
+			devices, _ := store.DevicesForUser("user-1")
+			So(len(devices), ShouldEqual, 2)
+			So(devices[0].Token, ShouldEqual, "t-busy")
+			So(devices[1].Token, ShouldEqual, "t-ok")
+		})
+
+		Convey("should work without a store", func() {
+			backend := WithDeviceFeedback(&failingBackend{failures: map[string]error{"t-expired": expired}}, nil, nil)
+			_, err := backend.Send([]string{"t-expired"}, map[string]interface{}{})
+			So(err, ShouldBeNil)
+		})
+	})
+}
@@ -0,0 +1,86 @@
+package pushnotifications
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Returned, as the cause, when a target filter leaves no target to publish to
+var ErrTargetNotAllowed = errors.New("The targets are not allowed by the target filter")
+
+// Restricts the interests and users a client publishes to, e.g. to internal test users
+// in non-production builds. Interests may be `*` patterns, such as "debug-*".
+// Empty allow lists allow every target; deny lists win over allow lists.
+type TargetFilter struct {
+	AllowInterests []string
+	DenyInterests  []string
+	AllowUsers     []string
+	DenyUsers      []string
+}
+
+// Reports whether the filter lets publishes reach the interest
+func (f TargetFilter) AllowsInterest(interest string) bool {
+	return allowed(interest, f.AllowInterests, f.DenyInterests, matchInterestPattern)
+}
+
+// Reports whether the filter lets publishes reach the user
+func (f TargetFilter) AllowsUser(userId string) bool {
+	return allowed(userId, f.AllowUsers, f.DenyUsers, func(listed, userId string) bool {
+		return listed == userId
+	})
+}
+
+func allowed(target string, allow, deny []string, match func(listed, target string) bool) bool {
+	for _, listed := range deny {
+		if match(listed, target) {
+			return false
+		}
+	}
+	for _, listed := range allow {
+		if match(listed, target) {
+			return true
+		}
+	}
+	return len(allow) == 0
+}
+
+func matchInterestPattern(pattern, interest string) bool {
+	if !strings.Contains(pattern, "*") {
+		return pattern == interest
+	}
+	return matchInterest(pattern, interest)
+}
+
+// Leaves the targets the filter doesn't allow out of every publish, calling `onFiltered`, if not nil,
+// with the targets left out. Publishes with no target left fail with `ErrTargetNotAllowed`.
+func WithTargetFilter(filter TargetFilter, onFiltered func(Target)) Option {
+	return WithPublishStage(ValidateStage, PublishStageFunc(func(job *PublishJob) error {
+		allows, target := filter.AllowsUser, Users()
+		if job.Operation == publishToInterestsOperation {
+			allows, target = filter.AllowsInterest, Interests()
+		}
+
+		kept := make([]string, 0, len(job.Targets))
+		for _, id := range job.Targets {
+			if allows(id) {
+				kept = append(kept, id)
+			} else {
+				target.Ids = append(target.Ids, id)
+			}
+		}
+		if len(target.Ids) > 0 && onFiltered != nil {
+			onFiltered(target)
+		}
+
+		if len(kept) == 0 {
+			return errors.Wrapf(ErrTargetNotAllowed, "Failed to publish to %s", strings.Join(target.Ids, ", "))
+		}
+		if len(target.Ids) > 0 {
+			job.Warn(AudienceReducedWarning, "%d of %d %s were filtered out",
+				len(target.Ids), len(job.Targets), targetsKey(job.Operation))
+		}
+		job.Targets = kept
+		return nil
+	}))
+}
@@ -0,0 +1,66 @@
+package pushnotifications
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTargetFilter(t *testing.T) {
+	Convey("A target filter", t, func() {
+		filter := TargetFilter{
+			AllowInterests: []string{"debug-*", "hello"},
+			DenyInterests:  []string{"debug-all"},
+			AllowUsers:     []string{"tester-1", "tester-2"},
+		}
+
+		Convey("should allow listed interests and patterns", func() {
+			So(filter.AllowsInterest("hello"), ShouldBeTrue)
+			So(filter.AllowsInterest("debug-ios"), ShouldBeTrue)
+			So(filter.AllowsInterest("hello-world"), ShouldBeFalse)
+			So(filter.AllowsInterest("debug-all"), ShouldBeFalse)
+		})
+
+		Convey("should allow every target when its allow list is empty", func() {
+			So(TargetFilter{DenyUsers: []string{"ceo"}}.AllowsUser("user-1"), ShouldBeTrue)
+			So(TargetFilter{DenyUsers: []string{"ceo"}}.AllowsUser("ceo"), ShouldBeFalse)
+		})
+
+		Convey("on a Push Notifications Instance", func() {
+			var body map[string]interface{}
+			testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				bodyBytes, _ := io.ReadAll(r.Body)
+				body = map[string]interface{}{}
+				json.Unmarshal(bodyBytes, &body)
+				w.Write([]byte(`{"publishId":"pub-123"}`))
+			}))
+			defer testServer.Close()
+
+			filtered := []Target{}
+			pn, _ := New(testInstanceId, testSecretKey,
+				WithCustomBaseURL(testServer.URL),
+				WithTargetFilter(filter, func(target Target) { filtered = append(filtered, target) }))
+			request := map[string]interface{}{"fcm": map[string]interface{}{}}
+
+			Convey("should leave out the targets it doesn't allow", func() {
+				_, err := pn.PublishToUsers([]string{"tester-1", "customer-1"}, request)
+				So(err, ShouldBeNil)
+				So(body["users"], ShouldResemble, []interface{}{"tester-1"})
+				So(filtered, ShouldResemble, []Target{Users("customer-1")})
+			})
+
+			Convey("should not publish when no target is allowed", func() {
+				body = nil
+				_, err := pn.PublishToInterests([]string{"everyone"}, request)
+				So(errors.Cause(err), ShouldEqual, ErrTargetNotAllowed)
+				So(err.Error(), ShouldContainSubstring, "Failed to publish to everyone")
+				So(body, ShouldBeNil)
+			})
+		})
+	})
+}
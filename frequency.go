@@ -0,0 +1,116 @@
+package pushnotifications
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Reason of the suppressions made by a frequency cap
+var ErrFrequencyCapped = errors.New("The user has reached their notification frequency cap")
+
+// Counts events by key over time, such as the notifications sent to each user.
+// Implementations must be safe for concurrent use.
+type CounterStore interface {
+	// Records an event for `key` at `at`
+	Add(key string, at time.Time) error
+	// Returns the number of events recorded for `key` since `since`
+	CountSince(key string, since time.Time) (int, error)
+}
+
+// Returns a `CounterStore` keeping events in memory, forgetting them after `retention`
+func NewMemoryCounterStore(retention time.Duration) CounterStore {
+	return &memoryCounterStore{retention: retention, events: map[string][]time.Time{}}
+}
+
+type memoryCounterStore struct {
+	mutex     sync.Mutex
+	retention time.Duration
+	events    map[string][]time.Time
+}
+
+func (s *memoryCounterStore) Add(key string, at time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	events := s.events[key]
+	cutoff := at.Add(-s.retention)
+	for len(events) > 0 && events[0].Before(cutoff) {
+		events = events[1:]
+	}
+	s.events[key] = append(events, at)
+	return nil
+}
+
+func (s *memoryCounterStore) CountSince(key string, since time.Time) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	count := 0
+	for _, at := range s.events[key] {
+		if !at.Before(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Limits how many notifications each user receives within a sliding window.
+// It is both the `SuppressionRule` skipping capped users and the `PublishRecorder`
+// counting the notifications sent, so it must be added with both `WithSuppression`
+// and `WithPublishRecorder`, or with `WithFrequencyCap`.
+//
+// The cap is best-effort: publishes are counted once they are sent, so concurrent
+// publishes to the same user can all pass the check and exceed the cap between them.
+type FrequencyCap struct {
+	// Maximum number of notifications per user within the window
+	Max int
+	// Length of the sliding window, e.g. an hour
+	Per   time.Duration
+	Store CounterStore
+	// Called when the store fails to count a publish to a user, if not nil.
+	// `WithFrequencyCap` logs these errors to the `WithLogger` logger.
+	OnError func(userId string, err error)
+}
+
+// Skips the user with `ErrFrequencyCapped` if they were sent `Max` notifications in the last `Per`
+func (c *FrequencyCap) Check(userId string, now time.Time) (*Suppression, error) {
+	count, err := c.Store.CountSince(userId, now.Add(-c.Per))
+	if err != nil {
+		return nil, err
+	}
+	if count >= c.Max {
+		return &Suppression{UserId: userId, Reason: ErrFrequencyCapped}, nil
+	}
+	return nil, nil
+}
+
+// Counts a successful publish against the cap of each of its users
+func (c *FrequencyCap) Record(job *PublishJob) {
+	if job.Err != nil || job.Operation != publishToUsersOperation {
+		return
+	}
+	now := time.Now()
+	for _, userId := range job.Targets {
+		if err := c.Store.Add(userId, now); err != nil && c.OnError != nil {
+			c.OnError(userId, err)
+		}
+	}
+}
+
+// Skips users who were sent `max` notifications in the last `per`, passing them to `onCapped`
+// (which may be nil). Publishes where every user is capped fail with a `*SuppressedError`.
+// Errors of the store when counting publishes are logged to the `WithLogger` logger.
+func WithFrequencyCap(max int, per time.Duration, store CounterStore, onCapped func(Suppression)) Option {
+	return func(pn *pushNotifications) {
+		frequencyCap := &FrequencyCap{Max: max, Per: per, Store: store}
+		frequencyCap.OnError = func(userId string, err error) {
+			if pn.logger != nil {
+				pn.logger.Printf("Failed to count the publish to user %s against its frequency cap: %s", userId, err)
+			}
+		}
+		WithSuppression(onCapped, frequencyCap)(pn)
+		WithPublishRecorder(frequencyCap)(pn)
+	}
+}
@@ -0,0 +1,94 @@
+package pushnotifications
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFrequencyCap(t *testing.T) {
+	Convey("A memory counter store", t, func() {
+		store := NewMemoryCounterStore(time.Hour)
+		start := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+
+		Convey("should count events in a window", func() {
+			store.Add("user-1", start)
+			store.Add("user-1", start.Add(30*time.Minute))
+			store.Add("user-2", start.Add(30*time.Minute))
+
+			count, err := store.CountSince("user-1", start.Add(time.Minute))
+			So(err, ShouldBeNil)
+			So(count, ShouldEqual, 1)
+			count, _ = store.CountSince("user-1", start)
+			So(count, ShouldEqual, 2)
+		})
+	})
+
+	Convey("A Push Notifications Instance with a frequency cap", t, func() {
+		var body map[string]interface{}
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			bodyBytes, _ := io.ReadAll(r.Body)
+			body = map[string]interface{}{}
+			json.Unmarshal(bodyBytes, &body)
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"publishId":"pub-123"}`))
+		}))
+		defer testServer.Close()
+
+		capped := []Suppression{}
+		pn, err := New(testInstanceId, testSecretKey,
+			WithCustomBaseURL(testServer.URL),
+			WithHashedUserIds([]byte("salt")),
+			WithFrequencyCap(2, time.Hour, NewMemoryCounterStore(time.Hour), func(suppression Suppression) {
+				capped = append(capped, suppression)
+			}),
+		)
+		So(err, ShouldBeNil)
+
+		Convey("should skip users who reached the cap", func() {
+			for i := 0; i < 2; i++ {
+				_, err := pn.PublishToUsers([]string{"user-1"}, map[string]interface{}{})
+				So(err, ShouldBeNil)
+			}
+
+			_, err := pn.PublishToUsers([]string{"user-1", "user-2"}, map[string]interface{}{})
+			So(err, ShouldBeNil)
+			So(body["users"], ShouldResemble, []interface{}{HashUserId([]byte("salt"), "user-2")})
+			So(capped, ShouldResemble, []Suppression{{UserId: "user-1", Reason: ErrFrequencyCapped}})
+
+			_, err = pn.PublishToUsers([]string{"user-1"}, map[string]interface{}{})
+			So(err.(*SuppressedError).Suppressions[0].Reason, ShouldEqual, ErrFrequencyCapped)
+		})
+
+		Convey("should log the errors of the store when counting publishes", func() {
+			logger := &recordingLogger{}
+			pn, _ := New(testInstanceId, testSecretKey,
+				WithCustomBaseURL(testServer.URL),
+				WithFrequencyCap(2, time.Hour, failingCounterStore{}, nil),
+				WithLogger(logger))
+
+			_, err := pn.PublishToUsers([]string{"user-1"}, map[string]interface{}{})
+			So(err, ShouldBeNil)
+			So(logger.messages, ShouldResemble, []string{
+				"Failed to count the publish to user user-1 against its frequency cap: store unavailable",
+			})
+		})
+	})
+}
+
+// A store that counts nothing, and fails to record events
+type failingCounterStore struct{}
+
+func (failingCounterStore) Add(key string, at time.Time) error {
+	return errors.New("store unavailable")
+}
+
+func (failingCounterStore) CountSince(key string, since time.Time) (int, error) {
+	return 0, nil
+}
@@ -0,0 +1,105 @@
+//go:build go1.18
+// +build go1.18
+
+package pushnotifications
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// Run with e.g. `go test -fuzz FuzzValidateInterests`, with Go 1.18 or later
+
+func FuzzValidateInterests(f *testing.F) {
+	for _, seed := range []string{"hello", "", "a-b_c=d@e,f.g;h", "héllo", "with space", strings.Repeat("a", 165)} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, interest string) {
+		err := validateInterests([]string{interest}, maxNumInterestsWhenPublishing)
+		if err == nil && (interest == "" || len(interest) > 164 || !interestValidationRegex.MatchString(interest)) {
+			t.Errorf("Accepted the invalid interest %q", interest)
+		}
+		if err != nil && Classify(err) != Validation {
+			t.Errorf("Expected a validation error for %q, got %v", interest, err)
+		}
+	})
+}
+
+func FuzzValidateUsers(f *testing.F) {
+	for _, seed := range []string{"user-1", "", "\xff", "用户", strings.Repeat("u", 165)} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, userId string) {
+		err := validateUsers([]string{userId}, maxNumUserIdsWhenPublishing, false)
+		if err == nil && (userId == "" || len(userId) > maxUserIdLength || !utf8.ValidString(userId)) {
+			t.Errorf("Accepted the invalid user id %q", userId)
+		}
+		if err != nil && Classify(err) != Validation {
+			t.Errorf("Expected a validation error for %q, got %v", userId, err)
+		}
+	})
+}
+
+func FuzzInjectTargets(f *testing.F) {
+	for _, seed := range []string{`{}`, ` { "fcm" : {} } `, `{"interests":["a"]}`, `[]`, `{`, `"{}"`, ``} {
+		f.Add([]byte(seed), "target")
+	}
+	f.Fuzz(func(t *testing.T, request []byte, target string) {
+		body, err := injectTargets(request, "interests", []string{target})
+		if err != nil {
+			return
+		}
+		decoded := map[string]interface{}{}
+		if err := json.Unmarshal(body, &decoded); err != nil {
+			t.Fatalf("Produced invalid JSON %q from %q: %v", body, request, err)
+		}
+		// invalid UTF-8 is replaced when encoded
+		encodedTarget, _ := json.Marshal(target)
+		var expected string
+		json.Unmarshal(encodedTarget, &expected)
+		targets, _ := decoded["interests"].([]interface{})
+		if len(targets) != 1 || targets[0] != expected {
+			t.Errorf("Expected the targets to be [%q], got %v", expected, decoded["interests"])
+		}
+	})
+}
+
+func FuzzEncodeRequest(f *testing.F) {
+	for _, seed := range []string{
+		`{"fcm":{"notification":{"title":"Hi","body":"Hello"}}}`,
+		`{"apns":{"aps":{"alert":"Hi","badge":1}}}`,
+		`{"web":{"notification":{"title":"Hi","actions":[{"title":"Open"}]}}}`,
+		`{"apns":"not an object"}`,
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, encoded string) {
+		request := map[string]interface{}{}
+		if json.Unmarshal([]byte(encoded), &request) != nil {
+			return
+		}
+		ValidateRequest(request)
+		RequestWarnings(request)
+		Preview(request)
+		if _, err := json.Marshal(request); err != nil {
+			t.Errorf("Failed to encode %q: %v", encoded, err)
+		}
+	})
+}
+
+func FuzzTruncate(f *testing.F) {
+	for _, seed := range []string{"Hello, world", "👩‍👩‍👧 family", "🇫🇷🇩🇪", "é\r\n", "\xff\xfe"} {
+		f.Add(seed, 5)
+	}
+	f.Fuzz(func(t *testing.T, text string, max int) {
+		truncated := Truncate(text, max)
+		if max <= 0 && truncated != "" {
+			t.Errorf("Expected nothing left of %q, got %q", text, truncated)
+		}
+		if max > 0 && len(graphemeBoundaries(truncated)) > max {
+			t.Errorf("Truncated %q to %q, longer than %d characters", text, truncated, max)
+		}
+	})
+}
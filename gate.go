@@ -0,0 +1,48 @@
+package pushnotifications
+
+import "github.com/pkg/errors"
+
+// Key of the notification type set with `WithNotificationType` in the publish metadata
+const NotificationTypeMetadataKey = "notification_type"
+
+// Returned by publishes that a `Gate` turned off
+var ErrGated = errors.New("Notifications of this type are turned off")
+
+// Decides whether notifications may be published, e.g. backed by a feature flag service,
+// so that a type of notification can be turned off during an incident without a deploy
+type Gate interface {
+	// Reports whether notifications of the type (see `WithNotificationType`) may be published.
+	// The type is empty for publishes that don't have one.
+	Allowed(notificationType string) (bool, error)
+}
+
+// Adapts a function to the `Gate` interface
+type GateFunc func(notificationType string) (bool, error)
+
+func (f GateFunc) Allowed(notificationType string) (bool, error) {
+	return f(notificationType)
+}
+
+// Sets the type of the notification, e.g. "order_shipped", for gates to decide on.
+// It is kept in the publish metadata under `NotificationTypeMetadataKey`.
+func WithNotificationType(notificationType string) CallOption {
+	return func(callOpts *callOptions) {
+		callOpts.notificationType = notificationType
+	}
+}
+
+// Consults `gate` before every publish, failing the publishes it turns off with `ErrGated`.
+// Publishes also fail if the gate can't decide; wrap the gate to allow them instead.
+func WithGate(gate Gate) Option {
+	return WithPublishStage(ValidateStage, PublishStageFunc(func(job *PublishJob) error {
+		notificationType := job.Metadata[NotificationTypeMetadataKey]
+		allowed, err := gate.Allowed(notificationType)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to check whether `%s` notifications are allowed", notificationType)
+		}
+		if !allowed {
+			return errors.Wrapf(ErrGated, "Refused to publish a `%s` notification", notificationType)
+		}
+		return nil
+	}))
+}
@@ -0,0 +1,63 @@
+package pushnotifications
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestGate(t *testing.T) {
+	Convey("A Push Notifications Instance with a gate", t, func() {
+		requests := 0
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.Write([]byte(`{"publishId":"pub-123"}`))
+		}))
+		defer testServer.Close()
+
+		disabled := map[string]bool{"marketing": true}
+		var gateErr error
+		checked := []string{}
+		gate := GateFunc(func(notificationType string) (bool, error) {
+			checked = append(checked, notificationType)
+			return !disabled[notificationType], gateErr
+		})
+		recorder := &recordingRecorder{}
+		pn, _ := New(testInstanceId, testSecretKey, WithCustomBaseURL(testServer.URL), WithGate(gate), WithPublishRecorder(recorder))
+
+		Convey("should publish notifications of allowed types", func() {
+			_, err := pn.PublishToUsers([]string{"user-1"}, map[string]interface{}{},
+				WithNotificationType("order_shipped"), WithMetadata(map[string]string{"campaign": "c-1"}))
+			So(err, ShouldBeNil)
+			So(requests, ShouldEqual, 1)
+			So(checked, ShouldResemble, []string{"order_shipped"})
+			So(recorder.jobs[0].Metadata, ShouldResemble, map[string]string{
+				"campaign":                  "c-1",
+				NotificationTypeMetadataKey: "order_shipped",
+			})
+		})
+
+		Convey("should refuse notifications of types turned off", func() {
+			_, err := pn.PublishToInterests([]string{"hello"}, map[string]interface{}{}, WithNotificationType("marketing"))
+			So(errors.Cause(err), ShouldEqual, ErrGated)
+			So(err.Error(), ShouldContainSubstring, "`marketing`")
+			So(requests, ShouldEqual, 0)
+		})
+
+		Convey("should consult the gate for publishes without a type", func() {
+			disabled[""] = true
+			_, err := pn.PublishToInterests([]string{"hello"}, map[string]interface{}{})
+			So(errors.Cause(err), ShouldEqual, ErrGated)
+		})
+
+		Convey("should refuse publishes when the gate fails", func() {
+			gateErr = errors.New("flag service unavailable")
+			_, err := pn.PublishToInterests([]string{"hello"}, map[string]interface{}{})
+			So(err.Error(), ShouldContainSubstring, "flag service unavailable")
+			So(requests, ShouldEqual, 0)
+		})
+	})
+}
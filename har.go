@@ -0,0 +1,251 @@
+package pushnotifications
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const redactedValue = "[REDACTED]"
+
+// Headers whose values are replaced before being exported
+var harRedactedHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	Cookies     []harNameValue `json:"cookies"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Headers     []harNameValue `json:"headers"`
+	Cookies     []harNameValue `json:"cookies"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+type harEntry struct {
+	StartedDateTime string                 `json:"startedDateTime"`
+	Time            float64                `json:"time"`
+	Request         harRequest             `json:"request"`
+	Response        harResponse            `json:"response"`
+	Cache           map[string]interface{} `json:"cache"`
+	Timings         harTimings             `json:"timings"`
+	Error           string                 `json:"_error,omitempty"`
+}
+
+// Records the requests sent to the Beams service and their responses, with credentials
+// redacted, so they can be exported as an HTTP Archive (HAR) file and shared for debugging
+type HARRecorder struct {
+	mutex   sync.Mutex
+	entries []harEntry
+}
+
+func NewHARRecorder() *HARRecorder {
+	return &HARRecorder{}
+}
+
+// Records every request sent by the client with `recorder`.
+// Bodies are recorded up to the size set with `WithMaxResponseSize`.
+func WithHAR(recorder *HARRecorder) Option {
+	return func(pn *pushNotifications) {
+		pn.transportDecorators = append(pn.transportDecorators, func(next http.RoundTripper) http.RoundTripper {
+			return recorder.decorate(next, pn.maxResponseSize)
+		})
+	}
+}
+
+func (r *HARRecorder) decorate(next http.RoundTripper, maxBodySize int64) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		requestBody, err := copyBody(&req.Body, maxBodySize)
+		if err != nil {
+			return nil, err
+		}
+
+		start := time.Now()
+		resp, err := next.RoundTrip(req)
+		elapsed := float64(time.Since(start)) / float64(time.Millisecond)
+
+		entry := harEntry{
+			StartedDateTime: start.Format(time.RFC3339Nano),
+			Time:            elapsed,
+			Request:         newHARRequest(req, requestBody),
+			Cache:           map[string]interface{}{},
+			Timings:         harTimings{Wait: elapsed},
+		}
+
+		if err != nil {
+			entry.Error = err.Error()
+			entry.Response = harResponse{Headers: []harNameValue{}, Cookies: []harNameValue{}}
+		} else {
+			responseBody, readErr := copyBody(&resp.Body, maxBodySize)
+			if readErr != nil {
+				return nil, readErr
+			}
+			entry.Response = newHARResponse(resp, responseBody)
+		}
+
+		r.mutex.Lock()
+		r.entries = append(r.entries, entry)
+		r.mutex.Unlock()
+
+		return resp, err
+	})
+}
+
+// Writes the recorded requests as a HAR 1.2 document
+func (r *HARRecorder) WriteTo(w io.Writer) (int64, error) {
+	r.mutex.Lock()
+	entries := append([]harEntry{}, r.entries...)
+	r.mutex.Unlock()
+
+	har := map[string]interface{}{
+		"log": map[string]interface{}{
+			"version": "1.2",
+			"creator": map[string]string{
+				"name":    "pusher-push-notifications-go",
+				"version": sdkVersion,
+			},
+			"entries": entries,
+		},
+	}
+
+	harBytes, err := json.MarshalIndent(har, "", "  ")
+	if err != nil {
+		return 0, errors.Wrap(err, "Failed to marshal the HAR document")
+	}
+
+	n, err := w.Write(harBytes)
+	return int64(n), err
+}
+
+func newHARRequest(req *http.Request, body []byte) harRequest {
+	queryString := []harNameValue{}
+	for name, values := range req.URL.Query() {
+		for _, value := range values {
+			queryString = append(queryString, harNameValue{Name: name, Value: value})
+		}
+	}
+
+	harReq := harRequest{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		HTTPVersion: req.Proto,
+		Headers:     harHeaders(req.Header),
+		QueryString: queryString,
+		Cookies:     []harNameValue{},
+		HeadersSize: -1,
+		BodySize:    len(body),
+	}
+	if len(body) > 0 {
+		harReq.PostData = &harPostData{MimeType: req.Header.Get("Content-Type"), Text: string(body)}
+	}
+	return harReq
+}
+
+func newHARResponse(resp *http.Response, body []byte) harResponse {
+	return harResponse{
+		Status:      resp.StatusCode,
+		StatusText:  http.StatusText(resp.StatusCode),
+		HTTPVersion: resp.Proto,
+		Headers:     harHeaders(resp.Header),
+		Cookies:     []harNameValue{},
+		Content: harContent{
+			Size:     len(body),
+			MimeType: resp.Header.Get("Content-Type"),
+			Text:     string(body),
+		},
+		HeadersSize: -1,
+		BodySize:    len(body),
+	}
+}
+
+func harHeaders(header http.Header) []harNameValue {
+	headers := []harNameValue{}
+	for name, values := range header {
+		for _, value := range values {
+			if harRedactedHeaders[http.CanonicalHeaderKey(name)] {
+				value = redactedValue
+			}
+			headers = append(headers, harNameValue{Name: name, Value: value})
+		}
+	}
+	sort.Slice(headers, func(i, j int) bool {
+		return headers[i].Name < headers[j].Name
+	})
+	return headers
+}
+
+// Reads up to `limit` bytes of a request or response body, replacing it with one that still reads
+// the whole of it. Larger bodies are left for the client to read, and fail as they would without a recorder.
+func copyBody(body *io.ReadCloser, limit int64) ([]byte, error) {
+	if *body == nil {
+		return nil, nil
+	}
+
+	contents, err := io.ReadAll(io.LimitReader(*body, limit+1))
+	if err != nil {
+		(*body).Close()
+		return nil, errors.Wrap(err, "Failed to read the body")
+	}
+	if int64(len(contents)) > limit {
+		*body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(contents), *body), *body}
+		return contents[:limit], nil
+	}
+	(*body).Close()
+	*body = io.NopCloser(bytes.NewReader(contents))
+	return contents, nil
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
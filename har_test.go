@@ -0,0 +1,89 @@
+package pushnotifications
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHAR(t *testing.T) {
+	Convey("A Push Notifications Instance recording HAR entries", t, func() {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasPrefix(r.URL.Path, "/slow") {
+				time.Sleep(200 * time.Millisecond)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"Bad Request","description":"nope"}`))
+		}))
+		defer testServer.Close()
+
+		recorder := NewHARRecorder()
+		pn, _ := New(testInstanceId, testSecretKey, WithCustomBaseURL(testServer.URL), WithHAR(recorder))
+		pn.PublishToInterests([]string{"hello"}, map[string]interface{}{})
+
+		export := func() map[string]interface{} {
+			buffer := &bytes.Buffer{}
+			_, err := recorder.WriteTo(buffer)
+			So(err, ShouldBeNil)
+			So(strings.Contains(buffer.String(), testSecretKey), ShouldBeFalse)
+
+			har := map[string]interface{}{}
+			So(json.Unmarshal(buffer.Bytes(), &har), ShouldBeNil)
+			return har["log"].(map[string]interface{})
+		}
+
+		Convey("should export requests and responses as HAR", func() {
+			log := export()
+			So(log["version"], ShouldEqual, "1.2")
+
+			entries := log["entries"].([]interface{})
+			So(len(entries), ShouldEqual, 1)
+			entry := entries[0].(map[string]interface{})
+
+			request := entry["request"].(map[string]interface{})
+			So(request["method"], ShouldEqual, "POST")
+			So(request["url"], ShouldEqual, testServer.URL+"/publish_api/v1/instances/i-123/publishes")
+			So(request["postData"].(map[string]interface{})["text"], ShouldEqual, `{"interests":["hello"]}`)
+			So(request["headers"], ShouldContain, map[string]interface{}{"name": "Authorization", "value": "[REDACTED]"})
+
+			response := entry["response"].(map[string]interface{})
+			So(response["status"], ShouldEqual, 400)
+			So(response["content"].(map[string]interface{})["text"], ShouldEqual, `{"error":"Bad Request","description":"nope"}`)
+		})
+
+		Convey("should record requests that failed without a response", func() {
+			pn, _ := New(testInstanceId, testSecretKey,
+				WithCustomBaseURL(testServer.URL+"/slow"),
+				WithRequestTimeout(50*time.Millisecond),
+				WithHAR(recorder),
+			)
+			pn.DeleteUser("user-1")
+
+			entries := export()["entries"].([]interface{})
+			So(len(entries), ShouldEqual, 2)
+			So(entries[1].(map[string]interface{})["_error"], ShouldNotBeEmpty)
+		})
+
+		Convey("should record no more of a body than the maximum response size", func() {
+			pn, _ := New(testInstanceId, testSecretKey,
+				WithCustomBaseURL(testServer.URL),
+				WithMaxResponseSize(10),
+				WithHAR(recorder),
+			)
+			_, err := pn.PublishToInterests([]string{"hello"}, map[string]interface{}{})
+			So(errors.Cause(err), ShouldEqual, ErrResponseTooLarge)
+
+			entries := export()["entries"].([]interface{})
+			response := entries[1].(map[string]interface{})["response"].(map[string]interface{})
+			So(response["content"].(map[string]interface{})["text"], ShouldEqual, `{"error":"`)
+		})
+	})
+}
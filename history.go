@@ -0,0 +1,102 @@
+package pushnotifications
+
+import (
+	"sync"
+	"time"
+)
+
+// What the `History` keeps of a publish
+type PublishRecord struct {
+	Time      time.Time
+	Operation string
+	// Interests or user ids the notification was published to
+	Targets   []string
+	PublishId string
+	// "success", or the `ErrorClass` of the failure
+	Outcome  string
+	Error    string
+	Metadata map[string]string
+}
+
+// Keeps the most recent publishes in memory, so that webhook events can be matched up
+// with the metadata of the publish they are about. Add it with `WithPublishRecorder`.
+type History struct {
+	mutex     sync.Mutex
+	capacity  int
+	records   []PublishRecord
+	next      int
+	published map[string]int
+}
+
+// Returns a `History` keeping up to `capacity` publishes, dropping the oldest ones first
+func NewHistory(capacity int) *History {
+	return &History{capacity: capacity, published: map[string]int{}}
+}
+
+func (h *History) Record(job *PublishJob) {
+	record := PublishRecord{
+		Time:      time.Now(),
+		Operation: job.Operation,
+		Targets:   job.Targets,
+		PublishId: job.PublishId,
+		Outcome:   outcomeOf(job.Err),
+		Metadata:  job.Metadata,
+	}
+	if job.Err != nil {
+		record.Error = job.Err.Error()
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.capacity <= 0 {
+		return
+	}
+	if len(h.records) < h.capacity {
+		h.records = append(h.records, record)
+	} else {
+		if dropped := h.records[h.next].PublishId; dropped != "" {
+			delete(h.published, dropped)
+		}
+		h.records[h.next] = record
+	}
+	if record.PublishId != "" {
+		h.published[record.PublishId] = h.next
+	}
+	h.next = (h.next + 1) % h.capacity
+}
+
+// Returns the record of the publish with the given id, if it is still kept
+func (h *History) Get(publishId string) (PublishRecord, bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	i, ok := h.published[publishId]
+	if !ok {
+		return PublishRecord{}, false
+	}
+	return h.records[i], true
+}
+
+// Returns the publishes kept, oldest first
+func (h *History) Records() []PublishRecord {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	records := make([]PublishRecord, 0, len(h.records))
+	if len(h.records) == h.capacity {
+		records = append(records, h.records[h.next:]...)
+		return append(records, h.records[:h.next]...)
+	}
+	return append(records, h.records...)
+}
+
+// Sets the metadata of the event to that of the publish it is about.
+// Returns false if the publish is not in the history.
+func (h *History) Match(event *WebhookEvent) bool {
+	record, ok := h.Get(event.PublishId)
+	if ok {
+		event.Metadata = record.Metadata
+	}
+	return ok
+}
@@ -0,0 +1,72 @@
+package pushnotifications
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHistory(t *testing.T) {
+	Convey("A publish history", t, func() {
+		publishes := 0
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			publishes++
+			fmt.Fprintf(w, `{"publishId":"pub-%d"}`, publishes)
+		}))
+		defer testServer.Close()
+
+		history := NewHistory(2)
+		events := []PublishEvent{}
+		pn, _ := New(testInstanceId, testSecretKey,
+			WithCustomBaseURL(testServer.URL),
+			WithPublishRecorder(history),
+			WithPublishHook(func(event PublishEvent) { events = append(events, event) }))
+		request := map[string]interface{}{"fcm": map[string]interface{}{}}
+		metadata := map[string]string{"campaign": "spring-sale"}
+
+		Convey("should keep the metadata of publishes", func() {
+			publishId, err := pn.PublishToUsers([]string{"user-1"}, request, WithMetadata(metadata))
+			So(err, ShouldBeNil)
+
+			record, ok := history.Get(publishId)
+			So(ok, ShouldBeTrue)
+			So(record.Targets, ShouldResemble, []string{"user-1"})
+			So(record.Outcome, ShouldEqual, "success")
+			So(record.Metadata, ShouldResemble, metadata)
+			So(events[0].Metadata, ShouldResemble, metadata)
+		})
+
+		Convey("should keep failed publishes", func() {
+			pn.PublishToUsers([]string{""}, request, WithMetadata(metadata))
+			records := history.Records()
+			So(len(records), ShouldEqual, 1)
+			So(records[0].Outcome, ShouldEqual, "Validation")
+			So(records[0].Error, ShouldContainSubstring, "Empty user ids")
+		})
+
+		Convey("should drop the oldest publishes beyond its capacity", func() {
+			for i := 0; i < 3; i++ {
+				pn.PublishToInterests([]string{"donuts"}, request)
+			}
+			_, ok := history.Get("pub-1")
+			So(ok, ShouldBeFalse)
+
+			records := history.Records()
+			So(len(records), ShouldEqual, 2)
+			So(records[0].PublishId, ShouldEqual, "pub-2")
+			So(records[1].PublishId, ShouldEqual, "pub-3")
+		})
+
+		Convey("should match webhook events with the metadata of their publish", func() {
+			publishId, _ := pn.PublishToUsers([]string{"user-1"}, request, WithMetadata(metadata))
+
+			event := &WebhookEvent{PublishId: publishId}
+			So(history.Match(event), ShouldBeTrue)
+			So(event.Metadata, ShouldResemble, metadata)
+			So(history.Match(&WebhookEvent{PublishId: "pub-unknown"}), ShouldBeFalse)
+		})
+	})
+}
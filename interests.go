@@ -0,0 +1,182 @@
+package pushnotifications
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// A set of known interests that wildcard patterns such as `orders.*` are expanded against
+// before publishing. `*` matches any run of characters, including none.
+// A registry is safe for concurrent use.
+type InterestRegistry struct {
+	mutex     sync.RWMutex
+	interests map[string]bool
+}
+
+// Creates an `InterestRegistry` knowing `interests`
+func NewInterestRegistry(interests ...string) (*InterestRegistry, error) {
+	r := &InterestRegistry{interests: map[string]bool{}}
+	if err := r.Add(interests...); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Adds `interests` to the registry.
+// Returns a non-nil error, without adding any of them, if one is not a valid interest name.
+func (r *InterestRegistry) Add(interests ...string) error {
+	if len(interests) == 0 {
+		return nil
+	}
+	for _, interest := range interests {
+		if err := validateInterests([]string{interest}, maxNumInterestsWhenPublishing); err != nil {
+			return err
+		}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, interest := range interests {
+		r.interests[interest] = true
+	}
+	return nil
+}
+
+// Removes `interests` from the registry
+func (r *InterestRegistry) Remove(interests ...string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, interest := range interests {
+		delete(r.interests, interest)
+	}
+}
+
+// Returns the sorted, deduplicated known interests matching any of `patterns`.
+// Patterns without a `*` are returned as they are, even if they are not known.
+func (r *InterestRegistry) Expand(patterns ...string) []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	matched := map[string]bool{}
+	for _, pattern := range patterns {
+		if !strings.Contains(pattern, "*") {
+			matched[pattern] = true
+			continue
+		}
+		for interest := range r.interests {
+			if matchInterest(pattern, interest) {
+				matched[interest] = true
+			}
+		}
+	}
+
+	interests := make([]string, 0, len(matched))
+	for interest := range matched {
+		interests = append(interests, interest)
+	}
+	sort.Strings(interests)
+	return interests
+}
+
+// Publishes `request` to the interests matching `patterns`, in as many publishes as the
+// API's limit of interests per publish requires. Returns the publish ids of the chunks
+// published, stopping at the first chunk that fails.
+func (r *InterestRegistry) Publish(
+	pn InterestPublisher,
+	patterns []string,
+	request map[string]interface{},
+	options ...CallOption,
+) ([]string, error) {
+	interests := r.Expand(patterns...)
+	if len(interests) == 0 {
+		return nil, newValidationError("No known interests match %s", strings.Join(patterns, ", "))
+	}
+
+	chunkSize := limitsOf(pn).MaxInterests
+	chunks := (len(interests) + chunkSize - 1) / chunkSize
+	publishIds := []string{}
+	for start := 0; start < len(interests); start += chunkSize {
+		end := start + chunkSize
+		if end > len(interests) {
+			end = len(interests)
+		}
+		publishId, err := pn.PublishToInterests(interests[start:end], request, options...)
+		if err != nil {
+			return publishIds, errors.Wrapf(err, "Failed to publish chunk %d of %d", len(publishIds)+1, chunks)
+		}
+		publishIds = append(publishIds, publishId)
+	}
+	return publishIds, nil
+}
+
+func matchInterest(pattern, interest string) bool {
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(interest, parts[0]) {
+		return false
+	}
+	interest = interest[len(parts[0]):]
+
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(interest, part)
+		if i < 0 {
+			return false
+		}
+		interest = interest[i+len(part):]
+	}
+	return len(interest) >= len(last) && strings.HasSuffix(interest, last)
+}
+
+// How publishes treat interest names that break the rule of `InterestPattern` and `MaxInterestLength`
+type InterestValidation int
+
+const (
+	// Publishes to invalid interest names fail with a `Validation` error
+	StrictInterests InterestValidation = iota
+	// Invalid interest names are replaced by `SanitizeInterest`, with an `InterestSanitizedWarning`.
+	// Different names can be sanitized to the same interest, which is then published to once.
+	SanitizedInterests
+)
+
+// Sets how publishes treat invalid interest names, strictly by default.
+// Sanitizing suits interests built from user-generated content, such as tags.
+func WithInterestValidation(validation InterestValidation) Option {
+	return func(pn *pushNotifications) {
+		pn.interestValidation = validation
+	}
+}
+
+// Returns the interest name with every forbidden character replaced by `_`, cut to `MaxInterestLength`.
+// An empty name stays empty, and so invalid.
+func SanitizeInterest(interest string) string {
+	sanitized := strings.Map(func(r rune) rune {
+		if r < utf8.RuneSelf && interestValidationRegex.MatchString(string(r)) {
+			return r
+		}
+		return '_'
+	}, interest)
+	if len(sanitized) > MaxInterestLength {
+		sanitized = sanitized[:MaxInterestLength]
+	}
+	return sanitized
+}
+
+func sanitizeInterests(job *PublishJob) {
+	targets := make([]string, 0, len(job.Targets))
+	seen := make(map[string]bool, len(job.Targets))
+	for _, interest := range job.Targets {
+		sanitized := SanitizeInterest(interest)
+		if sanitized != interest {
+			job.Warn(InterestSanitizedWarning, "Published to interest `%s` instead of the invalid `%s`", sanitized, interest)
+		}
+		if !seen[sanitized] {
+			seen[sanitized] = true
+			targets = append(targets, sanitized)
+		}
+	}
+	job.Targets = targets
+}
@@ -0,0 +1,114 @@
+package pushnotifications
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestInterestRegistry(t *testing.T) {
+	Convey("An interest registry", t, func() {
+		registry, err := NewInterestRegistry("orders.eu.1", "orders.us.2", "orders", "news", "news.sport")
+		So(err, ShouldBeNil)
+
+		Convey("should expand wildcard patterns to known interests", func() {
+			So(registry.Expand("orders.*"), ShouldResemble, []string{"orders.eu.1", "orders.us.2"})
+			So(registry.Expand("*.eu.*", "news*"), ShouldResemble, []string{"news", "news.sport", "orders.eu.1"})
+			So(registry.Expand("*2"), ShouldResemble, []string{"orders.us.2"})
+		})
+
+		Convey("should keep interests without wildcards as they are", func() {
+			So(registry.Expand("unknown", "news", "news"), ShouldResemble, []string{"news", "unknown"})
+		})
+
+		Convey("should forget removed interests", func() {
+			registry.Remove("orders.eu.1")
+			So(registry.Expand("orders.*"), ShouldResemble, []string{"orders.us.2"})
+		})
+
+		Convey("should reject invalid interest names", func() {
+			So(registry.Add("orders.eu.3", "bad interest"), ShouldNotBeNil)
+			So(registry.Expand("orders.eu.*"), ShouldResemble, []string{"orders.eu.1"})
+		})
+
+		Convey("should publish in chunks of the API's interest limit", func() {
+			published := [][]string{}
+			testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				bodyBytes, _ := io.ReadAll(r.Body)
+				body := struct{ Interests []string }{}
+				json.Unmarshal(bodyBytes, &body)
+				published = append(published, body.Interests)
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(fmt.Sprintf(`{"publishId":"pub-%d"}`, len(published))))
+			}))
+			defer testServer.Close()
+			pn, _ := New(testInstanceId, testSecretKey, WithCustomBaseURL(testServer.URL))
+
+			for i := 0; i < 150; i++ {
+				registry.Add(fmt.Sprintf("stores.%03d", i))
+			}
+			publishIds, err := registry.Publish(pn, []string{"stores.*"}, map[string]interface{}{})
+			So(err, ShouldBeNil)
+			So(publishIds, ShouldResemble, []string{"pub-1", "pub-2"})
+			So(len(published[0]), ShouldEqual, 100)
+			So(len(published[1]), ShouldEqual, 50)
+			So(published[1][49], ShouldEqual, "stores.149")
+		})
+
+		Convey("should not publish when no interest matches", func() {
+			_, err := registry.Publish(nil, []string{"stores.*"}, map[string]interface{}{})
+			So(Classify(err), ShouldEqual, Validation)
+		})
+	})
+}
+
+func TestInterestValidation(t *testing.T) {
+	Convey("Interest validation", t, func() {
+		var body map[string]interface{}
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			bodyBytes, _ := io.ReadAll(r.Body)
+			body = map[string]interface{}{}
+			json.Unmarshal(bodyBytes, &body)
+			w.Write([]byte(`{"publishId":"pub-123"}`))
+		}))
+		defer testServer.Close()
+		request := map[string]interface{}{"fcm": map[string]interface{}{"notification": map[string]interface{}{"title": "Hi"}}}
+
+		Convey("should allow the characters the error advertises", func() {
+			pn, _ := New(testInstanceId, testSecretKey, WithCustomBaseURL(testServer.URL))
+			_, err := pn.PublishToInterests([]string{"a_b-c=d@e,f.g;h"}, request)
+			So(err, ShouldBeNil)
+
+			_, err = pn.PublishToInterests([]string{"a:b"}, request)
+			So(err.Error(), ShouldEndWith, "numbers or one of _-=@,.;")
+		})
+
+		Convey("should sanitize interests when asked to", func() {
+			pn, _ := New(testInstanceId, testSecretKey, WithCustomBaseURL(testServer.URL), WithInterestValidation(SanitizedInterests))
+			response, err := pn.PublishToInterestsWithResponse([]string{"#gaming", "_gaming", "café", "news"}, request)
+			So(err, ShouldBeNil)
+			So(body["interests"], ShouldResemble, []interface{}{"_gaming", "caf_", "news"})
+			So(response.Warnings, ShouldResemble, []Warning{
+				{Code: InterestSanitizedWarning, Message: "Published to interest `_gaming` instead of the invalid `#gaming`"},
+				{Code: InterestSanitizedWarning, Message: "Published to interest `caf_` instead of the invalid `café`"},
+			})
+		})
+
+		Convey("should still reject empty interests when sanitizing", func() {
+			pn, _ := New(testInstanceId, testSecretKey, WithCustomBaseURL(testServer.URL), WithInterestValidation(SanitizedInterests))
+			_, err := pn.PublishToInterests([]string{""}, request)
+			So(Classify(err), ShouldEqual, Validation)
+		})
+
+		Convey("should cut sanitized interests to the maximum length", func() {
+			So(len(SanitizeInterest(strings.Repeat("a", 200))), ShouldEqual, MaxInterestLength)
+			So(SanitizeInterest("hello world"), ShouldEqual, "hello_world")
+		})
+	})
+}
@@ -0,0 +1,30 @@
+package pushnotifications
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCapabilityInterfaces(t *testing.T) {
+	Convey("A Push Notifications Instance", t, func() {
+		pn, _ := New(testInstanceId, testSecretKey)
+
+		Convey("should provide each capability on its own", func() {
+			var interestPublisher InterestPublisher = pn
+			var userPublisher UserPublisher = pn
+			var userAuthenticator UserAuthenticator = pn
+			var userDeleter UserDeleter = pn
+			var reporter Reporter = pn
+
+			So(interestPublisher, ShouldNotBeNil)
+			So(userPublisher, ShouldNotBeNil)
+			So(userDeleter, ShouldNotBeNil)
+			So(reporter.Stats(), ShouldResemble, Stats{})
+
+			token, err := userAuthenticator.GenerateToken("user-1")
+			So(err, ShouldBeNil)
+			So(token["token"], ShouldNotBeEmpty)
+		})
+	})
+}
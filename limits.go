@@ -0,0 +1,58 @@
+package pushnotifications
+
+// The limits publishes are checked against before they are sent.
+// `DefaultLimits` are those of the Beams service; they can be changed with `WithLimits`
+// when the service raises them, or when requests go through a gateway with other constraints.
+type Limits struct {
+	// Most interests a publish can target
+	MaxInterests int
+	// Most user ids a publish can target. Bulk publishes are split in chunks of this size.
+	MaxUsers int
+	// Size in bytes of the largest platform section (apns, fcm or web) of a request,
+	// above 90% of which a `PayloadSizeWarning` is raised
+	MaxPlatformPayloadSize int
+}
+
+// Returns the limits of the Beams service
+func DefaultLimits() Limits {
+	return Limits{
+		MaxInterests:           maxNumInterestsWhenPublishing,
+		MaxUsers:               maxNumUserIdsWhenPublishing,
+		MaxPlatformPayloadSize: maxPlatformPayloadSize,
+	}
+}
+
+// Replaces the limits publishes are checked against. Fields left at zero keep their default.
+func WithLimits(limits Limits) Option {
+	return func(pn *pushNotifications) {
+		pn.limits = limits.withDefaults()
+	}
+}
+
+func (limits Limits) withDefaults() Limits {
+	defaults := DefaultLimits()
+	if limits.MaxInterests <= 0 {
+		limits.MaxInterests = defaults.MaxInterests
+	}
+	if limits.MaxUsers <= 0 {
+		limits.MaxUsers = defaults.MaxUsers
+	}
+	if limits.MaxPlatformPayloadSize <= 0 {
+		limits.MaxPlatformPayloadSize = defaults.MaxPlatformPayloadSize
+	}
+	return limits
+}
+
+// Returns the limits publishes are checked against, set with `WithLimits`
+func (pn *pushNotifications) Limits() Limits {
+	return pn.limits
+}
+
+// Returns the limits of `pn` if it reports them, like the client returned by `New` does,
+// so that helpers splitting publishes in chunks follow the limits set with `WithLimits`
+func limitsOf(pn interface{}) Limits {
+	if limited, ok := pn.(interface{ Limits() Limits }); ok {
+		return limited.Limits()
+	}
+	return DefaultLimits()
+}
@@ -0,0 +1,101 @@
+package pushnotifications
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLimits(t *testing.T) {
+	Convey("Limits", t, func() {
+		var mutex sync.Mutex
+		publishedUsers := []int{}
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body := struct{ Users []string }{}
+			json.NewDecoder(r.Body).Decode(&body)
+			mutex.Lock()
+			publishedUsers = append(publishedUsers, len(body.Users))
+			mutex.Unlock()
+			w.Write([]byte(`{"publishId":"pub-123"}`))
+		}))
+		defer testServer.Close()
+		request := map[string]interface{}{"fcm": map[string]interface{}{"notification": map[string]interface{}{"title": "Hi"}}}
+		users := func(n int) []string {
+			ids := make([]string, n)
+			for i := range ids {
+				ids[i] = fmt.Sprintf("user-%d", i)
+			}
+			return ids
+		}
+
+		Convey("should default to those of the Beams service", func() {
+			pn, _ := New(testInstanceId, testSecretKey, WithCustomBaseURL(testServer.URL))
+			So(limitsOf(pn), ShouldResemble, Limits{MaxInterests: 100, MaxUsers: 1000, MaxPlatformPayloadSize: 4096})
+			So(limitsOf(struct{}{}), ShouldResemble, DefaultLimits())
+
+			_, err := pn.PublishToUsers(users(1001), request)
+			So(err.Error(), ShouldContainSubstring, "API supports up to 1000, got 1001")
+		})
+
+		Convey("should keep the defaults of the fields left at zero", func() {
+			pn, _ := New(testInstanceId, testSecretKey, WithLimits(Limits{MaxUsers: 10}))
+			So(limitsOf(pn), ShouldResemble, Limits{MaxInterests: 100, MaxUsers: 10, MaxPlatformPayloadSize: 4096})
+		})
+
+		Convey("when lowered", func() {
+			warnings := []Warning{}
+			pn, _ := New(testInstanceId, testSecretKey,
+				WithCustomBaseURL(testServer.URL),
+				WithLimits(Limits{MaxInterests: 2, MaxUsers: 10, MaxPlatformPayloadSize: 100}),
+				WithWarningHandler(func(job *PublishJob, warning Warning) { warnings = append(warnings, warning) }))
+
+			Convey("should reject publishes to more targets", func() {
+				_, err := pn.PublishToInterests([]string{"a", "b", "c"}, request)
+				So(Classify(err), ShouldEqual, Validation)
+				So(err.Error(), ShouldContainSubstring, "Too many interests supplied (3): API only supports up to 2")
+
+				_, err = pn.PublishToUsers(users(11), request)
+				So(Classify(err), ShouldEqual, Validation)
+				So(err.Error(), ShouldContainSubstring, "API supports up to 10, got 11")
+				So(publishedUsers, ShouldBeEmpty)
+			})
+
+			Convey("should split bulk publishes in smaller chunks", func() {
+				result, err := pn.PublishToUsersFromReader(strings.NewReader(strings.Join(users(25), "\n")), CSV, request)
+				So(err, ShouldBeNil)
+				So(len(result.Chunks), ShouldEqual, 3)
+
+				publishedUsers = []int{}
+				_, err = PublishVariants(pn, VariantSelectorFunc(func(string, string) (string, error) { return "a", nil }),
+					users(25), map[string]map[string]interface{}{"a": request}, "a")
+				So(err, ShouldBeNil)
+				So(publishedUsers, ShouldResemble, []int{10, 10, 5})
+			})
+
+			Convey("should warn about smaller payloads", func() {
+				large := map[string]interface{}{
+					"fcm": map[string]interface{}{"notification": map[string]interface{}{"title": strings.Repeat("a", 90)}},
+				}
+				_, err := pn.PublishToUsers([]string{"user-1"}, large)
+				So(err, ShouldBeNil)
+				So(warnings, ShouldContain, Warning{
+					Code:    PayloadSizeWarning,
+					Message: "fcm: is 119 bytes, close to the 100 bytes platforms accept",
+				})
+			})
+		})
+
+		Convey("when raised, should allow publishes to more targets", func() {
+			pn, _ := New(testInstanceId, testSecretKey, WithCustomBaseURL(testServer.URL), WithLimits(Limits{MaxUsers: 2000}))
+			_, err := pn.PublishToUsers(users(2000), request)
+			So(err, ShouldBeNil)
+			So(publishedUsers, ShouldResemble, []int{2000})
+		})
+	})
+}
@@ -0,0 +1,67 @@
+package pushnotifications
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Finds the locale of users, such as "en-GB" or "fr"
+type LocaleResolver interface {
+	Locale(userId string) (string, error)
+}
+
+// Adapts a function to the `LocaleResolver` interface
+type LocaleResolverFunc func(userId string) (string, error)
+
+func (f LocaleResolverFunc) Locale(userId string) (string, error) {
+	return f(userId)
+}
+
+// Publishes to each user the request of their locale, grouping users by locale to make as few
+// publishes as possible. A user whose locale has no request gets that of its language ("fr" for
+// "fr-CA"), or failing that the request of `fallback`. Users whose locale can't be resolved, or
+// who have no request at all, are skipped and reported in the result.
+func PublishLocalized(
+	pn UserPublisher,
+	users []string,
+	resolver LocaleResolver,
+	requests map[string]map[string]interface{},
+	fallback string,
+	options ...CallOption,
+) (*BulkResult, error) {
+	result := &BulkResult{}
+	groups := map[string][]string{}
+	for _, userId := range users {
+		locale, err := resolver.Locale(userId)
+		if err != nil {
+			err = errors.Wrapf(err, "Failed to resolve the locale of user `%s`", userId)
+		} else if locale = matchLocale(locale, requests, fallback); locale == "" {
+			err = newValidationError("No request for the locale of user `%s`", userId)
+		}
+		if err != nil {
+			result.Rejected = append(result.Rejected, RejectedUserId{UserId: userId, Err: err})
+			continue
+		}
+		groups[locale] = append(groups[locale], userId)
+	}
+
+	locales := make([]string, 0, len(groups))
+	for locale := range groups {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+
+	return publishGroups(pn, locales, groups, requests, nil, result, options)
+}
+
+// Returns the locale of `requests` to use for `locale`, or "" if there is none
+func matchLocale(locale string, requests map[string]map[string]interface{}, fallback string) string {
+	for _, candidate := range []string{locale, strings.SplitN(strings.Replace(locale, "_", "-", -1), "-", 2)[0], fallback} {
+		if _, ok := requests[candidate]; ok {
+			return candidate
+		}
+	}
+	return ""
+}
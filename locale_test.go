@@ -0,0 +1,63 @@
+package pushnotifications
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPublishLocalized(t *testing.T) {
+	Convey("Publishing localized requests", t, func() {
+		bodies := []map[string]interface{}{}
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			bodyBytes, _ := io.ReadAll(r.Body)
+			body := map[string]interface{}{}
+			json.Unmarshal(bodyBytes, &body)
+			bodies = append(bodies, body)
+			w.Write([]byte(`{"publishId":"pub-123"}`))
+		}))
+		defer testServer.Close()
+		pn, _ := New(testInstanceId, testSecretKey, WithCustomBaseURL(testServer.URL))
+
+		locales := map[string]string{"user-1": "fr-CA", "user-2": "en-GB", "user-3": "de", "user-4": "fr"}
+		resolver := LocaleResolverFunc(func(userId string) (string, error) {
+			locale, ok := locales[userId]
+			if !ok {
+				return "", errors.New("unknown user")
+			}
+			return locale, nil
+		})
+		notification := func(title string) map[string]interface{} {
+			return map[string]interface{}{"web": map[string]interface{}{"notification": map[string]interface{}{"title": title}}}
+		}
+		requests := map[string]map[string]interface{}{
+			"en":    notification("Hello"),
+			"en-GB": notification("Hello, mate"),
+			"fr":    notification("Bonjour"),
+		}
+
+		Convey("should publish to each locale the request of its locale, language or the fallback", func() {
+			result, err := PublishLocalized(pn, []string{"user-1", "user-2", "user-3", "user-4", "user-5"}, resolver, requests, "en")
+			So(err, ShouldBeNil)
+			So(len(result.Chunks), ShouldEqual, 3)
+			So(result.Chunks[0].Users, ShouldResemble, []string{"user-3"})
+			So(result.Chunks[1].Users, ShouldResemble, []string{"user-2"})
+			So(result.Chunks[2].Users, ShouldResemble, []string{"user-1", "user-4"})
+			So(bodies[2]["web"], ShouldResemble, notification("Bonjour")["web"])
+			So(result.Rejected[0].UserId, ShouldEqual, "user-5")
+			So(result.Rejected[0].Err.Error(), ShouldContainSubstring, "unknown user")
+		})
+
+		Convey("should skip users without a request for their locale", func() {
+			result, err := PublishLocalized(pn, []string{"user-3"}, resolver, requests, "")
+			So(err, ShouldBeNil)
+			So(result.Chunks, ShouldBeEmpty)
+			So(Classify(result.Rejected[0].Err), ShouldEqual, Validation)
+		})
+	})
+}
@@ -0,0 +1,122 @@
+package pushnotifications
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// Encodes the records of a publish log, JSON by default. Set another with `WithLogCodec`,
+// such as MessagePack or Protocol Buffers, to make large logs smaller and faster to replay.
+// Records are `LoggedPublish` values, or for logs made with `NewEncryptedPublishLog`,
+// the `map[string]interface{}` envelopes made by `EncryptData`.
+type PublishLogCodec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// The default codec, writing every record as one JSON line. Other codecs prefix every record
+// with its length, as a uvarint, as their records may contain newlines.
+var JSONCodec PublishLogCodec = jsonCodec{}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+// Largest record read from a publish log
+const maxLogRecordSize = 10 * 1024 * 1024
+
+// Configures how a publish log is written and read
+type PublishLogOption func(*publishLogConfig)
+
+type publishLogConfig struct {
+	codec PublishLogCodec
+	keys  []EncryptionKey
+}
+
+func newPublishLogConfig(options []PublishLogOption) publishLogConfig {
+	config := publishLogConfig{codec: JSONCodec}
+	for _, option := range options {
+		option(&config)
+	}
+	return config
+}
+
+// Sets the codec records are written with by a `PublishLog`, and read with by `Replay`
+func WithLogCodec(codec PublishLogCodec) PublishLogOption {
+	return func(config *publishLogConfig) {
+		config.codec = codec
+	}
+}
+
+// Sets the keys `Replay` decrypts the records of logs made with `NewEncryptedPublishLog` with,
+// picking the one with the key id of each record, so that logs written while keys were rotated can be read
+func WithLogKeys(keys ...EncryptionKey) PublishLogOption {
+	return func(config *publishLogConfig) {
+		config.keys = keys
+	}
+}
+
+func (config publishLogConfig) lines() bool {
+	_, ok := config.codec.(jsonCodec)
+	return ok
+}
+
+// Writes a record, followed by a newline or prefixed with its length depending on the codec
+func (config publishLogConfig) writeRecord(w io.Writer, record interface{}) error {
+	data, err := config.codec.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if config.lines() {
+		_, err = w.Write(append(data, '\n'))
+		return err
+	}
+	framed := make([]byte, binary.MaxVarintLen64, binary.MaxVarintLen64+len(data))
+	framed = append(framed[:binary.PutUvarint(framed, uint64(len(data)))], data...)
+	_, err = w.Write(framed)
+	return err
+}
+
+// Calls `record` with every record of the log and its number, counting from 1, until it returns an error
+func (config publishLogConfig) readRecords(r io.Reader, record func(number int, data []byte) error) error {
+	if config.lines() {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxLogRecordSize)
+		for line := 1; scanner.Scan(); line++ {
+			if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+				continue
+			}
+			if err := record(line, scanner.Bytes()); err != nil {
+				return err
+			}
+		}
+		return errors.Wrap(scanner.Err(), "Failed to read the publish log")
+	}
+
+	reader := bufio.NewReader(r)
+	for number := 1; ; number++ {
+		size, err := binary.ReadUvarint(reader)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrapf(err, "Failed to read the length of record %d of the publish log", number)
+		}
+		if size > maxLogRecordSize {
+			return newValidationError("Record %d of the publish log is %d bytes long, over the limit of %d", number, size, maxLogRecordSize)
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return errors.Wrapf(err, "Failed to read record %d of the publish log", number)
+		}
+		if err := record(number, data); err != nil {
+			return err
+		}
+	}
+}
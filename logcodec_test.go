@@ -0,0 +1,93 @@
+package pushnotifications
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// A binary codec, whose records may contain newlines
+type flateCodec struct{}
+
+func (flateCodec) Marshal(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	compressed := &bytes.Buffer{}
+	w, _ := flate.NewWriter(compressed, flate.BestCompression)
+	w.Write(data)
+	w.Close()
+	return compressed.Bytes(), nil
+}
+
+func (flateCodec) Unmarshal(data []byte, v interface{}) error {
+	decompressed, err := io.ReadAll(flate.NewReader(bytes.NewReader(data)))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(decompressed, v)
+}
+
+func TestPublishLogCodec(t *testing.T) {
+	Convey("A publish log with a codec", t, func() {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"publishId":"pub-123"}`))
+		}))
+		defer testServer.Close()
+
+		buffer := &bytes.Buffer{}
+		request := map[string]interface{}{"fcm": map[string]interface{}{"notification": map[string]interface{}{"title": "Hi"}}}
+		publish := func(log *PublishLog) {
+			pn, _ := New(testInstanceId, testSecretKey, WithCustomBaseURL(testServer.URL), WithPublishRecorder(log))
+			for i := 0; i < 3; i++ {
+				pn.PublishToUsers([]string{"user-1"}, request)
+			}
+			So(log.Err(), ShouldBeNil)
+		}
+		pn, _ := New(testInstanceId, testSecretKey, WithCustomBaseURL(testServer.URL))
+
+		Convey("should write records the codec can replay", func() {
+			publish(NewPublishLog(buffer, WithLogCodec(flateCodec{})))
+			So(bytes.Contains(buffer.Bytes(), []byte("user-1")), ShouldBeFalse)
+
+			results, err := Replay(pn, bytes.NewReader(buffer.Bytes()), ReplayFilter{}, WithLogCodec(flateCodec{}))
+			So(err, ShouldBeNil)
+			So(len(results), ShouldEqual, 3)
+			So(results[2].Original.Targets, ShouldResemble, []string{"user-1"})
+			So(results[2].PublishId, ShouldEqual, "pub-123")
+		})
+
+		Convey("should write encrypted records the codec can replay", func() {
+			key := EncryptionKey{Id: "2024-01", Secret: bytes.Repeat([]byte{7}, 32)}
+			log, _ := NewEncryptedPublishLog(buffer, key, WithLogCodec(flateCodec{}))
+			publish(log)
+
+			results, err := Replay(pn, bytes.NewReader(buffer.Bytes()), ReplayFilter{}, WithLogCodec(flateCodec{}), WithLogKeys(key))
+			So(err, ShouldBeNil)
+			So(len(results), ShouldEqual, 3)
+		})
+
+		Convey("should fail to replay a truncated log", func() {
+			publish(NewPublishLog(buffer, WithLogCodec(flateCodec{})))
+			truncated := buffer.Bytes()[:buffer.Len()-1]
+
+			results, err := Replay(pn, bytes.NewReader(truncated), ReplayFilter{}, WithLogCodec(flateCodec{}))
+			So(len(results), ShouldEqual, 2)
+			So(err.Error(), ShouldStartWith, "Failed to read record 3 of the publish log")
+		})
+
+		Convey("should fail to replay a log written with another codec", func() {
+			publish(NewPublishLog(buffer))
+
+			_, err := Replay(pn, bytes.NewReader(buffer.Bytes()), ReplayFilter{}, WithLogCodec(flateCodec{}))
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,27 @@
+package pushnotifications
+
+// Receives diagnostic messages from the client, such as warnings about API quota usage.
+// A `*log.Logger` from the standard library satisfies this interface.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// A leveled logger with a warning method, such as zap's `*SugaredLogger`
+// (use `Sugar()` to get one from a `*zap.Logger`) or logrus' `*Logger` and `*Entry`
+type WarnLogger interface {
+	Warnf(template string, args ...interface{})
+}
+
+// Adapts a leveled logger to `Logger`, logging messages at the warning level
+// rather than the info level used by the `Printf` of logrus
+func LogWarnings(logger WarnLogger) Logger {
+	return warnfLogger{logger}
+}
+
+type warnfLogger struct {
+	logger WarnLogger
+}
+
+func (l warnfLogger) Printf(format string, v ...interface{}) {
+	l.logger.Warnf(format, v...)
+}
@@ -0,0 +1,26 @@
+package pushnotifications
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type leveledLogger struct {
+	warnings []string
+}
+
+func (l *leveledLogger) Warnf(template string, args ...interface{}) {
+	l.warnings = append(l.warnings, fmt.Sprintf(template, args...))
+}
+
+func TestLogWarnings(t *testing.T) {
+	Convey("The leveled logger adapter", t, func() {
+		Convey("should log messages as warnings", func() {
+			leveled := &leveledLogger{}
+			LogWarnings(leveled).Printf("quota at %d%%", 80)
+			So(leveled.warnings, ShouldResemble, []string{"quota at 80%"})
+		})
+	})
+}
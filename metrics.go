@@ -0,0 +1,40 @@
+package pushnotifications
+
+import "time"
+
+// Receives every measurement taken by the client, so that any metrics system can be plugged in.
+// Tags always include the `instance` id; call measurements also carry the `operation` and `outcome`,
+// which is "success" or the `ErrorClass` of the failure.
+// Implementations must be safe for concurrent use.
+type Metrics interface {
+	// Adds `value` to the named counter
+	Counter(name string, value float64, tags map[string]string)
+	// Records one observation of the named distribution, such as a latency in seconds
+	Histogram(name string, value float64, tags map[string]string)
+	// Records the current value of the named measurement
+	Gauge(name string, value float64, tags map[string]string)
+}
+
+func (pn *pushNotifications) recordCall(operation string, elapsed time.Duration, err error) {
+	if pn.metrics == nil {
+		return
+	}
+
+	tags := map[string]string{
+		"instance":  pn.InstanceId,
+		"operation": operation,
+		"outcome":   outcomeOf(err),
+	}
+	pn.metrics.Histogram("request.duration", elapsed.Seconds(), tags)
+	pn.metrics.Counter("request.count", 1, tags)
+}
+
+func (pn *pushNotifications) recordQuota(quota Quota) {
+	if pn.metrics == nil {
+		return
+	}
+
+	tags := map[string]string{"instance": pn.InstanceId}
+	pn.metrics.Gauge("quota.limit", float64(quota.Limit), tags)
+	pn.metrics.Gauge("quota.remaining", float64(quota.Remaining), tags)
+}
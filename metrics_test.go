@@ -0,0 +1,46 @@
+package pushnotifications
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMetrics(t *testing.T) {
+	Convey("A Push Notifications Instance with metrics", t, func() {
+		status := http.StatusOK
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+			w.Write([]byte(`{"publishId":"pub-123","error":"Unauthorized","description":"Bad key"}`))
+		}))
+		defer testServer.Close()
+
+		metrics := newRecordingMetrics()
+		pn, err := New(testInstanceId, testSecretKey, WithCustomBaseURL(testServer.URL), WithMetrics(metrics))
+		So(err, ShouldBeNil)
+
+		Convey("should count successful calls, tagged with the instance and operation", func() {
+			_, err := pn.PublishToInterests([]string{"hello"}, map[string]interface{}{})
+			So(err, ShouldBeNil)
+
+			So(metrics.counters["request.count"], ShouldEqual, 1)
+			So(metrics.tags["request.count"], ShouldResemble, map[string]string{
+				"instance":  testInstanceId,
+				"operation": "publish_to_interests",
+				"outcome":   "success",
+			})
+			So(metrics.tags["request.duration"], ShouldResemble, metrics.tags["request.count"])
+		})
+
+		Convey("should tag failed calls with the class of error", func() {
+			status = http.StatusUnauthorized
+			err := pn.DeleteUser("user-1")
+			So(err, ShouldNotBeNil)
+
+			So(metrics.tags["request.count"]["operation"], ShouldEqual, "delete_user")
+			So(metrics.tags["request.count"]["outcome"], ShouldEqual, "Unauthorized")
+		})
+	})
+}
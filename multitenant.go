@@ -0,0 +1,112 @@
+package pushnotifications
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// The credentials of a Beams instance
+type Credentials struct {
+	InstanceId string
+	SecretKey  string
+}
+
+// Publishes on behalf of many tenants, each with its own Beams instance, as is common in SaaS apps
+type MultiTenant struct {
+	resolve func(tenantId string) (Credentials, error)
+	options []Option
+
+	mutex   sync.Mutex
+	tenants map[string]*tenant
+}
+
+type tenant struct {
+	mutex sync.Mutex
+	pn    PushNotifications
+}
+
+// Returns a `MultiTenant` finding the credentials of a tenant with `resolve` the first time
+// it is used, and keeping a client for it built with `options` until it is forgotten.
+// Each tenant's client has its own state, so that with `WithRateLimit` and `WithCircuitBreaker`
+// one tenant's failing instance or exhausted quota doesn't hold up the others.
+func NewMultiTenant(resolve func(tenantId string) (Credentials, error), options ...Option) *MultiTenant {
+	return &MultiTenant{
+		resolve: resolve,
+		options: options,
+		tenants: map[string]*tenant{},
+	}
+}
+
+// Returns the client of the tenant, resolving its credentials if it has none yet.
+// Failures to resolve credentials are not kept, so the next call tries again.
+func (m *MultiTenant) Client(tenantId string) (PushNotifications, error) {
+	m.mutex.Lock()
+	t, ok := m.tenants[tenantId]
+	if !ok {
+		t = &tenant{}
+		m.tenants[tenantId] = t
+	}
+	m.mutex.Unlock()
+
+	// resolving holds up the tenant's callers only, not those of other tenants
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.pn != nil {
+		return t.pn, nil
+	}
+
+	credentials, err := m.resolve(tenantId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to resolve the credentials of tenant `%s`", tenantId)
+	}
+	pn, err := New(credentials.InstanceId, credentials.SecretKey, m.options...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid credentials for tenant `%s`", tenantId)
+	}
+	t.pn = pn
+	return pn, nil
+}
+
+// Drops the client of the tenant, so that its credentials are resolved again, e.g. after they are rotated
+func (m *MultiTenant) Forget(tenantId string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.tenants, tenantId)
+}
+
+// Like `PushNotifications.PublishToInterests`, with the instance of the tenant
+func (m *MultiTenant) PublishToInterests(tenantId string, interests []string, request map[string]interface{}, options ...CallOption) (string, error) {
+	pn, err := m.Client(tenantId)
+	if err != nil {
+		return "", err
+	}
+	return pn.PublishToInterests(interests, request, options...)
+}
+
+// Like `PushNotifications.PublishToUsers`, with the instance of the tenant
+func (m *MultiTenant) PublishToUsers(tenantId string, users []string, request map[string]interface{}, options ...CallOption) (string, error) {
+	pn, err := m.Client(tenantId)
+	if err != nil {
+		return "", err
+	}
+	return pn.PublishToUsers(users, request, options...)
+}
+
+// Like `PushNotifications.GenerateToken`, with the instance of the tenant
+func (m *MultiTenant) GenerateToken(tenantId string, userId string) (map[string]interface{}, error) {
+	pn, err := m.Client(tenantId)
+	if err != nil {
+		return nil, err
+	}
+	return pn.GenerateToken(userId)
+}
+
+// Like `PushNotifications.DeleteUser`, with the instance of the tenant
+func (m *MultiTenant) DeleteUser(tenantId string, userId string) error {
+	pn, err := m.Client(tenantId)
+	if err != nil {
+		return err
+	}
+	return pn.DeleteUser(userId)
+}
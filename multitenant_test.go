@@ -0,0 +1,64 @@
+package pushnotifications
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMultiTenant(t *testing.T) {
+	Convey("A multi-tenant client", t, func() {
+		paths := []string{}
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			paths = append(paths, r.URL.Path)
+			w.Write([]byte(`{"publishId":"pub-123"}`))
+		}))
+		defer testServer.Close()
+
+		resolved := []string{}
+		resolveErr := errors.New("unknown tenant")
+		m := NewMultiTenant(func(tenantId string) (Credentials, error) {
+			resolved = append(resolved, tenantId)
+			if tenantId == "unknown" {
+				return Credentials{}, resolveErr
+			}
+			return Credentials{InstanceId: "instance-" + tenantId, SecretKey: testSecretKey}, nil
+		}, WithCustomBaseURL(testServer.URL))
+		request := map[string]interface{}{"fcm": map[string]interface{}{}}
+
+		Convey("should publish with the instance of each tenant", func() {
+			_, err := m.PublishToInterests("acme", []string{"hello"}, request)
+			So(err, ShouldBeNil)
+			_, err = m.PublishToUsers("globex", []string{"user-1"}, request)
+			So(err, ShouldBeNil)
+
+			So(paths[0], ShouldEqual, "/publish_api/v1/instances/instance-acme/publishes")
+			So(paths[1], ShouldEqual, "/publish_api/v1/instances/instance-globex/publishes/users")
+		})
+
+		Convey("should resolve the credentials of a tenant once", func() {
+			m.PublishToInterests("acme", []string{"hello"}, request)
+			m.GenerateToken("acme", "user-1")
+			So(resolved, ShouldResemble, []string{"acme"})
+
+			Convey("until it is forgotten", func() {
+				m.Forget("acme")
+				m.DeleteUser("acme", "user-1")
+				So(resolved, ShouldResemble, []string{"acme", "acme"})
+				So(strings.Contains(paths[len(paths)-1], "instance-acme/users/user-1"), ShouldBeTrue)
+			})
+		})
+
+		Convey("should not keep failures to resolve credentials", func() {
+			_, err := m.PublishToInterests("unknown", []string{"hello"}, request)
+			So(err.Error(), ShouldContainSubstring, "Failed to resolve the credentials of tenant `unknown`")
+			m.PublishToInterests("unknown", []string{"hello"}, request)
+			So(resolved, ShouldResemble, []string{"unknown", "unknown"})
+			So(paths, ShouldBeEmpty)
+		})
+	})
+}
@@ -0,0 +1,171 @@
+package pushnotifications
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// The kind of recipients a `Target` refers to
+type TargetKind int
+
+const (
+	// Devices subscribed to any of the interests
+	InterestsTarget TargetKind = iota
+	// Devices associated with any of the user ids
+	UsersTarget
+	// Device tokens of a single platform, for direct backends
+	DevicesTarget
+)
+
+// The recipients of a notification
+type Target struct {
+	Kind TargetKind
+	// Interests, user ids or device tokens, depending on `Kind`
+	Ids []string
+	// Name of the backend the device tokens belong to (e.g. "apns"), for `DevicesTarget` only
+	Platform string
+}
+
+// Targets devices subscribed to any of the interests
+func Interests(interests ...string) Target {
+	return Target{Kind: InterestsTarget, Ids: interests}
+}
+
+// Targets devices associated with any of the user ids
+func Users(users ...string) Target {
+	return Target{Kind: UsersTarget, Ids: users}
+}
+
+// Targets device tokens of the backend named `platform`
+func Devices(platform string, deviceTokens ...string) Target {
+	return Target{Kind: DevicesTarget, Ids: deviceTokens, Platform: platform}
+}
+
+// Outcome of a notification sent through a `Notifier`
+type Delivery struct {
+	// Name of the backend that delivered the notification, e.g. "beams" or "apns"
+	Backend string
+	// The publish id, when delivered through Beams
+	PublishId string
+	// Per-device outcome, when delivered through a direct backend
+	Devices *SendResult
+	// Errors of the backends that were tried first and failed, when delivered through a fallback chain
+	FailedBackends map[string]error
+}
+
+// Delivers notifications to a target independently of the provider behind it
+type Notifier interface {
+	Notify(target Target, request map[string]interface{}) (*Delivery, error)
+}
+
+type beamsNotifier struct {
+	pn PushNotifications
+}
+
+// Returns a `Notifier` publishing to interests and users through Beams
+func BeamsNotifier(pn PushNotifications) Notifier {
+	return &beamsNotifier{pn: pn}
+}
+
+func (n *beamsNotifier) Notify(target Target, request map[string]interface{}) (*Delivery, error) {
+	var publishId string
+	var err error
+	switch target.Kind {
+	case InterestsTarget:
+		publishId, err = n.pn.PublishToInterests(target.Ids, request)
+	case UsersTarget:
+		publishId, err = n.pn.PublishToUsers(target.Ids, request)
+	default:
+		return nil, newValidationError("Beams can only notify interests and users")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Delivery{Backend: "beams", PublishId: publishId}, nil
+}
+
+// The error of a direct backend that sent a notification to none of the devices
+type NotSentError struct {
+	Backend string
+	// Errors for each device token
+	Failed map[string]error
+}
+
+func (e *NotSentError) Error() string {
+	tokens := make([]string, 0, len(e.Failed))
+	for token := range e.Failed {
+		tokens = append(tokens, token)
+	}
+	sort.Strings(tokens)
+	if len(tokens) == 0 {
+		return fmt.Sprintf("%s sent the notification to no device", e.Backend)
+	}
+	return fmt.Sprintf("%s sent the notification to none of %d devices, e.g. %s: %s",
+		e.Backend, len(tokens), tokens[0], e.Failed[tokens[0]])
+}
+
+type backendNotifier struct {
+	backend Backend
+}
+
+// Returns a `Notifier` sending to device tokens through a direct backend.
+// Fails with a `NotSentError` if the notification could be sent to none of the devices.
+func BackendNotifier(backend Backend) Notifier {
+	return &backendNotifier{backend: backend}
+}
+
+func (n *backendNotifier) Notify(target Target, request map[string]interface{}) (*Delivery, error) {
+	if target.Kind != DevicesTarget {
+		return nil, newValidationError("The %s backend can only notify devices", n.backend.Name())
+	}
+
+	result, err := n.backend.Send(target.Ids, request)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to send notification through %s", n.backend.Name())
+	}
+	if len(result.Sent) == 0 {
+		// fails, so that a fallback chain moves on to its next step
+		return nil, errors.WithStack(&NotSentError{Backend: n.backend.Name(), Failed: result.Failed})
+	}
+
+	return &Delivery{Backend: n.backend.Name(), Devices: result}, nil
+}
+
+type router struct {
+	beams    Notifier
+	backends map[string]Notifier
+}
+
+// Returns a `Notifier` that publishes to interests and users through Beams, and sends
+// to devices through the backend whose name matches the target's platform
+func NewRouter(pn PushNotifications, backends ...Backend) Notifier {
+	r := &router{
+		backends: map[string]Notifier{},
+	}
+	if pn != nil {
+		r.beams = BeamsNotifier(pn)
+	}
+	for _, backend := range backends {
+		r.backends[backend.Name()] = BackendNotifier(backend)
+	}
+
+	return r
+}
+
+func (r *router) Notify(target Target, request map[string]interface{}) (*Delivery, error) {
+	if target.Kind != DevicesTarget {
+		if r.beams == nil {
+			return nil, newValidationError("No Beams instance to notify interests and users")
+		}
+		return r.beams.Notify(target, request)
+	}
+
+	notifier, ok := r.backends[target.Platform]
+	if !ok {
+		return nil, newValidationError("No backend for devices on platform `%s`", target.Platform)
+	}
+	return notifier.Notify(target, request)
+}
@@ -0,0 +1,96 @@
+package pushnotifications
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type fakeBackend struct {
+	name  string
+	err   error
+	sends [][]string
+	// Errors of the device tokens that fail
+	failed map[string]error
+}
+
+func (b *fakeBackend) Name() string {
+	return b.name
+}
+
+func (b *fakeBackend) Send(deviceTokens []string, request map[string]interface{}) (*SendResult, error) {
+	b.sends = append(b.sends, deviceTokens)
+	if b.err != nil {
+		return nil, b.err
+	}
+	result := &SendResult{Sent: []string{}, Failed: map[string]error{}}
+	for _, token := range deviceTokens {
+		if err, ok := b.failed[token]; ok {
+			result.Failed[token] = err
+		} else {
+			result.Sent = append(result.Sent, token)
+		}
+	}
+	return result, nil
+}
+
+func TestNotifier(t *testing.T) {
+	Convey("A router", t, func() {
+		var lastPath string
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lastPath = r.URL.Path
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"publishId":"pub-123"}`))
+		}))
+		defer testServer.Close()
+
+		pn, _ := New(testInstanceId, testSecretKey, WithCustomBaseURL(testServer.URL))
+		apns := &fakeBackend{name: "apns"}
+		fcm := &fakeBackend{name: "fcm"}
+		notifier := NewRouter(pn, apns, fcm)
+
+		Convey("should publish to interests through Beams", func() {
+			delivery, err := notifier.Notify(Interests("hello"), map[string]interface{}{})
+			So(err, ShouldBeNil)
+			So(delivery.Backend, ShouldEqual, "beams")
+			So(delivery.PublishId, ShouldEqual, "pub-123")
+			So(lastPath, ShouldEqual, "/publish_api/v1/instances/i-123/publishes")
+		})
+
+		Convey("should publish to users through Beams", func() {
+			delivery, err := notifier.Notify(Users("user-1"), map[string]interface{}{})
+			So(err, ShouldBeNil)
+			So(delivery.PublishId, ShouldEqual, "pub-123")
+			So(lastPath, ShouldEqual, "/publish_api/v1/instances/i-123/publishes/users")
+		})
+
+		Convey("should send to devices through the backend of their platform", func() {
+			delivery, err := notifier.Notify(Devices("fcm", "token-1", "token-2"), map[string]interface{}{})
+			So(err, ShouldBeNil)
+			So(delivery.Backend, ShouldEqual, "fcm")
+			So(delivery.Devices.Sent, ShouldResemble, []string{"token-1", "token-2"})
+			So(fcm.sends, ShouldResemble, [][]string{{"token-1", "token-2"}})
+			So(apns.sends, ShouldBeEmpty)
+		})
+
+		Convey("should fail for platforms without a backend", func() {
+			_, err := notifier.Notify(Devices("web", "sub-1"), map[string]interface{}{})
+			So(Classify(err), ShouldEqual, Validation)
+			So(err.Error(), ShouldContainSubstring, "No backend for devices on platform `web`")
+		})
+
+		Convey("should fail for interests and users without a Beams instance", func() {
+			_, err := NewRouter(nil, apns).Notify(Users("user-1"), map[string]interface{}{})
+			So(Classify(err), ShouldEqual, Validation)
+		})
+
+		Convey("should report backend failures", func() {
+			apns.err = errors.New("boom")
+			_, err := notifier.Notify(Devices("apns", "token-1"), map[string]interface{}{})
+			So(err.Error(), ShouldContainSubstring, "Failed to send notification through apns: boom")
+		})
+	})
+}
@@ -1,6 +1,10 @@
 package pushnotifications
 
 import (
+	"context"
+	"io"
+	"net"
+	"net/http"
 	"time"
 )
 
@@ -17,3 +21,213 @@ func WithCustomBaseURL(url string) Option {
 		pn.baseEndpoint = url
 	}
 }
+
+// Sets the logger used to report diagnostic messages, such as quota warnings
+func WithLogger(logger Logger) Option {
+	return func(pn *pushNotifications) {
+		pn.logger = logger
+	}
+}
+
+// Sets where measurements taken by the client, such as the remaining API quota, are reported
+func WithMetrics(metrics Metrics) Option {
+	return func(pn *pushNotifications) {
+		pn.metrics = metrics
+	}
+}
+
+// Logs a warning when the fraction of the API quota used crosses `threshold` (e.g. 0.8 for 80%).
+// Requires a logger to be set with `WithLogger`.
+func WithQuotaWarning(threshold float64) Option {
+	return func(pn *pushNotifications) {
+		pn.quotaWarningThreshold = threshold
+	}
+}
+
+// Calls `hook` with a `PublishEvent` after every publish, whether it succeeded or not.
+// Hooks are called in the order they were added, on the goroutine that made the publish.
+func WithPublishHook(hook func(PublishEvent)) Option {
+	return func(pn *pushNotifications) {
+		pn.publishHooks = append(pn.publishHooks, hook)
+	}
+}
+
+// Signs the `data` of every published notification with `signer`, so that apps can verify
+// where it came from before acting on it (see `SignatureDataKey`)
+func WithSigner(signer Signer) Option {
+	return func(pn *pushNotifications) {
+		pn.signer = signer
+	}
+}
+
+// Replaces user ids with `HashUserId(salt, userId)` in every publish, token and user deletion,
+// so the original ids never leave your infrastructure. The salt must be kept secret and never change,
+// or users will stop receiving notifications until they authenticate again.
+// Apps must call `setUserId` with the pseudonym that `GenerateToken` returns under "user_id",
+// as the Beams service only accepts a token for the id the device sets.
+func WithHashedUserIds(salt []byte) Option {
+	return func(pn *pushNotifications) {
+		pn.userIdSalt = salt
+	}
+}
+
+// Checks user ids against the limit of 164 in characters (runes) rather than bytes, so that ids
+// with multi-byte characters, such as names in non-Latin scripts, can be as long as ASCII ones
+func WithUserIdLengthInRunes() Option {
+	return func(pn *pushNotifications) {
+		pn.userIdLengthInRunes = true
+	}
+}
+
+// Inserts `stage` in the publish pipeline, right after the built-in stage named `after`
+// (e.g. `ValidateStage`). Stages added after the same built-in stage run in the order they were added.
+// Concurrent publishes run the stage concurrently, each with its own `PublishJob`.
+func WithPublishStage(after string, stage PublishStage) Option {
+	return func(pn *pushNotifications) {
+		pn.customStages = append(pn.customStages, namedStage{name: after, stage: stage})
+	}
+}
+
+// Calls `recorder` with every publish once the pipeline has finished with it.
+// Concurrent publishes call it concurrently.
+func WithPublishRecorder(recorder PublishRecorder) Option {
+	return func(pn *pushNotifications) {
+		pn.recorders = append(pn.recorders, recorder)
+	}
+}
+
+// Sets how failed requests are retried. By default requests are not retried.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(pn *pushNotifications) {
+		// copied, so that changing the caller's map can't race with calls in flight
+		retryable := make(map[ErrorClass]bool, len(policy.Retryable))
+		for class, retry := range policy.Retryable {
+			retryable[class] = retry
+		}
+		policy.Retryable = retryable
+		pn.retryPolicy = policy
+	}
+}
+
+// Limits how many bytes of a response from the Beams service are read, 1MiB by default,
+// so that a misbehaving proxy can't exhaust memory. Larger responses fail with `ErrResponseTooLarge`.
+func WithMaxResponseSize(bytes int64) Option {
+	return func(pn *pushNotifications) {
+		pn.maxResponseSize = bytes
+	}
+}
+
+// An option that applies to a single call, such as a publish
+type CallOption func(*callOptions)
+
+type callOptions struct {
+	timeout          time.Duration
+	concurrency      int
+	checkpoint       BulkCheckpoint
+	metadata         map[string]string
+	retryPolicy      *RetryPolicy
+	resultsWriter    io.Writer
+	progress         func(done, total int, lastErr error)
+	ctx              context.Context
+	notificationType string
+	experimentId     string
+	transactional    bool
+}
+
+func newCallOptions(options []CallOption) callOptions {
+	callOpts := callOptions{}
+	for _, option := range options {
+		option(&callOpts)
+	}
+	return callOpts
+}
+
+// Returns the context of the call, which is never done if none was given
+func (callOpts callOptions) context() context.Context {
+	if callOpts.ctx == nil {
+		return context.Background()
+	}
+	return callOpts.ctx
+}
+
+// Cancels the call when `ctx` is done, aborting the request in flight and any retries, and returning `ctx.Err()`.
+// A request aborted in flight may still have been received by the service.
+// Bulk operations stop publishing chunks, and return the chunks started before they stopped.
+func WithContext(ctx context.Context) CallOption {
+	return func(callOpts *callOptions) {
+		callOpts.ctx = ctx
+	}
+}
+
+// Attaches caller metadata, such as a campaign id or an experiment name, to the publish.
+// It is not sent to Beams, but is passed to publish hooks and recorders and kept in the `History`.
+func WithMetadata(metadata map[string]string) CallOption {
+	return func(callOpts *callOptions) {
+		callOpts.metadata = metadata
+	}
+}
+
+// Overrides the request timeout set with `WithRequestTimeout` for every attempt of this call
+func WithCallTimeout(timeout time.Duration) CallOption {
+	return func(callOpts *callOptions) {
+		callOpts.timeout = timeout
+	}
+}
+
+// Adds a header to every request sent to the Beams service.
+// Headers set this way take precedence over the ones set by the SDK.
+func WithHeader(key, value string) Option {
+	return func(pn *pushNotifications) {
+		if pn.headers == nil {
+			pn.headers = http.Header{}
+		}
+		pn.headers.Add(key, value)
+	}
+}
+
+// Calls `headerFunc` on every request sent to the Beams service, after all other headers are set,
+// so that headers can be computed per request (e.g. short-lived proxy credentials)
+func WithHeaderFunc(headerFunc func(*http.Request)) Option {
+	return func(pn *pushNotifications) {
+		pn.headerFuncs = append(pn.headerFuncs, headerFunc)
+	}
+}
+
+// Replaces the transport used to send requests to the Beams service.
+// By default `http.DefaultTransport` is used.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(pn *pushNotifications) {
+		pn.httpClient.Transport = transport
+	}
+}
+
+// Wraps the transport used to send requests to the Beams service, e.g. to add tracing or egress policies.
+// Decorators are applied in the order given, on top of the transport set with `WithTransport`,
+// so the last one given sees each request first.
+func WithTransportDecorator(decorate func(http.RoundTripper) http.RoundTripper) Option {
+	return func(pn *pushNotifications) {
+		pn.transportDecorators = append(pn.transportDecorators, decorate)
+	}
+}
+
+// Caches the addresses the Beams hostname resolves to for `ttl`.
+// If a lookup fails after the cache expired, the previous addresses keep being used.
+func WithDNSCache(ttl time.Duration) Option {
+	return func(pn *pushNotifications) {
+		pn.dnsResolver().ttl = ttl
+	}
+}
+
+// Connects to the given IP addresses for `host` instead of resolving it with DNS
+func WithResolvedAddresses(host string, ips ...string) Option {
+	return func(pn *pushNotifications) {
+		pn.dnsResolver().static[host] = ips
+	}
+}
+
+// Uses `resolver` instead of `net.DefaultResolver` to resolve the Beams hostname
+func WithResolver(resolver *net.Resolver) Option {
+	return func(pn *pushNotifications) {
+		pn.dnsResolver().lookupHost = resolver.LookupHost
+	}
+}
@@ -0,0 +1,147 @@
+package pushnotifications
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestOptions(t *testing.T) {
+	Convey("A Push Notifications Instance with custom headers", t, func() {
+		var lastHeader http.Header
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lastHeader = r.Header
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"publishId":"pub-123"}`))
+		}))
+		defer testServer.Close()
+
+		calls := 0
+		pn, err := New(testInstanceId, testSecretKey,
+			WithCustomBaseURL(testServer.URL),
+			WithHeader("X-Route", "eu"),
+			WithHeader("X-Route", "fallback"),
+			WithHeaderFunc(func(r *http.Request) {
+				calls++
+				r.Header.Set("Proxy-Authorization", "Bearer proxy-token")
+			}),
+		)
+		So(err, ShouldBeNil)
+
+		Convey("should send them on publishes", func() {
+			_, err := pn.PublishToInterests([]string{"hello"}, map[string]interface{}{})
+			So(err, ShouldBeNil)
+			So(lastHeader["X-Route"], ShouldResemble, []string{"eu", "fallback"})
+			So(lastHeader.Get("Proxy-Authorization"), ShouldEqual, "Bearer proxy-token")
+			So(lastHeader.Get("Authorization"), ShouldEqual, "Bearer "+testSecretKey)
+			So(calls, ShouldEqual, 1)
+		})
+
+		Convey("should send them when deleting users", func() {
+			err := pn.DeleteUser("user-1")
+			So(err, ShouldBeNil)
+			So(lastHeader.Get("X-Route"), ShouldEqual, "eu")
+			So(lastHeader.Get("Proxy-Authorization"), ShouldEqual, "Bearer proxy-token")
+		})
+	})
+	Convey("A Push Notifications Instance with a maximum response size", t, func() {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"publishId":"pub-123","padding":"` + strings.Repeat("x", 100) + `"}`))
+		}))
+		defer testServer.Close()
+
+		Convey("should read responses within the limit", func() {
+			pn, _ := New(testInstanceId, testSecretKey, WithCustomBaseURL(testServer.URL), WithMaxResponseSize(1024))
+			pubId, err := pn.PublishToInterests([]string{"hello"}, map[string]interface{}{})
+			So(err, ShouldBeNil)
+			So(pubId, ShouldEqual, "pub-123")
+		})
+
+		Convey("should fail on responses over the limit", func() {
+			pn, _ := New(testInstanceId, testSecretKey, WithCustomBaseURL(testServer.URL), WithMaxResponseSize(64))
+			_, err := pn.PublishToInterests([]string{"hello"}, map[string]interface{}{})
+			So(errors.Cause(err), ShouldEqual, ErrResponseTooLarge)
+
+			err = pn.DeleteUser("user-1")
+			So(errors.Cause(err), ShouldEqual, ErrResponseTooLarge)
+		})
+	})
+	Convey("A Push Notifications Instance with a custom transport", t, func() {
+		var trace []string
+		transport := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			trace = append(trace, "transport")
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{},
+				Body:       io.NopCloser(strings.NewReader(`{"publishId":"pub-123"}`)),
+				Request:    r,
+			}, nil
+		})
+		tracingDecorator := func(name string) func(http.RoundTripper) http.RoundTripper {
+			return func(next http.RoundTripper) http.RoundTripper {
+				return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+					trace = append(trace, name)
+					return next.RoundTrip(r)
+				})
+			}
+		}
+
+		Convey("should send requests through it", func() {
+			pn, _ := New(testInstanceId, testSecretKey, WithTransport(transport))
+			pubId, err := pn.PublishToInterests([]string{"hello"}, map[string]interface{}{})
+			So(err, ShouldBeNil)
+			So(pubId, ShouldEqual, "pub-123")
+			So(trace, ShouldResemble, []string{"transport"})
+		})
+
+		Convey("should apply decorators on top of it regardless of option order", func() {
+			pn, _ := New(testInstanceId, testSecretKey,
+				WithTransportDecorator(tracingDecorator("inner")),
+				WithTransport(transport),
+				WithTransportDecorator(tracingDecorator("outer")),
+			)
+			err := pn.DeleteUser("user-1")
+			So(err, ShouldBeNil)
+			So(trace, ShouldResemble, []string{"outer", "inner", "transport"})
+		})
+	})
+}
+
+func TestUserIdLength(t *testing.T) {
+	Convey("User id lengths", t, func() {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"publishId":"pub-123"}`))
+		}))
+		defer testServer.Close()
+		request := map[string]interface{}{"fcm": map[string]interface{}{"notification": map[string]interface{}{"title": "Hi"}}}
+		// 100 characters, 200 bytes
+		cyrillic := strings.Repeat("ж", 100)
+
+		Convey("should be counted in bytes by default", func() {
+			pn, _ := New(testInstanceId, testSecretKey, WithCustomBaseURL(testServer.URL))
+			_, err := pn.PublishToUsers([]string{cyrillic}, request)
+			So(Classify(err), ShouldEqual, Validation)
+			So(err.Error(), ShouldEndWith, "length too long (expected at most 164 bytes, got 200 bytes and 100 characters)")
+		})
+
+		Convey("should be counted in characters when asked to", func() {
+			pn, _ := New(testInstanceId, testSecretKey, WithCustomBaseURL(testServer.URL), WithUserIdLengthInRunes())
+			_, err := pn.PublishToUsers([]string{cyrillic}, request)
+			So(err, ShouldBeNil)
+			_, err = pn.GenerateToken(cyrillic)
+			So(err, ShouldBeNil)
+			So(pn.DeleteUser(cyrillic), ShouldBeNil)
+
+			result, err := pn.PublishToUsersFromReader(strings.NewReader(cyrillic+"\n"+cyrillic+"ж"+strings.Repeat("a", 64)+"\n"), CSV, request)
+			So(err, ShouldBeNil)
+			So(len(result.Rejected), ShouldEqual, 1)
+			So(result.Rejected[0].Err.Error(), ShouldEndWith, "(expected at most 164 characters, got 266 bytes and 165 characters)")
+		})
+	})
+}
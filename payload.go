@@ -0,0 +1,108 @@
+package pushnotifications
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// The error of a publish the Beams service refused as too large (413 Payload Too Large),
+// naming the platform section of the request to shrink
+type PayloadTooLargeError struct {
+	*APIError
+	// The largest platform section of the request, "apns", "fcm" or "web",
+	// or empty if the request has none
+	Section string
+	// Size in bytes of the section
+	SectionSize int
+}
+
+func (e *PayloadTooLargeError) Error() string {
+	if e.Section == "" {
+		return e.APIError.Error()
+	}
+	return fmt.Sprintf("%s (the largest section is %s, with %d bytes)", e.APIError.Error(), e.Section, e.SectionSize)
+}
+
+func newPayloadTooLargeError(apiError *APIError, body []byte) *PayloadTooLargeError {
+	tooLarge := &PayloadTooLargeError{APIError: apiError}
+	for platform, size := range platformSectionSizes(body) {
+		if size > tooLarge.SectionSize || (size == tooLarge.SectionSize && platform < tooLarge.Section) {
+			tooLarge.Section, tooLarge.SectionSize = platform, size
+		}
+	}
+	return tooLarge
+}
+
+// Returns the size in bytes of each platform section of an encoded request
+func platformSectionSizes(body []byte) map[string]int {
+	sections := map[string]json.RawMessage{}
+	if err := json.Unmarshal(body, &sections); err != nil {
+		return nil
+	}
+
+	sizes := map[string]int{}
+	for _, platform := range []string{"apns", "fcm", "web"} {
+		if section, ok := sections[platform]; ok {
+			sizes[platform] = len(section)
+		}
+	}
+	return sizes
+}
+
+// Sends the publish to `targets` with `body`. A publish to users the service refuses as too large
+// is sent to each half of the users in turn, splitting again as needed, as the user ids can be
+// what makes the request too large. Returns the ids of the publishes that succeeded, and the error
+// of the first half that failed, after which the rest of the users are not published to.
+func (pn *pushNotifications) sendSplitting(
+	ctx context.Context,
+	job *PublishJob,
+	url string,
+	policy RetryPolicy,
+	targets []string,
+	body []byte,
+) ([]string, error) {
+	var publishId string
+	err := pn.retryWith(ctx, policy, func() (err error) {
+		job.Attempts++
+		publishId, job.RequestId, err = pn.attemptPublish(ctx, url, body, job.callOpts)
+		return err
+	})
+	if err == nil {
+		return []string{publishId}, nil
+	}
+	if job.Operation != publishToUsersOperation || len(targets) < 2 || Classify(err) != PayloadTooLarge {
+		return nil, err
+	}
+
+	half := len(targets) / 2
+	publishIds := []string{}
+	for i, users := range [][]string{targets[:half], targets[half:]} {
+		halfBody, err := replaceTargets(body, targetsKey(job.Operation), pn.userIds(users))
+		if err == nil {
+			var halfIds []string
+			halfIds, err = pn.sendSplitting(ctx, job, url, policy, users, halfBody)
+			publishIds = append(publishIds, halfIds...)
+		}
+		if err != nil {
+			return publishIds, errors.Wrapf(err, "Failed to publish half %d of a publish split for being too large", i+1)
+		}
+	}
+	return publishIds, nil
+}
+
+// Returns the encoded request with its targets replaced
+func replaceTargets(body []byte, key string, targets []string) ([]byte, error) {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode the publish request to split it")
+	}
+	targetsBytes, err := json.Marshal(targets)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to marshal the publish request JSON body")
+	}
+	fields[key] = targetsBytes
+	return json.Marshal(fields)
+}
@@ -0,0 +1,125 @@
+package pushnotifications
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPayloadTooLarge(t *testing.T) {
+	Convey("A publish refused as too large", t, func() {
+		maxUsers := 2
+		published := [][]string{}
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body := struct{ Users []string }{}
+			json.NewDecoder(r.Body).Decode(&body)
+			tooLargeAlone := len(body.Users) == 1 && body.Users[0] == "u-too-large"
+			if !strings.HasSuffix(r.URL.Path, "/users") || len(body.Users) > maxUsers || tooLargeAlone {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				w.Write([]byte(`{"error":"Payload Too Large","description":"Request body too large"}`))
+				return
+			}
+			published = append(published, body.Users)
+			w.Write([]byte(fmt.Sprintf(`{"publishId":"pub-%d"}`, len(published))))
+		}))
+		defer testServer.Close()
+
+		events := []PublishEvent{}
+		pn, _ := New(testInstanceId, testSecretKey,
+			WithCustomBaseURL(testServer.URL),
+			WithPublishHook(func(event PublishEvent) { events = append(events, event) }))
+		request := map[string]interface{}{
+			"apns": map[string]interface{}{"aps": map[string]interface{}{"alert": "Hi"}},
+			"fcm":  map[string]interface{}{"notification": map[string]interface{}{"title": "Hi", "body": "Hello"}},
+		}
+
+		// users sampled in, whose first half alone would be sampled out
+		users := []string{}
+		for i := 0; len(users) == 0; i++ {
+			candidate := []string{fmt.Sprintf("u%d", i), "u-a", "u-b", "u-c"}
+			if sampled(candidate, 0.5) && !sampled(candidate[:2], 0.5) {
+				users = candidate
+			}
+		}
+
+		Convey("to users should be sent in requests to halves of the users, as one publish", func() {
+			validated := 0
+			pn, _ := New(testInstanceId, testSecretKey,
+				WithCustomBaseURL(testServer.URL),
+				WithSampling(0.5),
+				WithPublishStage(ValidateStage, PublishStageFunc(func(job *PublishJob) error {
+					validated++
+					return nil
+				})),
+				WithPublishHook(func(event PublishEvent) { events = append(events, event) }))
+
+			response, err := pn.PublishToUsersWithResponse(users, request)
+			So(err, ShouldBeNil)
+			So(response.PublishId, ShouldEqual, "pub-1")
+			So(response.Attempts, ShouldEqual, 3)
+			So(published, ShouldResemble, [][]string{users[:2], users[2:]})
+
+			So(validated, ShouldEqual, 1)
+			So(len(events), ShouldEqual, 1)
+			So(events[0].Outcome, ShouldEqual, "success")
+			So(events[0].TargetCount, ShouldEqual, 4)
+			So(events[0].Warnings, ShouldResemble, []Warning{{
+				Code:    PayloadSizeWarning,
+				Message: "The publish was refused as too large, and split in publishes pub-1, pub-2",
+			}})
+		})
+
+		Convey("to users should not return a publish id when a half fails", func() {
+			maxUsers = 0
+			response, err := pn.PublishToUsersWithResponse(users[:2], request)
+			So(err, ShouldNotBeNil)
+			So(response.PublishId, ShouldBeEmpty)
+			So(response.Attempts, ShouldEqual, 2)
+			So(response.Warnings, ShouldBeEmpty)
+		})
+
+		Convey("to users should report the halves delivered before one failed", func() {
+			maxUsers = 1
+			response, err := pn.PublishToUsersWithResponse([]string{"u1", "u-too-large", "u3"}, request)
+			So(err.Error(), ShouldStartWith, "Failed to publish half 2 of a publish split for being too large")
+			So(response.PublishId, ShouldBeEmpty)
+			So(published, ShouldResemble, [][]string{{"u1"}})
+			So(response.Warnings, ShouldResemble, []Warning{{
+				Code:    PayloadSizeWarning,
+				Message: "The publish was refused as too large, split, and only delivered in publishes pub-1",
+			}})
+		})
+
+		Convey("to a single user should fail with the largest section of the request", func() {
+			maxUsers = 0
+			_, err := pn.PublishToUsers([]string{"u1", "u2"}, request)
+			So(Classify(err), ShouldEqual, PayloadTooLarge)
+			So(err.Error(), ShouldStartWith, "Failed to publish half 1 of a publish split for being too large")
+
+			tooLarge, ok := errors.Cause(err).(*PayloadTooLargeError)
+			So(ok, ShouldBeTrue)
+			So(tooLarge.StatusCode, ShouldEqual, http.StatusRequestEntityTooLarge)
+			So(tooLarge.Section, ShouldEqual, "fcm")
+			So(tooLarge.SectionSize, ShouldEqual, 46)
+		})
+
+		Convey("to interests should fail with the largest section of the request", func() {
+			_, err := pn.PublishToInterests([]string{"news"}, request)
+			So(Classify(err), ShouldEqual, PayloadTooLarge)
+			So(err.Error(), ShouldEndWith,
+				"Payload Too Large: Request body too large (the largest section is fcm, with 46 bytes)")
+			So(len(events), ShouldEqual, 1)
+		})
+
+		Convey("without platform sections should fail with the error of the service", func() {
+			_, err := pn.PublishRawToInterests([]string{"news"}, json.RawMessage(`{"custom":{}}`))
+			So(err.Error(), ShouldEndWith, "Payload Too Large: Request body too large")
+		})
+	})
+}
@@ -0,0 +1,179 @@
+package pushnotifications
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// A publish request whose strings are `text/template` templates, e.g. "Hi {{.name}}",
+// rendered for each user with their own variables
+type Template struct {
+	request map[string]interface{}
+}
+
+// Parses the strings of the request as templates.
+// Returns a non-nil `error` naming the first string that is not a valid template.
+func NewTemplate(request map[string]interface{}) (*Template, error) {
+	parsed, err := parseTemplates("", request)
+	if err != nil {
+		return nil, err
+	}
+	return &Template{request: parsed.(map[string]interface{})}, nil
+}
+
+func parseTemplates(path string, value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		if !strings.Contains(v, "{{") {
+			return v, nil
+		}
+		t, err := template.New(path).Option("missingkey=error").Parse(v)
+		return t, errors.Wrapf(err, "Invalid template at %s", path)
+	case map[string]interface{}:
+		parsed := make(map[string]interface{}, len(v))
+		for key, nested := range v {
+			nestedPath := key
+			if path != "" {
+				nestedPath = path + "." + key
+			}
+			var err error
+			if parsed[key], err = parseTemplates(nestedPath, nested); err != nil {
+				return nil, err
+			}
+		}
+		return parsed, nil
+	default:
+		return v, nil
+	}
+}
+
+// Returns the request rendered with the variables
+func (t *Template) Render(variables interface{}) (map[string]interface{}, error) {
+	rendered, err := render(t.request, variables)
+	if err != nil {
+		return nil, err
+	}
+	return rendered.(map[string]interface{}), nil
+}
+
+func render(value interface{}, variables interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case *template.Template:
+		buffer := &bytes.Buffer{}
+		if err := v.Execute(buffer, variables); err != nil {
+			return nil, errors.Wrapf(err, "Failed to render the template at %s", v.Name())
+		}
+		return buffer.String(), nil
+	case map[string]interface{}:
+		rendered := make(map[string]interface{}, len(v))
+		for key, nested := range v {
+			var err error
+			if rendered[key], err = render(nested, variables); err != nil {
+				return nil, err
+			}
+		}
+		return rendered, nil
+	default:
+		return v, nil
+	}
+}
+
+// Publishes the template rendered for each user with their variables, in as few publishes as possible:
+// users whose rendered requests are identical are published to together, up to `Limits.MaxUsers` at a time.
+// Users whose request fails to render are skipped and reported in the result.
+func PublishPersonalized(
+	pn UserPublisher,
+	t *Template,
+	variables map[string]interface{},
+	options ...CallOption,
+) (*BulkResult, error) {
+	userIds := make([]string, 0, len(variables))
+	for userId := range variables {
+		userIds = append(userIds, userId)
+	}
+	sort.Strings(userIds)
+
+	result := &BulkResult{}
+	groups := map[string][]string{}
+	requests := map[string]map[string]interface{}{}
+	order := []string{}
+	for _, userId := range userIds {
+		request, err := t.Render(variables[userId])
+		var encoded []byte
+		if err == nil {
+			// keys of maps are sorted when encoded, so identical requests encode identically
+			encoded, err = json.Marshal(request)
+		}
+		if err != nil {
+			result.Rejected = append(result.Rejected, RejectedUserId{UserId: userId, Err: err})
+			continue
+		}
+
+		key := string(encoded)
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+			requests[key] = request
+		}
+		groups[key] = append(groups[key], userId)
+	}
+
+	return publishGroups(pn, order, groups, requests, nil, result, options)
+}
+
+// Publishes the request of each group, in order, to its users, up to `Limits.MaxUsers` at a time.
+// The group's options, if any, are added to the options of its publishes.
+func publishGroups(
+	pn UserPublisher,
+	order []string,
+	groups map[string][]string,
+	requests map[string]map[string]interface{},
+	groupOptions map[string][]CallOption,
+	result *BulkResult,
+	options []CallOption,
+) (*BulkResult, error) {
+	chunkSize := limitsOf(pn).MaxUsers
+	total := 0
+	for _, key := range order {
+		total += (len(groups[key]) + chunkSize - 1) / chunkSize
+	}
+
+	callOpts := newCallOptions(options)
+	ctx := callOpts.context()
+	reporter := newChunkReporter(callOpts)
+	for _, key := range order {
+		users := groups[key]
+		publishOptions := options
+		if len(groupOptions[key]) > 0 {
+			publishOptions = append(append([]CallOption{}, options...), groupOptions[key]...)
+		}
+		for start := 0; start < len(users); start += chunkSize {
+			if err := ctx.Err(); err != nil {
+				return result, err
+			}
+			end := start + chunkSize
+			if end > len(users) {
+				end = len(users)
+			}
+			chunk := ChunkResult{Index: len(result.Chunks), Users: users[start:end]}
+			chunk.PublishId, chunk.Err = pn.PublishToUsers(chunk.Users, requests[key], publishOptions...)
+			result.Chunks = append(result.Chunks, chunk)
+			reporter.report(chunk, total)
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+	if err := reporter.err(); err != nil {
+		return result, err
+	}
+	if failed := result.Failed(); len(failed) > 0 {
+		return result, errors.Wrapf(failed[0].Err, "%d of %d chunks failed to publish", len(failed), len(result.Chunks))
+	}
+	return result, nil
+}
@@ -0,0 +1,75 @@
+package pushnotifications
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPersonalization(t *testing.T) {
+	Convey("A template", t, func() {
+		template, err := NewTemplate(map[string]interface{}{
+			"apns": map[string]interface{}{"aps": map[string]interface{}{
+				"alert": map[string]interface{}{"title": "Hi {{.name}}", "body": "Your order shipped"},
+				"badge": 1,
+			}},
+		})
+		So(err, ShouldBeNil)
+
+		Convey("should render strings with the variables", func() {
+			request, err := template.Render(map[string]string{"name": "Ada"})
+			So(err, ShouldBeNil)
+			So(request, ShouldResemble, map[string]interface{}{
+				"apns": map[string]interface{}{"aps": map[string]interface{}{
+					"alert": map[string]interface{}{"title": "Hi Ada", "body": "Your order shipped"},
+					"badge": 1,
+				}},
+			})
+		})
+
+		Convey("should fail to render without a variable", func() {
+			_, err := template.Render(map[string]string{})
+			So(err.Error(), ShouldContainSubstring, "Failed to render the template at apns.aps.alert.title")
+		})
+
+		Convey("should not be created from invalid templates", func() {
+			_, err := NewTemplate(map[string]interface{}{"web": map[string]interface{}{"title": "{{.name"}})
+			So(err.Error(), ShouldContainSubstring, "Invalid template at web.title")
+		})
+
+		Convey("published to users", func() {
+			bodies := []map[string]interface{}{}
+			testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				bodyBytes, _ := io.ReadAll(r.Body)
+				body := map[string]interface{}{}
+				json.Unmarshal(bodyBytes, &body)
+				bodies = append(bodies, body)
+				w.Write([]byte(`{"publishId":"pub-123"}`))
+			}))
+			defer testServer.Close()
+			pn, _ := New(testInstanceId, testSecretKey, WithCustomBaseURL(testServer.URL))
+
+			Convey("should publish once per distinct rendered request", func() {
+				result, err := PublishPersonalized(pn, template, map[string]interface{}{
+					"user-1": map[string]string{"name": "Ada"},
+					"user-2": map[string]string{"name": "Grace"},
+					"user-3": map[string]string{"name": "Ada"},
+					"user-4": map[string]string{},
+				})
+				So(err, ShouldBeNil)
+				So(len(result.Chunks), ShouldEqual, 2)
+				So(result.Chunks[0].Users, ShouldResemble, []string{"user-1", "user-3"})
+				So(result.Chunks[1].Users, ShouldResemble, []string{"user-2"})
+				So(result.Rejected[0].UserId, ShouldEqual, "user-4")
+
+				So(bodies[0]["users"], ShouldResemble, []interface{}{"user-1", "user-3"})
+				title := bodies[1]["apns"].(map[string]interface{})["aps"].(map[string]interface{})["alert"].(map[string]interface{})["title"]
+				So(title, ShouldEqual, "Hi Grace")
+			})
+		})
+	})
+}
@@ -0,0 +1,277 @@
+package pushnotifications
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Names of the built-in stages of the publish pipeline, in the order they run
+const (
+	// Checks the targets and the shape of the request
+	ValidateStage = "validate"
+	// Adds the targets to the request
+	EnrichStage = "enrich"
+	// Encodes the request to JSON, signing it if a `Signer` is set
+	EncodeStage = "encode"
+	// Sends the encoded request to the Beams service, retrying as the `RetryPolicy` allows
+	SendStage = "send"
+)
+
+var builtInStages = []string{ValidateStage, EnrichStage, EncodeStage, SendStage}
+
+// A publish on its way through the publish pipeline
+type PublishJob struct {
+	// "publish_to_interests" or "publish_to_users"
+	Operation string
+	// Interests or user ids the notification is published to, before any hashing of user ids
+	Targets []string
+	// The publish request. It is nil for raw publishes, which only have a `Body`.
+	Request map[string]interface{}
+	// The encoded publish request, set by the encode stage.
+	// Raw publishes start with the request as given, without its targets.
+	Body []byte
+	// Set by the send stage
+	PublishId string
+	// Id of the last request, as reported by the Beams service, for support
+	RequestId string
+	// Number of requests made by the send stage
+	Attempts int
+	// Time taken by the send stage, including backoff between attempts
+	Latency time.Duration
+	// Why the publish failed, set before recorders run
+	Err error
+	// Caller metadata set with `WithMetadata`, such as a campaign id
+	Metadata map[string]string
+	// Conditions that didn't stop the publish, added with `Warn`
+	Warnings []Warning
+
+	callOpts callOptions
+}
+
+// A step of the publish pipeline, such as a policy check or the scrubbing of personal data.
+// Returning a non-nil error stops the publish, and the error is returned to the caller.
+type PublishStage interface {
+	Process(job *PublishJob) error
+}
+
+// Adapts a function to the `PublishStage` interface
+type PublishStageFunc func(job *PublishJob) error
+
+func (f PublishStageFunc) Process(job *PublishJob) error {
+	return f(job)
+}
+
+// Receives every publish once the pipeline has finished with it, whether it succeeded or not
+type PublishRecorder interface {
+	Record(job *PublishJob)
+}
+
+type namedStage struct {
+	name  string
+	stage PublishStage
+}
+
+// Builds the pipeline from the built-in stages and the stages added with `WithPublishStage`
+func (pn *pushNotifications) buildPipeline() error {
+	builtIn := map[string]PublishStage{
+		ValidateStage: PublishStageFunc(pn.validateStage),
+		EnrichStage:   PublishStageFunc(pn.enrichStage),
+		EncodeStage:   PublishStageFunc(pn.encodeStage),
+		SendStage:     PublishStageFunc(pn.sendStage),
+	}
+
+	pn.pipeline = nil
+	for _, name := range builtInStages {
+		pn.pipeline = append(pn.pipeline, namedStage{name: name, stage: builtIn[name]})
+		for _, custom := range pn.customStages {
+			if custom.name == name {
+				pn.pipeline = append(pn.pipeline, namedStage{name: name, stage: custom.stage})
+			}
+		}
+	}
+	for _, custom := range pn.customStages {
+		if builtIn[custom.name] == nil {
+			return newValidationError("Unknown publish stage `%s`", custom.name)
+		}
+	}
+	return nil
+}
+
+func (pn *pushNotifications) publish(job *PublishJob) (string, error) {
+	if job.Metadata == nil {
+		job.Metadata = job.callOpts.metadata
+	}
+	job.Metadata = withMetadata(job.Metadata, NotificationTypeMetadataKey, job.callOpts.notificationType)
+	job.Metadata = withMetadata(job.Metadata, ExperimentMetadataKey, job.callOpts.experimentId)
+	for _, stage := range pn.pipeline {
+		if err := stage.stage.Process(job); err != nil {
+			job.Err = err
+			break
+		}
+	}
+
+	pn.record(job)
+	pn.handleWarnings(job)
+	for _, recorder := range pn.recorders {
+		recorder.Record(job)
+	}
+	return job.PublishId, job.Err
+}
+
+// Returns a copy of the metadata with the value under `key`, or the metadata itself if the value is empty
+func withMetadata(metadata map[string]string, key, value string) map[string]string {
+	if value == "" {
+		return metadata
+	}
+	copied := make(map[string]string, len(metadata)+1)
+	for k, v := range metadata {
+		copied[k] = v
+	}
+	copied[key] = value
+	return copied
+}
+
+func (pn *pushNotifications) validateStage(job *PublishJob) error {
+	if job.Operation == publishToInterestsOperation {
+		if pn.interestValidation == SanitizedInterests {
+			sanitizeInterests(job)
+		}
+		if err := validateInterests(job.Targets, pn.limits.MaxInterests); err != nil {
+			return err
+		}
+	} else if err := validateUsers(job.Targets, pn.limits.MaxUsers, pn.userIdLengthInRunes); err != nil {
+		return err
+	}
+
+	// raw publishes are decoded to be checked the same way, but are sent as they are
+	request := job.Request
+	if request == nil {
+		request = map[string]interface{}{}
+		if err := json.Unmarshal(job.Body, &request); err != nil {
+			return newValidationError("The publish request must be a JSON object")
+		}
+	}
+	if err := ValidateRequest(request); err != nil {
+		return err
+	}
+	for _, warning := range RequestWarnings(request) {
+		job.Warn(ContentWarning, "%s", warning)
+	}
+	return nil
+}
+
+func (pn *pushNotifications) enrichStage(job *PublishJob) error {
+	if job.Request != nil {
+		// the caller's request may be shared between publishes, so the targets are added to a copy
+		job.Request = copyRequest(job.Request)
+		job.Request[targetsKey(job.Operation)] = pn.requestTargets(job)
+	}
+	return nil
+}
+
+// Returns the targets as sent to the Beams service, with user ids hashed if `WithHashedUserIds` is set
+func (pn *pushNotifications) requestTargets(job *PublishJob) []string {
+	if job.Operation == publishToUsersOperation {
+		return pn.userIds(job.Targets)
+	}
+	return job.Targets
+}
+
+func (pn *pushNotifications) encodeStage(job *PublishJob) error {
+	var err error
+	if job.Request != nil {
+		job.Body, err = json.Marshal(job.Request)
+		if err != nil {
+			return errors.Wrap(err, "Failed to marshal the publish request JSON body")
+		}
+	} else {
+		job.Body, err = injectTargets(job.Body, targetsKey(job.Operation), pn.requestTargets(job))
+		if err != nil {
+			return err
+		}
+	}
+
+	if pn.signer != nil {
+		if job.Body, err = signRequestBody(pn.signer, job.Body); err != nil {
+			return err
+		}
+	}
+	warnPayloadSize(job, pn.limits.MaxPlatformPayloadSize)
+	return nil
+}
+
+func (pn *pushNotifications) sendStage(job *PublishJob) error {
+	url := pn.usersPublishURL()
+	if job.Operation == publishToInterestsOperation {
+		url = pn.interestsPublishURL()
+	}
+
+	policy := pn.retryPolicy
+	if job.callOpts.retryPolicy != nil {
+		policy = *job.callOpts.retryPolicy
+	}
+
+	start := time.Now()
+	var publishIds []string
+	err := pn.labeled(job.callOpts.context(), job.Operation, func(ctx context.Context) (err error) {
+		publishIds, err = pn.sendSplitting(ctx, job, url, policy, job.Targets, job.Body)
+		return err
+	})
+	job.Latency = time.Since(start)
+	switch {
+	// only the halves of a split publish can succeed when the publish fails
+	case err != nil && len(publishIds) > 0:
+		job.Warn(PayloadSizeWarning, "The publish was refused as too large, split, and only delivered in publishes %s",
+			strings.Join(publishIds, ", "))
+	case len(publishIds) > 1:
+		job.Warn(PayloadSizeWarning, "The publish was refused as too large, and split in publishes %s",
+			strings.Join(publishIds, ", "))
+	}
+	if err == nil && len(publishIds) > 0 {
+		job.PublishId = publishIds[0]
+	}
+	return err
+}
+
+// Reports publishes that reached the Beams service to the metrics, publish hooks and stats
+func (pn *pushNotifications) record(job *PublishJob) {
+	if job.Attempts == 0 {
+		return
+	}
+
+	pn.recordCall(job.Operation, job.Latency, job.Err)
+	event := PublishEvent{
+		InstanceId:  pn.InstanceId,
+		Operation:   job.Operation,
+		PublishId:   job.PublishId,
+		TargetCount: len(job.Targets),
+		Latency:     job.Latency,
+		Attempts:    job.Attempts,
+		Outcome:     outcomeOf(job.Err),
+		Err:         job.Err,
+		Metadata:    job.Metadata,
+		Warnings:    job.Warnings,
+	}
+	if job.Operation == publishToInterestsOperation {
+		event.Interests = job.Targets
+	}
+	pn.emitPublishEvent(event)
+	pn.updateStats(func(stats *Stats) {
+		if job.Err != nil {
+			stats.PublishesFailed++
+		} else {
+			stats.PublishesSent++
+		}
+	})
+}
+
+func targetsKey(operation string) string {
+	if operation == publishToInterestsOperation {
+		return "interests"
+	}
+	return "users"
+}
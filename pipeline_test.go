@@ -0,0 +1,126 @@
+package pushnotifications
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type recordingRecorder struct {
+	jobs []*PublishJob
+}
+
+func (r *recordingRecorder) Record(job *PublishJob) {
+	r.jobs = append(r.jobs, job)
+}
+
+func TestPublishPipeline(t *testing.T) {
+	Convey("A Push Notifications Instance with custom publish stages", t, func() {
+		var body map[string]interface{}
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			bodyBytes, _ := io.ReadAll(r.Body)
+			body = map[string]interface{}{}
+			json.Unmarshal(bodyBytes, &body)
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"publishId":"pub-123"}`))
+		}))
+		defer testServer.Close()
+
+		stages := []string{}
+		trace := func(name string) PublishStage {
+			return PublishStageFunc(func(job *PublishJob) error {
+				stages = append(stages, name)
+				return nil
+			})
+		}
+		policyErr := errors.New("Publishing to `banned` is not allowed")
+		policy := PublishStageFunc(func(job *PublishJob) error {
+			for _, target := range job.Targets {
+				if target == "banned" {
+					return policyErr
+				}
+			}
+			return nil
+		})
+		scrubber := PublishStageFunc(func(job *PublishJob) error {
+			if job.Request != nil {
+				delete(job.Request, "email")
+			}
+			return nil
+		})
+		recorder := &recordingRecorder{}
+
+		pn, err := New(testInstanceId, testSecretKey,
+			WithCustomBaseURL(testServer.URL),
+			WithPublishStage(SendStage, trace("after send")),
+			WithPublishStage(ValidateStage, trace("after validate")),
+			WithPublishStage(ValidateStage, policy),
+			WithPublishStage(EnrichStage, scrubber),
+			WithPublishRecorder(recorder),
+		)
+		So(err, ShouldBeNil)
+
+		Convey("should run custom stages after the built-in stage they were added to", func() {
+			publishId, err := pn.PublishToInterests([]string{"hello"}, map[string]interface{}{"email": "a@example.com"})
+			So(err, ShouldBeNil)
+			So(publishId, ShouldEqual, "pub-123")
+
+			So(stages, ShouldResemble, []string{"after validate", "after send"})
+			So(body, ShouldResemble, map[string]interface{}{"interests": []interface{}{"hello"}})
+		})
+
+		Convey("should leave the caller's request as it was", func() {
+			request := map[string]interface{}{"email": "a@example.com", "fcm": map[string]interface{}{}}
+			concurrentServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"publishId":"pub-123"}`))
+			}))
+			defer concurrentServer.Close()
+			concurrent, _ := New(testInstanceId, testSecretKey, WithCustomBaseURL(concurrentServer.URL))
+			done := make(chan error)
+			for _, target := range []string{"a", "b", "c", "d"} {
+				go func(target string) {
+					_, err := concurrent.PublishToUsers([]string{target}, request)
+					done <- err
+				}(target)
+			}
+			for i := 0; i < 4; i++ {
+				So(<-done, ShouldBeNil)
+			}
+			_, err := pn.PublishToInterests([]string{"hello"}, request)
+			So(err, ShouldBeNil)
+
+			So(request, ShouldResemble, map[string]interface{}{"email": "a@example.com", "fcm": map[string]interface{}{}})
+			So(body, ShouldResemble, map[string]interface{}{"interests": []interface{}{"hello"}, "fcm": map[string]interface{}{}})
+		})
+
+		Convey("should stop publishes rejected by a stage", func() {
+			_, err := pn.PublishToInterests([]string{"hello", "banned"}, map[string]interface{}{})
+			So(err, ShouldEqual, policyErr)
+			So(body, ShouldBeNil)
+			So(stages, ShouldResemble, []string{"after validate"})
+		})
+
+		Convey("should let recorders see every publish", func() {
+			pn.PublishRawToUsers([]string{"user-1"}, json.RawMessage(`{}`))
+			pn.PublishToInterests([]string{"banned"}, map[string]interface{}{})
+
+			So(len(recorder.jobs), ShouldEqual, 2)
+			So(recorder.jobs[0].Operation, ShouldEqual, "publish_to_users")
+			So(string(recorder.jobs[0].Body), ShouldEqual, `{"users":["user-1"]}`)
+			So(recorder.jobs[0].Attempts, ShouldEqual, 1)
+			So(recorder.jobs[1].Err, ShouldEqual, policyErr)
+			So(recorder.jobs[1].Attempts, ShouldEqual, 0)
+		})
+
+		Convey("should reject stages added after an unknown stage", func() {
+			_, err := New(testInstanceId, testSecretKey, WithPublishStage("transmit", policy))
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "Unknown publish stage `transmit`")
+		})
+	})
+}
@@ -0,0 +1,103 @@
+package pushnotifications
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Reasons of the suppressions made by `WithPreferences`
+var (
+	ErrOptedOut          = errors.New("The user opted out of notifications")
+	ErrCategoryMuted     = errors.New("The user muted the category of the notification")
+	ErrChannelNotAllowed = errors.New("The user allows none of the platforms of the notification")
+)
+
+// What notifications a user has consented to receive
+type Preferences struct {
+	// The user doesn't want any notification
+	OptedOut bool
+	// Platforms the user accepts notifications on, e.g. "apns". Nil allows every platform.
+	Channels []string
+	// Categories of notifications the user doesn't want, matched against the APNs `category`
+	// of the request (see `Notification.Category`)
+	MutedCategories []string
+}
+
+// Looks up the notification preferences of users, e.g. from a preference center
+type PreferenceResolver interface {
+	Preferences(userId string) (Preferences, error)
+}
+
+// Consults `resolver` before every publish to users, leaving out the users who opted out,
+// muted the category of the notification, or allow none of the platforms it is published on.
+// Each user left out is passed to `onSuppressed` (which may be nil).
+// Publishes where every user is left out fail with a `*SuppressedError`.
+//
+// As a single publish goes to every platform of its users, users who allow some but not all
+// of the platforms of a request are sent the notification on all of them.
+func WithPreferences(resolver PreferenceResolver, onSuppressed func(Suppression)) Option {
+	return WithPublishStage(ValidateStage, suppressionStage(onSuppressed, func(job *PublishJob) (suppressionCheck, error) {
+		platforms, category, err := describeRequest(job)
+		if err != nil {
+			return nil, err
+		}
+
+		return func(userId string, now time.Time) (*Suppression, error) {
+			preferences, err := resolver.Preferences(userId)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Failed to resolve the preferences of user `%s`", userId)
+			}
+			if reason := preferences.reject(platforms, category); reason != nil {
+				return &Suppression{UserId: userId, Reason: reason}, nil
+			}
+			return nil, nil
+		}, nil
+	}))
+}
+
+// Returns why the user doesn't want a notification for `platforms` in `category`, or nil if they do
+func (p Preferences) reject(platforms []string, category string) error {
+	if p.OptedOut {
+		return ErrOptedOut
+	}
+	for _, muted := range p.MutedCategories {
+		if category != "" && muted == category {
+			return ErrCategoryMuted
+		}
+	}
+	if p.Channels == nil {
+		return nil
+	}
+	for _, platform := range platforms {
+		for _, channel := range p.Channels {
+			if channel == platform {
+				return nil
+			}
+		}
+	}
+	return ErrChannelNotAllowed
+}
+
+// Returns the platforms a publish request has a section for, and its APNs category
+func describeRequest(job *PublishJob) ([]string, string, error) {
+	request := job.Request
+	if request == nil {
+		request = map[string]interface{}{}
+		if err := json.Unmarshal(job.Body, &request); err != nil {
+			return nil, "", newValidationError("The publish request must be a JSON object")
+		}
+	}
+
+	platforms := []string{}
+	for _, platform := range []string{"apns", "fcm", "web"} {
+		if _, ok := request[platform]; ok {
+			platforms = append(platforms, platform)
+		}
+	}
+	apns, _ := request["apns"].(map[string]interface{})
+	aps, _ := apns["aps"].(map[string]interface{})
+	category, _ := aps["category"].(string)
+	return platforms, category, nil
+}
@@ -0,0 +1,71 @@
+package pushnotifications
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type preferenceMap map[string]Preferences
+
+func (m preferenceMap) Preferences(userId string) (Preferences, error) {
+	return m[userId], nil
+}
+
+func TestPreferences(t *testing.T) {
+	Convey("A Push Notifications Instance enforcing user preferences", t, func() {
+		var body map[string]interface{}
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			bodyBytes, _ := io.ReadAll(r.Body)
+			body = map[string]interface{}{}
+			json.Unmarshal(bodyBytes, &body)
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"publishId":"pub-123"}`))
+		}))
+		defer testServer.Close()
+
+		suppressed := map[string]error{}
+		pn, err := New(testInstanceId, testSecretKey,
+			WithCustomBaseURL(testServer.URL),
+			WithPreferences(preferenceMap{
+				"opted-out":   {OptedOut: true},
+				"no-promos":   {MutedCategories: []string{"PROMO"}},
+				"web-only":    {Channels: []string{"web"}},
+				"ios-and-web": {Channels: []string{"apns", "web"}},
+			}, func(suppression Suppression) {
+				suppressed[suppression.UserId] = suppression.Reason
+			}),
+		)
+		So(err, ShouldBeNil)
+		users := []string{"anyone", "opted-out", "no-promos", "web-only", "ios-and-web"}
+
+		Convey("should leave out users who don't want the notification", func() {
+			request, _ := NewNotification("Sale", "50% off").Category(Category{Id: "PROMO"}).Request()
+			delete(request, "web")
+
+			_, err := pn.PublishToUsers(users, request)
+			So(err, ShouldBeNil)
+			So(body["users"], ShouldResemble, []interface{}{"anyone", "ios-and-web"})
+			So(suppressed, ShouldResemble, map[string]error{
+				"opted-out": ErrOptedOut,
+				"no-promos": ErrCategoryMuted,
+				"web-only":  ErrChannelNotAllowed,
+			})
+		})
+
+		Convey("should read the preferences of raw requests", func() {
+			_, err := pn.PublishRawToUsers(users, json.RawMessage(`{"web":{"notification":{"title":"Hi"}}}`))
+			So(err, ShouldBeNil)
+			So(body["users"], ShouldResemble, []interface{}{"anyone", "no-promos", "web-only", "ios-and-web"})
+		})
+
+		Convey("should fail when nobody wants the notification", func() {
+			_, err := pn.PublishToUsers([]string{"opted-out"}, map[string]interface{}{})
+			So(err, ShouldHaveSameTypeAs, &SuppressedError{})
+		})
+	})
+}
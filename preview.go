@@ -0,0 +1,129 @@
+package pushnotifications
+
+// An approximation of how a notification appears on one platform, for admin UIs and review workflows
+type NotificationPreview struct {
+	// "apns", "fcm" or "web"
+	Platform string
+	// Texts as displayed, shortened with an ellipsis where the platform typically cuts them off
+	Title    string
+	Subtitle string
+	Body     string
+	// Whether any of the texts had to be shortened
+	Truncated bool
+	// The badge count set on the app icon, nil if the badge is left unchanged
+	Badge    *int
+	Image    string
+	DeepLink string
+	// The APNs category, whose buttons are registered by the app rather than sent in the request
+	Category string
+	// Titles of the buttons shown on web notifications
+	Actions []string
+}
+
+// Returns a preview of the notification for every platform section of `request`, in the
+// order APNs, FCM, web. Requests built from a `Template` must be rendered first (see `Template.Preview`).
+func Preview(request map[string]interface{}) []NotificationPreview {
+	previews := []NotificationPreview{}
+	if apns, ok := request["apns"].(map[string]interface{}); ok {
+		previews = append(previews, previewAPNs(apns))
+	}
+	if fcm, ok := request["fcm"].(map[string]interface{}); ok {
+		previews = append(previews, previewFCM(fcm))
+	}
+	if web, ok := request["web"].(map[string]interface{}); ok {
+		previews = append(previews, previewWeb(web))
+	}
+	return previews
+}
+
+// Renders the template with `variables` and returns the preview of the rendered request
+func (t *Template) Preview(variables interface{}) ([]NotificationPreview, error) {
+	request, err := t.Render(variables)
+	if err != nil {
+		return nil, err
+	}
+	return Preview(request), nil
+}
+
+func previewAPNs(apns map[string]interface{}) NotificationPreview {
+	preview := NotificationPreview{Platform: "apns"}
+	aps, _ := apns["aps"].(map[string]interface{})
+	switch alert := aps["alert"].(type) {
+	case string:
+		preview.Body = alert
+	case map[string]interface{}:
+		preview.Title, _ = alert["title"].(string)
+		preview.Subtitle, _ = alert["subtitle"].(string)
+		preview.Body, _ = alert["body"].(string)
+	}
+	preview.Badge = previewBadge(aps["badge"])
+	preview.Category, _ = aps["category"].(string)
+
+	data, _ := apns["data"].(map[string]interface{})
+	if kind, _ := data[AttachmentTypeDataKey].(string); kind == "image" {
+		preview.Image, _ = data[AttachmentURLDataKey].(string)
+	}
+	preview.DeepLink, _ = data[DeepLinkDataKey].(string)
+	return preview.truncate()
+}
+
+func previewFCM(fcm map[string]interface{}) NotificationPreview {
+	preview := NotificationPreview{Platform: "fcm"}
+	notification, _ := fcm["notification"].(map[string]interface{})
+	preview.Title, _ = notification["title"].(string)
+	preview.Body, _ = notification["body"].(string)
+	preview.Image, _ = notification["image"].(string)
+
+	data, _ := fcm["data"].(map[string]interface{})
+	preview.DeepLink, _ = data[DeepLinkDataKey].(string)
+	return preview.truncate()
+}
+
+func previewWeb(web map[string]interface{}) NotificationPreview {
+	preview := NotificationPreview{Platform: "web"}
+	notification, _ := web["notification"].(map[string]interface{})
+	preview.Title, _ = notification["title"].(string)
+	preview.Body, _ = notification["body"].(string)
+	preview.Image, _ = notification["image"].(string)
+	preview.DeepLink, _ = notification["deep_link"].(string)
+
+	actions, _ := notification["actions"].([]interface{})
+	for _, action := range actions {
+		if action, ok := action.(map[string]interface{}); ok {
+			if title, ok := action["title"].(string); ok {
+				preview.Actions = append(preview.Actions, title)
+			}
+		}
+	}
+	return preview.truncate()
+}
+
+// Accepts the integers of requests built in Go and the float64 of decoded JSON
+func previewBadge(value interface{}) *int {
+	var badge int
+	switch v := value.(type) {
+	case int:
+		badge = v
+	case float64:
+		badge = int(v)
+	default:
+		return nil
+	}
+	return &badge
+}
+
+func (p NotificationPreview) truncate() NotificationPreview {
+	limits := platformTextLimits[p.Platform]
+	for _, text := range []struct {
+		value *string
+		max   int
+	}{{&p.Title, limits.title}, {&p.Subtitle, limits.subtitle}, {&p.Body, limits.body}} {
+		if *text.value == "" {
+			continue
+		}
+		truncated := Truncate(*text.value, text.max)
+		p.Truncated = p.Truncated || truncated != *text.value
+		*text.value = truncated
+	}
+	return p
+}
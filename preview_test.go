@@ -0,0 +1,64 @@
+package pushnotifications
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPreview(t *testing.T) {
+	Convey("Previewing a notification", t, func() {
+		Convey("should resolve what every platform displays", func() {
+			request, err := NewNotification("Your order shipped", "It will arrive on Monday").
+				Image("https://example.com/parcel.png").
+				DeepLink("https://example.com/orders/1").
+				Badge(3).
+				Category(Category{Id: "order", Actions: []Action{{Id: "track", Title: "Track"}}}).
+				Request()
+			So(err, ShouldBeNil)
+
+			previews := Preview(request)
+			So(len(previews), ShouldEqual, 3)
+
+			apns := previews[0]
+			So(apns.Platform, ShouldEqual, "apns")
+			So(apns.Title, ShouldEqual, "Your order shipped")
+			So(*apns.Badge, ShouldEqual, 3)
+			So(apns.Image, ShouldEqual, "https://example.com/parcel.png")
+			So(apns.DeepLink, ShouldEqual, "https://example.com/orders/1")
+			So(apns.Category, ShouldEqual, "order")
+
+			So(previews[1].Platform, ShouldEqual, "fcm")
+			So(previews[1].Body, ShouldEqual, "It will arrive on Monday")
+			So(previews[1].Badge, ShouldBeNil)
+
+			So(previews[2].Platform, ShouldEqual, "web")
+			So(previews[2].Actions, ShouldResemble, []string{"Track"})
+			So(previews[2].Truncated, ShouldBeFalse)
+		})
+
+		Convey("should shorten texts the platform cuts off", func() {
+			previews := Preview(map[string]interface{}{
+				"web":  map[string]interface{}{"notification": map[string]interface{}{"body": strings.Repeat("a", 200)}},
+				"apns": map[string]interface{}{"aps": map[string]interface{}{"alert": "Hello", "badge": float64(1)}},
+			})
+			So(previews[0].Body, ShouldEqual, "Hello")
+			So(*previews[0].Badge, ShouldEqual, 1)
+			So(previews[1].Truncated, ShouldBeTrue)
+			So(previews[1].Body, ShouldEqual, strings.Repeat("a", 119)+"…")
+		})
+
+		Convey("should render templates first", func() {
+			template, _ := NewTemplate(map[string]interface{}{
+				"fcm": map[string]interface{}{"notification": map[string]interface{}{"title": "Hi {{.name}}"}},
+			})
+			previews, err := template.Preview(map[string]interface{}{"name": "Ada"})
+			So(err, ShouldBeNil)
+			So(previews[0].Title, ShouldEqual, "Hi Ada")
+
+			_, err = template.Preview(map[string]interface{}{})
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,23 @@
+package pushnotifications
+
+import (
+	"context"
+	"runtime/pprof"
+)
+
+const (
+	instanceIdLabel = "beams_instance_id"
+	operationLabel  = "beams_operation"
+)
+
+// Runs `work` with pprof labels naming the instance and operation, so that CPU and heap
+// profiles attribute the time and memory spent talking to Beams.
+// The labelled context is passed on to `work`, for requests to carry the labels too.
+func (pn *pushNotifications) labeled(ctx context.Context, operation string, work func(ctx context.Context) error) error {
+	var err error
+	labels := pprof.Labels(instanceIdLabel, pn.InstanceId, operationLabel, operation)
+	pprof.Do(ctx, labels, func(ctx context.Context) {
+		err = work(ctx)
+	})
+	return err
+}
@@ -0,0 +1,40 @@
+package pushnotifications
+
+import (
+	"io"
+	"net/http"
+	"runtime/pprof"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestProfilingLabels(t *testing.T) {
+	Convey("A Push Notifications Instance", t, func() {
+		labels := []string{}
+		transport := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			instanceId, _ := pprof.Label(r.Context(), instanceIdLabel)
+			operation, _ := pprof.Label(r.Context(), operationLabel)
+			labels = append(labels, instanceId+"/"+operation)
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{},
+				Body:       io.NopCloser(strings.NewReader(`{"publishId":"pub-123"}`)),
+			}, nil
+		})
+		pn, _ := New(testInstanceId, testSecretKey, WithTransport(transport))
+
+		Convey("should label the work of every operation", func() {
+			pn.PublishToInterests([]string{"hello"}, map[string]interface{}{})
+			pn.PublishToUsers([]string{"user-1"}, map[string]interface{}{})
+			pn.DeleteUser("user-1")
+
+			So(labels, ShouldResemble, []string{
+				"i-123/publish_to_interests",
+				"i-123/publish_to_users",
+				"i-123/delete_user",
+			})
+		})
+	})
+}
@@ -0,0 +1,34 @@
+package pushnotifications
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Returns a pseudonym for `userId`: the hex encoded HMAC-SHA256 of the id keyed with `salt`.
+// The same salt and id always give the same pseudonym, so apps authenticating with
+// a token from `GenerateToken` match the ids published to, without the service seeing the original id.
+func HashUserId(salt []byte, userId string) string {
+	mac := hmac.New(sha256.New, salt)
+	mac.Write([]byte(userId))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (pn *pushNotifications) userId(userId string) string {
+	if pn.userIdSalt == nil {
+		return userId
+	}
+	return HashUserId(pn.userIdSalt, userId)
+}
+
+func (pn *pushNotifications) userIds(userIds []string) []string {
+	if pn.userIdSalt == nil {
+		return userIds
+	}
+	hashed := make([]string, len(userIds))
+	for i, userId := range userIds {
+		hashed[i] = HashUserId(pn.userIdSalt, userId)
+	}
+	return hashed
+}
@@ -0,0 +1,69 @@
+package pushnotifications
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHashedUserIds(t *testing.T) {
+	Convey("Hashing user ids", t, func() {
+		salt := []byte("pepper")
+
+		Convey("should be deterministic and depend on the salt", func() {
+			So(HashUserId(salt, "user-1"), ShouldEqual, HashUserId(salt, "user-1"))
+			So(HashUserId(salt, "user-1"), ShouldNotEqual, HashUserId(salt, "user-2"))
+			So(HashUserId([]byte("salt"), "user-1"), ShouldNotEqual, HashUserId(salt, "user-1"))
+			So(len(HashUserId(salt, "user-1")), ShouldEqual, 64)
+		})
+
+		Convey("with a Push Notifications Instance", func() {
+			var requestPath string
+			var body map[string]interface{}
+			testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requestPath = r.URL.Path
+				bodyBytes, _ := io.ReadAll(r.Body)
+				json.Unmarshal(bodyBytes, &body)
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(`{"publishId":"pub-123"}`))
+			}))
+			defer testServer.Close()
+
+			pn, err := New(testInstanceId, testSecretKey, WithCustomBaseURL(testServer.URL), WithHashedUserIds(salt))
+			So(err, ShouldBeNil)
+			hashed := HashUserId(salt, "user-1")
+
+			Convey("should publish to hashed user ids", func() {
+				_, err := pn.PublishToUsers([]string{"user-1"}, map[string]interface{}{})
+				So(err, ShouldBeNil)
+				So(body["users"], ShouldResemble, []interface{}{hashed})
+
+				_, err = pn.PublishRawToUsers([]string{"user-1"}, json.RawMessage(`{}`))
+				So(err, ShouldBeNil)
+				So(body["users"], ShouldResemble, []interface{}{hashed})
+			})
+
+			Convey("should delete the hashed user id", func() {
+				So(pn.DeleteUser("user-1"), ShouldBeNil)
+				So(requestPath, ShouldEqual, "/customer_api/v1/instances/"+testInstanceId+"/users/"+hashed)
+			})
+
+			Convey("should generate tokens for the hashed user id", func() {
+				token, err := pn.GenerateToken("user-1")
+				So(err, ShouldBeNil)
+
+				parsedToken, err := jwt.Parse(token["token"].(string), func(token *jwt.Token) (interface{}, error) {
+					return []byte(testSecretKey), nil
+				})
+				So(err, ShouldBeNil)
+				So(parsedToken.Claims.(jwt.MapClaims)["sub"], ShouldEqual, hashed)
+				So(token["user_id"], ShouldEqual, parsedToken.Claims.(jwt.MapClaims)["sub"])
+			})
+		})
+	})
+}
@@ -0,0 +1,196 @@
+package pushnotifications
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Metadata key set on replayed publishes, holding the publish id (if any) and time of the original publish
+const ReplayOfMetadataKey = "replay_of"
+
+// A publish as written to a `PublishLog`
+type LoggedPublish struct {
+	Time      time.Time `json:"time"`
+	Operation string    `json:"operation"`
+	// Interests or user ids, before any hashing of user ids
+	Targets []string `json:"targets"`
+	// The publish request as it was sent, or would have been sent
+	Body      json.RawMessage   `json:"body,omitempty"`
+	PublishId string            `json:"publish_id,omitempty"`
+	Outcome   string            `json:"outcome"`
+	Error     string            `json:"error,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+}
+
+// Writes every publish, whether it succeeded or not, as one JSON line to a writer (see `WithLogCodec`),
+// so that publishes can be audited and replayed with `Replay`. Add it with `WithPublishRecorder`.
+type PublishLog struct {
+	mutex  sync.Mutex
+	w      io.Writer
+	config publishLogConfig
+	key    *EncryptionKey
+	// Set when a publish could not be written
+	err error
+}
+
+// Returns a `PublishLog` writing to `w`, typically a file opened with `os.O_APPEND`
+func NewPublishLog(w io.Writer, options ...PublishLogOption) *PublishLog {
+	return &PublishLog{w: w, config: newPublishLogConfig(options)}
+}
+
+// Returns a `PublishLog` writing to `w` every publish encrypted with `key`, as an envelope made by
+// `EncryptData`, so that the requests and user ids it holds, which may be personal data, are not
+// stored in plaintext. Pass the key to `Replay` with `WithLogKeys` to read it back.
+// Returns a non-nil `error` if the key is not a valid 256-bit key.
+func NewEncryptedPublishLog(w io.Writer, key EncryptionKey, options ...PublishLogOption) (*PublishLog, error) {
+	if _, err := key.aead(); err != nil {
+		return nil, err
+	}
+	return &PublishLog{w: w, config: newPublishLogConfig(options), key: &key}, nil
+}
+
+func (l *PublishLog) Record(job *PublishJob) {
+	logged := LoggedPublish{
+		Time:      time.Now().UTC(),
+		Operation: job.Operation,
+		Targets:   job.Targets,
+		Body:      job.Body,
+		PublishId: job.PublishId,
+		Outcome:   outcomeOf(job.Err),
+		Metadata:  job.Metadata,
+	}
+	if job.Err != nil {
+		logged.Error = job.Err.Error()
+	}
+	if logged.Body == nil && job.Request != nil {
+		// the publish stopped before the request was encoded
+		logged.Body, _ = json.Marshal(job.Request)
+	}
+
+	var record interface{} = logged
+	var err error
+	if l.key != nil {
+		record, err = EncryptData(*l.key, logged)
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if err == nil {
+		err = l.config.writeRecord(l.w, record)
+	}
+	if err != nil && l.err == nil {
+		l.err = errors.Wrap(err, "Failed to write to the publish log")
+	}
+}
+
+// Returns the first error met writing to the log, if any
+func (l *PublishLog) Err() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.err
+}
+
+// Selects the logged publishes to replay. Zero fields match every publish.
+type ReplayFilter struct {
+	// Only publishes made at or after this time
+	From time.Time
+	// Only publishes made before this time
+	To time.Time
+	// Only publishes that failed
+	FailedOnly bool
+	// Only publishes of this operation, "publish_to_interests" or "publish_to_users"
+	Operation string
+}
+
+func (f ReplayFilter) Matches(logged LoggedPublish) bool {
+	switch {
+	case !f.From.IsZero() && logged.Time.Before(f.From):
+		return false
+	case !f.To.IsZero() && !logged.Time.Before(f.To):
+		return false
+	case f.FailedOnly && logged.Outcome == "success":
+		return false
+	case f.Operation != "" && logged.Operation != f.Operation:
+		return false
+	}
+	return true
+}
+
+// The outcome of replaying one logged publish
+type ReplayResult struct {
+	Original  LoggedPublish
+	PublishId string
+	Err       error
+}
+
+// Reads a publish log written by a `PublishLog` and publishes again those matching the filter,
+// to the same targets with the same request. Replayed publishes carry the `ReplayOfMetadataKey` metadata.
+// The log must be read with the codec it was written with, and the keys it was encrypted with, if any.
+// Returns a non-nil `error` if the log can't be read; failed replays are reported in the results.
+func Replay(pn PushNotifications, r io.Reader, filter ReplayFilter, options ...PublishLogOption) ([]ReplayResult, error) {
+	config := newPublishLogConfig(options)
+	decodeError := "Record %d of the publish log can't be decoded"
+	if config.lines() {
+		decodeError = "Line %d of the publish log is not valid JSON"
+	}
+
+	results := []ReplayResult{}
+	err := config.readRecords(r, func(number int, data []byte) error {
+		envelope := map[string]interface{}{}
+		if err := config.codec.Unmarshal(data, &envelope); err != nil {
+			return errors.Wrapf(err, decodeError, number)
+		}
+		logged := LoggedPublish{}
+		if _, encrypted := envelope["ciphertext"]; encrypted {
+			if err := DecryptData(config.keys, envelope, &logged); err != nil {
+				return errors.Wrapf(err, "Failed to decrypt record %d of the publish log", number)
+			}
+		} else if err := config.codec.Unmarshal(data, &logged); err != nil {
+			return errors.Wrapf(err, "Record %d of the publish log is not a logged publish", number)
+		}
+		if !filter.Matches(logged) {
+			return nil
+		}
+
+		result := ReplayResult{Original: logged}
+		result.PublishId, result.Err = replay(pn, logged)
+		results = append(results, result)
+		return nil
+	})
+	return results, err
+}
+
+func replay(pn PushNotifications, logged LoggedPublish) (string, error) {
+	// the targets are added again by the publish, hashed if need be
+	request := map[string]interface{}{}
+	decoder := json.NewDecoder(bytes.NewReader(logged.Body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&request); err != nil {
+		return "", errors.Wrap(err, "Failed to decode the logged publish request")
+	}
+	delete(request, targetsKey(logged.Operation))
+	body, err := json.Marshal(request)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to marshal the publish request JSON body")
+	}
+
+	metadata := map[string]string{}
+	for key, value := range logged.Metadata {
+		metadata[key] = value
+	}
+	metadata[ReplayOfMetadataKey] = logged.PublishId + "@" + logged.Time.Format(time.RFC3339Nano)
+
+	switch logged.Operation {
+	case publishToInterestsOperation:
+		return pn.PublishRawToInterests(logged.Targets, body, WithMetadata(metadata))
+	case publishToUsersOperation:
+		return pn.PublishRawToUsers(logged.Targets, body, WithMetadata(metadata))
+	default:
+		return "", newValidationError("Publishes of operation `%s` can't be replayed", logged.Operation)
+	}
+}
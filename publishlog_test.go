@@ -0,0 +1,142 @@
+package pushnotifications
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPublishLog(t *testing.T) {
+	Convey("A publish log", t, func() {
+		bodies := []map[string]interface{}{}
+		failInterest := "broken"
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			bodyBytes, _ := io.ReadAll(r.Body)
+			body := map[string]interface{}{}
+			json.Unmarshal(bodyBytes, &body)
+			bodies = append(bodies, body)
+			if strings.Contains(string(bodyBytes), failInterest) {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":"Invalid request","description":"broken"}`))
+				return
+			}
+			fmt.Fprintf(w, `{"publishId":"pub-%d"}`, len(bodies))
+		}))
+		defer testServer.Close()
+
+		buffer := &bytes.Buffer{}
+		log := NewPublishLog(buffer)
+		pn, _ := New(testInstanceId, testSecretKey, WithCustomBaseURL(testServer.URL), WithPublishRecorder(log))
+		request := func() map[string]interface{} {
+			return map[string]interface{}{"fcm": map[string]interface{}{"notification": map[string]interface{}{"title": "Hi"}}}
+		}
+
+		pn.PublishToUsers([]string{"user-1"}, request(), WithMetadata(map[string]string{"campaign": "spring"}))
+		pn.PublishToInterests([]string{"broken"}, request())
+		So(log.Err(), ShouldBeNil)
+
+		Convey("should write every publish as a JSON line", func() {
+			lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+			So(len(lines), ShouldEqual, 2)
+
+			logged := LoggedPublish{}
+			So(json.Unmarshal([]byte(lines[1]), &logged), ShouldBeNil)
+			So(logged.Operation, ShouldEqual, "publish_to_interests")
+			So(logged.Targets, ShouldResemble, []string{"broken"})
+			So(logged.Outcome, ShouldEqual, "Validation")
+			So(logged.Error, ShouldContainSubstring, "broken")
+		})
+
+		Convey("should replay the publishes matching a filter", func() {
+			failInterest = "nothing fails anymore"
+			results, err := Replay(pn, bytes.NewReader(buffer.Bytes()), ReplayFilter{FailedOnly: true})
+			So(err, ShouldBeNil)
+			So(len(results), ShouldEqual, 1)
+			So(results[0].Err, ShouldBeNil)
+			So(results[0].PublishId, ShouldEqual, "pub-3")
+			So(bodies[2], ShouldResemble, map[string]interface{}{
+				"fcm":       map[string]interface{}{"notification": map[string]interface{}{"title": "Hi"}},
+				"interests": []interface{}{"broken"},
+			})
+		})
+
+		Convey("should keep the metadata of replayed publishes", func() {
+			results, err := Replay(pn, bytes.NewReader(buffer.Bytes()), ReplayFilter{Operation: "publish_to_users"})
+			So(err, ShouldBeNil)
+			So(len(results), ShouldEqual, 1)
+			So(bodies[2]["users"], ShouldResemble, []interface{}{"user-1"})
+
+			replayed := LoggedPublish{}
+			lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+			json.Unmarshal([]byte(lines[2]), &replayed)
+			So(replayed.Metadata["campaign"], ShouldEqual, "spring")
+			So(replayed.Metadata[ReplayOfMetadataKey], ShouldStartWith, "pub-1@")
+		})
+
+		Convey("should filter publishes by time", func() {
+			results, err := Replay(pn, bytes.NewReader(buffer.Bytes()), ReplayFilter{From: time.Now().Add(time.Hour)})
+			So(err, ShouldBeNil)
+			So(len(results), ShouldEqual, 0)
+		})
+
+		Convey("should return an error for a malformed log", func() {
+			_, err := Replay(pn, strings.NewReader("{}\nnot json\n"), ReplayFilter{})
+			So(err.Error(), ShouldContainSubstring, "Line 2 of the publish log is not valid JSON")
+		})
+	})
+}
+
+func TestEncryptedPublishLog(t *testing.T) {
+	Convey("An encrypted publish log", t, func() {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"publishId":"pub-123"}`))
+		}))
+		defer testServer.Close()
+
+		key := EncryptionKey{Id: "2024-01", Secret: bytes.Repeat([]byte{7}, 32)}
+		buffer := &bytes.Buffer{}
+		log, err := NewEncryptedPublishLog(buffer, key)
+		So(err, ShouldBeNil)
+		pn, _ := New(testInstanceId, testSecretKey, WithCustomBaseURL(testServer.URL), WithPublishRecorder(log))
+		request := map[string]interface{}{"fcm": map[string]interface{}{"notification": map[string]interface{}{"title": "Your order"}}}
+		pn.PublishToUsers([]string{"alice@example.com"}, request)
+		So(log.Err(), ShouldBeNil)
+
+		Convey("should not store the publishes in plaintext", func() {
+			So(buffer.String(), ShouldNotContainSubstring, "alice")
+			So(buffer.String(), ShouldNotContainSubstring, "Your order")
+
+			envelope := map[string]interface{}{}
+			So(json.Unmarshal(buffer.Bytes(), &envelope), ShouldBeNil)
+			So(envelope["kid"], ShouldEqual, "2024-01")
+			So(envelope["alg"], ShouldEqual, "A256GCM")
+		})
+
+		Convey("should be replayed with the key", func() {
+			oldKey := EncryptionKey{Id: "2023-12", Secret: bytes.Repeat([]byte{1}, 32)}
+			results, err := Replay(pn, bytes.NewReader(buffer.Bytes()), ReplayFilter{}, WithLogKeys(oldKey, key))
+			So(err, ShouldBeNil)
+			So(len(results), ShouldEqual, 1)
+			So(results[0].Err, ShouldBeNil)
+			So(results[0].Original.Targets, ShouldResemble, []string{"alice@example.com"})
+		})
+
+		Convey("should not be replayed without the key", func() {
+			_, err := Replay(pn, bytes.NewReader(buffer.Bytes()), ReplayFilter{})
+			So(err.Error(), ShouldStartWith, "Failed to decrypt record 1 of the publish log: No encryption key with id `2024-01`")
+		})
+
+		Convey("should not be made with an invalid key", func() {
+			_, err := NewEncryptedPublishLog(buffer, EncryptionKey{Id: "short", Secret: []byte("secret")})
+			So(Classify(err), ShouldEqual, Validation)
+		})
+	})
+}
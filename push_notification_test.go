@@ -2,7 +2,7 @@ package pushnotifications
 
 import (
 	"fmt"
-	"io/ioutil"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
@@ -50,7 +50,7 @@ func TestPushNotifications(t *testing.T) {
 		So(pn, ShouldNotBeNil)
 
 		Convey("when publishing to interests", func() {
-			functions := map[string]func(interests []string, request map[string]interface{}) (publishId string, err error){
+			functions := map[string]func(interests []string, request map[string]interface{}, options ...CallOption) (publishId string, err error){
 				"PublishToInterests": pn.PublishToInterests,
 				"Publish":            pn.Publish, // this is a deprecated alias
 			}
@@ -89,7 +89,7 @@ func TestPushNotifications(t *testing.T) {
 					Convey("should fail if it contains invalid chars", func() {
 						pubId, err := publishToInterests([]string{`#not<>|ok`}, testPublishRequest)
 						So(pubId, ShouldEqual, "")
-						So(err.Error(), ShouldContainSubstring, "Interest `#not<>|ok` contains an forbidden character")
+						So(err.Error(), ShouldContainSubstring, "Interest `#not<>|ok` contains a forbidden character")
 					})
 
 					Convey("should fail if 101 interests are given", func() {
@@ -109,7 +109,7 @@ func TestPushNotifications(t *testing.T) {
 						var serverRequestHandler http.HandlerFunc = func(w http.ResponseWriter, r *http.Request) {} // no-op
 
 						successHttpHandler := func(w http.ResponseWriter, r *http.Request) {
-							lastHttpPayload, _ = ioutil.ReadAll(r.Body)
+							lastHttpPayload, _ = io.ReadAll(r.Body)
 							serverRequestHandler(w, r)
 						}
 						testServer := httptest.NewServer(http.HandlerFunc(successHttpHandler))
@@ -117,7 +117,7 @@ func TestPushNotifications(t *testing.T) {
 
 						pn.(*pushNotifications).baseEndpoint = testServer.URL
 
-						Convey("should return an error if the server 400 Bad Request response and contains invalid JSON", func() {
+						Convey("should return an error with the body if the server 400 Bad Request response is not JSON", func() {
 							serverRequestHandler = func(w http.ResponseWriter, r *http.Request) {
 								w.WriteHeader(http.StatusBadRequest)
 								w.Write([]byte(`{bad-json"}`))
@@ -125,7 +125,7 @@ func TestPushNotifications(t *testing.T) {
 
 							pubId, err := publishToInterests([]string{"hello"}, testPublishRequest)
 							So(pubId, ShouldEqual, "")
-							So(err.Error(), ShouldContainSubstring, "invalid JSON")
+							So(err.Error(), ShouldContainSubstring, `Bad Request: Unexpected response body "{bad-json\"}"`)
 						})
 
 						Convey("should return an error if the server responds with 400 Bad Request", func() {
@@ -218,7 +218,7 @@ func TestPushNotifications(t *testing.T) {
 				So(
 					err.Error(),
 					ShouldContainSubstring,
-					fmt.Sprintf("User Id ('%s') length too long (expected fewer than %d characters, got %d)", longerUserId, maxUserIdLength+1, len(longerUserId)),
+					fmt.Sprintf("User Id ('%s') length too long (expected at most %d bytes, got %d bytes and %d characters)", longerUserId, maxUserIdLength, len(longerUserId), len(longerUserId)),
 				)
 			})
 
@@ -281,7 +281,7 @@ func TestPushNotifications(t *testing.T) {
 				So(
 					err.Error(),
 					ShouldContainSubstring,
-					fmt.Sprintf("User Id ('%s') length too long (expected fewer than %d characters, got %d)", tooLong, maxUserIdLength, len(tooLong)),
+					fmt.Sprintf("User Id ('%s') length too long (expected at most %d bytes, got %d bytes and %d characters)", tooLong, maxUserIdLength, len(tooLong), len(tooLong)),
 				)
 			})
 
@@ -302,7 +302,7 @@ func TestPushNotifications(t *testing.T) {
 				var serverRequestHandler http.HandlerFunc = func(w http.ResponseWriter, r *http.Request) {} // no-op
 
 				successHttpHandler := func(w http.ResponseWriter, r *http.Request) {
-					lastHttpPayload, _ = ioutil.ReadAll(r.Body)
+					lastHttpPayload, _ = io.ReadAll(r.Body)
 					serverRequestHandler(w, r)
 				}
 				testServer := httptest.NewServer(http.HandlerFunc(successHttpHandler))
@@ -310,7 +310,7 @@ func TestPushNotifications(t *testing.T) {
 
 				pn.(*pushNotifications).baseEndpoint = testServer.URL
 
-				Convey("should return an error if the server returns a 400 Bad Request response and contains invalid JSON", func() {
+				Convey("should return an error with the body if the server returns a 400 Bad Request response that is not JSON", func() {
 					serverRequestHandler = func(w http.ResponseWriter, r *http.Request) {
 						w.WriteHeader(http.StatusBadRequest)
 						w.Write([]byte(`{bad-json"}`))
@@ -319,7 +319,7 @@ func TestPushNotifications(t *testing.T) {
 					pubId, err := pn.PublishToUsers([]string{"user-id-1"}, testPublishRequest)
 					So(pubId, ShouldEqual, "")
 					So(err, ShouldNotBeNil)
-					So(err.Error(), ShouldContainSubstring, "invalid JSON")
+					So(err.Error(), ShouldContainSubstring, `Bad Request: Unexpected response body "{bad-json\"}"`)
 				})
 
 				Convey("should return an error if the server responds with 400 Bad Request", func() {
@@ -348,6 +348,37 @@ func TestPushNotifications(t *testing.T) {
 					So(err.Error(), ShouldContainSubstring, "invalid JSON")
 				})
 
+				Convey("should succeed on any 2xx response", func() {
+					serverRequestHandler = func(w http.ResponseWriter, r *http.Request) {
+						w.WriteHeader(http.StatusCreated)
+						w.Write([]byte(`{"publishId": "pub-123"}`))
+					}
+
+					pubId, err := pn.PublishToUsers([]string{"user-id-1"}, testPublishRequest)
+					So(err, ShouldBeNil)
+					So(pubId, ShouldEqual, "pub-123")
+				})
+
+				Convey("should succeed without a publish id on a 2xx response without a body", func() {
+					serverRequestHandler = func(w http.ResponseWriter, r *http.Request) {
+						w.WriteHeader(http.StatusAccepted)
+					}
+
+					pubId, err := pn.PublishToUsers([]string{"user-id-1"}, testPublishRequest)
+					So(err, ShouldBeNil)
+					So(pubId, ShouldEqual, "")
+				})
+
+				Convey("should fail on responses outside the 2xx class", func() {
+					serverRequestHandler = func(w http.ResponseWriter, r *http.Request) {
+						w.WriteHeader(http.StatusMultipleChoices)
+					}
+
+					_, err := pn.PublishToUsers([]string{"user-id-1"}, testPublishRequest)
+					So(err, ShouldNotBeNil)
+					So(Classify(err), ShouldEqual, Unknown)
+				})
+
 				Convey("should return the publish id if the request is valid", func() {
 					serverRequestHandler = func(w http.ResponseWriter, r *http.Request) {
 						w.WriteHeader(http.StatusOK)
@@ -406,6 +437,14 @@ func TestPushNotifications(t *testing.T) {
 					So(err, ShouldNotBeNil)
 					So(err.Error(), ShouldContainSubstring, "Failed to publish notifications due to a network error")
 				})
+
+				Convey("should allow a single call more time than the request timeout", func() {
+					pn.(*pushNotifications).httpClient.Timeout = time.Nanosecond
+					// the server answers 200 OK without a body once it is done sleeping
+					_, err := pn.PublishToUsers([]string{"user-id-1"}, testPublishRequest, WithCallTimeout(time.Minute))
+					So(err, ShouldBeNil)
+					So(pn.(*pushNotifications).httpClient.Timeout, ShouldEqual, time.Nanosecond)
+				})
 			})
 		})
 
@@ -428,7 +467,7 @@ func TestPushNotifications(t *testing.T) {
 				So(
 					err.Error(),
 					ShouldContainSubstring,
-					fmt.Sprintf("User Id ('%s') length too long (expected fewer than %d characters, got %d)", s+"a", maxUserIdLength+1, len(s)+1),
+					fmt.Sprintf("User Id ('%s') length too long (expected at most %d bytes, got %d bytes and %d characters)", s+"a", maxUserIdLength, len(s)+1, len(s)+1),
 				)
 			})
 
@@ -448,7 +487,7 @@ func TestPushNotifications(t *testing.T) {
 				var serverRequestHandler http.HandlerFunc = func(w http.ResponseWriter, r *http.Request) {} // no-op
 
 				successHttpHandler := func(w http.ResponseWriter, r *http.Request) {
-					lastHttpPayload, _ = ioutil.ReadAll(r.Body)
+					lastHttpPayload, _ = io.ReadAll(r.Body)
 					serverRequestHandler(w, r)
 				}
 				testServer := httptest.NewServer(http.HandlerFunc(successHttpHandler))
@@ -456,7 +495,7 @@ func TestPushNotifications(t *testing.T) {
 
 				pn.(*pushNotifications).baseEndpoint = testServer.URL
 
-				Convey("should return an error if the server returns a 400 Bad Request response and contains invalid JSON", func() {
+				Convey("should return an error with the body if the server returns a 400 Bad Request response that is not JSON", func() {
 					serverRequestHandler = func(w http.ResponseWriter, r *http.Request) {
 						w.WriteHeader(http.StatusBadRequest)
 						w.Write([]byte(`{bad-json"}`))
@@ -464,7 +503,7 @@ func TestPushNotifications(t *testing.T) {
 
 					err := pn.DeleteUser("user-id-1")
 					So(err, ShouldNotBeNil)
-					So(err.Error(), ShouldContainSubstring, "invalid JSON")
+					So(err.Error(), ShouldContainSubstring, `Bad Request: Unexpected response body "{bad-json\"}"`)
 				})
 
 				Convey("should return an error if the server responds with 400 Bad Request", func() {
@@ -490,6 +529,14 @@ func TestPushNotifications(t *testing.T) {
 						So(string(lastHttpPayload), ShouldResemble, expectedHttpPayload)
 					}
 				})
+
+				Convey("should succeed on a 204 No Content response", func() {
+					serverRequestHandler = func(w http.ResponseWriter, r *http.Request) {
+						w.WriteHeader(http.StatusNoContent)
+					}
+
+					So(pn.DeleteUser("user-id-1"), ShouldBeNil)
+				})
 			})
 
 			Convey("given a slow server, it", func() {
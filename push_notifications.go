@@ -2,12 +2,14 @@ package pushnotifications
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"net/http"
 	"net/url"
 	"regexp"
+	"sync"
 	"time"
 	"unicode/utf8"
 
@@ -15,38 +17,124 @@ import (
 	"github.com/pkg/errors"
 )
 
-// The Pusher Push Notifications Server API client
-type PushNotifications interface {
+// Publishes notifications to devices subscribed to interests
+type InterestPublisher interface {
 	// Publishes notifications to all devices subscribed to at least 1 of the interests given
 	// Returns a non-empty `publishId` JSON string if successful; or a non-nil `error` otherwise.
-	PublishToInterests(interests []string, request map[string]interface{}) (publishId string, err error)
+	PublishToInterests(interests []string, request map[string]interface{}, options ...CallOption) (publishId string, err error)
 
 	// DEPRECATED. An alias for `PublishToInterests`
-	Publish(interests []string, request map[string]interface{}) (publishId string, err error)
+	Publish(interests []string, request map[string]interface{}, options ...CallOption) (publishId string, err error)
+
+	// Like `PublishToInterests`, returning the publish id along with the details of how the publish went.
+	// The response is not nil, even when the publish fails.
+	PublishToInterestsWithResponse(interests []string, request map[string]interface{}, options ...CallOption) (response *PublishResponse, err error)
+
+	// Like `PublishToInterests`, for a request that is already encoded as a JSON object.
+	// The request must not contain an `interests` field, and is validated like other requests.
+	PublishRawToInterests(interests []string, request json.RawMessage, options ...CallOption) (publishId string, err error)
+
+	// Like `PublishToInterests`, for any value that encodes to a JSON object, such as a struct
+	// with `apns`, `fcm` and `web` fields. The payload must not contain an `interests` field.
+	PublishPayloadToInterests(interests []string, payload interface{}, options ...CallOption) (publishId string, err error)
+}
 
+// Publishes notifications to the devices of users
+type UserPublisher interface {
 	// Publishes notifications to all devices associated with the given user ids
 	// Returns a non-empty `publishId` JSON string successful, or a non-nil `error` otherwise.
-	PublishToUsers(users []string, request map[string]interface{}) (publishId string, err error)
+	// A publish the service refuses as too large is sent in requests to halves of the users,
+	// and the publish id of the first is returned, with the others in a `PayloadSizeWarning`.
+	// When a half fails, the rest are not sent, and the warning names the publishes that were.
+	PublishToUsers(users []string, request map[string]interface{}, options ...CallOption) (publishId string, err error)
+
+	// Like `PublishToUsers`, returning the publish id along with the details of how the publish went.
+	// The response is not nil, even when the publish fails.
+	PublishToUsersWithResponse(users []string, request map[string]interface{}, options ...CallOption) (response *PublishResponse, err error)
+
+	// Like `PublishToUsers`, for a request that is already encoded as a JSON object.
+	// The request must not contain a `users` field, and is validated like other requests.
+	PublishRawToUsers(users []string, request json.RawMessage, options ...CallOption) (publishId string, err error)
+
+	// Like `PublishToUsers`, for any value that encodes to a JSON object, such as a struct
+	// with `apns`, `fcm` and `web` fields. The payload must not contain a `users` field.
+	PublishPayloadToUsers(users []string, payload interface{}, options ...CallOption) (publishId string, err error)
+
+	// Publishes notifications to users whose ids are read from `r`, in chunks of up to `Limits.MaxUsers` users (1000 by default).
+	// Invalid user ids are skipped and reported in the result. See `WithCheckpoint` to resume interrupted publishes.
+	// Returns a non-nil `error` if the input can't be read or any chunk fails to publish.
+	PublishToUsersFromReader(r io.Reader, format InputFormat, request map[string]interface{}, options ...CallOption) (result *BulkResult, err error)
+
+	// Publishes a latency-critical notification, such as a login code, to a single user right away,
+	// with a short timeout and few retries (see `WithTransactionalSLO`).
+	// Returns a non-empty `publishId` JSON string if successful, or a non-nil `error` otherwise.
+	PublishTransactional(userId string, request map[string]interface{}, options ...CallOption) (publishId string, err error)
+}
 
+// Authenticates users of the client SDKs
+type UserAuthenticator interface {
 	// Creates a signed JWT for a user id.
 	// Returns a signed JWT if successful, or a non-nil `error` otherwise.
+	// With `WithHashedUserIds`, the token is for the pseudonym of the user, which is returned under "user_id".
 	GenerateToken(userId string) (token map[string]interface{}, err error)
+}
 
+// Deletes users and their devices
+type UserDeleter interface {
 	// Contacts the Beams service to remove all the devices of the given user
 	// Return a non-nil `error` if there's a problem.
 	DeleteUser(userId string) (err error)
 }
 
+// The Pusher Push Notifications Server API client.
+// Code needing only part of it can depend on `InterestPublisher`, `UserPublisher`,
+// `UserAuthenticator`, `UserDeleter` or `Reporter` instead.
+//
+// A client is safe for concurrent use by multiple goroutines, and is meant to be created once
+// and shared. The hooks, stages and recorders it is given are called from the goroutines making
+// the calls, so they must be safe for concurrent use too.
+type PushNotifications interface {
+	InterestPublisher
+	UserPublisher
+	UserAuthenticator
+	UserDeleter
+	Reporter
+}
+
+// The operations of the client that change nothing, so that code such as a dashboard can be given
+// the client as a `Reporter` and depend on nothing else. They report what the client observes.
+type Reporter interface {
+	// Returns the API rate-limit quota reported by the most recent Beams response.
+	// The zero value is returned until the service has reported one.
+	Quota() Quota
+
+	// Returns the counters of publishes and requests made by this instance so far.
+	Stats() Stats
+}
+
+const (
+	defaultRequestTimeout         = time.Minute
+	defaultMaxResponseSize        = 1 << 20
+	defaultBaseEndpointFormat     = "https://%s.pushnotifications.pusher.com"
+	maxUserIdLength               = 164
+	maxNumUserIdsWhenPublishing   = 1000
+	maxNumInterestsWhenPublishing = 100
+	tokenTTL                      = 24 * time.Hour
+
+	publishToInterestsOperation = "publish_to_interests"
+	publishToUsersOperation     = "publish_to_users"
+	deleteUserOperation         = "delete_user"
+)
+
+// The rule interest names must follow, as enforced by the Beams service
 const (
-	defaultRequestTimeout       = time.Minute
-	defaultBaseEndpointFormat   = "https://%s.pushnotifications.pusher.com"
-	maxUserIdLength             = 164
-	maxNumUserIdsWhenPublishing = 1000
-	tokenTTL                    = 24 * time.Hour
+	// Interest names are made of ASCII letters, digits and the characters _-=@,.;
+	InterestPattern   = `^[a-zA-Z0-9_\-=@,.;]+$`
+	MaxInterestLength = 164
 )
 
 var (
-	interestValidationRegex = regexp.MustCompile(`^[a-zA-Z0-9_\-=@,.;]+$`)
+	interestValidationRegex = regexp.MustCompile(InterestPattern)
 )
 
 type pushNotifications struct {
@@ -55,18 +143,48 @@ type pushNotifications struct {
 
 	baseEndpoint string
 	httpClient   *http.Client
+
+	logger                Logger
+	metrics               Metrics
+	quotaWarningThreshold float64
+	retryPolicy           RetryPolicy
+	headers               http.Header
+	headerFuncs           []func(*http.Request)
+	transportDecorators   []func(http.RoundTripper) http.RoundTripper
+	resolver              *cachingResolver
+	publishHooks          []func(PublishEvent)
+	warningHandlers       []func(*PublishJob, Warning)
+	signer                Signer
+	userIdSalt            []byte
+	customStages          []namedStage
+	recorders             []PublishRecorder
+	pipeline              []namedStage
+	maxResponseSize       int64
+	transactionalSLO      time.Duration
+	interestValidation    InterestValidation
+	userIdLengthInRunes   bool
+	limits                Limits
+
+	quotaMutex sync.Mutex
+	quota      Quota
+
+	statsMutex sync.Mutex
+	stats      Stats
 }
 
 // Creates a New `PushNotifications` instance.
 // Returns an non-nil error if `instanceId` or `secretKey` are empty
 func New(instanceId string, secretKey string, options ...Option) (PushNotifications, error) {
 	if instanceId == "" {
-		return nil, errors.New("Instance Id cannot be an empty string")
+		return nil, newValidationError("Instance Id cannot be an empty string")
 	}
 	if secretKey == "" {
-		return nil, errors.New("Secret Key cannot be an empty string")
+		return nil, newValidationError("Secret Key cannot be an empty string")
 	}
+	return newClient(instanceId, secretKey, options)
+}
 
+func newClient(instanceId string, secretKey string, options []Option) (*pushNotifications, error) {
 	pn := &pushNotifications{
 		InstanceId: instanceId,
 		SecretKey:  secretKey,
@@ -75,15 +193,59 @@ func New(instanceId string, secretKey string, options ...Option) (PushNotificati
 		httpClient: &http.Client{
 			Timeout: defaultRequestTimeout,
 		},
+		maxResponseSize: defaultMaxResponseSize,
+		limits:          DefaultLimits(),
 	}
 
 	for _, option := range options {
 		option(pn)
 	}
 
+	if err := pn.installResolver(); err != nil {
+		return nil, err
+	}
+	if err := pn.buildPipeline(); err != nil {
+		return nil, err
+	}
+
+	if len(pn.transportDecorators) > 0 {
+		transport := pn.httpClient.Transport
+		if transport == nil {
+			transport = http.DefaultTransport
+		}
+		for _, decorate := range pn.transportDecorators {
+			transport = decorate(transport)
+		}
+		pn.httpClient.Transport = transport
+	}
+
 	return pn, nil
 }
 
+func (pn *pushNotifications) httpClientFor(callOpts callOptions) *http.Client {
+	if callOpts.timeout <= 0 {
+		return pn.httpClient
+	}
+
+	httpClient := *pn.httpClient
+	httpClient.Timeout = callOpts.timeout
+	return &httpClient
+}
+
+func (pn *pushNotifications) setHeaders(httpReq *http.Request) {
+	httpReq.Header.Set("Authorization", "Bearer "+pn.SecretKey)
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("X-Pusher-Library", "pusher-push-notifications-go "+sdkVersion)
+
+	for key, values := range pn.headers {
+		// copied, so that header funcs adding values don't append to the client's
+		httpReq.Header[key] = append([]string(nil), values...)
+	}
+	for _, headerFunc := range pn.headerFuncs {
+		headerFunc(httpReq)
+	}
+}
+
 type publishResponse struct {
 	PublishId string `json:"publishId"`
 }
@@ -93,19 +255,33 @@ type errorResponse struct {
 	Description string `json:"description"`
 }
 
+// Returned when a response from the Beams service is larger than the limit set with `WithMaxResponseSize`
+var ErrResponseTooLarge = errors.New("The response is larger than the maximum response size")
+
+// Reads the whole of `body`, failing with `ErrResponseTooLarge` rather than reading more than `limit` bytes
+func readResponse(body io.Reader, limit int64) ([]byte, error) {
+	responseBytes, err := io.ReadAll(io.LimitReader(body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(responseBytes)) > limit {
+		return nil, ErrResponseTooLarge
+	}
+	return responseBytes, nil
+}
+
 func (pn *pushNotifications) GenerateToken(userId string) (map[string]interface{}, error) {
 	if len(userId) == 0 {
-		return nil, errors.New("User Id cannot be empty")
+		return nil, newValidationError("User Id cannot be empty")
 	}
 
-	if len(userId) > maxUserIdLength {
-		return nil, errors.Errorf(
-			"User Id ('%s') length too long (expected fewer than %d characters, got %d)",
-			userId, maxUserIdLength+1, len(userId))
+	if err := checkUserIdLength(userId, pn.userIdLengthInRunes); err != nil {
+		return nil, err
 	}
 
+	subject := pn.userId(userId)
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub": userId,
+		"sub": subject,
 		"exp": time.Now().Add(tokenTTL).Unix(),
 		"iss": "https://" + pn.InstanceId + ".pushnotifications.pusher.com",
 	})
@@ -118,156 +294,194 @@ func (pn *pushNotifications) GenerateToken(userId string) (map[string]interface{
 	tokenMap := map[string]interface{}{
 		"token": tokenString,
 	}
+	if subject != userId {
+		// the client SDKs must set the user id the token is for, which is the pseudonym
+		tokenMap["user_id"] = subject
+	}
 
 	return tokenMap, nil
 }
 
 // Deprecated: Use PublishToInterests instead
-func (pn *pushNotifications) Publish(interests []string, request map[string]interface{}) (string, error) {
-	return pn.PublishToInterests(interests, request)
+func (pn *pushNotifications) Publish(interests []string, request map[string]interface{}, options ...CallOption) (string, error) {
+	return pn.PublishToInterests(interests, request, options...)
+}
+
+func (pn *pushNotifications) PublishToInterests(interests []string, request map[string]interface{}, options ...CallOption) (string, error) {
+	return pn.publish(&PublishJob{
+		Operation: publishToInterestsOperation,
+		Targets:   interests,
+		Request:   request,
+		callOpts:  newCallOptions(options),
+	})
+}
+
+func (pn *pushNotifications) interestsPublishURL() string {
+	return fmt.Sprintf(pn.baseEndpoint+"/publish_api/v1/instances/%s/publishes", pn.InstanceId)
 }
 
-func (pn *pushNotifications) PublishToInterests(interests []string, request map[string]interface{}) (string, error) {
+func validateInterests(interests []string, maxInterests int) error {
 	if len(interests) == 0 {
 		// this request was not very interesting :/
-		return "", errors.New("No interests were supplied")
+		return newValidationError("No interests were supplied")
 	}
 
-	if len(interests) > 100 {
-		return "",
-			errors.Errorf("Too many interests supplied (%d): API only supports up to 100", len(interests))
+	if len(interests) > maxInterests {
+		return newValidationError(
+			"Too many interests supplied (%d): API only supports up to %d", len(interests), maxInterests)
 	}
 
 	for _, interest := range interests {
 		if len(interest) == 0 {
-			return "", errors.New("An empty interest name is not valid")
+			return newValidationError("An empty interest name is not valid")
 		}
 
-		if len(interest) > 164 {
-			return "",
-				errors.Errorf("Interest length is %d which is over 164 characters", len(interest))
+		if len(interest) > MaxInterestLength {
+			return newValidationError("Interest length is %d which is over %d characters", len(interest), MaxInterestLength)
 		}
 
 		if !interestValidationRegex.MatchString(interest) {
-			return "",
-				errors.Errorf(
-					"Interest `%s` contains an forbidden character: "+
-						"Allowed characters are: ASCII upper/lower-case letters, "+
-						"numbers or one of _-=@,.:",
-					interest)
+			return newValidationError(
+				"Interest `%s` contains a forbidden character: "+
+					"Allowed characters are: ASCII upper/lower-case letters, "+
+					"numbers or one of _-=@,.;",
+				interest)
 		}
 	}
-	// TODO: don't mutate `request`
-	request["interests"] = interests
-	bodyRequestBytes, err := json.Marshal(request)
-	if err != nil {
-		return "", errors.Wrap(err, "Failed to marshal the publish request JSON body")
-	}
 
-	URL := fmt.Sprintf(pn.baseEndpoint+"/publish_api/v1/instances/%s/publishes", pn.InstanceId)
-	return pn.publishToAPI(URL, bodyRequestBytes)
+	return nil
+}
+
+func (pn *pushNotifications) PublishToUsers(users []string, request map[string]interface{}, options ...CallOption) (string, error) {
+	return pn.publish(&PublishJob{
+		Operation: publishToUsersOperation,
+		Targets:   users,
+		Request:   request,
+		callOpts:  newCallOptions(options),
+	})
 }
 
-func (pn *pushNotifications) PublishToUsers(users []string, request map[string]interface{}) (string, error) {
+func (pn *pushNotifications) usersPublishURL() string {
+	return fmt.Sprintf("%s/publish_api/v1/instances/%s/publishes/users", pn.baseEndpoint, pn.InstanceId)
+}
+
+func validateUsers(users []string, maxUsers int, lengthInRunes bool) error {
 	if len(users) == 0 {
-		return "", errors.New("Must supply at least one user id")
+		return newValidationError("Must supply at least one user id")
 	}
-	if len(users) > maxNumUserIdsWhenPublishing {
-		return "", errors.New(
-			fmt.Sprintf("Too many user ids supplied. API supports up to %d, got %d", maxNumUserIdsWhenPublishing, len(users)),
-		)
+	if len(users) > maxUsers {
+		return newValidationError(
+			"Too many user ids supplied. API supports up to %d, got %d", maxUsers, len(users))
 	}
 	for i, userId := range users {
 		if userId == "" {
-			return "", errors.New("Empty user ids are not valid")
+			return newValidationError("Empty user ids are not valid")
 		}
-		if len(userId) > maxUserIdLength {
-			return "", errors.New(
-				fmt.Sprintf("User Id ('%s') length too long (expected fewer than %d characters, got %d)", userId, maxUserIdLength, len(userId)),
-			)
+		if err := checkUserIdLength(userId, lengthInRunes); err != nil {
+			return err
 		}
 		// test for invalid characters
 		if !utf8.ValidString(userId) {
-			return "", errors.New(fmt.Sprintf("User Id at index %d is not valid utf8", i))
+			return newValidationError("User Id at index %d is not valid utf8", i)
 		}
 	}
-	// TODO: don't mutate `request`
-	request["users"] = users
-	bodyRequestBytes, err := json.Marshal(request)
-	if err != nil {
-		return "", errors.Wrap(err, "Failed to marshal the publish request JSON body")
-	}
 
-	URL := fmt.Sprintf("%s/publish_api/v1/instances/%s/publishes/users", pn.baseEndpoint, pn.InstanceId)
-	return pn.publishToAPI(URL, bodyRequestBytes)
+	return nil
+}
+
+// Returns a non-nil error if the user id is over `maxUserIdLength`, counted in bytes or in runes.
+// The error reports both lengths, as they differ for ids with multi-byte characters.
+func checkUserIdLength(userId string, inRunes bool) error {
+	length, unit := len(userId), "bytes"
+	if inRunes {
+		length, unit = utf8.RuneCountInString(userId), "characters"
+	}
+	if length <= maxUserIdLength {
+		return nil
+	}
+	return newValidationError(
+		"User Id ('%s') length too long (expected at most %d %s, got %d bytes and %d characters)",
+		userId, maxUserIdLength, unit, len(userId), utf8.RuneCountInString(userId))
 }
 
-func (pn *pushNotifications) publishToAPI(url string, bodyRequestBytes []byte) (string, error) {
+func (pn *pushNotifications) attemptPublish(ctx context.Context, url string, bodyRequestBytes []byte, callOpts callOptions) (publishId, requestId string, err error) {
 	httpReq, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(bodyRequestBytes))
 	if err != nil {
-		return "", errors.Wrap(err, "Failed to prepare the publish request")
+		return "", "", errors.Wrap(err, "Failed to prepare the publish request")
 	}
+	httpReq = httpReq.WithContext(ctx)
 
-	httpReq.Header.Add("Authorization", "Bearer "+pn.SecretKey)
-	httpReq.Header.Add("Content-Type", "application/json")
-	httpReq.Header.Add("X-Pusher-Library", "pusher-push-notifications-go "+sdkVersion)
+	pn.setHeaders(httpReq)
 
-	httpResp, err := pn.httpClient.Do(httpReq)
+	pn.updateStats(func(stats *Stats) {
+		stats.BytesSent += uint64(len(bodyRequestBytes))
+	})
+	httpResp, err := pn.httpClientFor(callOpts).Do(httpReq)
 	if err != nil {
-		return "", errors.Wrap(err, "Failed to publish notifications due to a network error")
+		return "", "", errors.Wrap(err, "Failed to publish notifications due to a network error")
 	}
 
 	defer httpResp.Body.Close()
-	responseBytes, err := ioutil.ReadAll(httpResp.Body)
+	pn.trackQuota(httpResp.Header)
+	requestId = httpResp.Header.Get(requestIdHeader)
+	responseBytes, err := readResponse(httpResp.Body, pn.maxResponseSize)
+	if err == ErrResponseTooLarge {
+		return "", requestId, errors.Wrap(err, "Failed to read publish notification response")
+	}
 	if err != nil {
-		return "", errors.Wrap(err, "Failed to read publish notification response due to a network error")
+		return "", requestId, errors.Wrap(err, "Failed to read publish notification response due to a network error")
 	}
 
-	switch httpResp.StatusCode {
-	case http.StatusOK:
-		pubResponse := &publishResponse{}
-		err = json.Unmarshal(responseBytes, pubResponse)
-		if err != nil {
-			return "", errors.Wrap(err, "Failed to read publish notification response due to invalid JSON")
-		}
-
-		return pubResponse.PublishId, nil
-	default:
-		pubErrorResponse := &errorResponse{}
-		err = json.Unmarshal(responseBytes, pubErrorResponse)
-		if err != nil {
-			return "", errors.Wrap(err, "Failed to read publish notification response due to invalid JSON")
-		}
-
-		errorMessage := fmt.Sprintf("%s: %s", pubErrorResponse.Error, pubErrorResponse.Description)
-		return "", errors.Wrap(errors.New(errorMessage), "Failed to publish notification")
+	if httpResp.StatusCode == http.StatusRequestEntityTooLarge {
+		tooLarge := newPayloadTooLargeError(NewAPIError(httpResp.StatusCode, responseBytes), bodyRequestBytes)
+		return "", requestId, errors.Wrap(tooLarge, "Failed to publish notification")
+	}
+	if !isSuccessStatus(httpResp.StatusCode) {
+		return "", requestId, errors.Wrap(NewAPIError(httpResp.StatusCode, responseBytes), "Failed to publish notification")
 	}
+	// a publish accepted for later processing may not have an id yet
+	if len(bytes.TrimSpace(responseBytes)) == 0 {
+		return "", requestId, nil
+	}
+	pubResponse := &publishResponse{}
+	if err := json.Unmarshal(responseBytes, pubResponse); err != nil {
+		return "", requestId, errors.Wrap(err, "Failed to read publish notification response due to invalid JSON")
+	}
+	return pubResponse.PublishId, requestId, nil
 }
 
 func (pn *pushNotifications) DeleteUser(userId string) error {
 	if len(userId) == 0 {
-		return errors.New("User Id cannot be empty")
+		return newValidationError("User Id cannot be empty")
 	}
 
-	if len(userId) > maxUserIdLength {
-		return errors.Errorf(
-			"User Id ('%s') length too long (expected fewer than %d characters, got %d)",
-			userId, maxUserIdLength+1, len(userId))
+	if err := checkUserIdLength(userId, pn.userIdLengthInRunes); err != nil {
+		return err
 	}
 
 	if !utf8.ValidString(userId) {
-		return errors.New("User Id must be encoded using utf8")
+		return newValidationError("User Id must be encoded using utf8")
 	}
 
-	URL := fmt.Sprintf("%s/customer_api/v1/instances/%s/users/%s", pn.baseEndpoint, pn.InstanceId, url.PathEscape(userId))
+	URL := fmt.Sprintf("%s/customer_api/v1/instances/%s/users/%s", pn.baseEndpoint, pn.InstanceId, url.PathEscape(pn.userId(userId)))
+	start := time.Now()
+	err := pn.labeled(context.Background(), deleteUserOperation, func(ctx context.Context) error {
+		return pn.retry(ctx, func() error {
+			return pn.attemptDeleteUser(ctx, URL)
+		})
+	})
+	pn.recordCall(deleteUserOperation, time.Since(start), err)
+	return err
+}
+
+func (pn *pushNotifications) attemptDeleteUser(ctx context.Context, URL string) error {
 	httpReq, err := http.NewRequest(http.MethodDelete, URL, nil)
 	if err != nil {
 		return errors.Wrap(err, "Failed to prepare the delete user request")
 	}
+	httpReq = httpReq.WithContext(ctx)
 
-	httpReq.Header.Add("Authorization", "Bearer "+pn.SecretKey)
-	httpReq.Header.Add("Content-Type", "application/json")
-	httpReq.Header.Add("X-Pusher-Library", "pusher-push-notifications-go "+sdkVersion)
+	pn.setHeaders(httpReq)
 
 	httpResp, err := pn.httpClient.Do(httpReq)
 	if err != nil {
@@ -275,24 +489,17 @@ func (pn *pushNotifications) DeleteUser(userId string) error {
 	}
 
 	defer httpResp.Body.Close()
-	responseBytes, err := ioutil.ReadAll(httpResp.Body)
+	pn.trackQuota(httpResp.Header)
+	responseBytes, err := readResponse(httpResp.Body, pn.maxResponseSize)
+	if err == ErrResponseTooLarge {
+		return errors.Wrap(err, "Failed to read delete user response")
+	}
 	if err != nil {
 		return errors.Wrap(err, "Failed to read delete user response due to a network error")
 	}
 
-	switch httpResp.StatusCode {
-	case http.StatusOK:
-		return nil
-	default:
-		errResponse := &errorResponse{}
-		err = json.Unmarshal(responseBytes, errResponse)
-		if err != nil {
-			return errors.Wrap(err, "Failed to read delete user response due to invalid JSON")
-		}
-
-		errorMessage := fmt.Sprintf("%s: %s", errResponse.Error, errResponse.Description)
-		return errors.Wrap(errors.New(errorMessage), "Failed to delete user")
+	if !isSuccessStatus(httpResp.StatusCode) {
+		return errors.Wrap(NewAPIError(httpResp.StatusCode, responseBytes), "Failed to delete user")
 	}
-
 	return nil
 }
@@ -0,0 +1,125 @@
+package pushnotificationstest
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// Matches publishes whose notification has the title on any platform
+func HasTitle(title string) PublishMatcher {
+	return anyOf(
+		HasField("apns.aps.alert.title", title),
+		HasField("fcm.notification.title", title),
+		HasField("web.notification.title", title),
+	)
+}
+
+// Matches publishes whose notification has the body on any platform.
+// An APNs alert that is a plain string is its body.
+func HasBody(body string) PublishMatcher {
+	return anyOf(
+		HasField("apns.aps.alert", body),
+		HasField("apns.aps.alert.body", body),
+		HasField("fcm.notification.body", body),
+		HasField("web.notification.body", body),
+	)
+}
+
+// Matches publishes whose request has the value at the dot-separated path, e.g. "fcm.data.order_id".
+// Values are compared as JSON, so that `1` matches the number decoded from the request.
+func HasField(path string, value interface{}) PublishMatcher {
+	expected := normalize(value)
+	return func(p Publish) bool {
+		var actual interface{} = p.Request
+		for _, key := range strings.Split(path, ".") {
+			object, ok := actual.(map[string]interface{})
+			if !ok {
+				return false
+			}
+			if actual, ok = object[key]; !ok {
+				return false
+			}
+		}
+		return reflect.DeepEqual(actual, expected)
+	}
+}
+
+func anyOf(matchers ...PublishMatcher) PublishMatcher {
+	return func(p Publish) bool {
+		for _, matcher := range matchers {
+			if matcher(p) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func normalize(value interface{}) interface{} {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var normalized interface{}
+	json.Unmarshal(encoded, &normalized)
+	return normalized
+}
+
+// Fails the test unless a successful publish to the user matches all the matchers
+func (f *Fake) AssertPublishedToUser(t TestingT, userId string, matchers ...PublishMatcher) {
+	t.Helper()
+	f.assertPublished(t, "user "+userId, ToUser(userId), matchers)
+}
+
+// Fails the test unless a successful publish to the interest matches all the matchers
+func (f *Fake) AssertPublishedToInterest(t TestingT, interest string, matchers ...PublishMatcher) {
+	t.Helper()
+	f.assertPublished(t, "interest "+interest, ToInterest(interest), matchers)
+}
+
+// Fails the test if a successful publish matches all the matchers
+func (f *Fake) AssertNotPublished(t TestingT, matchers ...PublishMatcher) {
+	t.Helper()
+	for _, publish := range f.Publishes() {
+		if publish.FailedWith == 0 && matchesAll(publish, matchers) {
+			t.Errorf("Expected no matching publish, got publish %d:\n%s", publish.Index, describe(publish))
+		}
+	}
+}
+
+func (f *Fake) assertPublished(t TestingT, target string, toTarget PublishMatcher, matchers []PublishMatcher) {
+	t.Helper()
+	received := []string{}
+	for _, publish := range f.Publishes() {
+		if publish.FailedWith != 0 || !toTarget(publish) {
+			continue
+		}
+		if matchesAll(publish, matchers) {
+			return
+		}
+		received = append(received, describe(publish))
+	}
+	if len(received) == 0 {
+		t.Errorf("Expected a publish to %s, got none", target)
+		return
+	}
+	t.Errorf("Expected a matching publish to %s, got:\n%s", target, strings.Join(received, ""))
+}
+
+func matchesAll(publish Publish, matchers []PublishMatcher) bool {
+	for _, matcher := range matchers {
+		if !matcher(publish) {
+			return false
+		}
+	}
+	return true
+}
+
+func describe(publish Publish) string {
+	canonical, err := CanonicalJSON(publish.Request)
+	if err != nil {
+		return err.Error()
+	}
+	return string(canonical)
+}
@@ -0,0 +1,57 @@
+package pushnotificationstest
+
+import (
+	"net/http"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAssertions(t *testing.T) {
+	Convey("Assertions on a fake client", t, func() {
+		fake := NewFake()
+		fake.PublishToUsers([]string{"user-1"}, map[string]interface{}{
+			"apns": map[string]interface{}{"aps": map[string]interface{}{"alert": map[string]interface{}{"title": "Hi", "body": "Your order shipped"}}},
+			"fcm": map[string]interface{}{
+				"notification": map[string]interface{}{"title": "Hi"},
+				"data":         map[string]interface{}{"order_id": 42},
+			},
+		})
+		fake.PublishToInterests([]string{"news"}, map[string]interface{}{
+			"apns": map[string]interface{}{"aps": map[string]interface{}{"alert": "Read the news"}},
+		})
+		recorder := &recordingT{}
+
+		Convey("should pass when a publish to the user matches", func() {
+			fake.AssertPublishedToUser(recorder, "user-1", HasTitle("Hi"), HasBody("Your order shipped"), HasField("fcm.data.order_id", 42))
+			fake.AssertPublishedToInterest(recorder, "news", HasBody("Read the news"))
+			So(recorder.errors, ShouldBeEmpty)
+		})
+
+		Convey("should show the publishes to the user when none matches", func() {
+			fake.AssertPublishedToUser(recorder, "user-1", HasTitle("Bye"))
+			So(len(recorder.errors), ShouldEqual, 1)
+			So(recorder.errors[0], ShouldStartWith, "Expected a matching publish to user user-1, got:\n")
+			So(recorder.errors[0], ShouldContainSubstring, `"title": "Hi"`)
+		})
+
+		Convey("should fail when nothing was published to the user", func() {
+			fake.AssertPublishedToUser(recorder, "user-2")
+			So(recorder.errors, ShouldResemble, []string{"Expected a publish to user user-2, got none"})
+		})
+
+		Convey("should not count failed publishes", func() {
+			fake.FailWhen(ToUser("user-3"), http.StatusServiceUnavailable)
+			fake.PublishToUsers([]string{"user-3"}, map[string]interface{}{"fcm": map[string]interface{}{}})
+			fake.AssertPublishedToUser(recorder, "user-3")
+			So(len(recorder.errors), ShouldEqual, 1)
+		})
+
+		Convey("should check that nothing matching was published", func() {
+			fake.AssertNotPublished(recorder, HasTitle("Bye"))
+			So(recorder.errors, ShouldBeEmpty)
+			fake.AssertNotPublished(recorder, ToInterest("news"))
+			So(len(recorder.errors), ShouldEqual, 1)
+		})
+	})
+}
@@ -0,0 +1,142 @@
+package pushnotificationstest
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// The faults a `Chaos` transport injects, each with the probability (between 0 and 1) of a request getting it.
+// A request gets at most one of the timeout, 429 and 5xx faults, and may be delayed as well.
+type ChaosConfig struct {
+	// Delays requests by `Latency`
+	LatencyProbability float64
+	Latency            time.Duration
+	// Fails requests with a timeout error after `Timeout`, or when the request is cancelled first
+	TimeoutProbability float64
+	Timeout            time.Duration
+	// Answers requests with 429 Too Many Requests, asking to retry after `RetryAfter`
+	RateLimitProbability float64
+	RetryAfter           time.Duration
+	// Answers requests with 500, 502, 503 or 504
+	ServerErrorProbability float64
+	// Makes the faults injected the same on every run
+	Seed int64
+}
+
+// An `http.RoundTripper` injecting latency, timeouts, 429 and 5xx responses into the requests it sends
+// through the transport it wraps, for checking that retry and queue settings hold up under failure.
+// Use it with `pushnotifications.WithTransport`.
+type Chaos struct {
+	next   http.RoundTripper
+	config ChaosConfig
+
+	mutex  sync.Mutex
+	random *rand.Rand
+	faults map[string]int
+}
+
+// Returns a transport injecting the faults of `config` into requests sent through `next`,
+// or `http.DefaultTransport` if nil
+func NewChaos(next http.RoundTripper, config ChaosConfig) *Chaos {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Chaos{next: next, config: config, random: rand.New(rand.NewSource(config.Seed)), faults: map[string]int{}}
+}
+
+var serverErrorStatuses = []int{
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+func (c *Chaos) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.mutex.Lock()
+	delayed := c.random.Float64() < c.config.LatencyProbability
+	fault := c.random.Float64()
+	status := serverErrorStatuses[c.random.Intn(len(serverErrorStatuses))]
+	c.mutex.Unlock()
+
+	if delayed {
+		c.count("latency")
+		if err := sleep(req, c.config.Latency); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case fault < c.config.TimeoutProbability:
+		c.count("timeout")
+		if err := sleep(req, c.config.Timeout); err != nil {
+			return nil, err
+		}
+		return nil, timeoutError{}
+	case fault < c.config.TimeoutProbability+c.config.RateLimitProbability:
+		c.count("rate_limit")
+		resp := response(req, http.StatusTooManyRequests, errorBody(http.StatusTooManyRequests, "Injected by pushnotificationstest.Chaos"))
+		resp.Header.Set("Retry-After", strconv.Itoa(int(c.config.RetryAfter.Seconds())))
+		return resp, nil
+	case fault < c.config.TimeoutProbability+c.config.RateLimitProbability+c.config.ServerErrorProbability:
+		c.count("server_error")
+		return response(req, status, errorBody(status, "Injected by pushnotificationstest.Chaos")), nil
+	default:
+		return c.next.RoundTrip(req)
+	}
+}
+
+// Returns how many requests got each fault so far, under "latency", "timeout", "rate_limit" and "server_error"
+func (c *Chaos) Faults() map[string]int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	faults := make(map[string]int, len(c.faults))
+	for fault, count := range c.faults {
+		faults[fault] = count
+	}
+	return faults
+}
+
+func (c *Chaos) count(fault string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.faults[fault]++
+}
+
+// Waits for `delay`, or until the request is cancelled
+func sleep(req *http.Request, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
+
+func response(req *http.Request, status int, body string) *http.Response {
+	return &http.Response{
+		Status:        http.StatusText(status),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader([]byte(body))),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// A `net.Error`, so that the client treats it as a network failure
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "Injected timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
@@ -0,0 +1,95 @@
+package pushnotificationstest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	pushnotifications "github.com/pusher/push-notifications-go"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestChaos(t *testing.T) {
+	Convey("A chaos transport", t, func() {
+		requests := 0
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"publishId":"pub-123"}`))
+		}))
+		defer testServer.Close()
+		request := map[string]interface{}{"fcm": map[string]interface{}{}}
+		newClient := func(chaos *Chaos, options ...pushnotifications.Option) pushnotifications.PushNotifications {
+			options = append([]pushnotifications.Option{
+				pushnotifications.WithCustomBaseURL(testServer.URL),
+				pushnotifications.WithTransport(chaos),
+			}, options...)
+			pn, _ := pushnotifications.New("i-123", "k-456", options...)
+			return pn
+		}
+
+		Convey("should pass requests through without faults", func() {
+			chaos := NewChaos(nil, ChaosConfig{})
+			_, err := newClient(chaos).PublishToInterests([]string{"hello"}, request)
+			So(err, ShouldBeNil)
+			So(requests, ShouldEqual, 1)
+			So(chaos.Faults(), ShouldBeEmpty)
+		})
+
+		Convey("should answer with server errors", func() {
+			chaos := NewChaos(nil, ChaosConfig{ServerErrorProbability: 1})
+			_, err := newClient(chaos, pushnotifications.WithRetryPolicy(pushnotifications.RetryPolicy{MaxAttempts: 1})).
+				PublishToInterests([]string{"hello"}, request)
+			So(pushnotifications.Classify(err), ShouldEqual, pushnotifications.ServerError)
+			So(requests, ShouldEqual, 0)
+			So(chaos.Faults(), ShouldResemble, map[string]int{"server_error": 1})
+		})
+
+		Convey("should answer with 429s", func() {
+			chaos := NewChaos(nil, ChaosConfig{RateLimitProbability: 1})
+			_, err := newClient(chaos, pushnotifications.WithRetryPolicy(pushnotifications.RetryPolicy{MaxAttempts: 1})).
+				PublishToInterests([]string{"hello"}, request)
+			So(pushnotifications.Classify(err), ShouldEqual, pushnotifications.RateLimited)
+		})
+
+		Convey("should time out", func() {
+			chaos := NewChaos(nil, ChaosConfig{TimeoutProbability: 1, Timeout: time.Hour})
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			_, err := newClient(chaos).PublishToInterests([]string{"hello"}, request, pushnotifications.WithContext(ctx))
+			So(err, ShouldNotBeNil)
+			So(chaos.Faults()["timeout"], ShouldBeGreaterThanOrEqualTo, 1)
+		})
+
+		Convey("should fail with network errors that retries get through", func() {
+			chaos := NewChaos(nil, ChaosConfig{TimeoutProbability: 0.5, LatencyProbability: 0.5, Latency: time.Millisecond, Seed: 1})
+			pn := newClient(chaos, pushnotifications.WithRetryPolicy(pushnotifications.RetryPolicy{
+				MaxAttempts: 10,
+				Retryable:   map[pushnotifications.ErrorClass]bool{pushnotifications.Network: true},
+				Backoff:     func(retry int) time.Duration { return time.Millisecond },
+			}))
+			for i := 0; i < 10; i++ {
+				_, err := pn.PublishToInterests([]string{"hello"}, request)
+				So(err, ShouldBeNil)
+			}
+			So(chaos.Faults()["timeout"], ShouldBeGreaterThan, 0)
+			So(chaos.Faults()["latency"], ShouldBeGreaterThan, 0)
+		})
+
+		Convey("should inject the same faults for the same seed", func() {
+			config := ChaosConfig{ServerErrorProbability: 0.5, RateLimitProbability: 0.2, Seed: 42}
+			outcomes := func() []string {
+				pn := newClient(NewChaos(nil, config), pushnotifications.WithRetryPolicy(pushnotifications.RetryPolicy{MaxAttempts: 1}))
+				classes := []string{}
+				for i := 0; i < 20; i++ {
+					_, err := pn.PublishToInterests([]string{"hello"}, request)
+					classes = append(classes, pushnotifications.Classify(err).String())
+				}
+				return classes
+			}
+			So(outcomes(), ShouldResemble, outcomes())
+		})
+	})
+}
@@ -0,0 +1,137 @@
+package pushnotificationstest
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	pushnotifications "github.com/pusher/push-notifications-go"
+)
+
+// Environment variables holding the credentials of the Beams instance the contract tests run against
+const (
+	InstanceIdEnv = "BEAMS_INSTANCE_ID"
+	SecretKeyEnv  = "BEAMS_SECRET_KEY"
+)
+
+// Runs the contract tests against the Beams instance whose credentials are in the `BEAMS_INSTANCE_ID`
+// and `BEAMS_SECRET_KEY` environment variables, skipping them if either is unset.
+// Call it from a test to check that a fork or configuration of the client still works with the real service.
+func RunContractFromEnv(t *testing.T, options ...pushnotifications.Option) {
+	instanceId, secretKey := os.Getenv(InstanceIdEnv), os.Getenv(SecretKeyEnv)
+	if instanceId == "" || secretKey == "" {
+		t.Skipf("Set %s and %s to run the contract tests against a Beams instance", InstanceIdEnv, SecretKeyEnv)
+	}
+	RunContract(t, instanceId, secretKey, options...)
+}
+
+// Checks that every method of a client made with the options works with a real Beams instance,
+// publishing to the `contract-test` interest and to users whose ids start with `contract-test-`.
+// Devices subscribed to them get real notifications.
+func RunContract(t *testing.T, instanceId, secretKey string, options ...pushnotifications.Option) {
+	pn, err := pushnotifications.New(instanceId, secretKey, options...)
+	if err != nil {
+		t.Fatalf("Failed to create the client: %v", err)
+	}
+
+	interests := []string{"contract-test"}
+	users := []string{"contract-test-user"}
+	request := func() map[string]interface{} {
+		return map[string]interface{}{
+			"fcm": map[string]interface{}{
+				"notification": map[string]interface{}{"title": "Contract test", "body": "Sent by pushnotificationstest.RunContract"},
+			},
+		}
+	}
+	raw, _ := json.Marshal(request())
+	payload := struct {
+		FCM map[string]interface{} `json:"fcm"`
+	}{FCM: request()["fcm"].(map[string]interface{})}
+
+	publishes := []struct {
+		name    string
+		publish func() (string, error)
+	}{
+		{"PublishToInterests", func() (string, error) { return pn.PublishToInterests(interests, request()) }},
+		{"Publish", func() (string, error) { return pn.Publish(interests, request()) }},
+		{"PublishToInterestsWithResponse", func() (string, error) {
+			response, err := pn.PublishToInterestsWithResponse(interests, request())
+			return response.PublishId, err
+		}},
+		{"PublishRawToInterests", func() (string, error) { return pn.PublishRawToInterests(interests, raw) }},
+		{"PublishPayloadToInterests", func() (string, error) { return pn.PublishPayloadToInterests(interests, payload) }},
+		{"PublishToUsers", func() (string, error) { return pn.PublishToUsers(users, request()) }},
+		{"PublishToUsersWithResponse", func() (string, error) {
+			response, err := pn.PublishToUsersWithResponse(users, request())
+			return response.PublishId, err
+		}},
+		{"PublishRawToUsers", func() (string, error) { return pn.PublishRawToUsers(users, raw) }},
+		{"PublishPayloadToUsers", func() (string, error) { return pn.PublishPayloadToUsers(users, payload) }},
+		{"PublishTransactional", func() (string, error) { return pn.PublishTransactional(users[0], request()) }},
+	}
+	for _, publish := range publishes {
+		publish := publish
+		t.Run(publish.name, func(t *testing.T) {
+			publishId, err := publish.publish()
+			if err != nil {
+				t.Fatalf("Expected the publish to succeed, got %v", err)
+			}
+			if publishId == "" {
+				t.Errorf("Expected a publish id")
+			}
+		})
+	}
+
+	t.Run("PublishToUsersFromReader", func(t *testing.T) {
+		result, err := pn.PublishToUsersFromReader(strings.NewReader("contract-test-user-1\ncontract-test-user-2\n"), pushnotifications.CSV, request())
+		if err != nil {
+			t.Fatalf("Expected the publish to succeed, got %v", err)
+		}
+		if sent := len(result.Sent()); sent != 1 {
+			t.Errorf("Expected 1 chunk to be sent, got %d", sent)
+		}
+	})
+
+	t.Run("GenerateToken", func(t *testing.T) {
+		token, err := pn.GenerateToken(users[0])
+		if err != nil {
+			t.Fatalf("Expected a token, got %v", err)
+		}
+		if token["token"] == "" {
+			t.Errorf("Expected a non-empty token")
+		}
+	})
+
+	t.Run("DeleteUser", func(t *testing.T) {
+		if err := pn.DeleteUser("contract-test-deleted-user"); err != nil {
+			t.Errorf("Expected the user to be deleted, got %v", err)
+		}
+	})
+
+	t.Run("Quota", func(t *testing.T) {
+		// the service doesn't have to report a quota, but must report a sensible one if it does
+		quota := pn.Quota()
+		if !quota.UpdatedAt.IsZero() && (quota.Remaining > quota.Limit || quota.UpdatedAt.After(time.Now())) {
+			t.Errorf("Expected a sensible quota, got %+v", quota)
+		}
+	})
+
+	t.Run("Stats", func(t *testing.T) {
+		if stats := pn.Stats(); stats.PublishesSent == 0 {
+			t.Errorf("Expected the publishes to be counted, got %+v", stats)
+		}
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		unauthorized, err := pushnotifications.New(instanceId, "not-the-secret-key", options...)
+		if err != nil {
+			t.Fatalf("Failed to create the client: %v", err)
+		}
+		_, err = unauthorized.PublishToInterests(interests, request())
+		if class := pushnotifications.Classify(err); class != pushnotifications.Unauthorized {
+			t.Errorf("Expected an Unauthorized error, got %v (%v)", class, err)
+		}
+	})
+}
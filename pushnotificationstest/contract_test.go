@@ -0,0 +1,7 @@
+package pushnotificationstest
+
+import "testing"
+
+func TestContract(t *testing.T) {
+	RunContractFromEnv(t)
+}
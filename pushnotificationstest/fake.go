@@ -0,0 +1,213 @@
+package pushnotificationstest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	pushnotifications "github.com/pusher/push-notifications-go"
+)
+
+// A publish received by a `Fake`
+type Publish struct {
+	// Position of the publish among those received, starting at 0
+	Index int
+	// Set for publishes to interests
+	Interests []string
+	// Set for publishes to users
+	Users []string
+	// The publish request, without the `interests` or `users` field
+	Request map[string]interface{}
+	// The publish id answered, empty if the publish was failed
+	PublishId string
+	// The status the publish was failed with, 0 if it succeeded
+	FailedWith int
+}
+
+// Decides whether a publish matches, for injecting failures and making assertions
+type PublishMatcher func(Publish) bool
+
+// Matches publishes to the interest
+func ToInterest(interest string) PublishMatcher {
+	return func(p Publish) bool {
+		return contains(p.Interests, interest)
+	}
+}
+
+// Matches publishes to the user
+func ToUser(userId string) PublishMatcher {
+	return func(p Publish) bool {
+		return contains(p.Users, userId)
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+type injectedFailure struct {
+	index   int
+	matcher PublishMatcher
+	status  int
+}
+
+// A client publishing to an in-memory Beams service rather than the real one, for testing code that sends
+// notifications. It goes through the same validation and encoding as a real client, records every publish
+// and deleted user, and answers with the publish ids and failures it is set up with.
+type Fake struct {
+	pushnotifications.PushNotifications
+
+	mutex        sync.Mutex
+	publishes    []Publish
+	deletedUsers []string
+	publishIds   []string
+	failures     []injectedFailure
+}
+
+// Returns a fake client made with the options, which don't retry failed publishes by default
+func NewFake(options ...pushnotifications.Option) *Fake {
+	f := &Fake{}
+	options = append([]pushnotifications.Option{
+		pushnotifications.WithRetryPolicy(pushnotifications.RetryPolicy{MaxAttempts: 1}),
+	}, options...)
+	options = append(options,
+		pushnotifications.WithCustomBaseURL("https://beams.fake"),
+		pushnotifications.WithTransport(f),
+	)
+	pn, err := pushnotifications.New("fake-instance", "fake-secret-key", options...)
+	if err != nil {
+		panic(err)
+	}
+	f.PushNotifications = pn
+	return f
+}
+
+// Answers publishes with the ids, in order, instead of "pub-1", "pub-2" and so on.
+// Publishes after the last id get the default ids again.
+func (f *Fake) SetPublishIds(publishIds ...string) *Fake {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.publishIds = publishIds
+	return f
+}
+
+// Fails the publish at `index` (the first publish is 0) with the HTTP status, e.g. 503.
+// Only publishes count, and every attempt of a retried publish counts as one.
+func (f *Fake) FailPublish(index int, status int) *Fake {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.failures = append(f.failures, injectedFailure{index: index, status: status})
+	return f
+}
+
+// Fails every publish the matcher matches with the HTTP status, e.g. 422
+func (f *Fake) FailWhen(matcher PublishMatcher, status int) *Fake {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.failures = append(f.failures, injectedFailure{index: -1, matcher: matcher, status: status})
+	return f
+}
+
+// Returns the publishes received so far, failed ones included
+func (f *Fake) Publishes() []Publish {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return append([]Publish{}, f.publishes...)
+}
+
+// Returns the users deleted so far
+func (f *Fake) DeletedUsers() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return append([]string{}, f.deletedUsers...)
+}
+
+// Clears the publishes and deleted users recorded, keeping the publish ids and failures set up
+func (f *Fake) Reset() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.publishes = nil
+	f.deletedUsers = nil
+}
+
+// Serves the requests of the client as the Beams service would
+func (f *Fake) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Method == http.MethodDelete {
+		segments := strings.Split(req.URL.EscapedPath(), "/")
+		userId, err := url.PathUnescape(segments[len(segments)-1])
+		if err != nil {
+			return nil, err
+		}
+		f.mutex.Lock()
+		f.deletedUsers = append(f.deletedUsers, userId)
+		f.mutex.Unlock()
+		return response(req, http.StatusOK, ""), nil
+	}
+
+	publish := Publish{Request: map[string]interface{}{}}
+	if err := json.Unmarshal(body, &publish.Request); err != nil {
+		return response(req, http.StatusBadRequest, errorBody(http.StatusBadRequest, err.Error())), nil
+	}
+	publish.Interests = stringsOf(publish.Request["interests"])
+	publish.Users = stringsOf(publish.Request["users"])
+	delete(publish.Request, "interests")
+	delete(publish.Request, "users")
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	publish.Index = len(f.publishes)
+	for _, failure := range f.failures {
+		if failure.index == publish.Index || (failure.matcher != nil && failure.matcher(publish)) {
+			publish.FailedWith = failure.status
+			f.publishes = append(f.publishes, publish)
+			return response(req, failure.status, errorBody(failure.status, "Injected by pushnotificationstest.Fake")), nil
+		}
+	}
+
+	successes := 0
+	for _, p := range f.publishes {
+		if p.FailedWith == 0 {
+			successes++
+		}
+	}
+	publish.PublishId = fmt.Sprintf("pub-%d", successes+1)
+	if successes < len(f.publishIds) {
+		publish.PublishId = f.publishIds[successes]
+	}
+	f.publishes = append(f.publishes, publish)
+
+	encoded, _ := json.Marshal(map[string]string{"publishId": publish.PublishId})
+	return response(req, http.StatusOK, string(encoded)), nil
+}
+
+func stringsOf(value interface{}) []string {
+	values, _ := value.([]interface{})
+	if values == nil {
+		return nil
+	}
+	strs := make([]string, 0, len(values))
+	for _, v := range values {
+		s, _ := v.(string)
+		strs = append(strs, s)
+	}
+	return strs
+}
+
+func errorBody(status int, description string) string {
+	encoded, _ := json.Marshal(map[string]string{"error": http.StatusText(status), "description": description})
+	return string(encoded)
+}
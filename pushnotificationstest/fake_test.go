@@ -0,0 +1,85 @@
+package pushnotificationstest
+
+import (
+	"net/http"
+	"testing"
+
+	pushnotifications "github.com/pusher/push-notifications-go"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFake(t *testing.T) {
+	Convey("A fake client", t, func() {
+		fake := NewFake()
+		request := func() map[string]interface{} {
+			return map[string]interface{}{"fcm": map[string]interface{}{"notification": map[string]interface{}{"title": "Hi"}}}
+		}
+
+		Convey("should record publishes", func() {
+			pubId, err := fake.PublishToInterests([]string{"hello"}, request())
+			So(err, ShouldBeNil)
+			So(pubId, ShouldEqual, "pub-1")
+			pubId, err = fake.PublishToUsers([]string{"user-1"}, request())
+			So(err, ShouldBeNil)
+			So(pubId, ShouldEqual, "pub-2")
+
+			So(fake.Publishes(), ShouldResemble, []Publish{
+				{Index: 0, Interests: []string{"hello"}, Request: map[string]interface{}{
+					"fcm": map[string]interface{}{"notification": map[string]interface{}{"title": "Hi"}},
+				}, PublishId: "pub-1"},
+				{Index: 1, Users: []string{"user-1"}, Request: map[string]interface{}{
+					"fcm": map[string]interface{}{"notification": map[string]interface{}{"title": "Hi"}},
+				}, PublishId: "pub-2"},
+			})
+		})
+
+		Convey("should still validate publishes", func() {
+			_, err := fake.PublishToInterests([]string{}, request())
+			So(pushnotifications.Classify(err), ShouldEqual, pushnotifications.Validation)
+			So(fake.Publishes(), ShouldBeEmpty)
+		})
+
+		Convey("should record deleted users", func() {
+			So(fake.DeleteUser("user/1"), ShouldBeNil)
+			So(fake.DeletedUsers(), ShouldResemble, []string{"user/1"})
+		})
+
+		Convey("should answer with the publish ids set up", func() {
+			fake.SetPublishIds("first", "second")
+			ids := []string{}
+			for i := 0; i < 3; i++ {
+				pubId, _ := fake.PublishToInterests([]string{"hello"}, request())
+				ids = append(ids, pubId)
+			}
+			So(ids, ShouldResemble, []string{"first", "second", "pub-3"})
+		})
+
+		Convey("should fail the publish at an index", func() {
+			fake.FailPublish(1, http.StatusServiceUnavailable)
+			_, err := fake.PublishToInterests([]string{"hello"}, request())
+			So(err, ShouldBeNil)
+			_, err = fake.PublishToInterests([]string{"hello"}, request())
+			So(pushnotifications.Classify(err), ShouldEqual, pushnotifications.ServerError)
+			pubId, err := fake.PublishToInterests([]string{"hello"}, request())
+			So(err, ShouldBeNil)
+			So(pubId, ShouldEqual, "pub-2")
+			So(fake.Publishes()[1].FailedWith, ShouldEqual, http.StatusServiceUnavailable)
+		})
+
+		Convey("should fail the publishes matched", func() {
+			fake.FailWhen(ToUser("user-2"), http.StatusUnprocessableEntity)
+			_, err := fake.PublishToUsers([]string{"user-1"}, request())
+			So(err, ShouldBeNil)
+			_, err = fake.PublishToUsers([]string{"user-2", "user-3"}, request())
+			So(pushnotifications.Classify(err), ShouldEqual, pushnotifications.Validation)
+			_, err = fake.PublishToInterests([]string{"user-2"}, request())
+			So(err, ShouldBeNil)
+		})
+
+		Convey("should forget what it recorded when reset", func() {
+			fake.PublishToInterests([]string{"hello"}, request())
+			fake.Reset()
+			So(fake.Publishes(), ShouldBeEmpty)
+		})
+	})
+}
@@ -0,0 +1,123 @@
+// Package pushnotificationstest provides utilities for testing code that sends notifications.
+package pushnotificationstest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Environment variable that makes `AssertGolden` write golden files instead of comparing against them
+const UpdateGoldenEnv = "UPDATE_GOLDEN"
+
+// Directory golden files are read from and written to, relative to the package being tested
+var GoldenDir = "testdata"
+
+// The subset of `*testing.T` used by the assertion helpers
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// Encodes a publish request, or any value encoding to JSON, with sorted keys and indentation,
+// so that equal payloads always render the same way
+func CanonicalJSON(payload interface{}) ([]byte, error) {
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to marshal the payload")
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode the payload")
+	}
+
+	canonical, err := json.MarshalIndent(decoded, "", "  ")
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to marshal the payload")
+	}
+	return append(canonical, '\n'), nil
+}
+
+// Fails the test if the canonical JSON of the payload differs from the golden file `<name>.json`.
+// Run the tests with `UPDATE_GOLDEN=1` to create or update golden files.
+func AssertGolden(t TestingT, name string, payload interface{}) {
+	t.Helper()
+
+	actual, err := CanonicalJSON(payload)
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+
+	path := filepath.Join(GoldenDir, name+".json")
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Errorf("Failed to create the golden file directory: %v", err)
+			return
+		}
+		if err := os.WriteFile(path, actual, 0644); err != nil {
+			t.Errorf("Failed to write the golden file: %v", err)
+		}
+		return
+	}
+
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Errorf("Failed to read the golden file (run with %s=1 to create it): %v", UpdateGoldenEnv, err)
+		return
+	}
+
+	if !bytes.Equal(expected, actual) {
+		t.Errorf("Payload does not match %s (run with %s=1 to update it):\n%s",
+			path, UpdateGoldenEnv, diffLines(string(expected), string(actual)))
+	}
+}
+
+// Returns a line diff of two texts, with removed lines prefixed by "-" and added ones by "+"
+func diffLines(expected, actual string) string {
+	a := strings.Split(strings.TrimSuffix(expected, "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(actual, "\n"), "\n")
+
+	// lengths of the longest common subsequences of the suffixes of a and b
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	diff := &bytes.Buffer{}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			fmt.Fprintf(diff, "  %s\n", a[i])
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] > lcs[i+1][j]):
+			fmt.Fprintf(diff, "+ %s\n", b[j])
+			j++
+		default:
+			fmt.Fprintf(diff, "- %s\n", a[i])
+			i++
+		}
+	}
+	return diff.String()
+}
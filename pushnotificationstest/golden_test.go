@@ -0,0 +1,76 @@
+package pushnotificationstest
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type recordingT struct {
+	errors []string
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestGolden(t *testing.T) {
+	Convey("Golden file helpers", t, func() {
+		request := map[string]interface{}{
+			"fcm": map[string]interface{}{
+				"notification": map[string]interface{}{"title": "Hello", "body": "Hello, world"},
+			},
+			"apns": map[string]interface{}{
+				"aps": map[string]interface{}{"alert": "Hello", "badge": 1},
+			},
+		}
+
+		Convey("should render canonical JSON with sorted keys", func() {
+			canonical, err := CanonicalJSON(struct {
+				Web  string `json:"web"`
+				APNs int64  `json:"apns"`
+			}{"hello", 12345678901234567})
+			So(err, ShouldBeNil)
+			So(string(canonical), ShouldEqual, "{\n  \"apns\": 12345678901234567,\n  \"web\": \"hello\"\n}\n")
+		})
+
+		Convey("should pass when the payload matches the golden file", func() {
+			recorder := &recordingT{}
+			AssertGolden(recorder, "hello", request)
+			So(recorder.errors, ShouldBeEmpty)
+		})
+
+		Convey("should show a diff when the payload differs", func() {
+			request["fcm"].(map[string]interface{})["notification"].(map[string]interface{})["title"] = "Bye"
+			recorder := &recordingT{}
+			AssertGolden(recorder, "hello", request)
+			So(len(recorder.errors), ShouldEqual, 1)
+			So(recorder.errors[0], ShouldContainSubstring, "-       \"title\": \"Hello\"\n+       \"title\": \"Bye\"\n")
+		})
+
+		Convey("should fail when the golden file is missing", func() {
+			recorder := &recordingT{}
+			AssertGolden(recorder, "missing", request)
+			So(recorder.errors[0], ShouldContainSubstring, "Failed to read the golden file")
+		})
+
+		Convey("should write golden files when asked to", func() {
+			GoldenDir = os.TempDir()
+			defer func() { GoldenDir = "testdata" }()
+			os.Setenv(UpdateGoldenEnv, "1")
+			defer os.Unsetenv(UpdateGoldenEnv)
+
+			recorder := &recordingT{}
+			AssertGolden(recorder, "pushnotificationstest-golden", request)
+			So(recorder.errors, ShouldBeEmpty)
+
+			os.Unsetenv(UpdateGoldenEnv)
+			AssertGolden(recorder, "pushnotificationstest-golden", request)
+			So(recorder.errors, ShouldBeEmpty)
+		})
+	})
+}
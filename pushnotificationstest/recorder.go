@@ -0,0 +1,209 @@
+package pushnotificationstest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Headers that are never written to fixture files
+var redactedHeaders = []string{"Authorization", "Proxy-Authorization"}
+
+// A request recorded to a fixture file
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// A response recorded to a fixture file
+type RecordedResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// A request and the response it got
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// An `http.RoundTripper` recording every interaction with the transport it wraps,
+// for use with `pushnotifications.WithTransport`
+type Recorder struct {
+	next http.RoundTripper
+
+	mutex        sync.Mutex
+	interactions []Interaction
+}
+
+// Returns a recorder sending requests through `next`, or `http.DefaultTransport` if nil
+func NewRecorder(next http.RoundTripper) *Recorder {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Recorder{next: next}
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	requestBody, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	responseBody, err := readBody(&resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	header := req.Header
+	if header != nil {
+		header = cloneHeader(req.Header)
+		for _, name := range redactedHeaders {
+			header.Del(name)
+		}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.interactions = append(r.interactions, Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Header: header,
+			Body:   string(requestBody),
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     resp.Header,
+			Body:       string(responseBody),
+		},
+	})
+
+	return resp, nil
+}
+
+// Returns the interactions recorded so far
+func (r *Recorder) Interactions() []Interaction {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return append([]Interaction{}, r.interactions...)
+}
+
+// Writes the interactions recorded so far to a fixture file that `NewReplayer` can load
+func (r *Recorder) Save(path string) error {
+	fixture, err := json.MarshalIndent(r.Interactions(), "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "Failed to marshal the recorded interactions")
+	}
+	return os.WriteFile(path, append(fixture, '\n'), 0644)
+}
+
+// An `http.RoundTripper` answering requests with the responses in a fixture file,
+// without any network access
+type Replayer struct {
+	mutex        sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// Loads a fixture file written by `Recorder.Save`
+func NewReplayer(path string) (*Replayer, error) {
+	fixture, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read the fixture file")
+	}
+
+	interactions := []Interaction{}
+	if err := json.Unmarshal(fixture, &interactions); err != nil {
+		return nil, errors.Wrap(err, "Failed to parse the fixture file")
+	}
+
+	return &Replayer{interactions: interactions, used: make([]bool, len(interactions))}, nil
+}
+
+// Returns the response of the first interaction not replayed yet with the same method, URL and body
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	requestBody, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i, interaction := range r.interactions {
+		recorded := interaction.Request
+		if r.used[i] || recorded.Method != req.Method || recorded.URL != req.URL.String() || recorded.Body != string(requestBody) {
+			continue
+		}
+
+		r.used[i] = true
+		header := interaction.Response.Header
+		if header == nil {
+			header = http.Header{}
+		}
+		return &http.Response{
+			Status:        http.StatusText(interaction.Response.StatusCode),
+			StatusCode:    interaction.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader([]byte(interaction.Response.Body))),
+			ContentLength: int64(len(interaction.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	return nil, errors.Errorf("No recorded interaction matches %s %s", req.Method, req.URL)
+}
+
+// Returns the number of recorded interactions that were not replayed
+func (r *Replayer) Remaining() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	remaining := 0
+	for _, used := range r.used {
+		if !used {
+			remaining++
+		}
+	}
+	return remaining
+}
+
+// Reads a request or response body, replacing it with a copy so it can still be read
+func readBody(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil {
+		return nil, nil
+	}
+
+	contents, err := io.ReadAll(*body)
+	(*body).Close()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read the body")
+	}
+	*body = io.NopCloser(bytes.NewReader(contents))
+	return contents, nil
+}
+
+func cloneHeader(header http.Header) http.Header {
+	clone := make(http.Header, len(header))
+	for name, values := range header {
+		clone[name] = append([]string{}, values...)
+	}
+	return clone
+}
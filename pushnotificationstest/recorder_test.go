@@ -0,0 +1,85 @@
+package pushnotificationstest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pushnotifications "github.com/pusher/push-notifications-go"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRecordReplay(t *testing.T) {
+	Convey("Recording and replaying interactions", t, func() {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			if r.Method == http.MethodDelete {
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"publishId":"pub-123"}`))
+		}))
+
+		dir, _ := os.MkdirTemp("", "pushnotificationstest")
+		defer os.RemoveAll(dir)
+		fixture := filepath.Join(dir, "fixture.json")
+		request := map[string]interface{}{"fcm": map[string]interface{}{}}
+
+		recorder := NewRecorder(nil)
+		pn, _ := pushnotifications.New("i-123", "k-456",
+			pushnotifications.WithCustomBaseURL(testServer.URL),
+			pushnotifications.WithTransport(recorder),
+		)
+		pubId, err := pn.PublishToInterests([]string{"hello"}, request)
+		So(err, ShouldBeNil)
+		So(pubId, ShouldEqual, "pub-123")
+		So(pn.DeleteUser("user-1"), ShouldBeNil)
+		testServer.Close()
+
+		Convey("should record every interaction without credentials", func() {
+			interactions := recorder.Interactions()
+			So(len(interactions), ShouldEqual, 2)
+			So(interactions[0].Request.Method, ShouldEqual, http.MethodPost)
+			So(interactions[0].Request.Body, ShouldEqual, `{"fcm":{},"interests":["hello"]}`)
+			So(interactions[0].Request.Header.Get("Authorization"), ShouldEqual, "")
+			So(interactions[0].Response.Body, ShouldEqual, `{"publishId":"pub-123"}`)
+			So(interactions[1].Request.Method, ShouldEqual, http.MethodDelete)
+
+			So(recorder.Save(fixture), ShouldBeNil)
+			contents, _ := os.ReadFile(fixture)
+			So(strings.Contains(string(contents), "k-456"), ShouldBeFalse)
+		})
+
+		Convey("should replay the recorded responses offline", func() {
+			So(recorder.Save(fixture), ShouldBeNil)
+			replayer, err := NewReplayer(fixture)
+			So(err, ShouldBeNil)
+
+			pn, _ := pushnotifications.New("i-123", "k-456",
+				pushnotifications.WithCustomBaseURL(testServer.URL),
+				pushnotifications.WithTransport(replayer),
+			)
+			pubId, err := pn.PublishToInterests([]string{"hello"}, request)
+			So(err, ShouldBeNil)
+			So(pubId, ShouldEqual, "pub-123")
+			So(replayer.Remaining(), ShouldEqual, 1)
+
+			So(pn.DeleteUser("user-1"), ShouldBeNil)
+			So(replayer.Remaining(), ShouldEqual, 0)
+
+			Convey("and fail for requests that were not recorded", func() {
+				_, err := pn.PublishToInterests([]string{"hello"}, request)
+				So(err.Error(), ShouldContainSubstring, "No recorded interaction matches POST")
+			})
+		})
+
+		Convey("should fail to load missing fixtures", func() {
+			_, err := NewReplayer(filepath.Join(dir, "missing.json"))
+			So(err.Error(), ShouldContainSubstring, "Failed to read the fixture file")
+		})
+	})
+}
@@ -0,0 +1,87 @@
+package pushnotifications
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	rateLimitLimitHeader     = "X-RateLimit-Limit"
+	rateLimitRemainingHeader = "X-RateLimit-Remaining"
+	rateLimitResetHeader     = "X-RateLimit-Reset"
+)
+
+// The API rate-limit quota as last reported by the Beams service
+type Quota struct {
+	// Number of requests allowed in the current window
+	Limit int
+	// Number of requests left in the current window
+	Remaining int
+	// When the current window ends. Zero if the service did not report it.
+	Reset time.Time
+	// When the quota was last reported. Zero if no response carried rate-limit headers yet.
+	UpdatedAt time.Time
+}
+
+// Fraction of the current window's requests that have been used, between 0 and 1
+func (q Quota) Used() float64 {
+	if q.Limit <= 0 {
+		return 0
+	}
+	return float64(q.Limit-q.Remaining) / float64(q.Limit)
+}
+
+func parseQuota(header http.Header) (Quota, bool) {
+	limit, err := strconv.Atoi(header.Get(rateLimitLimitHeader))
+	if err != nil {
+		return Quota{}, false
+	}
+	remaining, err := strconv.Atoi(header.Get(rateLimitRemainingHeader))
+	if err != nil {
+		return Quota{}, false
+	}
+
+	quota := Quota{
+		Limit:     limit,
+		Remaining: remaining,
+		UpdatedAt: time.Now(),
+	}
+	if reset, err := strconv.ParseInt(header.Get(rateLimitResetHeader), 10, 64); err == nil {
+		quota.Reset = time.Unix(reset, 0)
+	}
+
+	return quota, true
+}
+
+func (pn *pushNotifications) Quota() Quota {
+	pn.quotaMutex.Lock()
+	defer pn.quotaMutex.Unlock()
+
+	return pn.quota
+}
+
+func (pn *pushNotifications) trackQuota(header http.Header) {
+	quota, ok := parseQuota(header)
+	if !ok {
+		return
+	}
+
+	pn.quotaMutex.Lock()
+	previous := pn.quota
+	pn.quota = quota
+	pn.quotaMutex.Unlock()
+
+	pn.recordQuota(quota)
+
+	// only warn when the threshold is crossed, not on every response after it
+	threshold := pn.quotaWarningThreshold
+	if pn.logger == nil || threshold <= 0 || quota.Used() < threshold {
+		return
+	}
+	if previous.Used() < threshold || !previous.Reset.Equal(quota.Reset) {
+		pn.logger.Printf(
+			"Beams API quota usage is at %.0f%% (%d of %d requests remaining)",
+			quota.Used()*100, quota.Remaining, quota.Limit)
+	}
+}
@@ -0,0 +1,115 @@
+package pushnotifications
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type recordingLogger struct {
+	messages []string
+}
+
+func (l *recordingLogger) Printf(format string, v ...interface{}) {
+	l.messages = append(l.messages, fmt.Sprintf(format, v...))
+}
+
+type recordingMetrics struct {
+	mutex    sync.Mutex
+	gauges   map[string]float64
+	counters map[string]float64
+	tags     map[string]map[string]string
+}
+
+func newRecordingMetrics() *recordingMetrics {
+	return &recordingMetrics{
+		gauges:   map[string]float64{},
+		counters: map[string]float64{},
+		tags:     map[string]map[string]string{},
+	}
+}
+
+func (m *recordingMetrics) Counter(name string, value float64, tags map[string]string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.counters[name] += value
+	m.tags[name] = tags
+}
+
+func (m *recordingMetrics) Histogram(name string, value float64, tags map[string]string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.tags[name] = tags
+}
+
+func (m *recordingMetrics) Gauge(name string, value float64, tags map[string]string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.gauges[name] = value
+	m.tags[name] = tags
+}
+
+func TestQuota(t *testing.T) {
+	Convey("A Push Notifications Instance tracking the API quota", t, func() {
+		remaining := "100"
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-RateLimit-Limit", "100")
+			w.Header().Set("X-RateLimit-Remaining", remaining)
+			w.Header().Set("X-RateLimit-Reset", "1600000000")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"publishId":"pub-123"}`))
+		}))
+		defer testServer.Close()
+
+		logger := &recordingLogger{}
+		metrics := newRecordingMetrics()
+		pn, err := New(testInstanceId, testSecretKey,
+			WithCustomBaseURL(testServer.URL),
+			WithLogger(logger),
+			WithMetrics(metrics),
+			WithQuotaWarning(0.8),
+		)
+		So(err, ShouldBeNil)
+
+		Convey("should report a zero quota before any request is made", func() {
+			So(pn.Quota(), ShouldResemble, Quota{})
+		})
+
+		Convey("should expose the quota from the last response", func() {
+			remaining = "42"
+			_, err := pn.PublishToInterests([]string{"hello"}, map[string]interface{}{})
+			So(err, ShouldBeNil)
+
+			quota := pn.Quota()
+			So(quota.Limit, ShouldEqual, 100)
+			So(quota.Remaining, ShouldEqual, 42)
+			So(quota.Reset, ShouldEqual, time.Unix(1600000000, 0))
+			So(quota.UpdatedAt.IsZero(), ShouldBeFalse)
+			So(metrics.gauges["quota.remaining"], ShouldEqual, 42)
+			So(metrics.gauges["quota.limit"], ShouldEqual, 100)
+		})
+
+		Convey("should warn once when usage crosses the threshold", func() {
+			remaining = "50"
+			pn.PublishToInterests([]string{"hello"}, map[string]interface{}{})
+			So(logger.messages, ShouldBeEmpty)
+
+			remaining = "15"
+			pn.PublishToInterests([]string{"hello"}, map[string]interface{}{})
+			remaining = "10"
+			pn.PublishToInterests([]string{"hello"}, map[string]interface{}{})
+			So(len(logger.messages), ShouldEqual, 1)
+			So(logger.messages[0], ShouldContainSubstring, "85%")
+		})
+
+		Convey("should ignore responses without rate-limit headers", func() {
+			pn.(*pushNotifications).trackQuota(http.Header{})
+			So(pn.Quota(), ShouldResemble, Quota{})
+		})
+	})
+}
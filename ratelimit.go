@@ -0,0 +1,46 @@
+package pushnotifications
+
+import (
+	"sync"
+	"time"
+)
+
+// Makes publishes wait so that no more than `perSecond` are sent on average, with bursts of up to `burst`.
+// Each client built with the option has its own limit, so a `MultiTenant` limits each tenant independently.
+func WithRateLimit(perSecond float64, burst int) Option {
+	return func(pn *pushNotifications) {
+		bucket := &tokenBucket{rate: perSecond, burst: float64(burst), tokens: float64(burst)}
+		WithPublishStage(EncodeStage, PublishStageFunc(func(job *PublishJob) error {
+			time.Sleep(bucket.reserve(time.Now()))
+			return nil
+		}))(pn)
+	}
+}
+
+type tokenBucket struct {
+	mutex  sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// Takes a token, returning how long to wait until it is actually available
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	b.last = now
+	b.tokens--
+
+	if b.tokens >= 0 || b.rate <= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
@@ -0,0 +1,83 @@
+package pushnotifications
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+func (pn *pushNotifications) PublishRawToInterests(interests []string, request json.RawMessage, options ...CallOption) (string, error) {
+	return pn.publish(&PublishJob{
+		Operation: publishToInterestsOperation,
+		Targets:   interests,
+		Body:      request,
+		callOpts:  newCallOptions(options),
+	})
+}
+
+func (pn *pushNotifications) PublishRawToUsers(users []string, request json.RawMessage, options ...CallOption) (string, error) {
+	return pn.publish(&PublishJob{
+		Operation: publishToUsersOperation,
+		Targets:   users,
+		Body:      request,
+		callOpts:  newCallOptions(options),
+	})
+}
+
+// Adds the targets as the last field of the JSON object in `request`,
+// without decoding and re-encoding the rest of it
+func injectTargets(request json.RawMessage, key string, targets []string) ([]byte, error) {
+	trimmed := bytes.TrimSpace(request)
+	if !json.Valid(trimmed) || len(trimmed) < 2 || trimmed[0] != '{' {
+		return nil, newValidationError("The publish request must be a JSON object")
+	}
+
+	targetsBytes, err := json.Marshal(targets)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to marshal the publish request JSON body")
+	}
+
+	body := bytes.TrimSpace(trimmed[1 : len(trimmed)-1])
+	bodyRequestBytes := make([]byte, 0, len(body)+len(key)+len(targetsBytes)+6)
+	bodyRequestBytes = append(bodyRequestBytes, '{')
+	if len(body) > 0 {
+		bodyRequestBytes = append(bodyRequestBytes, body...)
+		bodyRequestBytes = append(bodyRequestBytes, ',')
+	}
+	bodyRequestBytes = append(bodyRequestBytes, '"')
+	bodyRequestBytes = append(bodyRequestBytes, key...)
+	bodyRequestBytes = append(bodyRequestBytes, '"', ':')
+	bodyRequestBytes = append(bodyRequestBytes, targetsBytes...)
+	bodyRequestBytes = append(bodyRequestBytes, '}')
+
+	return bodyRequestBytes, nil
+}
+
+func (pn *pushNotifications) PublishPayloadToInterests(interests []string, payload interface{}, options ...CallOption) (string, error) {
+	request, err := marshalPayload(payload)
+	if err != nil {
+		return "", err
+	}
+	return pn.PublishRawToInterests(interests, request, options...)
+}
+
+func (pn *pushNotifications) PublishPayloadToUsers(users []string, payload interface{}, options ...CallOption) (string, error) {
+	request, err := marshalPayload(payload)
+	if err != nil {
+		return "", err
+	}
+	return pn.PublishRawToUsers(users, request, options...)
+}
+
+func marshalPayload(payload interface{}) (json.RawMessage, error) {
+	if raw, ok := payload.(json.RawMessage); ok {
+		return raw, nil
+	}
+
+	request, err := json.Marshal(payload)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to marshal the publish request JSON body")
+	}
+	return request, nil
+}
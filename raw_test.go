@@ -0,0 +1,128 @@
+package pushnotifications
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPublishRaw(t *testing.T) {
+	Convey("Publishing pre-encoded requests", t, func() {
+		var lastPath string
+		var lastBody []byte
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lastPath = r.URL.Path
+			lastBody, _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"publishId":"pub-123"}`))
+		}))
+		defer testServer.Close()
+
+		pn, _ := New(testInstanceId, testSecretKey, WithCustomBaseURL(testServer.URL))
+		request := json.RawMessage(` {"fcm":{"notification":{"title":"Hello"}}} `)
+
+		Convey("should add the interests to the request", func() {
+			pubId, err := pn.PublishRawToInterests([]string{"hello"}, request)
+			So(err, ShouldBeNil)
+			So(pubId, ShouldEqual, "pub-123")
+			So(lastPath, ShouldEqual, "/publish_api/v1/instances/i-123/publishes")
+			So(string(lastBody), ShouldEqual, `{"fcm":{"notification":{"title":"Hello"}},"interests":["hello"]}`)
+		})
+
+		Convey("should add the users to the request", func() {
+			pubId, err := pn.PublishRawToUsers([]string{"user-1", "user-2"}, request)
+			So(err, ShouldBeNil)
+			So(pubId, ShouldEqual, "pub-123")
+			So(lastPath, ShouldEqual, "/publish_api/v1/instances/i-123/publishes/users")
+			So(string(lastBody), ShouldEqual, `{"fcm":{"notification":{"title":"Hello"}},"users":["user-1","user-2"]}`)
+		})
+
+		Convey("should handle empty objects", func() {
+			pn.PublishRawToUsers([]string{"user-1"}, json.RawMessage(`{ }`))
+			So(string(lastBody), ShouldEqual, `{"users":["user-1"]}`)
+		})
+
+		Convey("should validate the targets", func() {
+			_, err := pn.PublishRawToInterests([]string{}, request)
+			So(err.Error(), ShouldContainSubstring, "No interests were supplied")
+
+			_, err = pn.PublishRawToUsers([]string{""}, request)
+			So(err.Error(), ShouldContainSubstring, "Empty user ids are not valid")
+		})
+
+		Convey("should reject requests that are not JSON objects", func() {
+			for _, invalid := range []string{``, `[]`, `"hello"`, `{"fcm":`} {
+				_, err := pn.PublishRawToUsers([]string{"user-1"}, json.RawMessage(invalid))
+				So(Classify(err), ShouldEqual, Validation)
+				So(err.Error(), ShouldContainSubstring, "The publish request must be a JSON object")
+			}
+			So(lastBody, ShouldBeNil)
+		})
+
+		Convey("should validate the request", func() {
+			_, err := pn.PublishRawToUsers([]string{"user-1"}, json.RawMessage(`{"fcm":{"notification":"Hello"}}`))
+			So(Classify(err), ShouldEqual, Validation)
+			So(err.Error(), ShouldContainSubstring, "Invalid publish request: fcm.notification")
+			So(lastBody, ShouldBeNil)
+		})
+
+		Convey("should warn about the content of the request", func() {
+			warnings := []Warning{}
+			pn, _ := New(testInstanceId, testSecretKey,
+				WithCustomBaseURL(testServer.URL),
+				WithWarningHandler(func(job *PublishJob, warning Warning) { warnings = append(warnings, warning) }))
+
+			_, err := pn.PublishRawToUsers([]string{"user-1"}, json.RawMessage(`{"fcm":{"notification":{}}}`))
+			So(err, ShouldBeNil)
+			So(len(warnings), ShouldBeGreaterThan, 0)
+			So(warnings[0].Code, ShouldEqual, ContentWarning)
+		})
+	})
+	Convey("Publishing typed payloads", t, func() {
+		var lastBody []byte
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lastBody, _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"publishId":"pub-123"}`))
+		}))
+		defer testServer.Close()
+
+		pn, _ := New(testInstanceId, testSecretKey, WithCustomBaseURL(testServer.URL))
+
+		type notification struct {
+			Title string `json:"title"`
+		}
+		type fcmSection struct {
+			Notification notification      `json:"notification"`
+			Data         map[string]string `json:"data,omitempty"`
+		}
+		payload := struct {
+			FCM fcmSection `json:"fcm"`
+		}{FCM: fcmSection{Notification: notification{Title: "Hello"}}}
+
+		Convey("should encode the payload without converting it to a map", func() {
+			pubId, err := pn.PublishPayloadToInterests([]string{"hello"}, payload)
+			So(err, ShouldBeNil)
+			So(pubId, ShouldEqual, "pub-123")
+			So(string(lastBody), ShouldEqual, `{"fcm":{"notification":{"title":"Hello"}},"interests":["hello"]}`)
+
+			pn.PublishPayloadToUsers([]string{"user-1"}, &payload)
+			So(string(lastBody), ShouldEqual, `{"fcm":{"notification":{"title":"Hello"}},"users":["user-1"]}`)
+		})
+
+		Convey("should reject payloads that don't encode to a JSON object", func() {
+			_, err := pn.PublishPayloadToUsers([]string{"user-1"}, []string{"hello"})
+			So(Classify(err), ShouldEqual, Validation)
+
+			_, err = pn.PublishPayloadToUsers([]string{"user-1"}, map[string]interface{}{"web": "Hello"})
+			So(Classify(err), ShouldEqual, Validation)
+
+			_, err = pn.PublishPayloadToUsers([]string{"user-1"}, func() {})
+			So(err.Error(), ShouldContainSubstring, "Failed to marshal the publish request JSON body")
+		})
+	})
+}
@@ -0,0 +1,52 @@
+package pushnotifications
+
+import "time"
+
+// Header of the Beams responses identifying the request, for support
+const requestIdHeader = "X-Request-Id"
+
+// How a publish went
+type PublishResponse struct {
+	// Empty unless the publish succeeded
+	PublishId string
+	// Id of the last request, as reported by the Beams service, if it reports one
+	RequestId string
+	// Time taken by every attempt, including backoff between them
+	Latency time.Duration
+	// Number of requests sent to the Beams service
+	Attempts int
+	// Interests or users the notification was published to, after any filtering or suppression
+	Targets []string
+	// Conditions that didn't stop the publish, but should be looked into
+	Warnings []Warning
+}
+
+func (pn *pushNotifications) PublishToInterestsWithResponse(interests []string, request map[string]interface{}, options ...CallOption) (*PublishResponse, error) {
+	return pn.publishWithResponse(&PublishJob{
+		Operation: publishToInterestsOperation,
+		Targets:   interests,
+		Request:   request,
+		callOpts:  newCallOptions(options),
+	})
+}
+
+func (pn *pushNotifications) PublishToUsersWithResponse(users []string, request map[string]interface{}, options ...CallOption) (*PublishResponse, error) {
+	return pn.publishWithResponse(&PublishJob{
+		Operation: publishToUsersOperation,
+		Targets:   users,
+		Request:   request,
+		callOpts:  newCallOptions(options),
+	})
+}
+
+func (pn *pushNotifications) publishWithResponse(job *PublishJob) (*PublishResponse, error) {
+	publishId, err := pn.publish(job)
+	return &PublishResponse{
+		PublishId: publishId,
+		RequestId: job.RequestId,
+		Latency:   job.Latency,
+		Attempts:  job.Attempts,
+		Targets:   job.Targets,
+		Warnings:  job.Warnings,
+	}, err
+}
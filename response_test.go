@@ -0,0 +1,46 @@
+package pushnotifications
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPublishResponse(t *testing.T) {
+	Convey("Publishing with a response", t, func() {
+		status := http.StatusOK
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Request-Id", "req-42")
+			w.WriteHeader(status)
+			w.Write([]byte(`{"publishId":"pub-123","error":"Unauthorized","description":"Bad key"}`))
+		}))
+		defer testServer.Close()
+
+		pn, _ := New(testInstanceId, testSecretKey,
+			WithCustomBaseURL(testServer.URL),
+			WithTargetFilter(TargetFilter{DenyInterests: []string{"secret"}}, nil))
+		request := map[string]interface{}{"fcm": map[string]interface{}{"notification": map[string]interface{}{"title": "Hi"}}}
+
+		Convey("should return the details of the publish", func() {
+			response, err := pn.PublishToInterestsWithResponse([]string{"hello", "secret"}, request)
+			So(err, ShouldBeNil)
+			So(response.PublishId, ShouldEqual, "pub-123")
+			So(response.RequestId, ShouldEqual, "req-42")
+			So(response.Attempts, ShouldEqual, 1)
+			So(response.Latency, ShouldBeGreaterThan, 0)
+			So(response.Targets, ShouldResemble, []string{"hello"})
+			So(response.Warnings, ShouldResemble, []Warning{{Code: AudienceReducedWarning, Message: "1 of 2 interests were filtered out"}})
+		})
+
+		Convey("should return the details of a failed publish", func() {
+			status = http.StatusUnauthorized
+			response, err := pn.PublishToUsersWithResponse([]string{"user-1"}, request)
+			So(err, ShouldNotBeNil)
+			So(response.PublishId, ShouldEqual, "")
+			So(response.RequestId, ShouldEqual, "req-42")
+			So(response.Attempts, ShouldEqual, 1)
+		})
+	})
+}
@@ -0,0 +1,144 @@
+package pushnotifications
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Describes when and how failed requests to the Beams service are retried.
+// The zero value makes a single attempt and never retries.
+//
+// Retrying `Network` or `ServerError` failures of a publish may deliver the
+// same notification twice, if the service received the original request.
+type RetryPolicy struct {
+	// Maximum number of attempts, including the first one
+	MaxAttempts int
+	// Error classes (see `Classify`) that are worth retrying
+	Retryable map[ErrorClass]bool
+	// Returns how long to wait before the given retry (starting at 1).
+	// A nil function retries immediately.
+	Backoff func(retry int) time.Duration
+	// Stops retrying once this much time has passed since the first attempt.
+	// Zero means there is no limit.
+	MaxElapsedTime time.Duration
+}
+
+// Returns a policy making up to 3 attempts for rate-limited, server and network errors,
+// with an exponential backoff starting at 100ms
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		Retryable: map[ErrorClass]bool{
+			RateLimited: true,
+			ServerError: true,
+			Network:     true,
+		},
+		Backoff:        ExponentialBackoff(100*time.Millisecond, 5*time.Second),
+		MaxElapsedTime: 30 * time.Second,
+	}
+}
+
+// Returns a backoff function that doubles the delay on every retry, starting at `base` and capped at `max`
+func ExponentialBackoff(base, max time.Duration) func(retry int) time.Duration {
+	return func(retry int) time.Duration {
+		delay := float64(base) * math.Pow(2, float64(retry-1))
+		if delay > float64(max) {
+			return max
+		}
+		return time.Duration(delay)
+	}
+}
+
+func (p RetryPolicy) shouldRetry(err error, attempts int, elapsed time.Duration) bool {
+	if attempts >= p.MaxAttempts || !p.Retryable[Classify(err)] {
+		return false
+	}
+	return p.MaxElapsedTime <= 0 || elapsed+p.backoff(attempts) < p.MaxElapsedTime
+}
+
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	if p.Backoff == nil {
+		return 0
+	}
+	return p.Backoff(retry)
+}
+
+// Overrides the `RetryPolicy` of the client for this call
+func WithCallRetryPolicy(policy RetryPolicy) CallOption {
+	return func(callOpts *callOptions) {
+		callOpts.retryPolicy = &policy
+	}
+}
+
+func (pn *pushNotifications) retry(ctx context.Context, attempt func() error) error {
+	return pn.retryWith(ctx, pn.retryPolicy, attempt)
+}
+
+// One failed attempt of a request
+type Attempt struct {
+	// When the attempt started
+	Time time.Time
+	// The HTTP status code returned by the service, 0 if there was no response
+	StatusCode int
+	Err        error
+}
+
+// Returned when every attempt of a retried request failed, listing the attempts in order.
+// `Classify` and `errors.Cause` see the error of the last attempt.
+type RetriesError struct {
+	Attempts []Attempt
+}
+
+func (e *RetriesError) Error() string {
+	return fmt.Sprintf("Failed after %d attempts: %s", len(e.Attempts), e.Cause())
+}
+
+// Returns the error of the last attempt
+func (e *RetriesError) Cause() error {
+	return e.Attempts[len(e.Attempts)-1].Err
+}
+
+// Stops retrying, and returns `ctx.Err()`, once `ctx` is done
+func (pn *pushNotifications) retryWith(ctx context.Context, policy RetryPolicy, attempt func() error) error {
+	start := time.Now()
+	failed := []Attempt{}
+	for attempts := 1; ; attempts++ {
+		attemptStart := time.Now()
+		err := attempt()
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		failed = append(failed, newAttempt(attemptStart, err))
+		if !policy.shouldRetry(err, attempts, time.Since(start)) {
+			if len(failed) == 1 {
+				return err
+			}
+			return &RetriesError{Attempts: failed}
+		}
+		timer := time.NewTimer(policy.backoff(attempts))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		pn.updateStats(func(stats *Stats) {
+			stats.Retries++
+		})
+	}
+}
+
+func newAttempt(start time.Time, err error) Attempt {
+	attempt := Attempt{Time: start, Err: err}
+	if apiError, ok := errors.Cause(err).(*APIError); ok {
+		attempt.StatusCode = apiError.StatusCode
+	}
+	return attempt
+}
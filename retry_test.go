@@ -0,0 +1,92 @@
+package pushnotifications
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	Convey("A Push Notifications Instance with a retry policy", t, func() {
+		requests := 0
+		failures := 2
+		statusCode := http.StatusServiceUnavailable
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if requests <= failures {
+				w.WriteHeader(statusCode)
+				w.Write([]byte(`{"error":"Unavailable","description":"try again"}`))
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"publishId":"pub-123"}`))
+		}))
+		defer testServer.Close()
+
+		policy := RetryPolicy{
+			MaxAttempts: 3,
+			Retryable:   map[ErrorClass]bool{ServerError: true},
+		}
+
+		Convey("should not retry by default", func() {
+			pn, _ := New(testInstanceId, testSecretKey, WithCustomBaseURL(testServer.URL))
+			_, err := pn.PublishToInterests([]string{"hello"}, map[string]interface{}{})
+			So(err, ShouldNotBeNil)
+			So(requests, ShouldEqual, 1)
+		})
+
+		Convey("should retry retryable errors until an attempt succeeds", func() {
+			pn, _ := New(testInstanceId, testSecretKey, WithCustomBaseURL(testServer.URL), WithRetryPolicy(policy))
+			pubId, err := pn.PublishToInterests([]string{"hello"}, map[string]interface{}{})
+			So(err, ShouldBeNil)
+			So(pubId, ShouldEqual, "pub-123")
+			So(requests, ShouldEqual, 3)
+		})
+
+		Convey("should give up after the maximum number of attempts", func() {
+			failures = 10
+			pn, _ := New(testInstanceId, testSecretKey, WithCustomBaseURL(testServer.URL), WithRetryPolicy(policy))
+			err := pn.DeleteUser("user-1")
+			So(Classify(err), ShouldEqual, ServerError)
+			So(requests, ShouldEqual, 3)
+
+			retriesErr, ok := err.(*RetriesError)
+			So(ok, ShouldBeTrue)
+			So(len(retriesErr.Attempts), ShouldEqual, 3)
+			So(retriesErr.Attempts[2].StatusCode, ShouldEqual, http.StatusServiceUnavailable)
+			So(retriesErr.Attempts[0].Time.After(retriesErr.Attempts[1].Time), ShouldBeFalse)
+			So(err.Error(), ShouldEqual, "Failed after 3 attempts: Failed to delete user: Unavailable: try again")
+		})
+
+		Convey("should not retry errors of other classes", func() {
+			statusCode = http.StatusBadRequest
+			pn, _ := New(testInstanceId, testSecretKey, WithCustomBaseURL(testServer.URL), WithRetryPolicy(policy))
+			_, err := pn.PublishToUsers([]string{"user-1"}, map[string]interface{}{})
+			So(Classify(err), ShouldEqual, Validation)
+			So(requests, ShouldEqual, 1)
+		})
+
+		Convey("should stop once the next retry would exceed the maximum elapsed time", func() {
+			policy.Backoff = func(int) time.Duration { return time.Hour }
+			policy.MaxElapsedTime = time.Minute
+			pn, _ := New(testInstanceId, testSecretKey, WithCustomBaseURL(testServer.URL), WithRetryPolicy(policy))
+			_, err := pn.PublishToInterests([]string{"hello"}, map[string]interface{}{})
+			So(err, ShouldNotBeNil)
+			So(requests, ShouldEqual, 1)
+		})
+	})
+
+	Convey("An exponential backoff", t, func() {
+		backoff := ExponentialBackoff(100*time.Millisecond, time.Second)
+
+		Convey("should double the delay on every retry up to the maximum", func() {
+			So(backoff(1), ShouldEqual, 100*time.Millisecond)
+			So(backoff(2), ShouldEqual, 200*time.Millisecond)
+			So(backoff(4), ShouldEqual, 800*time.Millisecond)
+			So(backoff(5), ShouldEqual, time.Second)
+		})
+	})
+}
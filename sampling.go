@@ -0,0 +1,34 @@
+package pushnotifications
+
+import (
+	"hash/fnv"
+	"math"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Returned by publishes dropped by `WithSampling`
+var ErrSampledOut = errors.New("The publish was shed by sampling")
+
+// Sends only the fraction `rate` (between 0 and 1) of publishes, failing the others with `ErrSampledOut`,
+// to shed marketing traffic during overload incidents. Transactional publishes (see `PublishTransactional`)
+// are always sent. Which publishes are sent is decided by their targets, so that retrying a publish
+// that was shed doesn't get it through and the same users keep being spared.
+func WithSampling(rate float64) Option {
+	return WithPublishStage(ValidateStage, PublishStageFunc(func(job *PublishJob) error {
+		if job.callOpts.transactional || sampled(job.Targets, rate) {
+			return nil
+		}
+		return errors.Wrapf(ErrSampledOut, "Sent %g of publishes", rate)
+	}))
+}
+
+func sampled(targets []string, rate float64) bool {
+	if rate >= 1 {
+		return true
+	}
+	hash := fnv.New32a()
+	hash.Write([]byte(strings.Join(targets, "\n")))
+	return float64(hash.Sum32()) < rate*math.MaxUint32
+}
@@ -0,0 +1,62 @@
+package pushnotifications
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSampling(t *testing.T) {
+	Convey("A Push Notifications Instance with sampling", t, func() {
+		requests := 0
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.Write([]byte(`{"publishId":"pub-123"}`))
+		}))
+		defer testServer.Close()
+		pn, _ := New(testInstanceId, testSecretKey, WithCustomBaseURL(testServer.URL), WithSampling(0.25))
+		request := map[string]interface{}{"fcm": map[string]interface{}{}}
+
+		Convey("should send about the fraction of publishes", func() {
+			shed := 0
+			for i := 0; i < 1000; i++ {
+				_, err := pn.PublishToUsers([]string{fmt.Sprintf("user-%d", i)}, request)
+				if errors.Cause(err) == ErrSampledOut {
+					shed++
+				}
+			}
+			So(requests, ShouldBeBetween, 200, 300)
+			So(requests+shed, ShouldEqual, 1000)
+		})
+
+		Convey("should decide the same way for the same targets", func() {
+			_, first := pn.PublishToUsers([]string{"user-1"}, request)
+			for i := 0; i < 10; i++ {
+				_, err := pn.PublishToUsers([]string{"user-1"}, request)
+				So(errors.Cause(err), ShouldEqual, errors.Cause(first))
+			}
+		})
+
+		Convey("should always send transactional publishes", func() {
+			for i := 0; i < 100; i++ {
+				_, err := pn.PublishTransactional(fmt.Sprintf("user-%d", i), request)
+				So(err, ShouldBeNil)
+			}
+			So(requests, ShouldEqual, 100)
+		})
+	})
+
+	Convey("Sampling", t, func() {
+		Convey("should send nothing at 0", func() {
+			So(sampled([]string{"user-1"}, 0), ShouldBeFalse)
+		})
+
+		Convey("should send everything at 1", func() {
+			So(sampled([]string{"user-1"}, 1), ShouldBeTrue)
+		})
+	})
+}
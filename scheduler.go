@@ -0,0 +1,201 @@
+package pushnotifications
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// A publish to be sent at a given time by a `Scheduler`
+type ScheduledPublish struct {
+	At time.Time
+	// Interests or users to publish to
+	Target  Target
+	Request map[string]interface{}
+	Options []CallOption
+	// Called once the publish is done, if not nil
+	Done func(publishId string, err error)
+}
+
+// Sends publishes at the time they are scheduled for.
+// Scheduled publishes are kept in memory only, and are lost if the process stops.
+type Scheduler struct {
+	pn PushNotifications
+
+	mutex   sync.Mutex
+	nextId  int
+	timers  map[int]*time.Timer
+	stopped bool
+}
+
+// Returns a `Scheduler` publishing with `pn`
+func NewScheduler(pn PushNotifications) *Scheduler {
+	return &Scheduler{pn: pn, timers: map[int]*time.Timer{}}
+}
+
+// Schedules the publish, returning an id to cancel it with. Publishes scheduled in the past are sent at once.
+func (s *Scheduler) Schedule(publish ScheduledPublish) (int, error) {
+	if publish.Target.Kind != InterestsTarget && publish.Target.Kind != UsersTarget {
+		return 0, newValidationError("The scheduler can only publish to interests and users")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.stopped {
+		return 0, errors.New("The scheduler is stopped")
+	}
+
+	s.nextId++
+	id := s.nextId
+	s.timers[id] = time.AfterFunc(time.Until(publish.At), func() {
+		s.mutex.Lock()
+		_, pending := s.timers[id]
+		delete(s.timers, id)
+		s.mutex.Unlock()
+
+		if pending {
+			s.send(publish)
+		}
+	})
+	return id, nil
+}
+
+// Cancels the scheduled publish. Returns false if it was already sent or cancelled.
+func (s *Scheduler) Cancel(id int) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	timer, ok := s.timers[id]
+	if ok {
+		timer.Stop()
+		delete(s.timers, id)
+	}
+	return ok
+}
+
+// Returns the number of publishes waiting for their time
+func (s *Scheduler) Pending() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.timers)
+}
+
+// Cancels every pending publish and refuses new ones
+func (s *Scheduler) Stop() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.stopped = true
+	for id, timer := range s.timers {
+		timer.Stop()
+		delete(s.timers, id)
+	}
+}
+
+func (s *Scheduler) send(publish ScheduledPublish) {
+	var publishId string
+	var err error
+	if publish.Target.Kind == InterestsTarget {
+		publishId, err = s.pn.PublishToInterests(publish.Target.Ids, publish.Request, publish.Options...)
+	} else {
+		publishId, err = s.pn.PublishToUsers(publish.Target.Ids, publish.Request, publish.Options...)
+	}
+
+	if publish.Done != nil {
+		publish.Done(publishId, err)
+	}
+}
+
+// Finds the time zone of users
+type TimeZoneResolver interface {
+	TimeZone(userId string) (*time.Location, error)
+}
+
+// Adapts a function to the `TimeZoneResolver` interface
+type TimeZoneResolverFunc func(userId string) (*time.Location, error)
+
+func (f TimeZoneResolverFunc) TimeZone(userId string) (*time.Location, error) {
+	return f(userId)
+}
+
+// The users of a time zone, and when they are published to
+type LocalSchedule struct {
+	Location *time.Location
+	At       time.Time
+	Users    []string
+	// Ids of the scheduled publishes, one per `Limits.MaxUsers` users
+	Ids []int
+}
+
+// Schedules the request to be published to each user at the next `timeOfDay` (e.g. 9*time.Hour)
+// in their time zone, with one publish per time zone and `Limits.MaxUsers` users.
+// Users whose time zone can't be resolved are skipped and reported.
+func (s *Scheduler) ScheduleLocalTime(
+	users []string,
+	resolver TimeZoneResolver,
+	timeOfDay time.Duration,
+	request map[string]interface{},
+	options ...CallOption,
+) ([]LocalSchedule, []RejectedUserId, error) {
+	rejected := []RejectedUserId{}
+	byZone := map[string]*LocalSchedule{}
+	for _, userId := range users {
+		location, err := resolver.TimeZone(userId)
+		if err != nil {
+			rejected = append(rejected, RejectedUserId{
+				UserId: userId,
+				Err:    errors.Wrapf(err, "Failed to resolve the time zone of user `%s`", userId),
+			})
+			continue
+		}
+		if location == nil {
+			location = time.UTC
+		}
+
+		zone, ok := byZone[location.String()]
+		if !ok {
+			zone = &LocalSchedule{Location: location, At: nextTimeOfDay(time.Now(), location, timeOfDay)}
+			byZone[location.String()] = zone
+		}
+		zone.Users = append(zone.Users, userId)
+	}
+
+	chunkSize := limitsOf(s.pn).MaxUsers
+	schedules := make([]LocalSchedule, 0, len(byZone))
+	for _, zone := range byZone {
+		for start := 0; start < len(zone.Users); start += chunkSize {
+			end := start + chunkSize
+			if end > len(zone.Users) {
+				end = len(zone.Users)
+			}
+			id, err := s.Schedule(ScheduledPublish{
+				At:      zone.At,
+				Target:  Users(zone.Users[start:end]...),
+				Request: request,
+				Options: options,
+			})
+			if err != nil {
+				return schedules, rejected, err
+			}
+			zone.Ids = append(zone.Ids, id)
+		}
+		schedules = append(schedules, *zone)
+	}
+
+	sort.Slice(schedules, func(i, j int) bool {
+		return schedules[i].At.Before(schedules[j].At)
+	})
+	return schedules, rejected, nil
+}
+
+// Returns the first time at or after `now` that clocks show `timeOfDay` in the location
+func nextTimeOfDay(now time.Time, location *time.Location, timeOfDay time.Duration) time.Time {
+	local := now.In(location)
+	next := atClock(local, 0, timeOfDay)
+	if next.Before(now) {
+		next = atClock(local, 1, timeOfDay)
+	}
+	return next
+}
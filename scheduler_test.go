@@ -0,0 +1,89 @@
+package pushnotifications
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestScheduler(t *testing.T) {
+	Convey("A scheduler", t, func() {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"publishId":"pub-123"}`))
+		}))
+		defer testServer.Close()
+
+		pn, _ := New(testInstanceId, testSecretKey, WithCustomBaseURL(testServer.URL))
+		s := NewScheduler(pn)
+		defer s.Stop()
+		request := map[string]interface{}{"fcm": map[string]interface{}{}}
+
+		Convey("should publish at the scheduled time", func() {
+			done := make(chan time.Time, 1)
+			start := time.Now()
+			_, err := s.Schedule(ScheduledPublish{
+				At:      start.Add(50 * time.Millisecond),
+				Target:  Users("user-1"),
+				Request: request,
+				Done:    func(publishId string, err error) { done <- time.Now() },
+			})
+			So(err, ShouldBeNil)
+			So(s.Pending(), ShouldEqual, 1)
+			So((<-done).Sub(start), ShouldBeGreaterThanOrEqualTo, 50*time.Millisecond)
+			So(s.Pending(), ShouldEqual, 0)
+		})
+
+		Convey("should not publish cancelled publishes", func() {
+			id, _ := s.Schedule(ScheduledPublish{At: time.Now().Add(time.Hour), Target: Users("user-1"), Request: request})
+			So(s.Cancel(id), ShouldBeTrue)
+			So(s.Cancel(id), ShouldBeFalse)
+			So(s.Pending(), ShouldEqual, 0)
+		})
+
+		Convey("should schedule users at a time of day in their time zone", func() {
+			tokyo, _ := time.LoadLocation("Asia/Tokyo")
+			zones := map[string]*time.Location{"user-1": tokyo, "user-2": time.UTC, "user-3": tokyo}
+			resolver := TimeZoneResolverFunc(func(userId string) (*time.Location, error) {
+				if location, ok := zones[userId]; ok {
+					return location, nil
+				}
+				return nil, errors.New("unknown user")
+			})
+
+			schedules, rejected, err := s.ScheduleLocalTime([]string{"user-1", "user-2", "user-3", "user-4"}, resolver, 9*time.Hour, request)
+			So(err, ShouldBeNil)
+			So(len(schedules), ShouldEqual, 2)
+			So(s.Pending(), ShouldEqual, 2)
+			So(rejected[0].UserId, ShouldEqual, "user-4")
+			for _, schedule := range schedules {
+				So(schedule.At.In(schedule.Location).Hour(), ShouldEqual, 9)
+				if schedule.Location == tokyo {
+					So(schedule.Users, ShouldResemble, []string{"user-1", "user-3"})
+				}
+			}
+		})
+	})
+
+	Convey("The next time of day", t, func() {
+		now := time.Date(2018, 6, 1, 10, 0, 0, 0, time.UTC)
+		So(nextTimeOfDay(now, time.UTC, 11*time.Hour), ShouldResemble, time.Date(2018, 6, 1, 11, 0, 0, 0, time.UTC))
+		So(nextTimeOfDay(now, time.UTC, 9*time.Hour), ShouldResemble, time.Date(2018, 6, 2, 9, 0, 0, 0, time.UTC))
+
+		Convey("should follow the clocks on the days they change", func() {
+			berlin, err := time.LoadLocation("Europe/Berlin")
+			So(err, ShouldBeNil)
+
+			// clocks went forward from 2:00 to 3:00 on March 29 2020
+			now := time.Date(2020, 3, 29, 1, 0, 0, 0, berlin)
+			So(nextTimeOfDay(now, berlin, 9*time.Hour), ShouldEqual, time.Date(2020, 3, 29, 9, 0, 0, 0, berlin))
+			// and back from 3:00 to 2:00 on October 25 2020
+			now = time.Date(2020, 10, 25, 1, 0, 0, 0, berlin)
+			So(nextTimeOfDay(now, berlin, 9*time.Hour), ShouldEqual, time.Date(2020, 10, 25, 9, 0, 0, 0, berlin))
+			So(nextTimeOfDay(now, berlin, 0), ShouldEqual, time.Date(2020, 10, 26, 0, 0, 0, 0, berlin))
+		})
+	})
+}
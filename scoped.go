@@ -0,0 +1,150 @@
+package pushnotifications
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// An operation a client restricted with `Restrict` may be allowed to perform
+type Capability int
+
+const (
+	// The methods of `InterestPublisher`
+	PublishToInterestsCapability Capability = iota
+	// The methods of `UserPublisher`
+	PublishToUsersCapability
+	// The methods of `UserAuthenticator`
+	AuthenticateUsersCapability
+	// The methods of `UserDeleter`
+	DeleteUsersCapability
+)
+
+var capabilityNames = map[Capability]string{
+	PublishToInterestsCapability: "publish to interests",
+	PublishToUsersCapability:     "publish to users",
+	AuthenticateUsersCapability:  "authenticate users",
+	DeleteUsersCapability:        "delete users",
+}
+
+func (c Capability) String() string {
+	return capabilityNames[c]
+}
+
+// Returned, as the cause, by the methods of a restricted client it isn't allowed to perform
+var ErrNotPermitted = errors.New("The client is not permitted to perform this operation")
+
+// Returns a client that only performs the operations of the given capabilities, e.g. a publish-only
+// client for a worker, or an auth-only client for the service issuing tokens to the client SDKs.
+// Other operations fail with `ErrNotPermitted`. `Quota` and `Stats` are always allowed.
+func Restrict(pn PushNotifications, capabilities ...Capability) PushNotifications {
+	allowed := map[Capability]bool{}
+	for _, capability := range capabilities {
+		allowed[capability] = true
+	}
+	return &restricted{pn: pn, allowed: allowed}
+}
+
+type restricted struct {
+	pn      PushNotifications
+	allowed map[Capability]bool
+}
+
+func (r *restricted) check(capability Capability) error {
+	if !r.allowed[capability] {
+		return errors.Wrapf(ErrNotPermitted, "Failed to %s", capability)
+	}
+	return nil
+}
+
+func (r *restricted) PublishToInterests(interests []string, request map[string]interface{}, options ...CallOption) (string, error) {
+	if err := r.check(PublishToInterestsCapability); err != nil {
+		return "", err
+	}
+	return r.pn.PublishToInterests(interests, request, options...)
+}
+
+func (r *restricted) Publish(interests []string, request map[string]interface{}, options ...CallOption) (string, error) {
+	return r.PublishToInterests(interests, request, options...)
+}
+
+func (r *restricted) PublishToInterestsWithResponse(interests []string, request map[string]interface{}, options ...CallOption) (*PublishResponse, error) {
+	if err := r.check(PublishToInterestsCapability); err != nil {
+		return &PublishResponse{}, err
+	}
+	return r.pn.PublishToInterestsWithResponse(interests, request, options...)
+}
+
+func (r *restricted) PublishRawToInterests(interests []string, request json.RawMessage, options ...CallOption) (string, error) {
+	if err := r.check(PublishToInterestsCapability); err != nil {
+		return "", err
+	}
+	return r.pn.PublishRawToInterests(interests, request, options...)
+}
+
+func (r *restricted) PublishPayloadToInterests(interests []string, payload interface{}, options ...CallOption) (string, error) {
+	if err := r.check(PublishToInterestsCapability); err != nil {
+		return "", err
+	}
+	return r.pn.PublishPayloadToInterests(interests, payload, options...)
+}
+
+func (r *restricted) PublishToUsers(users []string, request map[string]interface{}, options ...CallOption) (string, error) {
+	if err := r.check(PublishToUsersCapability); err != nil {
+		return "", err
+	}
+	return r.pn.PublishToUsers(users, request, options...)
+}
+
+func (r *restricted) PublishToUsersWithResponse(users []string, request map[string]interface{}, options ...CallOption) (*PublishResponse, error) {
+	if err := r.check(PublishToUsersCapability); err != nil {
+		return &PublishResponse{}, err
+	}
+	return r.pn.PublishToUsersWithResponse(users, request, options...)
+}
+
+func (r *restricted) PublishRawToUsers(users []string, request json.RawMessage, options ...CallOption) (string, error) {
+	if err := r.check(PublishToUsersCapability); err != nil {
+		return "", err
+	}
+	return r.pn.PublishRawToUsers(users, request, options...)
+}
+
+func (r *restricted) PublishPayloadToUsers(users []string, payload interface{}, options ...CallOption) (string, error) {
+	if err := r.check(PublishToUsersCapability); err != nil {
+		return "", err
+	}
+	return r.pn.PublishPayloadToUsers(users, payload, options...)
+}
+
+func (r *restricted) PublishToUsersFromReader(reader io.Reader, format InputFormat, request map[string]interface{}, options ...CallOption) (*BulkResult, error) {
+	if err := r.check(PublishToUsersCapability); err != nil {
+		return nil, err
+	}
+	return r.pn.PublishToUsersFromReader(reader, format, request, options...)
+}
+
+func (r *restricted) PublishTransactional(userId string, request map[string]interface{}, options ...CallOption) (string, error) {
+	if err := r.check(PublishToUsersCapability); err != nil {
+		return "", err
+	}
+	return r.pn.PublishTransactional(userId, request, options...)
+}
+
+func (r *restricted) GenerateToken(userId string) (map[string]interface{}, error) {
+	if err := r.check(AuthenticateUsersCapability); err != nil {
+		return nil, err
+	}
+	return r.pn.GenerateToken(userId)
+}
+
+func (r *restricted) DeleteUser(userId string) error {
+	if err := r.check(DeleteUsersCapability); err != nil {
+		return err
+	}
+	return r.pn.DeleteUser(userId)
+}
+
+func (r *restricted) Quota() Quota { return r.pn.Quota() }
+func (r *restricted) Stats() Stats { return r.pn.Stats() }
@@ -0,0 +1,43 @@
+package pushnotifications
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRestrict(t *testing.T) {
+	Convey("A restricted Push Notifications Instance", t, func() {
+		requests := 0
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.Write([]byte(`{"publishId":"pub-123"}`))
+		}))
+		defer testServer.Close()
+
+		client, _ := New(testInstanceId, testSecretKey, WithCustomBaseURL(testServer.URL))
+		pn := Restrict(client, PublishToUsersCapability)
+		request := map[string]interface{}{"fcm": map[string]interface{}{}}
+
+		Convey("should perform the operations it is allowed to", func() {
+			publishId, err := pn.PublishToUsers([]string{"user-1"}, request)
+			So(err, ShouldBeNil)
+			So(publishId, ShouldEqual, "pub-123")
+			So(requests, ShouldEqual, 1)
+		})
+
+		Convey("should refuse the other operations", func() {
+			_, err := pn.PublishToInterests([]string{"hello"}, request)
+			So(errors.Cause(err), ShouldEqual, ErrNotPermitted)
+			So(err.Error(), ShouldStartWith, "Failed to publish to interests")
+
+			_, err = pn.GenerateToken("user-1")
+			So(errors.Cause(err), ShouldEqual, ErrNotPermitted)
+			So(errors.Cause(pn.DeleteUser("user-1")), ShouldEqual, ErrNotPermitted)
+			So(requests, ShouldEqual, 0)
+		})
+	})
+}
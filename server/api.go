@@ -0,0 +1,41 @@
+package server
+
+import _ "embed"
+
+// Where the OpenAPI specification is served
+const specPath = "/openapi.json"
+
+// The OpenAPI 3 specification of the gateway's API, to generate clients in other languages
+//
+//go:embed openapi.json
+var Spec []byte
+
+// Body of `POST /publish/interests`
+type PublishToInterestsRequest struct {
+	Interests []string `json:"interests"`
+	// A Beams publish request, with `apns`, `fcm` and `web` sections
+	Request map[string]interface{} `json:"request"`
+}
+
+// Body of `POST /publish/users`
+type PublishToUsersRequest struct {
+	Users []string `json:"users"`
+	// A Beams publish request, with `apns`, `fcm` and `web` sections
+	Request map[string]interface{} `json:"request"`
+}
+
+// Response to a successful publish
+type PublishResponse struct {
+	PublishId string `json:"publishId"`
+}
+
+// Response to `GET /auth-token`
+type TokenResponse struct {
+	Token string `json:"token"`
+}
+
+// Response to a failed request
+type ErrorResponse struct {
+	Error       string `json:"error"`
+	Description string `json:"description"`
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSpec(t *testing.T) {
+	Convey("The OpenAPI specification", t, func() {
+		spec := struct {
+			Paths      map[string]map[string]interface{} `json:"paths"`
+			Components struct {
+				Schemas map[string]struct {
+					Properties map[string]interface{} `json:"properties"`
+				} `json:"schemas"`
+			} `json:"components"`
+		}{}
+		So(json.Unmarshal(Spec, &spec), ShouldBeNil)
+
+		Convey("should describe every endpoint", func() {
+			So(spec.Paths, ShouldContainKey, "/publish/interests")
+			So(spec.Paths, ShouldContainKey, "/publish/users")
+			So(spec.Paths["/auth-token"], ShouldContainKey, "get")
+			So(spec.Paths["/users/{userId}"], ShouldContainKey, "delete")
+		})
+
+		Convey("should match the fields of the request and response types", func() {
+			for _, value := range []interface{}{
+				PublishToInterestsRequest{}, PublishToUsersRequest{}, PublishResponse{}, TokenResponse{}, ErrorResponse{},
+			} {
+				typ := reflect.TypeOf(value)
+				fields := []string{}
+				for i := 0; i < typ.NumField(); i++ {
+					fields = append(fields, strings.Split(typ.Field(i).Tag.Get("json"), ",")[0])
+				}
+				properties := []string{}
+				for property := range spec.Components.Schemas[typ.Name()].Properties {
+					properties = append(properties, property)
+				}
+				sort.Strings(fields)
+				sort.Strings(properties)
+				So(properties, ShouldResemble, fields)
+			}
+		})
+
+		Convey("should be served without an API key", func() {
+			handler, _ := New(nil, []string{testAPIKey})
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(w.Body.Bytes(), ShouldResemble, Spec)
+		})
+	})
+}
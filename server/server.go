@@ -0,0 +1,215 @@
+// Package server exposes a Beams client as a small REST service, so that services not written
+// in Go can publish, authenticate and delete users through one gateway holding the secret key.
+//
+// Every request must carry one of the gateway's API keys as `Authorization: Bearer <key>`:
+//
+//	POST   /publish/interests  {"interests": ["hello"], "request": {...}}  -> {"publishId": "..."}
+//	POST   /publish/users      {"users": ["user-1"], "request": {...}}    -> {"publishId": "..."}
+//	GET    /auth-token?user_id=user-1                                     -> {"token": "..."}
+//	DELETE /users/<user id>                                               -> 204 No Content
+//
+// Failures are returned as `{"error": "...", "description": "..."}`, like the Beams API.
+// The OpenAPI specification of the API is served, without an API key, at `/openapi.json`.
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+	pushnotifications "github.com/pusher/push-notifications-go"
+)
+
+const defaultMaxRequestSize = 1 << 20
+
+type Option func(*server)
+
+// Limits the size of request bodies, 1MiB by default
+func WithMaxRequestSize(bytes int64) Option {
+	return func(s *server) {
+		s.maxRequestSize = bytes
+	}
+}
+
+// Calls `logger` with every request that failed, e.g. to report it to an error tracker
+func WithErrorLogger(logger func(r *http.Request, err error)) Option {
+	return func(s *server) {
+		s.logError = logger
+	}
+}
+
+type server struct {
+	pn             pushnotifications.PushNotifications
+	apiKeys        [][]byte
+	maxRequestSize int64
+	logError       func(r *http.Request, err error)
+	mux            *http.ServeMux
+}
+
+// Creates a handler serving the REST API with `pn`, accepting requests carrying one of `apiKeys`.
+// Returns a non-nil error if no API key is given, as the gateway must never be left open.
+func New(pn pushnotifications.PushNotifications, apiKeys []string, options ...Option) (http.Handler, error) {
+	if len(apiKeys) == 0 {
+		return nil, errors.New("At least one API key is required")
+	}
+
+	s := &server{
+		pn:             pn,
+		maxRequestSize: defaultMaxRequestSize,
+		mux:            http.NewServeMux(),
+	}
+	for _, apiKey := range apiKeys {
+		if apiKey == "" {
+			return nil, errors.New("API keys cannot be empty strings")
+		}
+		s.apiKeys = append(s.apiKeys, []byte(apiKey))
+	}
+	for _, option := range options {
+		option(s)
+	}
+
+	s.mux.HandleFunc("/publish/interests", s.method(http.MethodPost, s.publishToInterests))
+	s.mux.HandleFunc("/publish/users", s.method(http.MethodPost, s.publishToUsers))
+	s.mux.HandleFunc("/auth-token", s.method(http.MethodGet, s.authToken))
+	s.mux.HandleFunc("/users/", s.method(http.MethodDelete, s.deleteUser))
+	return s, nil
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == specPath && r.Method == http.MethodGet {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(Spec)
+		return
+	}
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		s.fail(w, r, http.StatusUnauthorized, "Unauthorized", errors.New("A valid API key is required"))
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, s.maxRequestSize)
+	s.mux.ServeHTTP(w, r)
+}
+
+// Compares the key with every API key in constant time, so that timing doesn't reveal them
+func (s *server) authorized(r *http.Request) bool {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return false
+	}
+	key := []byte(strings.TrimPrefix(header, "Bearer "))
+	authorized := 0
+	for _, apiKey := range s.apiKeys {
+		authorized |= subtle.ConstantTimeCompare(key, apiKey)
+	}
+	return authorized == 1
+}
+
+func (s *server) method(method string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			s.fail(w, r, http.StatusMethodNotAllowed, "Method Not Allowed", errors.Errorf("Use %s", method))
+			return
+		}
+		handler(w, r)
+	}
+}
+
+func (s *server) publishToInterests(w http.ResponseWriter, r *http.Request) {
+	body := &PublishToInterestsRequest{}
+	if !s.decode(w, r, body, &body.Request) {
+		return
+	}
+	publishId, err := s.pn.PublishToInterests(body.Interests, body.Request)
+	s.respond(w, r, PublishResponse{PublishId: publishId}, err)
+}
+
+func (s *server) publishToUsers(w http.ResponseWriter, r *http.Request) {
+	body := &PublishToUsersRequest{}
+	if !s.decode(w, r, body, &body.Request) {
+		return
+	}
+	publishId, err := s.pn.PublishToUsers(body.Users, body.Request)
+	s.respond(w, r, PublishResponse{PublishId: publishId}, err)
+}
+
+func (s *server) authToken(w http.ResponseWriter, r *http.Request) {
+	token, err := s.pn.GenerateToken(r.URL.Query().Get("user_id"))
+	s.respond(w, r, token, err)
+}
+
+func (s *server) deleteUser(w http.ResponseWriter, r *http.Request) {
+	userId, err := url.PathUnescape(strings.TrimPrefix(r.URL.EscapedPath(), "/users/"))
+	if err != nil {
+		s.fail(w, r, http.StatusBadRequest, "Bad Request", errors.Wrap(err, "Invalid user id"))
+		return
+	}
+	if err := s.pn.DeleteUser(userId); err != nil {
+		s.respond(w, r, nil, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Decodes the body of a publish into `body`, whose publish request is `request`
+func (s *server) decode(w http.ResponseWriter, r *http.Request, body interface{}, request *map[string]interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(body); err != nil {
+		if tooLarge(err) {
+			s.fail(w, r, http.StatusRequestEntityTooLarge, "Payload Too Large",
+				errors.Errorf("The body is larger than the %d bytes the gateway accepts", s.maxRequestSize))
+			return false
+		}
+		s.fail(w, r, http.StatusBadRequest, "Bad Request", errors.Wrap(err, "The body must be a JSON publish request"))
+		return false
+	}
+	if *request == nil {
+		s.fail(w, r, http.StatusBadRequest, "Bad Request", errors.New("The body must have a `request` object"))
+		return false
+	}
+	return true
+}
+
+// Reports whether reading the body failed for being over the size `http.MaxBytesReader` allows.
+// The error is matched by its message, which is the same in the Go versions that lack `http.MaxBytesError`.
+func tooLarge(err error) bool {
+	return err.Error() == "http: request body too large"
+}
+
+func (s *server) respond(w http.ResponseWriter, r *http.Request, response interface{}, err error) {
+	if err != nil {
+		status, code := statusOf(err)
+		s.fail(w, r, status, code, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// Returns the status and error code of the gateway's response to a failed call to Beams
+func statusOf(err error) (int, string) {
+	switch pushnotifications.Classify(err) {
+	case pushnotifications.Validation:
+		return http.StatusBadRequest, "Bad Request"
+	case pushnotifications.PayloadTooLarge:
+		return http.StatusRequestEntityTooLarge, "Payload Too Large"
+	case pushnotifications.RateLimited:
+		return http.StatusTooManyRequests, "Too Many Requests"
+	default:
+		return http.StatusBadGateway, "Bad Gateway"
+	}
+}
+
+func (s *server) fail(w http.ResponseWriter, r *http.Request, status int, code string, err error) {
+	if s.logError != nil {
+		s.logError(r, err)
+	}
+	writeJSON(w, status, ErrorResponse{Error: code, Description: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	pushnotifications "github.com/pusher/push-notifications-go"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const (
+	testInstanceId = "a11aec92-146a-4708-9a62-8c61f46a82ad"
+	testSecretKey  = "EIJ2EESAH8DUUMAI8EE"
+	testAPIKey     = "gateway-key"
+)
+
+func TestServer(t *testing.T) {
+	Convey("A gateway", t, func() {
+		var beamsPath string
+		var beamsBody map[string]interface{}
+		beams := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			beamsPath = r.URL.Path
+			body, _ := io.ReadAll(r.Body)
+			json.Unmarshal(body, &beamsBody)
+			if strings.Contains(string(body), "rate-limited") {
+				w.WriteHeader(http.StatusTooManyRequests)
+				w.Write([]byte(`{"error":"Too Many Requests","description":"slow down"}`))
+				return
+			}
+			w.Write([]byte(`{"publishId":"pub-123"}`))
+		}))
+		defer beams.Close()
+
+		pn, _ := pushnotifications.New(testInstanceId, testSecretKey, pushnotifications.WithCustomBaseURL(beams.URL))
+		handler, err := New(pn, []string{"old-key", testAPIKey}, WithMaxRequestSize(1024))
+		So(err, ShouldBeNil)
+
+		call := func(method, path, body, apiKey string) (*httptest.ResponseRecorder, map[string]interface{}) {
+			r := httptest.NewRequest(method, path, strings.NewReader(body))
+			if apiKey != "" {
+				r.Header.Set("Authorization", "Bearer "+apiKey)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			response := map[string]interface{}{}
+			json.Unmarshal(w.Body.Bytes(), &response)
+			return w, response
+		}
+
+		Convey("should publish to interests", func() {
+			w, response := call(http.MethodPost, "/publish/interests",
+				`{"interests":["hello"],"request":{"fcm":{"notification":{"title":"Hi"}}}}`, testAPIKey)
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(response["publishId"], ShouldEqual, "pub-123")
+			So(beamsPath, ShouldEndWith, "/publishes")
+			So(beamsBody["interests"], ShouldResemble, []interface{}{"hello"})
+		})
+
+		Convey("should publish to users", func() {
+			w, _ := call(http.MethodPost, "/publish/users", `{"users":["user-1"],"request":{}}`, testAPIKey)
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(beamsBody["users"], ShouldResemble, []interface{}{"user-1"})
+		})
+
+		Convey("should generate auth tokens", func() {
+			w, response := call(http.MethodGet, "/auth-token?user_id=user-1", "", testAPIKey)
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(response["token"], ShouldNotBeEmpty)
+		})
+
+		Convey("should delete users", func() {
+			w, _ := call(http.MethodDelete, "/users/user%2F1", "", testAPIKey)
+			So(w.Code, ShouldEqual, http.StatusNoContent)
+			So(beamsPath, ShouldEndWith, "/users/user/1")
+		})
+
+		Convey("should reject requests without a valid API key", func() {
+			w, response := call(http.MethodPost, "/publish/users", `{"users":["user-1"],"request":{}}`, "wrong")
+			So(w.Code, ShouldEqual, http.StatusUnauthorized)
+			So(response["error"], ShouldEqual, "Unauthorized")
+			So(beamsBody, ShouldBeNil)
+
+			w, _ = call(http.MethodPost, "/publish/users", `{"users":["user-1"],"request":{}}`, "")
+			So(w.Code, ShouldEqual, http.StatusUnauthorized)
+		})
+
+		Convey("should reject invalid requests", func() {
+			w, _ := call(http.MethodGet, "/publish/users", "", testAPIKey)
+			So(w.Code, ShouldEqual, http.StatusMethodNotAllowed)
+
+			w, _ = call(http.MethodPost, "/publish/users", `{"users":["user-1"]}`, testAPIKey)
+			So(w.Code, ShouldEqual, http.StatusBadRequest)
+
+			w, _ = call(http.MethodPost, "/publish/users", `{"users":[],"request":{}}`, testAPIKey)
+			So(w.Code, ShouldEqual, http.StatusBadRequest)
+
+			w, _ = call(http.MethodPost, "/publish/users", `{"users":["`+strings.Repeat("a", 200)+`"],"request":{}}`, testAPIKey)
+			So(w.Code, ShouldEqual, http.StatusBadRequest)
+			So(beamsBody, ShouldBeNil)
+		})
+
+		Convey("should reject bodies over the maximum size", func() {
+			w, response := call(http.MethodPost, "/publish/users", `{"users":["`+strings.Repeat("a", 2000)+`"],"request":{}}`, testAPIKey)
+			So(w.Code, ShouldEqual, http.StatusRequestEntityTooLarge)
+			So(response["error"], ShouldEqual, "Payload Too Large")
+			So(response["description"], ShouldEqual, "The body is larger than the 1024 bytes the gateway accepts")
+			So(beamsBody, ShouldBeNil)
+		})
+
+		Convey("should pass on rate limiting", func() {
+			w, response := call(http.MethodPost, "/publish/users", `{"users":["rate-limited"],"request":{}}`, testAPIKey)
+			So(w.Code, ShouldEqual, http.StatusTooManyRequests)
+			So(response["description"], ShouldContainSubstring, "slow down")
+		})
+
+		Convey("should not be created without API keys", func() {
+			_, err := New(pn, nil)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
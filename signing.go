@@ -0,0 +1,112 @@
+package pushnotifications
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// Key of the signature added to the `data` of every platform section by `WithSigner`
+const SignatureDataKey = "beams_signature"
+
+// Signs the custom `data` of notifications so that apps can check they were sent by your servers
+type Signer interface {
+	// Id of the key, so that apps can pick the right key to verify with while keys are being rotated
+	KeyId() string
+	// Name of the signature algorithm, such as "HS256"
+	Algorithm() string
+	Sign(message []byte) ([]byte, error)
+}
+
+// Returns a `Signer` computing an HMAC-SHA256 of the data with a secret shared with the apps
+func HMACSigner(keyId string, secret []byte) Signer {
+	return hmacSigner{keyId: keyId, secret: secret}
+}
+
+type hmacSigner struct {
+	keyId  string
+	secret []byte
+}
+
+func (s hmacSigner) KeyId() string     { return s.keyId }
+func (s hmacSigner) Algorithm() string { return "HS256" }
+
+func (s hmacSigner) Sign(message []byte) ([]byte, error) {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write(message)
+	return mac.Sum(nil), nil
+}
+
+// Returns a `Signer` using an Ed25519 private key, such as an `ed25519.PrivateKey`,
+// so that apps only need the public key to verify notifications
+func Ed25519Signer(keyId string, key crypto.Signer) Signer {
+	return ed25519Signer{keyId: keyId, key: key}
+}
+
+type ed25519Signer struct {
+	keyId string
+	key   crypto.Signer
+}
+
+func (s ed25519Signer) KeyId() string     { return s.keyId }
+func (s ed25519Signer) Algorithm() string { return "EdDSA" }
+
+func (s ed25519Signer) Sign(message []byte) ([]byte, error) {
+	// Ed25519 signs the message itself rather than a digest of it
+	return s.key.Sign(rand.Reader, message, crypto.Hash(0))
+}
+
+// Adds a signature of the `data` of the `apns`, `fcm` and `web` sections of a JSON publish request.
+// The signature is stored in the data under `SignatureDataKey` as "<key id>.<algorithm>.<signature>",
+// with the signature base64url encoded without padding. It covers the JSON encoding of the
+// data without the signature key, with object keys sorted, no whitespace and no HTML escaping.
+func signRequestBody(signer Signer, bodyRequestBytes []byte) ([]byte, error) {
+	request := map[string]interface{}{}
+	decoder := json.NewDecoder(bytes.NewReader(bodyRequestBytes))
+	decoder.UseNumber()
+	if err := decoder.Decode(&request); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode the publish request to sign it")
+	}
+
+	for _, platform := range []string{"apns", "fcm", "web"} {
+		section, ok := request[platform].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		data, ok := section["data"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		delete(data, SignatureDataKey)
+		message, err := canonicalJSON(data)
+		if err != nil {
+			return nil, err
+		}
+		signature, err := signer.Sign(message)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to sign the notification data")
+		}
+		data[SignatureDataKey] = signer.KeyId() + "." + signer.Algorithm() + "." +
+			base64.RawURLEncoding.EncodeToString(signature)
+	}
+
+	return canonicalJSON(request)
+}
+
+// Encodes `v` with sorted object keys, without whitespace or HTML escaping
+func canonicalJSON(v interface{}) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+	encoder := json.NewEncoder(buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, errors.Wrap(err, "Failed to marshal the notification data")
+	}
+	return bytes.TrimSuffix(buffer.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,94 @@
+package pushnotifications
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type fakeCryptoSigner struct {
+	opts crypto.SignerOpts
+}
+
+func (s *fakeCryptoSigner) Public() crypto.PublicKey {
+	return nil
+}
+
+func (s *fakeCryptoSigner) Sign(rand io.Reader, message []byte, opts crypto.SignerOpts) ([]byte, error) {
+	s.opts = opts
+	return []byte("signature of " + string(message)), nil
+}
+
+func TestSigning(t *testing.T) {
+	Convey("A Push Notifications Instance signing notification data", t, func() {
+		var body map[string]interface{}
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			bodyBytes, _ := io.ReadAll(r.Body)
+			body = map[string]interface{}{}
+			json.Unmarshal(bodyBytes, &body)
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"publishId":"pub-123"}`))
+		}))
+		defer testServer.Close()
+
+		request := func() map[string]interface{} {
+			return map[string]interface{}{
+				"apns": map[string]interface{}{
+					"aps":  map[string]interface{}{"alert": "Hi"},
+					"data": map[string]interface{}{"link": "app://orders?id=1&x=<2>", "count": 3},
+				},
+				"web": map[string]interface{}{
+					"notification": map[string]interface{}{"title": "Hi"},
+				},
+			}
+		}
+
+		Convey("should add an HMAC of the data of each section", func() {
+			secret := []byte("shared secret")
+			pn, err := New(testInstanceId, testSecretKey,
+				WithCustomBaseURL(testServer.URL), WithSigner(HMACSigner("key-1", secret)))
+			So(err, ShouldBeNil)
+
+			_, err = pn.PublishToInterests([]string{"hello"}, request())
+			So(err, ShouldBeNil)
+
+			data := body["apns"].(map[string]interface{})["data"].(map[string]interface{})
+			signature := strings.Split(data[SignatureDataKey].(string), ".")
+			So(signature[0], ShouldEqual, "key-1")
+			So(signature[1], ShouldEqual, "HS256")
+
+			mac := hmac.New(sha256.New, secret)
+			mac.Write([]byte(`{"count":3,"link":"app://orders?id=1&x=<2>"}`))
+			So(signature[2], ShouldEqual, base64.RawURLEncoding.EncodeToString(mac.Sum(nil)))
+
+			So(body["web"], ShouldResemble, map[string]interface{}{
+				"notification": map[string]interface{}{"title": "Hi"},
+			})
+			So(body["interests"], ShouldResemble, []interface{}{"hello"})
+		})
+
+		Convey("should sign raw requests with an Ed25519 key", func() {
+			key := &fakeCryptoSigner{}
+			pn, err := New(testInstanceId, testSecretKey,
+				WithCustomBaseURL(testServer.URL), WithSigner(Ed25519Signer("key-2", key)))
+			So(err, ShouldBeNil)
+
+			_, err = pn.PublishRawToUsers([]string{"user-1"}, json.RawMessage(`{"fcm":{"data":{"b":"2","a":"1"}}}`))
+			So(err, ShouldBeNil)
+
+			data := body["fcm"].(map[string]interface{})["data"].(map[string]interface{})
+			So(data[SignatureDataKey], ShouldEqual,
+				"key-2.EdDSA."+base64.RawURLEncoding.EncodeToString([]byte(`signature of {"a":"1","b":"2"}`)))
+			So(key.opts, ShouldEqual, crypto.Hash(0))
+		})
+	})
+}
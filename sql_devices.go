@@ -0,0 +1,129 @@
+package pushnotifications
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const defaultDeviceTable = "beams_devices"
+
+// Returns the statements creating the table used by `NewSQLDeviceStore`, "beams_devices" if `table` is empty,
+// in SQL understood by PostgreSQL, MySQL and SQLite. Run them one at a time, as drivers such as
+// MySQL's reject several statements in one call by default.
+func DeviceTableSchema(table string) []string {
+	if table == "" {
+		table = defaultDeviceTable
+	}
+	return []string{
+		fmt.Sprintf(`CREATE TABLE %s (
+	platform VARCHAR(16) NOT NULL,
+	token VARCHAR(512) NOT NULL,
+	user_id VARCHAR(164) NOT NULL,
+	registered_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (platform, token)
+)`, table),
+		fmt.Sprintf("CREATE INDEX %s_user_id ON %s (user_id)", table, table),
+	}
+}
+
+// Configures a `DeviceStore` backed by a SQL database
+type SQLDeviceStoreConfig struct {
+	// Name of the table, "beams_devices" by default. Create it with the statements of `DeviceTableSchema`.
+	Table string
+	// Returns the placeholder for the n-th query argument (starting at 1).
+	// By default "?" is used, as MySQL and SQLite expect; PostgreSQL needs `PostgresPlaceholder`.
+	Placeholder func(n int) string
+}
+
+// Returns PostgreSQL placeholders, such as `$1`
+func PostgresPlaceholder(n int) string {
+	return fmt.Sprintf("$%d", n)
+}
+
+type sqlDeviceStore struct {
+	db          *sql.DB
+	table       string
+	placeholder func(n int) string
+}
+
+// Returns a `DeviceStore` keeping devices in a table of `db` created with `DeviceTableSchema`.
+// It only uses portable SQL, so works with any driver.
+func NewSQLDeviceStore(db *sql.DB, config SQLDeviceStoreConfig) DeviceStore {
+	s := &sqlDeviceStore{
+		db:          db,
+		table:       config.Table,
+		placeholder: config.Placeholder,
+	}
+	if s.table == "" {
+		s.table = defaultDeviceTable
+	}
+	if s.placeholder == nil {
+		s.placeholder = func(int) string { return "?" }
+	}
+	return s
+}
+
+// Fills in the table name, and replaces the `?`s in `query` with the placeholders of the database
+func (s *sqlDeviceStore) query(query string) string {
+	parts := strings.Split(fmt.Sprintf(query, s.table), "?")
+	expanded := parts[0]
+	for i, part := range parts[1:] {
+		expanded += s.placeholder(i+1) + part
+	}
+	return expanded
+}
+
+func (s *sqlDeviceStore) Register(device Device) error {
+	if err := validateDevice(device); err != nil {
+		return err
+	}
+	if device.RegisteredAt.IsZero() {
+		device.RegisteredAt = time.Now()
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "Failed to register the device")
+	}
+	// delete then insert, as every database has its own syntax for upserts
+	_, err = tx.Exec(s.query("DELETE FROM %s WHERE platform = ? AND token = ?"), device.Platform, device.Token)
+	if err == nil {
+		_, err = tx.Exec(
+			s.query("INSERT INTO %s (platform, token, user_id, registered_at) VALUES (?, ?, ?, ?)"),
+			device.Platform, device.Token, device.UserId, device.RegisteredAt.UTC())
+	}
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "Failed to register the device")
+	}
+	return errors.Wrap(tx.Commit(), "Failed to register the device")
+}
+
+func (s *sqlDeviceStore) Unregister(platform, token string) error {
+	_, err := s.db.Exec(s.query("DELETE FROM %s WHERE platform = ? AND token = ?"), platform, token)
+	return errors.Wrap(err, "Failed to unregister the device")
+}
+
+func (s *sqlDeviceStore) DevicesForUser(userId string) ([]Device, error) {
+	rows, err := s.db.Query(
+		s.query("SELECT platform, token, user_id, registered_at FROM %s WHERE user_id = ? ORDER BY platform, token"),
+		userId)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to look up the devices of the user")
+	}
+	defer rows.Close()
+
+	devices := []Device{}
+	for rows.Next() {
+		device := Device{}
+		if err := rows.Scan(&device.Platform, &device.Token, &device.UserId, &device.RegisteredAt); err != nil {
+			return nil, errors.Wrap(err, "Failed to read the devices of the user")
+		}
+		devices = append(devices, device)
+	}
+	return devices, errors.Wrap(rows.Err(), "Failed to read the devices of the user")
+}
@@ -0,0 +1,27 @@
+package pushnotifications
+
+// Counters of the work done by a `PushNotifications` instance since it was created.
+// It marshals to JSON, so it can be published with `expvar.Func`.
+type Stats struct {
+	// Publishes accepted by the Beams service
+	PublishesSent uint64
+	// Publishes that failed after every attempt was made
+	PublishesFailed uint64
+	// Requests that were retried after a failed attempt
+	Retries uint64
+	// Bytes of publish request bodies sent, including retries
+	BytesSent uint64
+}
+
+func (pn *pushNotifications) Stats() Stats {
+	pn.statsMutex.Lock()
+	defer pn.statsMutex.Unlock()
+
+	return pn.stats
+}
+
+func (pn *pushNotifications) updateStats(update func(stats *Stats)) {
+	pn.statsMutex.Lock()
+	update(&pn.stats)
+	pn.statsMutex.Unlock()
+}
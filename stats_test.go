@@ -0,0 +1,69 @@
+package pushnotifications
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestStats(t *testing.T) {
+	Convey("A Push Notifications Instance counting its work", t, func() {
+		responses := []int{}
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			status := http.StatusOK
+			if len(responses) > 0 {
+				status, responses = responses[0], responses[1:]
+			}
+			w.WriteHeader(status)
+			if status == http.StatusOK {
+				w.Write([]byte(`{"publishId":"pub-123"}`))
+			} else {
+				w.Write([]byte(`{"error":"Something went wrong","description":"Try again"}`))
+			}
+		}))
+		defer testServer.Close()
+
+		pn, err := New(testInstanceId, testSecretKey,
+			WithCustomBaseURL(testServer.URL),
+			WithRetryPolicy(RetryPolicy{
+				MaxAttempts: 2,
+				Retryable:   map[ErrorClass]bool{ServerError: true},
+			}),
+		)
+		So(err, ShouldBeNil)
+
+		Convey("should start with zero counters", func() {
+			So(pn.Stats(), ShouldResemble, Stats{})
+		})
+
+		Convey("should count sent publishes and their bytes", func() {
+			_, err := pn.PublishToInterests([]string{"hello"}, map[string]interface{}{})
+			So(err, ShouldBeNil)
+
+			So(pn.Stats(), ShouldResemble, Stats{
+				PublishesSent: 1,
+				BytesSent:     uint64(len(`{"interests":["hello"]}`)),
+			})
+		})
+
+		Convey("should count retries and failed publishes", func() {
+			responses = []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable}
+			_, err := pn.PublishToUsers([]string{"user-1"}, map[string]interface{}{})
+			So(err, ShouldNotBeNil)
+
+			stats := pn.Stats()
+			So(stats.PublishesSent, ShouldEqual, 0)
+			So(stats.PublishesFailed, ShouldEqual, 1)
+			So(stats.Retries, ShouldEqual, 1)
+			So(stats.BytesSent, ShouldEqual, 2*len(`{"users":["user-1"]}`))
+		})
+
+		Convey("should not count publishes rejected by validation", func() {
+			_, err := pn.PublishToUsers([]string{}, map[string]interface{}{})
+			So(err, ShouldNotBeNil)
+			So(pn.Stats(), ShouldResemble, Stats{})
+		})
+	})
+}
@@ -0,0 +1,183 @@
+package pushnotifications
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Reason of the suppressions made by `QuietHoursRule`
+var ErrQuietHours = errors.New("The user is in their quiet hours")
+
+// A user left out of a publish by a `SuppressionRule`
+type Suppression struct {
+	UserId string
+	// Why the user was left out, e.g. `ErrQuietHours`
+	Reason error
+	// When the user can be notified again, for callers that defer the notification.
+	// Zero if the notification should be dropped instead.
+	RetryAt time.Time
+}
+
+// Decides whether a user should be left out of publishes, e.g. because of their quiet hours
+type SuppressionRule interface {
+	// Returns a non-nil `Suppression` if the user should not be notified at `now`
+	Check(userId string, now time.Time) (*Suppression, error)
+}
+
+// Returned by publishes to users when every user was suppressed
+type SuppressedError struct {
+	Suppressions []Suppression
+}
+
+func (e *SuppressedError) Error() string {
+	return fmt.Sprintf("All %d users were suppressed: %s", len(e.Suppressions), e.Suppressions[0].Reason)
+}
+
+// Leaves users out of publishes to users when one of `rules` suppresses them, passing each
+// suppression to `onSuppressed` (which may be nil) so the notification can be deferred.
+// Publishes where every user is suppressed fail with a `*SuppressedError`.
+// Publishes to interests are not affected, as their audience isn't known.
+func WithSuppression(onSuppressed func(Suppression), rules ...SuppressionRule) Option {
+	return WithPublishStage(ValidateStage, suppressionStage(onSuppressed, func(*PublishJob) (suppressionCheck, error) {
+		return func(userId string, now time.Time) (*Suppression, error) {
+			return checkSuppression(rules, userId, now)
+		}, nil
+	}))
+}
+
+// Returns a suppression if the user should be left out of a publish
+type suppressionCheck func(userId string, now time.Time) (*Suppression, error)
+
+// Returns a stage leaving users out of publishes when the check made for the publish by `newCheck` suppresses them
+func suppressionStage(onSuppressed func(Suppression), newCheck func(job *PublishJob) (suppressionCheck, error)) PublishStage {
+	return PublishStageFunc(func(job *PublishJob) error {
+		if job.Operation != publishToUsersOperation {
+			return nil
+		}
+		check, err := newCheck(job)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		allowed := make([]string, 0, len(job.Targets))
+		suppressions := []Suppression{}
+		for _, userId := range job.Targets {
+			suppression, err := check(userId, now)
+			if err != nil {
+				return err
+			}
+			if suppression == nil {
+				allowed = append(allowed, userId)
+				continue
+			}
+			suppressions = append(suppressions, *suppression)
+			if onSuppressed != nil {
+				onSuppressed(*suppression)
+			}
+		}
+
+		if len(allowed) == 0 {
+			return &SuppressedError{Suppressions: suppressions}
+		}
+		if len(suppressions) > 0 {
+			job.Warn(AudienceReducedWarning, "%d of %d users were suppressed", len(suppressions), len(job.Targets))
+		}
+		job.Targets = allowed
+		return nil
+	})
+}
+
+func checkSuppression(rules []SuppressionRule, userId string, now time.Time) (*Suppression, error) {
+	for _, rule := range rules {
+		suppression, err := rule.Check(userId, now)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to check whether user `%s` is suppressed", userId)
+		}
+		if suppression != nil {
+			return suppression, nil
+		}
+	}
+	return nil, nil
+}
+
+// A daily period during which a user doesn't want to be notified
+type QuietHours struct {
+	// Start and end as times of day, e.g. 22*time.Hour and 7*time.Hour.
+	// A start after the end means the period spans midnight.
+	Start time.Duration
+	End   time.Duration
+	// Time zone of the user, UTC if nil
+	Location *time.Location
+}
+
+// Reports whether `t` falls in the quiet hours, and if so when they end
+func (q QuietHours) contains(t time.Time) (bool, time.Time) {
+	location := q.Location
+	if location == nil {
+		location = time.UTC
+	}
+	t = t.In(location)
+	clock := clockOf(t)
+
+	switch {
+	case q.Start <= q.End && clock >= q.Start && clock < q.End:
+		return true, atClock(t, 0, q.End)
+	case q.Start > q.End && clock >= q.Start:
+		return true, atClock(t, 1, q.End)
+	case q.Start > q.End && clock < q.End:
+		return true, atClock(t, 0, q.End)
+	default:
+		return false, time.Time{}
+	}
+}
+
+// Returns the time of day `t` shows on the clocks of its location, which on days
+// clocks change is not the time elapsed since midnight
+func clockOf(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+}
+
+// Returns the time clocks show `clock` on the day `days` after that of `t`, in its location
+func atClock(t time.Time, days int, clock time.Duration) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day()+days,
+		int(clock/time.Hour), int(clock%time.Hour/time.Minute), int(clock%time.Minute/time.Second), int(clock%time.Second),
+		t.Location())
+}
+
+// Looks up the quiet hours of users
+type QuietHoursStore interface {
+	// Returns the quiet hours of the user, and false if they have none
+	QuietHours(userId string) (QuietHours, bool, error)
+}
+
+// A `QuietHoursStore` of quiet hours by user id, for tests and fixed configurations
+type QuietHoursMap map[string]QuietHours
+
+func (m QuietHoursMap) QuietHours(userId string) (QuietHours, bool, error) {
+	hours, ok := m[userId]
+	return hours, ok, nil
+}
+
+// Returns a rule deferring notifications to users in their quiet hours until the hours end
+func QuietHoursRule(store QuietHoursStore) SuppressionRule {
+	return quietHoursRule{store: store}
+}
+
+type quietHoursRule struct {
+	store QuietHoursStore
+}
+
+func (r quietHoursRule) Check(userId string, now time.Time) (*Suppression, error) {
+	hours, ok, err := r.store.QuietHours(userId)
+	if err != nil || !ok {
+		return nil, err
+	}
+	if quiet, end := hours.contains(now); quiet {
+		return &Suppression{UserId: userId, Reason: ErrQuietHours, RetryAt: end}, nil
+	}
+	return nil, nil
+}
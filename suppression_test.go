@@ -0,0 +1,140 @@
+package pushnotifications
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type suppressAll struct{}
+
+func (suppressAll) Check(userId string, now time.Time) (*Suppression, error) {
+	return &Suppression{UserId: userId, Reason: ErrQuietHours}, nil
+}
+
+func TestQuietHours(t *testing.T) {
+	Convey("Quiet hours", t, func() {
+		at := func(hour, minute int) time.Time {
+			return time.Date(2020, 3, 1, hour, minute, 0, 0, time.UTC)
+		}
+
+		Convey("should cover a period within a day", func() {
+			hours := QuietHours{Start: 13 * time.Hour, End: 14 * time.Hour}
+			quiet, end := hours.contains(at(13, 30))
+			So(quiet, ShouldBeTrue)
+			So(end, ShouldEqual, at(14, 0))
+
+			quiet, _ = hours.contains(at(14, 0))
+			So(quiet, ShouldBeFalse)
+		})
+
+		Convey("should cover a period spanning midnight", func() {
+			hours := QuietHours{Start: 22 * time.Hour, End: 7 * time.Hour}
+			quiet, end := hours.contains(at(23, 0))
+			So(quiet, ShouldBeTrue)
+			So(end, ShouldEqual, at(7, 0).AddDate(0, 0, 1))
+
+			quiet, end = hours.contains(at(6, 59))
+			So(quiet, ShouldBeTrue)
+			So(end, ShouldEqual, at(7, 0))
+
+			quiet, _ = hours.contains(at(12, 0))
+			So(quiet, ShouldBeFalse)
+		})
+
+		Convey("should use the time zone of the user", func() {
+			hours := QuietHours{Start: 22 * time.Hour, End: 7 * time.Hour, Location: time.FixedZone("UTC+3", 3*3600)}
+			quiet, end := hours.contains(at(20, 0))
+			So(quiet, ShouldBeTrue)
+			So(end, ShouldEqual, at(4, 0).AddDate(0, 0, 1))
+		})
+
+		Convey("should follow the clocks on the days they change", func() {
+			berlin, err := time.LoadLocation("Europe/Berlin")
+			So(err, ShouldBeNil)
+			hours := QuietHours{Start: 22 * time.Hour, End: 7 * time.Hour, Location: berlin}
+
+			// clocks went forward from 2:00 to 3:00 on March 29 2020
+			quiet, end := hours.contains(time.Date(2020, 3, 29, 6, 30, 0, 0, berlin))
+			So(quiet, ShouldBeTrue)
+			So(end, ShouldEqual, time.Date(2020, 3, 29, 7, 0, 0, 0, berlin))
+			quiet, _ = hours.contains(time.Date(2020, 3, 29, 7, 30, 0, 0, berlin))
+			So(quiet, ShouldBeFalse)
+
+			// and back from 3:00 to 2:00 on October 25 2020
+			quiet, _ = hours.contains(time.Date(2020, 10, 25, 6, 30, 0, 0, berlin))
+			So(quiet, ShouldBeTrue)
+			quiet, end = hours.contains(time.Date(2020, 10, 24, 23, 0, 0, 0, berlin))
+			So(quiet, ShouldBeTrue)
+			So(end, ShouldEqual, time.Date(2020, 10, 25, 7, 0, 0, 0, berlin))
+		})
+
+		Convey("should defer notifications until the quiet hours end", func() {
+			rule := QuietHoursRule(QuietHoursMap{"user-1": {Start: 13 * time.Hour, End: 14 * time.Hour}})
+			suppression, err := rule.Check("user-1", at(13, 0))
+			So(err, ShouldBeNil)
+			So(suppression, ShouldResemble, &Suppression{UserId: "user-1", Reason: ErrQuietHours, RetryAt: at(14, 0)})
+
+			suppression, err = rule.Check("user-2", at(13, 0))
+			So(err, ShouldBeNil)
+			So(suppression, ShouldBeNil)
+		})
+	})
+
+	Convey("A Push Notifications Instance with suppression rules", t, func() {
+		var body map[string]interface{}
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			bodyBytes, _ := io.ReadAll(r.Body)
+			body = map[string]interface{}{}
+			json.Unmarshal(bodyBytes, &body)
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"publishId":"pub-123"}`))
+		}))
+		defer testServer.Close()
+
+		now := time.Now().UTC()
+		sinceMidnight := now.Sub(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC))
+		quietNow := QuietHours{Start: sinceMidnight - time.Minute, End: sinceMidnight + time.Hour}
+		if quietNow.Start < 0 {
+			quietNow.Start += 24 * time.Hour
+		}
+
+		suppressed := []Suppression{}
+		pn, err := New(testInstanceId, testSecretKey,
+			WithCustomBaseURL(testServer.URL),
+			WithSuppression(func(suppression Suppression) {
+				suppressed = append(suppressed, suppression)
+			}, QuietHoursRule(QuietHoursMap{"user-2": quietNow})),
+		)
+		So(err, ShouldBeNil)
+
+		Convey("should leave suppressed users out and report them", func() {
+			_, err := pn.PublishToUsers([]string{"user-1", "user-2"}, map[string]interface{}{})
+			So(err, ShouldBeNil)
+			So(body["users"], ShouldResemble, []interface{}{"user-1"})
+
+			So(len(suppressed), ShouldEqual, 1)
+			So(suppressed[0].UserId, ShouldEqual, "user-2")
+			So(suppressed[0].Reason, ShouldEqual, ErrQuietHours)
+			So(suppressed[0].RetryAt.After(now), ShouldBeTrue)
+		})
+
+		Convey("should fail when every user is suppressed", func() {
+			_, err := pn.PublishToUsers([]string{"user-2"}, map[string]interface{}{})
+			So(err, ShouldHaveSameTypeAs, &SuppressedError{})
+			So(err.Error(), ShouldEqual, "All 1 users were suppressed: The user is in their quiet hours")
+			So(body, ShouldBeNil)
+		})
+
+		Convey("should not affect publishes to interests", func() {
+			pn, _ := New(testInstanceId, testSecretKey, WithCustomBaseURL(testServer.URL), WithSuppression(nil, suppressAll{}))
+			_, err := pn.PublishToInterests([]string{"hello"}, map[string]interface{}{})
+			So(err, ShouldBeNil)
+		})
+	})
+}
@@ -0,0 +1,85 @@
+package pushnotifications
+
+import "unicode"
+
+const (
+	ellipsis      = "…"
+	zeroWidthJoin = '\u200d'
+)
+
+// Approximate number of characters each platform displays before cutting text off
+type textLimits struct {
+	title    int
+	subtitle int
+	body     int
+}
+
+var platformTextLimits = map[string]textLimits{
+	"apns": {title: 50, subtitle: 50, body: 178},
+	"fcm":  {title: 65, body: 240},
+	"web":  {title: 50, body: 120},
+}
+
+// Shortens `text` to what `platform` ("apns", "fcm" or "web") displays in a notification body,
+// ending it with an ellipsis when it had to be cut. Text is only cut between user-perceived
+// characters, so multi-byte runes, accented letters, flags and emoji sequences are kept whole.
+// Text for an unknown platform is returned unchanged.
+func TruncateForPlatform(text string, platform string) string {
+	limits, ok := platformTextLimits[platform]
+	if !ok {
+		return text
+	}
+	return Truncate(text, limits.body)
+}
+
+// Shortens `text` to at most `max` user-perceived characters, including the ellipsis
+// added when it had to be cut
+func Truncate(text string, max int) string {
+	if max <= 0 {
+		return ""
+	}
+	boundaries := graphemeBoundaries(text)
+	if len(boundaries) <= max {
+		return text
+	}
+	return text[:boundaries[max-1]] + ellipsis
+}
+
+// Returns the byte offset at which each user-perceived character of `text` starts.
+// This follows the main rules of Unicode text segmentation (UAX #29) that matter in
+// notifications: combining marks, variation selectors, emoji modifiers and tags,
+// zero width joiner sequences, flags and CRLF, without needing the full property tables.
+func graphemeBoundaries(text string) []int {
+	boundaries := []int{}
+	var previous rune
+	regionalIndicators := 0
+	for i, r := range text {
+		joins := i > 0 && (isGraphemeExtend(r) ||
+			previous == zeroWidthJoin ||
+			(previous == '\r' && r == '\n') ||
+			(isRegionalIndicator(r) && regionalIndicators%2 == 1))
+
+		if isRegionalIndicator(r) {
+			regionalIndicators++
+		} else {
+			regionalIndicators = 0
+		}
+		if !joins {
+			boundaries = append(boundaries, i)
+		}
+		previous = r
+	}
+	return boundaries
+}
+
+func isGraphemeExtend(r rune) bool {
+	return unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc) ||
+		r == zeroWidthJoin ||
+		(r >= 0xfe00 && r <= 0xfe0f) || // variation selectors
+		(r >= 0x1f3fb && r <= 0x1f3ff) || // emoji skin tone modifiers
+		(r >= 0xe0020 && r <= 0xe007f) // tags, used by subdivision flags
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1f1e6 && r <= 0x1f1ff
+}
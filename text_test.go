@@ -0,0 +1,49 @@
+package pushnotifications
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTruncation(t *testing.T) {
+	Convey("Truncating notification text", t, func() {
+		Convey("should leave short text unchanged", func() {
+			So(Truncate("Hello", 5), ShouldEqual, "Hello")
+			So(TruncateForPlatform("Hello", "web"), ShouldEqual, "Hello")
+		})
+
+		Convey("should cut long text and add an ellipsis", func() {
+			So(Truncate("Hello, world", 6), ShouldEqual, "Hello…")
+		})
+
+		Convey("should count multi-byte runes as one character", func() {
+			So(Truncate("日本語のテキスト", 4), ShouldEqual, "日本語…")
+		})
+
+		Convey("should keep combining marks with their letter", func() {
+			So(Truncate("café au lait", 5), ShouldEqual, "café…")
+		})
+
+		Convey("should keep emoji sequences whole", func() {
+			family := "👨‍👩‍👧"
+			thumbsUp := "👍🏽"
+			So(Truncate(family+family+family, 2), ShouldEqual, family+"…")
+			So(Truncate(thumbsUp+thumbsUp+"!", 2), ShouldEqual, thumbsUp+"…")
+		})
+
+		Convey("should keep flags whole", func() {
+			So(Truncate("🇬🇧🇫🇷🇩🇪", 3), ShouldEqual, "🇬🇧🇫🇷🇩🇪")
+			So(Truncate("🇬🇧🇫🇷🇩🇪🇮🇹", 3), ShouldEqual, "🇬🇧🇫🇷…")
+		})
+
+		Convey("should use the body limit of each platform", func() {
+			long := strings.Repeat("a", 500)
+			So(len([]rune(TruncateForPlatform(long, "apns"))), ShouldEqual, 178)
+			So(len([]rune(TruncateForPlatform(long, "fcm"))), ShouldEqual, 240)
+			So(len([]rune(TruncateForPlatform(long, "web"))), ShouldEqual, 120)
+			So(TruncateForPlatform(long, "unknown"), ShouldEqual, long)
+		})
+	})
+}
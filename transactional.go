@@ -0,0 +1,72 @@
+package pushnotifications
+
+import (
+	"strconv"
+	"time"
+)
+
+const (
+	transactionalOperation     = "publish_transactional"
+	transactionalTimeout       = 3 * time.Second
+	defaultTransactionalSLO    = 2 * time.Second
+	transactionalRetryAttempts = 2
+)
+
+// Sets the latency within which transactional publishes should complete, 2 seconds by default.
+// Each transactional publish is counted in the `transactional.count` metric, tagged with
+// `within_slo` "true" or "false", and its latency recorded in the `transactional.duration` metric.
+func WithTransactionalSLO(target time.Duration) Option {
+	return func(pn *pushNotifications) {
+		pn.transactionalSLO = target
+	}
+}
+
+// Retries network and server failures once, straight away, as a late notification is of little use
+func transactionalRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: transactionalRetryAttempts,
+		Retryable: map[ErrorClass]bool{
+			ServerError: true,
+			Network:     true,
+		},
+		MaxElapsedTime: transactionalTimeout,
+	}
+}
+
+func (pn *pushNotifications) PublishTransactional(userId string, request map[string]interface{}, options ...CallOption) (string, error) {
+	options = append([]CallOption{
+		WithCallTimeout(transactionalTimeout),
+		WithCallRetryPolicy(transactionalRetryPolicy()),
+	}, options...)
+
+	job := &PublishJob{
+		Operation: publishToUsersOperation,
+		Targets:   []string{userId},
+		Request:   request,
+		callOpts:  newCallOptions(options),
+	}
+	job.callOpts.transactional = true
+	start := time.Now()
+	publishId, err := pn.publish(job)
+	pn.recordTransactional(time.Since(start), err)
+	return publishId, err
+}
+
+func (pn *pushNotifications) recordTransactional(elapsed time.Duration, err error) {
+	if pn.metrics == nil {
+		return
+	}
+
+	slo := pn.transactionalSLO
+	if slo <= 0 {
+		slo = defaultTransactionalSLO
+	}
+	tags := map[string]string{
+		"instance":   pn.InstanceId,
+		"operation":  transactionalOperation,
+		"outcome":    outcomeOf(err),
+		"within_slo": strconv.FormatBool(err == nil && elapsed <= slo),
+	}
+	pn.metrics.Histogram("transactional.duration", elapsed.Seconds(), tags)
+	pn.metrics.Counter("transactional.count", 1, tags)
+}
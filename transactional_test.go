@@ -0,0 +1,62 @@
+package pushnotifications
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPublishTransactional(t *testing.T) {
+	Convey("A transactional publish", t, func() {
+		requests := 0
+		status := http.StatusOK
+		delay := time.Duration(0)
+		var body map[string]interface{}
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			bodyBytes, _ := io.ReadAll(r.Body)
+			body = map[string]interface{}{}
+			json.Unmarshal(bodyBytes, &body)
+			time.Sleep(delay)
+			w.WriteHeader(status)
+			w.Write([]byte(`{"publishId":"pub-123","error":"Internal Error","description":"oops"}`))
+		}))
+		defer testServer.Close()
+
+		metrics := newRecordingMetrics()
+		pn, _ := New(testInstanceId, testSecretKey,
+			WithCustomBaseURL(testServer.URL),
+			WithMetrics(metrics),
+			WithTransactionalSLO(50*time.Millisecond))
+		request := map[string]interface{}{"fcm": map[string]interface{}{"notification": map[string]interface{}{"title": "Code: 1234"}}}
+
+		Convey("should publish to the user and count it within the SLO", func() {
+			publishId, err := pn.PublishTransactional("user-1", request)
+			So(err, ShouldBeNil)
+			So(publishId, ShouldEqual, "pub-123")
+			So(body["users"], ShouldResemble, []interface{}{"user-1"})
+			So(metrics.counters["transactional.count"], ShouldEqual, 1)
+			So(metrics.tags["transactional.count"]["within_slo"], ShouldEqual, "true")
+		})
+
+		Convey("should count slow publishes outside the SLO", func() {
+			delay = 60 * time.Millisecond
+			_, err := pn.PublishTransactional("user-1", request)
+			So(err, ShouldBeNil)
+			So(metrics.tags["transactional.count"]["within_slo"], ShouldEqual, "false")
+		})
+
+		Convey("should retry a server error only once", func() {
+			status = http.StatusInternalServerError
+			_, err := pn.PublishTransactional("user-1", request)
+			So(err, ShouldNotBeNil)
+			So(requests, ShouldEqual, 2)
+			So(metrics.tags["transactional.count"]["outcome"], ShouldEqual, "ServerError")
+		})
+	})
+}
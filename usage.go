@@ -0,0 +1,92 @@
+package pushnotifications
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Records how often each interest is published to, to find interests nobody publishes to anymore.
+// Add `Record` as a hook with `WithPublishHook`. It is safe for concurrent use.
+type InterestUsage struct {
+	mutex sync.Mutex
+	uses  map[string]InterestUse
+	now   func() time.Time
+}
+
+// How often an interest has been published to
+type InterestUse struct {
+	Interest string
+	// Number of successful publishes to the interest
+	Publishes int
+	// When the interest was last published to
+	LastPublished time.Time
+}
+
+// Which interests are in use, out of a list of known interests
+type InterestUsageReport struct {
+	// Interests published to recently, most published first
+	Used []InterestUse
+	// Interests that were published to, but not recently
+	Stale []InterestUse
+	// Known interests never published to since usage started being recorded
+	NeverUsed []string
+}
+
+// Creates an empty `InterestUsage`
+func NewInterestUsage() *InterestUsage {
+	return &InterestUsage{uses: map[string]InterestUse{}, now: time.Now}
+}
+
+// Counts a successful publish to interests
+func (u *InterestUsage) Record(event PublishEvent) {
+	if event.Err != nil || len(event.Interests) == 0 {
+		return
+	}
+
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	now := u.now()
+	for _, interest := range event.Interests {
+		use := u.uses[interest]
+		use.Interest = interest
+		use.Publishes++
+		use.LastPublished = now
+		u.uses[interest] = use
+	}
+}
+
+// Sorts `known` interests, and any other interest that was published to, into those published to
+// within `staleAfter`, those published to before that, and those never published to.
+// An `InterestRegistry` can list its known interests with `Expand("*")`.
+func (u *InterestUsage) Report(known []string, staleAfter time.Duration) InterestUsageReport {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	report := InterestUsageReport{Used: []InterestUse{}, Stale: []InterestUse{}, NeverUsed: []string{}}
+	cutoff := u.now().Add(-staleAfter)
+	for _, use := range u.uses {
+		if use.LastPublished.Before(cutoff) {
+			report.Stale = append(report.Stale, use)
+		} else {
+			report.Used = append(report.Used, use)
+		}
+	}
+	for _, interest := range known {
+		if _, ok := u.uses[interest]; !ok {
+			report.NeverUsed = append(report.NeverUsed, interest)
+		}
+	}
+
+	sort.Slice(report.Used, func(i, j int) bool {
+		if report.Used[i].Publishes != report.Used[j].Publishes {
+			return report.Used[i].Publishes > report.Used[j].Publishes
+		}
+		return report.Used[i].Interest < report.Used[j].Interest
+	})
+	sort.Slice(report.Stale, func(i, j int) bool {
+		return report.Stale[i].LastPublished.Before(report.Stale[j].LastPublished)
+	})
+	sort.Strings(report.NeverUsed)
+	return report
+}
@@ -0,0 +1,49 @@
+package pushnotifications
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestInterestUsage(t *testing.T) {
+	Convey("Interest usage tracking", t, func() {
+		now := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+		usage := NewInterestUsage()
+		usage.now = func() time.Time { return now }
+
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"publishId":"pub-123"}`))
+		}))
+		defer testServer.Close()
+		pn, err := New(testInstanceId, testSecretKey, WithCustomBaseURL(testServer.URL), WithPublishHook(usage.Record))
+		So(err, ShouldBeNil)
+
+		Convey("should report used, stale and never used interests", func() {
+			pn.PublishToInterests([]string{"old"}, map[string]interface{}{})
+			now = now.Add(48 * time.Hour)
+			pn.PublishToInterests([]string{"news", "sport"}, map[string]interface{}{})
+			pn.PublishToInterests([]string{"sport"}, map[string]interface{}{})
+			pn.PublishToUsers([]string{"user-1"}, map[string]interface{}{})
+
+			report := usage.Report([]string{"weather", "news", "sport", "old", "cinema"}, 24*time.Hour)
+			So(report.Used, ShouldResemble, []InterestUse{
+				{Interest: "sport", Publishes: 2, LastPublished: now},
+				{Interest: "news", Publishes: 1, LastPublished: now},
+			})
+			So(report.Stale, ShouldResemble, []InterestUse{
+				{Interest: "old", Publishes: 1, LastPublished: now.Add(-48 * time.Hour)},
+			})
+			So(report.NeverUsed, ShouldResemble, []string{"cinema", "weather"})
+		})
+
+		Convey("should not count failed publishes", func() {
+			usage.Record(PublishEvent{Interests: []string{"news"}, Err: http.ErrHandlerTimeout})
+			So(usage.Report([]string{"news"}, time.Hour).NeverUsed, ShouldResemble, []string{"news"})
+		})
+	})
+}
@@ -0,0 +1,232 @@
+package pushnotifications
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+type valueKind int
+
+const (
+	objectValue valueKind = iota
+	stringValue
+	numberValue
+	nonNegativeIntegerValue
+	boolValue
+	stringOrObjectValue
+)
+
+// The expected shape of a field of the publish request.
+// Fields not in the schema are not checked, so new service features keep working.
+type field struct {
+	kind   valueKind
+	fields map[string]field
+}
+
+var (
+	stringField  = field{kind: stringValue}
+	integerField = field{kind: nonNegativeIntegerValue}
+	objectField  = field{kind: objectValue}
+
+	requestSchema = map[string]field{
+		"apns": {kind: objectValue, fields: map[string]field{
+			"aps": {kind: objectValue, fields: map[string]field{
+				"alert": {kind: stringOrObjectValue, fields: map[string]field{
+					"title":    stringField,
+					"subtitle": stringField,
+					"body":     stringField,
+				}},
+				"badge":             integerField,
+				"sound":             {kind: stringOrObjectValue},
+				"content-available": integerField,
+				"mutable-content":   integerField,
+				"category":          stringField,
+				"thread-id":         stringField,
+			}},
+		}},
+		"fcm": {kind: objectValue, fields: map[string]field{
+			"notification": {kind: objectValue, fields: map[string]field{
+				"title":        stringField,
+				"body":         stringField,
+				"icon":         stringField,
+				"image":        stringField,
+				"sound":        stringField,
+				"tag":          stringField,
+				"color":        stringField,
+				"click_action": stringField,
+			}},
+			"data":         objectField,
+			"priority":     stringField,
+			"time_to_live": integerField,
+			"collapse_key": stringField,
+		}},
+		"web": {kind: objectValue, fields: map[string]field{
+			"notification": {kind: objectValue, fields: map[string]field{
+				"title":                               stringField,
+				"body":                                stringField,
+				"icon":                                stringField,
+				"image":                               stringField,
+				"deep_link":                           stringField,
+				"hide_notification_if_site_has_focus": {kind: boolValue},
+			}},
+			"data":         objectField,
+			"time_to_live": integerField,
+		}},
+	}
+)
+
+// Checks the shape of the `apns`, `fcm` and `web` sections of a publish request.
+// Returns a non-nil `error` listing every problem found, e.g. `fcm.notification.title: must be a string`.
+func ValidateRequest(request map[string]interface{}) error {
+	problems := validateFields("", request, requestSchema)
+	if len(problems) == 0 {
+		return nil
+	}
+
+	sort.Strings(problems)
+	return newValidationError("Invalid publish request: %s", strings.Join(problems, "; "))
+}
+
+func validateFields(path string, object map[string]interface{}, schema map[string]field) []string {
+	problems := []string{}
+	for key, expected := range schema {
+		value, ok := object[key]
+		if !ok || value == nil {
+			continue
+		}
+
+		fieldPath := key
+		if path != "" {
+			fieldPath = path + "." + key
+		}
+		problems = append(problems, validateField(fieldPath, value, expected)...)
+	}
+	return problems
+}
+
+func validateField(path string, value interface{}, expected field) []string {
+	kind := reflect.ValueOf(value).Kind()
+	isObject := kind == reflect.Map || kind == reflect.Struct ||
+		(kind == reflect.Ptr && reflect.Indirect(reflect.ValueOf(value)).Kind() == reflect.Struct)
+
+	switch expected.kind {
+	case objectValue:
+		if !isObject {
+			return []string{path + ": must be an object"}
+		}
+	case stringValue:
+		if kind != reflect.String {
+			return []string{path + ": must be a string"}
+		}
+	case stringOrObjectValue:
+		if kind != reflect.String && !isObject {
+			return []string{path + ": must be a string or an object"}
+		}
+	case boolValue:
+		if kind != reflect.Bool {
+			return []string{path + ": must be a boolean"}
+		}
+	case nonNegativeIntegerValue:
+		if !isNonNegativeInteger(value) {
+			return []string{path + ": must be a non-negative integer"}
+		}
+	}
+
+	// only plain maps are inspected further; other objects are encoded as they are
+	if nested, ok := value.(map[string]interface{}); ok && expected.fields != nil {
+		return validateFields(path, nested, expected.fields)
+	}
+	return nil
+}
+
+func isNonNegativeInteger(value interface{}) bool {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() >= 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		return f >= 0 && f == math.Trunc(f)
+	default:
+		return false
+	}
+}
+
+// Checks the notification text of each platform section against what the platform displays.
+// Returns one message per problem that would not stop the publish but would get the
+// notification truncated or dropped by the device, e.g. `web.notification.title: is required`.
+func RequestWarnings(request map[string]interface{}) []string {
+	warnings := []string{}
+
+	if apns, ok := request["apns"].(map[string]interface{}); ok {
+		aps, _ := apns["aps"].(map[string]interface{})
+		switch alert := aps["alert"].(type) {
+		case string:
+			warnings = appendLengthWarning(warnings, "apns.aps.alert", alert, platformTextLimits["apns"].body)
+		case map[string]interface{}:
+			warnings = appendTextWarnings(warnings, "apns.aps.alert", alert, platformTextLimits["apns"])
+		default:
+			if aps["content-available"] == nil {
+				warnings = append(warnings, "apns.aps.alert: is required unless content-available is set")
+			}
+		}
+	}
+
+	if fcm, ok := request["fcm"].(map[string]interface{}); ok {
+		notification, hasNotification := fcm["notification"].(map[string]interface{})
+		switch {
+		case hasNotification && notification["title"] == nil && notification["body"] == nil:
+			warnings = append(warnings, "fcm.notification: needs a title or a body to be displayed")
+		case hasNotification:
+			warnings = appendTextWarnings(warnings, "fcm.notification", notification, platformTextLimits["fcm"])
+		case fcm["data"] == nil:
+			warnings = append(warnings, "fcm: needs a notification or data")
+		}
+	}
+
+	if web, ok := request["web"].(map[string]interface{}); ok {
+		notification, ok := web["notification"].(map[string]interface{})
+		switch {
+		case !ok:
+			warnings = append(warnings, "web.notification: is required")
+		case notification["title"] == nil:
+			warnings = append(warnings, "web.notification.title: is required")
+		}
+		warnings = appendTextWarnings(warnings, "web.notification", notification, platformTextLimits["web"])
+	}
+
+	sort.Strings(warnings)
+	return warnings
+}
+
+func appendTextWarnings(warnings []string, path string, object map[string]interface{}, limits textLimits) []string {
+	for _, text := range []struct {
+		key   string
+		limit int
+	}{
+		{"title", limits.title},
+		{"subtitle", limits.subtitle},
+		{"body", limits.body},
+	} {
+		if value, ok := object[text.key].(string); ok {
+			warnings = appendLengthWarning(warnings, path+"."+text.key, value, text.limit)
+		}
+	}
+	return warnings
+}
+
+func appendLengthWarning(warnings []string, path, text string, limit int) []string {
+	if limit <= 0 {
+		return warnings
+	}
+	if length := len(graphemeBoundaries(text)); length > limit {
+		warnings = append(warnings, fmt.Sprintf(
+			"%s: is %d characters long and will be truncated after about %d", path, length, limit))
+	}
+	return warnings
+}
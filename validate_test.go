@@ -0,0 +1,135 @@
+package pushnotifications
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestValidateRequest(t *testing.T) {
+	Convey("Validating a publish request", t, func() {
+		Convey("should accept well formed requests", func() {
+			request := map[string]interface{}{
+				"apns": map[string]interface{}{
+					"aps": map[string]interface{}{
+						"alert": map[string]interface{}{"title": "Hello", "body": "Hello, world"},
+						"badge": 3,
+					},
+					"custom": []interface{}{1, "two"},
+				},
+				"fcm": map[string]interface{}{
+					"notification": map[string]string{"title": "Hello"},
+					"data":         map[string]interface{}{"anything": 1.5},
+					"time_to_live": float64(3600),
+				},
+				"web": map[string]interface{}{
+					"notification": map[string]interface{}{
+						"title":                               "Hello",
+						"hide_notification_if_site_has_focus": true,
+					},
+				},
+				"unknown": "is not checked",
+			}
+			So(ValidateRequest(request), ShouldBeNil)
+			So(ValidateRequest(testPublishRequest), ShouldBeNil)
+			So(ValidateRequest(map[string]interface{}{}), ShouldBeNil)
+		})
+
+		Convey("should report every problem with its path", func() {
+			request := map[string]interface{}{
+				"apns": map[string]interface{}{
+					"aps": map[string]interface{}{
+						"alert": 42,
+						"badge": -1,
+					},
+				},
+				"fcm": map[string]interface{}{
+					"notification": map[string]interface{}{"title": 123},
+					"time_to_live": 1.5,
+				},
+				"web": "hello",
+			}
+
+			err := ValidateRequest(request)
+			So(Classify(err), ShouldEqual, Validation)
+			So(err.Error(), ShouldEqual, "Invalid publish request: "+
+				"apns.aps.alert: must be a string or an object; "+
+				"apns.aps.badge: must be a non-negative integer; "+
+				"fcm.notification.title: must be a string; "+
+				"fcm.time_to_live: must be a non-negative integer; "+
+				"web: must be an object")
+		})
+
+		Convey("should run before publishing", func() {
+			pn, _ := New(testInstanceId, testSecretKey, WithCustomBaseURL("http://localhost:0"))
+			_, err := pn.PublishToUsers([]string{"user-1"}, map[string]interface{}{"fcm": "hello"})
+			So(err.Error(), ShouldContainSubstring, "fcm: must be an object")
+
+			_, err = pn.PublishToInterests([]string{"hello"}, map[string]interface{}{"fcm": "hello"})
+			So(err.Error(), ShouldContainSubstring, "fcm: must be an object")
+		})
+	})
+}
+
+func TestRequestWarnings(t *testing.T) {
+	Convey("Checking the text of a publish request", t, func() {
+		Convey("should not warn about notifications every platform displays", func() {
+			request := map[string]interface{}{
+				"apns": map[string]interface{}{
+					"aps": map[string]interface{}{"alert": map[string]interface{}{"title": "Hi", "body": "Hello"}},
+				},
+				"fcm": map[string]interface{}{
+					"notification": map[string]interface{}{"title": "Hi", "body": "Hello"},
+				},
+				"web": map[string]interface{}{
+					"notification": map[string]interface{}{"title": "Hi", "body": "Hello"},
+				},
+			}
+			So(RequestWarnings(request), ShouldBeEmpty)
+		})
+
+		Convey("should not warn about silent or data-only notifications", func() {
+			request := map[string]interface{}{
+				"apns": map[string]interface{}{"aps": map[string]interface{}{"content-available": 1}},
+				"fcm":  map[string]interface{}{"data": map[string]interface{}{"sync": "true"}},
+			}
+			So(RequestWarnings(request), ShouldBeEmpty)
+		})
+
+		Convey("should warn about missing fields and text that will be truncated", func() {
+			request := map[string]interface{}{
+				"apns": map[string]interface{}{
+					"aps": map[string]interface{}{"alert": map[string]interface{}{
+						"subtitle": strings.Repeat("😀", 51),
+					}},
+				},
+				"fcm": map[string]interface{}{
+					"notification": map[string]interface{}{"icon": "bell"},
+				},
+				"web": map[string]interface{}{
+					"notification": map[string]interface{}{"body": strings.Repeat("a", 121)},
+				},
+			}
+			So(RequestWarnings(request), ShouldResemble, []string{
+				"apns.aps.alert.subtitle: is 51 characters long and will be truncated after about 50",
+				"fcm.notification: needs a title or a body to be displayed",
+				"web.notification.body: is 121 characters long and will be truncated after about 120",
+				"web.notification.title: is required",
+			})
+		})
+
+		Convey("should warn about alerts missing from visible notifications", func() {
+			request := map[string]interface{}{
+				"apns": map[string]interface{}{"aps": map[string]interface{}{"badge": 1}},
+				"fcm":  map[string]interface{}{},
+				"web":  map[string]interface{}{},
+			}
+			So(RequestWarnings(request), ShouldResemble, []string{
+				"apns.aps.alert: is required unless content-available is set",
+				"fcm: needs a notification or data",
+				"web.notification: is required",
+			})
+		})
+	})
+}
@@ -0,0 +1,59 @@
+package pushnotifications
+
+import "sort"
+
+// Key of the variant chosen by `PublishVariants` in the publish metadata
+const VariantMetadataKey = "variant"
+
+// Chooses which variant of a notification each user gets, e.g. backed by a feature flag service,
+// so that new notification formats can be rolled out gradually
+type VariantSelector interface {
+	// Returns the name of the variant of notifications of the type (see `WithNotificationType`) for the user
+	Variant(notificationType, userId string) (string, error)
+}
+
+// Adapts a function to the `VariantSelector` interface
+type VariantSelectorFunc func(notificationType, userId string) (string, error)
+
+func (f VariantSelectorFunc) Variant(notificationType, userId string) (string, error) {
+	return f(notificationType, userId)
+}
+
+// Publishes to each user the request of the variant `selector` chooses for them, publishing to the users
+// of each variant together, up to `Limits.MaxUsers` at a time, with the variant in the metadata under `VariantMetadataKey`.
+// Users get the `fallback` variant when the selector fails or chooses a variant that isn't in `variants`,
+// so that an outage of the flag service doesn't stop notifications.
+func PublishVariants(
+	pn UserPublisher,
+	selector VariantSelector,
+	users []string,
+	variants map[string]map[string]interface{},
+	fallback string,
+	options ...CallOption,
+) (*BulkResult, error) {
+	if _, ok := variants[fallback]; !ok {
+		return nil, newValidationError("The fallback variant `%s` is not one of the variants", fallback)
+	}
+
+	callOpts := newCallOptions(options)
+	groups := map[string][]string{}
+	for _, userId := range users {
+		variant, err := selector.Variant(callOpts.notificationType, userId)
+		if _, ok := variants[variant]; err != nil || !ok {
+			variant = fallback
+		}
+		groups[variant] = append(groups[variant], userId)
+	}
+
+	order := make([]string, 0, len(groups))
+	for variant := range groups {
+		order = append(order, variant)
+	}
+	sort.Strings(order)
+
+	groupOptions := make(map[string][]CallOption, len(groups))
+	for _, variant := range order {
+		groupOptions[variant] = []CallOption{WithMetadata(withMetadata(callOpts.metadata, VariantMetadataKey, variant))}
+	}
+	return publishGroups(pn, order, groups, variants, groupOptions, &BulkResult{}, options)
+}
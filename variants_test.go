@@ -0,0 +1,76 @@
+package pushnotifications
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestVariants(t *testing.T) {
+	Convey("Variants of a notification", t, func() {
+		bodies := []map[string]interface{}{}
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			bodyBytes, _ := io.ReadAll(r.Body)
+			body := map[string]interface{}{}
+			json.Unmarshal(bodyBytes, &body)
+			bodies = append(bodies, body)
+			w.Write([]byte(`{"publishId":"pub-123"}`))
+		}))
+		defer testServer.Close()
+		recorder := &recordingRecorder{}
+		pn, _ := New(testInstanceId, testSecretKey, WithCustomBaseURL(testServer.URL), WithPublishRecorder(recorder))
+
+		variants := map[string]map[string]interface{}{
+			"control": {"web": map[string]interface{}{"notification": map[string]interface{}{"title": "Your order shipped"}}},
+			"rich": {"web": map[string]interface{}{"notification": map[string]interface{}{
+				"title": "Your order shipped", "image": "https://example.com/parcel.png",
+			}}},
+		}
+		selected := map[string]string{"user-1": "rich", "user-2": "control", "user-3": "rich", "user-4": "unknown"}
+		types := []string{}
+		selector := VariantSelectorFunc(func(notificationType, userId string) (string, error) {
+			types = append(types, notificationType)
+			if userId == "user-5" {
+				return "", errors.New("flag service unavailable")
+			}
+			return selected[userId], nil
+		})
+
+		Convey("should publish to the users of each variant together", func() {
+			result, err := PublishVariants(pn, selector, []string{"user-1", "user-2", "user-3"}, variants, "control",
+				WithNotificationType("order_shipped"), WithMetadata(map[string]string{"campaign": "c-1"}))
+			So(err, ShouldBeNil)
+			So(len(result.Chunks), ShouldEqual, 2)
+			So(types, ShouldResemble, []string{"order_shipped", "order_shipped", "order_shipped"})
+			So(bodies[0]["users"], ShouldResemble, []interface{}{"user-2"})
+			So(bodies[1]["users"], ShouldResemble, []interface{}{"user-1", "user-3"})
+			So(bodies[1]["web"], ShouldResemble, map[string]interface{}{"notification": map[string]interface{}{
+				"title": "Your order shipped", "image": "https://example.com/parcel.png",
+			}})
+			So(recorder.jobs[1].Metadata, ShouldResemble, map[string]string{
+				"campaign":                  "c-1",
+				NotificationTypeMetadataKey: "order_shipped",
+				VariantMetadataKey:          "rich",
+			})
+		})
+
+		Convey("should give the fallback to users the selector fails for", func() {
+			_, err := PublishVariants(pn, selector, []string{"user-4", "user-5"}, variants, "control")
+			So(err, ShouldBeNil)
+			So(len(bodies), ShouldEqual, 1)
+			So(bodies[0]["users"], ShouldResemble, []interface{}{"user-4", "user-5"})
+			So(recorder.jobs[0].Metadata[VariantMetadataKey], ShouldEqual, "control")
+		})
+
+		Convey("should require the fallback to be a variant", func() {
+			_, err := PublishVariants(pn, selector, []string{"user-1"}, variants, "missing")
+			So(Classify(err), ShouldEqual, Validation)
+			So(len(bodies), ShouldEqual, 0)
+		})
+	})
+}
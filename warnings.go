@@ -0,0 +1,61 @@
+package pushnotifications
+
+import "fmt"
+
+// Codes of the warnings raised by the client
+const (
+	// A platform section of the request is close to, or over, the size platforms accept
+	PayloadSizeWarning = "payload_size"
+	// Some targets were left out of the publish, e.g. by suppression rules or a target filter
+	AudienceReducedWarning = "audience_reduced"
+	// The notification text will be truncated or dropped by devices (see `RequestWarnings`)
+	ContentWarning = "content"
+	// An invalid interest name was replaced by `SanitizeInterest` (see `WithInterestValidation`)
+	InterestSanitizedWarning = "interest_sanitized"
+)
+
+// Size of the largest notification payload APNs and FCM accept, by default (see `Limits`)
+const maxPlatformPayloadSize = 4096
+
+// A condition that doesn't stop a publish, but should be looked into
+type Warning struct {
+	// One of the `...Warning` constants
+	Code    string
+	Message string
+}
+
+func (w Warning) String() string {
+	return w.Code + ": " + w.Message
+}
+
+// Adds a warning to the publish, for stages raising their own warnings
+func (job *PublishJob) Warn(code, format string, args ...interface{}) {
+	job.Warnings = append(job.Warnings, Warning{Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+// Sets a function receiving every warning raised by a publish, once the publish is done
+func WithWarningHandler(handler func(job *PublishJob, warning Warning)) Option {
+	return func(pn *pushNotifications) {
+		pn.warningHandlers = append(pn.warningHandlers, handler)
+	}
+}
+
+func (pn *pushNotifications) handleWarnings(job *PublishJob) {
+	for _, warning := range job.Warnings {
+		for _, handler := range pn.warningHandlers {
+			handler(job, warning)
+		}
+	}
+}
+
+// Warns about the platform sections of the encoded request that are close to the size platforms accept
+func warnPayloadSize(job *PublishJob, maxSize int) {
+	sizes := platformSectionSizes(job.Body)
+	for _, platform := range []string{"apns", "fcm", "web"} {
+		size := sizes[platform]
+		if size > maxSize*9/10 {
+			job.Warn(PayloadSizeWarning, "%s: is %d bytes, close to the %d bytes platforms accept",
+				platform, size, maxSize)
+		}
+	}
+}
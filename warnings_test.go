@@ -0,0 +1,65 @@
+package pushnotifications
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWarnings(t *testing.T) {
+	Convey("A Push Notifications Instance with a warning handler", t, func() {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"publishId":"pub-123"}`))
+		}))
+		defer testServer.Close()
+
+		warnings := []Warning{}
+		events := []PublishEvent{}
+		pn, _ := New(testInstanceId, testSecretKey,
+			WithCustomBaseURL(testServer.URL),
+			WithTargetFilter(TargetFilter{DenyUsers: []string{"ceo"}}, nil),
+			WithPublishHook(func(event PublishEvent) { events = append(events, event) }),
+			WithWarningHandler(func(job *PublishJob, warning Warning) { warnings = append(warnings, warning) }))
+		notification := func(body string) map[string]interface{} {
+			return map[string]interface{}{
+				"fcm": map[string]interface{}{"notification": map[string]interface{}{"title": "Hi", "body": body}},
+			}
+		}
+
+		Convey("should not warn about a publish without problems", func() {
+			_, err := pn.PublishToUsers([]string{"user-1"}, notification("Hello"))
+			So(err, ShouldBeNil)
+			So(warnings, ShouldBeEmpty)
+		})
+
+		Convey("should warn about text that will be truncated", func() {
+			_, err := pn.PublishToUsers([]string{"user-1"}, notification(strings.Repeat("a", 300)))
+			So(err, ShouldBeNil)
+			So(warnings, ShouldResemble, []Warning{{
+				Code:    ContentWarning,
+				Message: "fcm.notification.body: is 300 characters long and will be truncated after about 240",
+			}})
+			So(events[0].Warnings, ShouldResemble, warnings)
+		})
+
+		Convey("should warn about payloads close to the size platforms accept", func() {
+			request := map[string]interface{}{
+				"fcm": map[string]interface{}{"data": map[string]interface{}{"blob": strings.Repeat("a", 4000)}},
+			}
+			_, err := pn.PublishToUsers([]string{"user-1"}, request)
+			So(err, ShouldBeNil)
+			So(len(warnings), ShouldEqual, 1)
+			So(warnings[0].Code, ShouldEqual, PayloadSizeWarning)
+			So(warnings[0].String(), ShouldStartWith, "payload_size: fcm: is 4")
+		})
+
+		Convey("should warn about targets left out of the publish", func() {
+			_, err := pn.PublishToUsers([]string{"user-1", "ceo"}, notification("Hello"))
+			So(err, ShouldBeNil)
+			So(warnings, ShouldResemble, []Warning{{Code: AudienceReducedWarning, Message: "1 of 2 users were filtered out"}})
+		})
+	})
+}
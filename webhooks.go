@@ -0,0 +1,151 @@
+package pushnotifications
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Types of the webhook events sent by Beams
+const (
+	// Beams tried to deliver a notification published to a user
+	PublishToUserAttemptEvent = "v1.PublishToUserAttempt"
+	// A device of the user received the notification
+	UserNotificationAcknowledgementEvent = "v1.UserNotificationAcknowledgement"
+	// The user opened the notification
+	UserNotificationOpenEvent = "v1.UserNotificationOpen"
+)
+
+const webhookSignatureHeader = "Webhook-Signature"
+
+// Largest webhook body read, as webhook events are small
+const maxWebhookSize = 64 * 1024
+
+var errInvalidWebhookSignature = &validationError{message: "Invalid webhook signature"}
+
+// A webhook event sent by Beams about a notification published to a user
+type WebhookEvent struct {
+	// One of the `...Event` constants
+	Type       string
+	InstanceId string
+	EventId    string
+	CreatedAt  time.Time
+	PublishId  string
+	UserId     string
+	DeviceId   string
+	// Metadata of the publish, set by `History.Match`
+	Metadata map[string]string
+}
+
+type webhookBody struct {
+	Metadata struct {
+		EventType  string    `json:"event_type"`
+		InstanceId string    `json:"instance_id"`
+		EventId    string    `json:"event_id"`
+		CreatedAt  time.Time `json:"created_at"`
+	} `json:"metadata"`
+	Payload struct {
+		PublishId string `json:"publish_id"`
+		UserId    string `json:"user_id"`
+		DeviceId  string `json:"device_id"`
+	} `json:"payload"`
+}
+
+// Reads the webhook event sent by Beams in the body of `r`, checking its signature
+// (the hex HMAC-SHA1 of the body in the `Webhook-Signature` header) with the webhook secret.
+// Returns a non-nil `error` if the signature doesn't match, or the body is not a webhook event or is over 64KiB.
+func ParseWebhook(r *http.Request, secret string) (*WebhookEvent, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookSize+1))
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read the webhook body")
+	}
+	if len(body) > maxWebhookSize {
+		return nil, newValidationError("The webhook body is larger than %d bytes", maxWebhookSize)
+	}
+
+	signature, err := hex.DecodeString(strings.TrimPrefix(r.Header.Get(webhookSignatureHeader), "sha1="))
+	mac := hmac.New(sha1.New, []byte(secret))
+	mac.Write(body)
+	if err != nil || !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, errors.WithStack(errInvalidWebhookSignature)
+	}
+
+	parsed := webhookBody{}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return nil, errors.Wrap(err, "Failed to read the webhook due to invalid JSON")
+	}
+	if parsed.Metadata.EventType == "" {
+		return nil, newValidationError("The webhook has no event type")
+	}
+
+	return &WebhookEvent{
+		Type:       parsed.Metadata.EventType,
+		InstanceId: parsed.Metadata.InstanceId,
+		EventId:    parsed.Metadata.EventId,
+		CreatedAt:  parsed.Metadata.CreatedAt,
+		PublishId:  parsed.Payload.PublishId,
+		UserId:     parsed.Payload.UserId,
+		DeviceId:   parsed.Payload.DeviceId,
+	}, nil
+}
+
+// Returns a handler calling `handle` with every webhook event sent by Beams, as read by `ParseWebhook`.
+// Requests that are not signed with `secret` are answered with 401 Unauthorized, and malformed
+// events with 400 Bad Request, so that Beams doesn't retry them.
+func WebhookHandler(secret string, handle func(WebhookEvent)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		event, err := ParseWebhook(r, secret)
+		switch {
+		case errors.Cause(err) == errInvalidWebhookSignature:
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			handle(*event)
+			w.WriteHeader(http.StatusOK)
+		}
+	})
+}
+
+var webhookCounters = map[string]string{
+	PublishToUserAttemptEvent:            "webhook.attempted",
+	UserNotificationAcknowledgementEvent: "webhook.delivered",
+	UserNotificationOpenEvent:            "webhook.opened",
+}
+
+// Returns a function counting webhook events in `metrics`, as `webhook.attempted`, `webhook.delivered`
+// and `webhook.opened`, so that delivery and open rates can be charted next to the publishes.
+// Events are matched with their publish in `history`, if not nil, and the publish metadata
+// under `metadataKeys` (e.g. "campaign") become tags, for rates per campaign.
+func WebhookMetrics(metrics Metrics, history *History, metadataKeys ...string) func(WebhookEvent) {
+	return func(event WebhookEvent) {
+		name, ok := webhookCounters[event.Type]
+		if !ok {
+			return
+		}
+		if history != nil {
+			history.Match(&event)
+		}
+
+		tags := map[string]string{"instance": event.InstanceId}
+		for _, key := range metadataKeys {
+			if value, ok := event.Metadata[key]; ok {
+				tags[key] = value
+			}
+		}
+		metrics.Counter(name, 1, tags)
+	}
+}
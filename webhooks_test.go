@@ -0,0 +1,123 @@
+package pushnotifications
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseWebhook(t *testing.T) {
+	Convey("Parsing a webhook", t, func() {
+		secret := "webhook-secret"
+		body := `{
+			"metadata": {
+				"event_type": "v1.UserNotificationOpen",
+				"instance_id": "` + testInstanceId + `",
+				"event_id": "evt-1",
+				"created_at": "2018-06-01T12:00:00Z"
+			},
+			"payload": {"publish_id": "pub-123", "user_id": "user-1", "device_id": "web-1"}
+		}`
+		sign := func(body string) string {
+			mac := hmac.New(sha1.New, []byte(secret))
+			mac.Write([]byte(body))
+			return hex.EncodeToString(mac.Sum(nil))
+		}
+
+		Convey("should read a signed event", func() {
+			r := httptest.NewRequest("POST", "/webhooks", strings.NewReader(body))
+			r.Header.Set("Webhook-Signature", "sha1="+sign(body))
+
+			event, err := ParseWebhook(r, secret)
+			So(err, ShouldBeNil)
+			So(event, ShouldResemble, &WebhookEvent{
+				Type:       UserNotificationOpenEvent,
+				InstanceId: testInstanceId,
+				EventId:    "evt-1",
+				CreatedAt:  time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC),
+				PublishId:  "pub-123",
+				UserId:     "user-1",
+				DeviceId:   "web-1",
+			})
+		})
+
+		Convey("should reject events with a wrong signature", func() {
+			r := httptest.NewRequest("POST", "/webhooks", strings.NewReader(body))
+			r.Header.Set("Webhook-Signature", sign(body+" "))
+
+			event, err := ParseWebhook(r, secret)
+			So(event, ShouldBeNil)
+			So(err.Error(), ShouldContainSubstring, "Invalid webhook signature")
+			So(Classify(err), ShouldEqual, Validation)
+		})
+
+		Convey("with a handler", func() {
+			events := []WebhookEvent{}
+			handler := WebhookHandler(secret, func(event WebhookEvent) {
+				events = append(events, event)
+			})
+			serve := func(body, signature string) int {
+				r := httptest.NewRequest("POST", "/webhooks", strings.NewReader(body))
+				r.Header.Set("Webhook-Signature", signature)
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, r)
+				return w.Code
+			}
+
+			Convey("should pass on signed events", func() {
+				So(serve(body, sign(body)), ShouldEqual, http.StatusOK)
+				So(len(events), ShouldEqual, 1)
+				So(events[0].UserId, ShouldEqual, "user-1")
+			})
+
+			Convey("should reject unsigned and malformed events", func() {
+				So(serve(body, "sha1=00"), ShouldEqual, http.StatusUnauthorized)
+				So(serve(`{}`, sign(`{}`)), ShouldEqual, http.StatusBadRequest)
+				large := strings.Repeat(" ", 64*1024) + body
+				So(serve(large, sign(large)), ShouldEqual, http.StatusBadRequest)
+				So(len(events), ShouldEqual, 0)
+			})
+		})
+	})
+
+	Convey("Webhook metrics", t, func() {
+		metrics := newRecordingMetrics()
+		history := NewHistory(10)
+		history.Record(&PublishJob{
+			Operation: publishToUsersOperation,
+			PublishId: "pub-123",
+			Metadata:  map[string]string{"campaign": "spring-sale", "email": "not a tag"},
+		})
+		record := WebhookMetrics(metrics, history, "campaign")
+
+		Convey("should count events, tagged with the metadata of their publish", func() {
+			record(WebhookEvent{Type: PublishToUserAttemptEvent, InstanceId: testInstanceId, PublishId: "pub-123"})
+			record(WebhookEvent{Type: UserNotificationOpenEvent, InstanceId: testInstanceId, PublishId: "pub-123"})
+
+			So(metrics.counters["webhook.attempted"], ShouldEqual, 1)
+			So(metrics.counters["webhook.opened"], ShouldEqual, 1)
+			So(metrics.tags["webhook.opened"], ShouldResemble, map[string]string{
+				"instance": testInstanceId,
+				"campaign": "spring-sale",
+			})
+		})
+
+		Convey("should count events of publishes it doesn't know without their metadata", func() {
+			record(WebhookEvent{Type: UserNotificationAcknowledgementEvent, InstanceId: testInstanceId, PublishId: "pub-old"})
+			So(metrics.counters["webhook.delivered"], ShouldEqual, 1)
+			So(metrics.tags["webhook.delivered"], ShouldResemble, map[string]string{"instance": testInstanceId})
+		})
+
+		Convey("should ignore unknown event types", func() {
+			record(WebhookEvent{Type: "v2.Unknown"})
+			So(len(metrics.counters), ShouldEqual, 0)
+		})
+	})
+}
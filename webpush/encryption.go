@@ -0,0 +1,100 @@
+package webpush
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	recordSize = 4096
+	// the padding delimiter of the last (and only) record
+	lastRecordDelimiter = 0x02
+)
+
+// Encrypts the payload for the subscription with the "aes128gcm" content encoding (RFC 8188 and RFC 8291)
+func encrypt(subscription *Subscription, payload []byte) ([]byte, error) {
+	curve := elliptic.P256()
+
+	userAgentPublic, err := decodeKey(subscription.Keys.P256dh)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to decode the subscription p256dh key")
+	}
+	x, y := elliptic.Unmarshal(curve, userAgentPublic)
+	if x == nil {
+		return nil, errors.New("The subscription p256dh key is not a valid P-256 public key")
+	}
+	authSecret, err := decodeKey(subscription.Keys.Auth)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to decode the subscription auth secret")
+	}
+
+	if len(payload)+1+aes.BlockSize > recordSize-86 {
+		return nil, errors.Errorf("Web Push payload is %d bytes which is too large to encrypt", len(payload))
+	}
+
+	serverPrivate, serverX, serverY, err := elliptic.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to generate the Web Push encryption key")
+	}
+	serverPublic := elliptic.Marshal(curve, serverX, serverY)
+	sharedX, _ := curve.ScalarMult(x, y, serverPrivate)
+	sharedXBytes := sharedX.Bytes()
+	sharedSecret := make([]byte, 32)
+	copy(sharedSecret[32-len(sharedXBytes):], sharedXBytes)
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, errors.Wrap(err, "Failed to generate the Web Push encryption salt")
+	}
+
+	keyInfo := append([]byte("WebPush: info\x00"), userAgentPublic...)
+	keyInfo = append(keyInfo, serverPublic...)
+	inputKey := hkdf(authSecret, sharedSecret, keyInfo, 32)
+	contentKey := hkdf(salt, inputKey, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	nonce := hkdf(salt, inputKey, []byte("Content-Encoding: nonce\x00"), 12)
+
+	block, err := aes.NewCipher(contentKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to prepare the Web Push cipher")
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to prepare the Web Push cipher")
+	}
+
+	header := make([]byte, 0, 16+4+1+len(serverPublic))
+	header = append(header, salt...)
+	header = append(header, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(header[16:20], recordSize)
+	header = append(header, byte(len(serverPublic)))
+	header = append(header, serverPublic...)
+
+	plaintext := append(append([]byte{}, payload...), lastRecordDelimiter)
+	return gcm.Seal(header, nonce, plaintext, nil), nil
+}
+
+// HKDF (RFC 5869) with SHA-256, for outputs of at most one hash length
+func hkdf(salt, inputKey, info []byte, length int) []byte {
+	extract := hmac.New(sha256.New, salt)
+	extract.Write(inputKey)
+	pseudoRandomKey := extract.Sum(nil)
+
+	expand := hmac.New(sha256.New, pseudoRandomKey)
+	expand.Write(info)
+	expand.Write([]byte{1})
+	return expand.Sum(nil)[:length]
+}
+
+// Subscription keys are base64url encoded, but some browsers include padding
+func decodeKey(key string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(key, "="))
+}
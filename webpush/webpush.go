@@ -0,0 +1,203 @@
+// Package webpush sends notifications straight to browsers with the Web Push protocol,
+// signing requests with VAPID and encrypting payloads as described in RFC 8291.
+package webpush
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/pkg/errors"
+	pushnotifications "github.com/pusher/push-notifications-go"
+)
+
+const (
+	defaultRequestTimeout = 30 * time.Second
+	// responses are read up to this size, so a misbehaving proxy can't exhaust memory
+	maxResponseSize = 1 << 20
+	defaultTTL      = 4 * 7 * 24 * time.Hour
+	vapidTokenTTL   = 12 * time.Hour
+)
+
+// Credentials and settings for a Web Push backend
+type Config struct {
+	// PEM encoded VAPID private key (a P-256 EC key)
+	PrivateKey []byte
+	// Contact for the application server, as a `mailto:` or `https:` URL
+	Subject string
+	// How long push services keep undelivered notifications. Defaults to 4 weeks.
+	TTL time.Duration
+}
+
+// A browser push subscription, as returned by `PushSubscription.toJSON()`
+type Subscription struct {
+	Endpoint string `json:"endpoint"`
+	Keys     struct {
+		P256dh string `json:"p256dh"`
+		Auth   string `json:"auth"`
+	} `json:"keys"`
+}
+
+type Option func(*backend)
+
+func WithRequestTimeout(timeout time.Duration) Option {
+	return func(b *backend) {
+		b.httpClient.Timeout = timeout
+	}
+}
+
+type backend struct {
+	config     Config
+	httpClient *http.Client
+	signingKey *ecdsa.PrivateKey
+	publicKey  string
+}
+
+// Creates a `pushnotifications.Backend` sending the `web` section of publish requests with Web Push.
+// Device tokens given to `Send` are subscriptions encoded as JSON (see `Subscription`).
+// Returns a non-nil error if the config is incomplete or the private key can't be parsed.
+func New(config Config, options ...Option) (pushnotifications.Backend, error) {
+	if config.Subject == "" {
+		return nil, errors.New("Subject cannot be an empty string")
+	}
+	if config.TTL <= 0 {
+		config.TTL = defaultTTL
+	}
+
+	signingKey, err := jwt.ParseECPrivateKeyFromPEM(config.PrivateKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to parse the VAPID private key")
+	}
+	if signingKey.Curve != elliptic.P256() {
+		return nil, errors.New("The VAPID private key must use the P-256 curve")
+	}
+
+	b := &backend{
+		config:     config,
+		httpClient: &http.Client{Timeout: defaultRequestTimeout},
+		signingKey: signingKey,
+		publicKey: base64.RawURLEncoding.EncodeToString(
+			elliptic.Marshal(signingKey.Curve, signingKey.X, signingKey.Y)),
+	}
+
+	for _, option := range options {
+		option(b)
+	}
+
+	return b, nil
+}
+
+func (b *backend) Name() string {
+	return "web"
+}
+
+func (b *backend) Send(deviceTokens []string, request map[string]interface{}) (*pushnotifications.SendResult, error) {
+	if len(deviceTokens) == 0 {
+		return nil, errors.New("Must supply at least one device token")
+	}
+
+	section, ok := request["web"].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("The publish request has no `web` section")
+	}
+	payload, err := json.Marshal(section)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to marshal the Web Push payload")
+	}
+
+	result := &pushnotifications.SendResult{Failed: map[string]error{}}
+	for _, deviceToken := range deviceTokens {
+		if err := b.sendToSubscription(deviceToken, payload); err != nil {
+			result.Failed[deviceToken] = err
+		} else {
+			result.Sent = append(result.Sent, deviceToken)
+		}
+	}
+
+	return result, nil
+}
+
+func (b *backend) sendToSubscription(deviceToken string, payload []byte) error {
+	subscription := &Subscription{}
+	if err := json.Unmarshal([]byte(deviceToken), subscription); err != nil {
+		return errors.Wrap(err, "Failed to parse the push subscription")
+	}
+
+	body, err := encrypt(subscription, payload)
+	if err != nil {
+		return err
+	}
+
+	authorization, err := b.vapidAuthorization(subscription.Endpoint)
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequest(http.MethodPost, subscription.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "Failed to prepare the Web Push request")
+	}
+
+	httpReq.Header.Set("Authorization", authorization)
+	httpReq.Header.Set("Content-Encoding", "aes128gcm")
+	httpReq.Header.Set("Content-Type", "application/octet-stream")
+	httpReq.Header.Set("TTL", strconv.Itoa(int(b.config.TTL/time.Second)))
+
+	httpResp, err := b.httpClient.Do(httpReq)
+	if err != nil {
+		return errors.Wrap(err, "Failed to send the Web Push notification due to a network error")
+	}
+
+	defer httpResp.Body.Close()
+	responseBytes, err := io.ReadAll(io.LimitReader(httpResp.Body, maxResponseSize))
+	if err != nil {
+		return errors.Wrap(err, "Failed to read the Web Push response due to a network error")
+	}
+
+	if httpResp.StatusCode >= 200 && httpResp.StatusCode < 300 {
+		return nil
+	}
+
+	// push services don't agree on an error format, so the body is kept as is
+	apiError := &pushnotifications.APIError{
+		StatusCode:  httpResp.StatusCode,
+		Code:        http.StatusText(httpResp.StatusCode),
+		Description: strings.TrimSpace(string(responseBytes)),
+		Body:        responseBytes,
+	}
+	// the subscription expired or was cancelled, and won't work again
+	if httpResp.StatusCode == http.StatusNotFound || httpResp.StatusCode == http.StatusGone {
+		apiError.Code = "Unregistered"
+	}
+	return errors.Wrap(apiError, "Failed to send the Web Push notification")
+}
+
+// Returns the VAPID `Authorization` header for a request to the push service at `endpoint`
+func (b *backend) vapidAuthorization(endpoint string) (string, error) {
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to parse the push subscription endpoint")
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"aud": endpointURL.Scheme + "://" + endpointURL.Host,
+		"exp": time.Now().Add(vapidTokenTTL).Unix(),
+		"sub": b.config.Subject,
+	})
+	tokenString, err := token.SignedString(b.signingKey)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to sign the VAPID token")
+	}
+
+	return fmt.Sprintf("vapid t=%s, k=%s", tokenString, b.publicKey), nil
+}
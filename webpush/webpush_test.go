@@ -0,0 +1,160 @@
+package webpush
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/pkg/errors"
+	pushnotifications "github.com/pusher/push-notifications-go"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// Decrypts an aes128gcm body the way a browser would
+func decrypt(body, userAgentPrivate, userAgentPublic, authSecret []byte) ([]byte, error) {
+	curve := elliptic.P256()
+	salt := body[:16]
+	keyIdLength := int(body[20])
+	serverPublic := body[21 : 21+keyIdLength]
+	ciphertext := body[21+keyIdLength:]
+	if binary.BigEndian.Uint32(body[16:20]) != recordSize {
+		return nil, errors.New("unexpected record size")
+	}
+
+	x, y := elliptic.Unmarshal(curve, serverPublic)
+	sharedX, _ := curve.ScalarMult(x, y, userAgentPrivate)
+	sharedSecret := make([]byte, 32)
+	copy(sharedSecret[32-len(sharedX.Bytes()):], sharedX.Bytes())
+
+	keyInfo := append([]byte("WebPush: info\x00"), userAgentPublic...)
+	keyInfo = append(keyInfo, serverPublic...)
+	inputKey := hkdf(authSecret, sharedSecret, keyInfo, 32)
+	contentKey := hkdf(salt, inputKey, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	nonce := hkdf(salt, inputKey, []byte("Content-Encoding: nonce\x00"), 12)
+
+	block, _ := aes.NewCipher(contentKey)
+	gcm, _ := cipher.NewGCM(block)
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, err
+	}
+	return plaintext[:len(plaintext)-1], nil
+}
+
+func TestWebPush(t *testing.T) {
+	Convey("A Web Push backend", t, func() {
+		vapidKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		vapidDER, _ := x509.MarshalECPrivateKey(vapidKey)
+		config := Config{
+			PrivateKey: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: vapidDER}),
+			Subject:    "mailto:push@example.com",
+		}
+
+		Convey("should not be created without a subject", func() {
+			config.Subject = ""
+			b, err := New(config)
+			So(b, ShouldBeNil)
+			So(err.Error(), ShouldContainSubstring, "Subject cannot be an empty string")
+		})
+
+		Convey("given a push service, it", func() {
+			var lastRequest *http.Request
+			var lastBody []byte
+			testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				lastRequest = r
+				lastBody, _ = io.ReadAll(r.Body)
+				if r.URL.Path == "/push/expired" {
+					w.WriteHeader(http.StatusGone)
+					w.Write([]byte("push subscription has unsubscribed or expired.\n"))
+					return
+				}
+				w.WriteHeader(http.StatusCreated)
+			}))
+			defer testServer.Close()
+
+			userAgentPrivate, userAgentX, userAgentY, _ := elliptic.GenerateKey(elliptic.P256(), rand.Reader)
+			userAgentPublic := elliptic.Marshal(elliptic.P256(), userAgentX, userAgentY)
+			authSecret := []byte("0123456789abcdef")
+			subscription := func(path string) string {
+				s := Subscription{Endpoint: testServer.URL + path}
+				s.Keys.P256dh = base64.RawURLEncoding.EncodeToString(userAgentPublic)
+				s.Keys.Auth = base64.URLEncoding.EncodeToString(authSecret)
+				subscriptionBytes, _ := json.Marshal(s)
+				return string(subscriptionBytes)
+			}
+
+			b, err := New(config)
+			So(err, ShouldBeNil)
+			So(b.Name(), ShouldEqual, "web")
+
+			request := map[string]interface{}{
+				"web": map[string]interface{}{
+					"notification": map[string]interface{}{"title": "Hello", "body": "Hello, world"},
+				},
+			}
+
+			Convey("should send the encrypted web section", func() {
+				result, err := b.Send([]string{subscription("/push/1")}, request)
+				So(err, ShouldBeNil)
+				So(len(result.Sent), ShouldEqual, 1)
+				So(lastRequest.Header.Get("Content-Encoding"), ShouldEqual, "aes128gcm")
+				So(lastRequest.Header.Get("TTL"), ShouldEqual, "2419200")
+
+				payload, err := decrypt(lastBody, userAgentPrivate, userAgentPublic, authSecret)
+				So(err, ShouldBeNil)
+				So(string(payload), ShouldEqual, `{"notification":{"body":"Hello, world","title":"Hello"}}`)
+			})
+
+			Convey("should sign requests with VAPID", func() {
+				b.Send([]string{subscription("/push/1")}, request)
+
+				authorization := lastRequest.Header.Get("Authorization")
+				So(authorization, ShouldStartWith, "vapid t=")
+				parts := strings.Split(strings.TrimPrefix(authorization, "vapid t="), ", k=")
+				So(len(parts), ShouldEqual, 2)
+				So(parts[1], ShouldEqual, base64.RawURLEncoding.EncodeToString(
+					elliptic.Marshal(elliptic.P256(), vapidKey.X, vapidKey.Y)))
+
+				token, err := jwt.Parse(parts[0], func(token *jwt.Token) (interface{}, error) {
+					return &vapidKey.PublicKey, nil
+				})
+				So(err, ShouldBeNil)
+				So(token.Claims.(jwt.MapClaims)["aud"], ShouldEqual, testServer.URL)
+				So(token.Claims.(jwt.MapClaims)["sub"], ShouldEqual, "mailto:push@example.com")
+			})
+
+			Convey("should report expired subscriptions", func() {
+				expired := subscription("/push/expired")
+				result, err := b.Send([]string{subscription("/push/1"), expired}, request)
+				So(err, ShouldBeNil)
+				So(len(result.Sent), ShouldEqual, 1)
+
+				apiError, ok := errors.Cause(result.Failed[expired]).(*pushnotifications.APIError)
+				So(ok, ShouldBeTrue)
+				So(apiError.StatusCode, ShouldEqual, http.StatusGone)
+				So(apiError.Code, ShouldEqual, "Unregistered")
+				So(pushnotifications.IsInvalidDeviceToken(result.Failed[expired]), ShouldBeTrue)
+				So(apiError.Description, ShouldEqual, "push subscription has unsubscribed or expired.")
+			})
+
+			Convey("should report subscriptions that can't be parsed", func() {
+				result, err := b.Send([]string{"not json"}, request)
+				So(err, ShouldBeNil)
+				So(result.Failed["not json"].Error(), ShouldContainSubstring, "Failed to parse the push subscription")
+			})
+		})
+	})
+}